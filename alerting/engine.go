@@ -0,0 +1,346 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	// DefaultInterval is how often the engine checks whether firing rules
+	// have resolved.
+	DefaultInterval = 10 * time.Second
+
+	queueSize = 1024
+)
+
+// Store persists rules and alert history.
+type Store interface {
+	ListRules() ([]Rule, error)
+	GetRule(id int) (Rule, error)
+	CreateRule(r Rule) (Rule, error)
+	UpdateRule(r Rule) (Rule, error)
+	DeleteRule(id int) error
+	InsertAlert(a Alert) (int, error)
+	ResolveAlert(id, count int, at time.Time) error
+	ListAlerts(filter AlertFilter) ([]Alert, error)
+}
+
+// Event is passed to notifiers when an alert fires or resolves.
+type Event struct {
+	Rule  Rule  `json:"rule"`
+	Alert Alert `json:"alert"`
+	// Log is the entry that made the rule fire; nil on resolution.
+	Log *models.Log `json:"log,omitempty"`
+}
+
+// Notifier is told about alert state changes. Notify is called from the
+// engine's goroutine, so implementations that do I/O should queue the
+// event and return.
+type Notifier interface {
+	Notify(ev Event)
+}
+
+// Engine evaluates the enabled rules against observed log entries.
+type Engine struct {
+	store    Store
+	interval time.Duration
+	now      func() time.Time
+	queue    chan models.Log
+
+	notifiersMu sync.RWMutex
+	notifiers   []Notifier
+
+	mu    sync.Mutex
+	rules map[int]*ruleState
+}
+
+type ruleState struct {
+	rule    Rule
+	filter  models.LogFilter
+	pattern *regexp.Regexp
+
+	// hits are the times of recent matches, oldest first. Only Threshold+1
+	// are needed to decide whether the rule fires.
+	hits  []time.Time
+	alert *Alert
+	// hold keeps an alert restored at startup firing for one window, since
+	// the matches that raised it are not known.
+	hold time.Time
+}
+
+// NewEngine creates an Engine backed by store. Call Load before Run.
+func NewEngine(store Store) *Engine {
+	return &Engine{
+		store:    store,
+		interval: DefaultInterval,
+		now:      time.Now,
+		queue:    make(chan models.Log, queueSize),
+		rules:    make(map[int]*ruleState),
+	}
+}
+
+// AddNotifier registers n to receive alert events.
+func (e *Engine) AddNotifier(n Notifier) {
+	e.notifiersMu.Lock()
+	e.notifiers = append(e.notifiers, n)
+	e.notifiersMu.Unlock()
+}
+
+// Seed creates the rules whose names are not in the store yet, so rules
+// from the configuration file can later be edited through the API.
+func (e *Engine) Seed(rules []Rule) error {
+	existing, err := e.store.ListRules()
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if slices.ContainsFunc(existing, func(x Rule) bool { return x.Name == r.Name }) {
+			continue
+		}
+		if _, err := e.store.CreateRule(r); err != nil {
+			return fmt.Errorf("creating rule %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Load reads the rules and still-firing alerts from the store. Alerts whose
+// rule is gone or disabled are resolved.
+func (e *Engine) Load() error {
+	if err := e.reload(); err != nil {
+		return err
+	}
+	open, err := e.store.ListAlerts(AlertFilter{State: StateFiring})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	for i := range open {
+		a := open[i]
+		st, ok := e.rules[a.RuleID]
+		if !ok || st.alert != nil {
+			e.resolveAlert(nil, &a, now)
+			continue
+		}
+		st.alert = &a
+		st.hold = now.Add(time.Duration(st.rule.Window))
+	}
+	return nil
+}
+
+// Observe queues a log entry for evaluation. It never blocks; entries are
+// dropped with a warning if the engine falls behind.
+func (e *Engine) Observe(l models.Log) {
+	select {
+	case e.queue <- l:
+	default:
+		log.Printf("Alerting queue full, dropping log %d", l.ID)
+	}
+}
+
+// Run evaluates queued entries and resolves quiet alerts until ctx is done.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case l := <-e.queue:
+			e.process(l)
+		case <-ticker.C:
+			e.evaluate()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Engine) process(l models.Log) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for _, st := range e.rules {
+		if !st.filter.Matches(l) || (st.pattern != nil && !st.pattern.MatchString(l.Message)) {
+			continue
+		}
+		st.trim(now)
+		st.hits = append(st.hits, now)
+		if len(st.hits) > st.rule.Threshold+1 {
+			st.hits = st.hits[1:]
+		}
+
+		if st.alert != nil {
+			st.alert.Count++
+			continue
+		}
+		if len(st.hits) > st.rule.Threshold {
+			e.fire(st, l, now)
+		}
+	}
+}
+
+// evaluate resolves firing rules whose match count has dropped to the
+// threshold.
+func (e *Engine) evaluate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for _, st := range e.rules {
+		st.trim(now)
+		if st.alert != nil && len(st.hits) <= st.rule.Threshold && !now.Before(st.hold) {
+			e.resolveAlert(st, st.alert, now)
+			st.alert = nil
+		}
+	}
+}
+
+func (st *ruleState) trim(now time.Time) {
+	cutoff := now.Add(-time.Duration(st.rule.Window))
+	i := 0
+	for i < len(st.hits) && !st.hits[i].After(cutoff) {
+		i++
+	}
+	st.hits = st.hits[i:]
+}
+
+func (e *Engine) fire(st *ruleState, l models.Log, now time.Time) {
+	a := Alert{
+		RuleID:   st.rule.ID,
+		RuleName: st.rule.Name,
+		State:    StateFiring,
+		Count:    len(st.hits),
+		Message:  l.Message,
+		LogID:    l.ID,
+		FiredAt:  now,
+	}
+	id, err := e.store.InsertAlert(a)
+	if err != nil {
+		log.Printf("Error recording alert for rule %q: %v", st.rule.Name, err)
+	}
+	a.ID = id
+	st.alert = &a
+
+	log.Printf("Alert %q firing: %s", st.rule.Name, l.Message)
+	e.notify(Event{Rule: st.rule, Alert: a, Log: &l})
+}
+
+// resolveAlert marks a as resolved. st is nil for alerts whose rule is no
+// longer active.
+func (e *Engine) resolveAlert(st *ruleState, a *Alert, now time.Time) {
+	a.State = StateResolved
+	a.ResolvedAt = &now
+	if a.ID != 0 {
+		if err := e.store.ResolveAlert(a.ID, a.Count, now); err != nil {
+			log.Printf("Error resolving alert %d: %v", a.ID, err)
+		}
+	}
+	log.Printf("Alert %q resolved after %d matches", a.RuleName, a.Count)
+	if st != nil {
+		e.notify(Event{Rule: st.rule, Alert: *a})
+	}
+}
+
+func (e *Engine) notify(ev Event) {
+	e.notifiersMu.RLock()
+	defer e.notifiersMu.RUnlock()
+	for _, n := range e.notifiers {
+		n.Notify(ev)
+	}
+}
+
+// reload replaces the active rules with the enabled rules in the store.
+// Rules whose definition is unchanged keep their state; alerts of removed
+// or changed rules are resolved.
+func (e *Engine) reload() error {
+	rules, err := e.store.ListRules()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	next := make(map[int]*ruleState, len(rules))
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		if st, ok := e.rules[r.ID]; ok && st.rule.UpdatedAt.Equal(r.UpdatedAt) {
+			next[r.ID] = st
+			continue
+		}
+		pattern, err := r.compile()
+		if err != nil {
+			log.Printf("Skipping alert rule %q: %v", r.Name, err)
+			continue
+		}
+		next[r.ID] = &ruleState{rule: r, filter: r.Filter(), pattern: pattern}
+	}
+	for id, st := range e.rules {
+		if next[id] != st && st.alert != nil {
+			e.resolveAlert(st, st.alert, now)
+			st.alert = nil
+		}
+	}
+	e.rules = next
+	return nil
+}
+
+// Rules returns all rules, including disabled ones.
+func (e *Engine) Rules() ([]Rule, error) {
+	return e.store.ListRules()
+}
+
+// Rule returns the rule with the given id.
+func (e *Engine) Rule(id int) (Rule, error) {
+	return e.store.GetRule(id)
+}
+
+// CreateRule validates and stores a new rule and starts evaluating it.
+func (e *Engine) CreateRule(r Rule) (Rule, error) {
+	if err := r.Validate(); err != nil {
+		return Rule{}, err
+	}
+	created, err := e.store.CreateRule(r)
+	if err != nil {
+		return Rule{}, err
+	}
+	return created, e.reload()
+}
+
+// UpdateRule validates and replaces an existing rule. A changed rule starts
+// counting from zero.
+func (e *Engine) UpdateRule(r Rule) (Rule, error) {
+	if err := r.Validate(); err != nil {
+		return Rule{}, err
+	}
+	updated, err := e.store.UpdateRule(r)
+	if err != nil {
+		return Rule{}, err
+	}
+	return updated, e.reload()
+}
+
+// DeleteRule removes a rule, resolving its alert if it is firing.
+func (e *Engine) DeleteRule(id int) error {
+	if err := e.store.DeleteRule(id); err != nil {
+		return err
+	}
+	return e.reload()
+}
+
+// Alerts returns alert history entries matching filter.
+func (e *Engine) Alerts(filter AlertFilter) ([]Alert, error) {
+	return e.store.ListAlerts(filter)
+}
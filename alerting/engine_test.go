@@ -0,0 +1,210 @@
+package alerting
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Notify(ev Event) {
+	r.mu.Lock()
+	r.events = append(r.events, ev)
+	r.mu.Unlock()
+}
+
+type testEngine struct {
+	*Engine
+	store  *MemoryStore
+	events *recorder
+	clock  time.Time
+}
+
+func newTestEngine(t *testing.T, store *MemoryStore) *testEngine {
+	t.Helper()
+	te := &testEngine{
+		store:  store,
+		events: &recorder{},
+		clock:  time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+	}
+	te.Engine = NewEngine(store)
+	te.now = func() time.Time { return te.clock }
+	te.AddNotifier(te.events)
+	if err := te.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	return te
+}
+
+func (te *testEngine) advance(d time.Duration) {
+	te.clock = te.clock.Add(d)
+}
+
+func TestThresholdRule(t *testing.T) {
+	te := newTestEngine(t, NewMemoryStore())
+	rule := NewRule()
+	rule.Name = "database errors"
+	rule.Levels = []string{models.LevelError}
+	rule.Types = []string{models.TypeDatabase}
+	rule.Threshold = 2
+	if _, err := te.CreateRule(rule); err != nil {
+		t.Fatalf("CreateRule() error: %v", err)
+	}
+
+	dbError := models.Log{ID: 1, Level: models.LevelError, Type: models.TypeDatabase, Message: "connection refused"}
+	te.process(dbError)
+	te.advance(time.Minute)
+	te.process(dbError)
+	te.process(models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "other type"})
+	if len(te.events.events) != 0 {
+		t.Fatalf("rule fired at the threshold: %+v", te.events.events)
+	}
+
+	te.process(dbError)
+	te.process(dbError)
+	if len(te.events.events) != 1 {
+		t.Fatalf("got %d events, want one firing event despite repeated matches", len(te.events.events))
+	}
+	fired := te.events.events[0]
+	if fired.Alert.State != StateFiring || fired.Alert.Count != 3 || fired.Log == nil || fired.Log.ID != 1 {
+		t.Errorf("firing event = %+v", fired)
+	}
+
+	// The first match leaves the window, but three remain.
+	te.advance(4*time.Minute + time.Second)
+	te.evaluate()
+	if len(te.events.events) != 1 {
+		t.Fatalf("rule resolved while still above the threshold")
+	}
+
+	te.advance(2 * time.Minute)
+	te.evaluate()
+	if len(te.events.events) != 2 || te.events.events[1].Alert.State != StateResolved {
+		t.Fatalf("events = %+v, want a resolution", te.events.events)
+	}
+
+	history, _ := te.Alerts(AlertFilter{})
+	if len(history) != 1 || history[0].State != StateResolved || history[0].Count != 4 || history[0].ResolvedAt == nil {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestPatternRule(t *testing.T) {
+	te := newTestEngine(t, NewMemoryStore())
+	rule := NewRule()
+	rule.Name = "panics"
+	rule.Pattern = `panic: \w+`
+	rule.Window = models.Duration(time.Minute)
+	if _, err := te.CreateRule(rule); err != nil {
+		t.Fatalf("CreateRule() error: %v", err)
+	}
+
+	te.process(models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "all good"})
+	te.process(models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "PANIC: nil map"})
+	if len(te.events.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(te.events.events))
+	}
+
+	te.advance(time.Minute)
+	te.evaluate()
+	if len(te.events.events) != 2 {
+		t.Fatalf("pattern rule did not resolve after a quiet window")
+	}
+
+	te.process(models.Log{Message: "panic: again"})
+	if len(te.events.events) != 3 {
+		t.Fatalf("rule did not fire again after resolving")
+	}
+	if history, _ := te.Alerts(AlertFilter{State: StateFiring}); len(history) != 1 {
+		t.Errorf("firing alerts = %+v, want 1", history)
+	}
+}
+
+func TestRuleChangesResolveAlerts(t *testing.T) {
+	te := newTestEngine(t, NewMemoryStore())
+	rule := NewRule()
+	rule.Name = "any error"
+	rule.Levels = []string{models.LevelError}
+	created, err := te.CreateRule(rule)
+	if err != nil {
+		t.Fatalf("CreateRule() error: %v", err)
+	}
+	te.process(models.Log{Level: models.LevelError, Message: "boom"})
+
+	created.Enabled = false
+	if _, err := te.UpdateRule(created); err != nil {
+		t.Fatalf("UpdateRule() error: %v", err)
+	}
+	if len(te.events.events) != 2 || te.events.events[1].Alert.State != StateResolved {
+		t.Fatalf("disabling a firing rule should resolve it: %+v", te.events.events)
+	}
+
+	te.process(models.Log{Level: models.LevelError, Message: "boom"})
+	if len(te.events.events) != 2 {
+		t.Error("disabled rule fired")
+	}
+
+	if err := te.DeleteRule(created.ID); err != nil {
+		t.Fatalf("DeleteRule() error: %v", err)
+	}
+	if _, err := te.Rule(created.ID); err != ErrNotFound {
+		t.Errorf("Rule() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLoadRestoresFiringAlerts(t *testing.T) {
+	store := NewMemoryStore()
+	first := newTestEngine(t, store)
+	rule := NewRule()
+	rule.Name = "errors"
+	rule.Levels = []string{models.LevelError}
+	if _, err := first.CreateRule(rule); err != nil {
+		t.Fatalf("CreateRule() error: %v", err)
+	}
+	first.process(models.Log{Level: models.LevelError, Message: "boom"})
+
+	// A restarted engine must not fire a duplicate alert, and keeps the
+	// restored alert open for a window.
+	second := newTestEngine(t, store)
+	second.process(models.Log{Level: models.LevelError, Message: "boom"})
+	second.evaluate()
+	if len(second.events.events) != 0 {
+		t.Fatalf("restored alert fired or resolved early: %+v", second.events.events)
+	}
+
+	second.advance(DefaultWindow)
+	second.evaluate()
+	history, _ := second.Alerts(AlertFilter{})
+	if len(history) != 1 || history[0].State != StateResolved || history[0].Count != 2 {
+		t.Errorf("history = %+v, want one resolved alert with both matches", history)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	te := newTestEngine(t, NewMemoryStore())
+	existing := NewRule()
+	existing.Name = "errors"
+	existing.Threshold = 5
+	if _, err := te.CreateRule(existing); err != nil {
+		t.Fatalf("CreateRule() error: %v", err)
+	}
+
+	seeded := NewRule()
+	seeded.Name = "errors"
+	other := NewRule()
+	other.Name = "panics"
+	if err := te.Seed([]Rule{seeded, other}); err != nil {
+		t.Fatalf("Seed() error: %v", err)
+	}
+
+	rules, _ := te.Rules()
+	if len(rules) != 2 || rules[0].Threshold != 5 || rules[1].Name != "panics" {
+		t.Errorf("rules = %+v, want the existing rule kept and one added", rules)
+	}
+}
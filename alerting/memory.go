@@ -0,0 +1,151 @@
+package alerting
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps rules and alert history in memory. It backs the memory
+// driver; everything is lost on restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	rules     []Rule
+	alerts    []Alert
+	lastRule  int
+	lastAlert int
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// ListRules returns all rules ordered by id.
+func (s *MemoryStore) ListRules() ([]Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := make([]Rule, len(s.rules))
+	for i, r := range s.rules {
+		rules[i] = cloneRule(r)
+	}
+	return rules, nil
+}
+
+// GetRule returns the rule with the given id.
+func (s *MemoryStore) GetRule(id int) (Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(id)
+	if i < 0 {
+		return Rule{}, ErrNotFound
+	}
+	return cloneRule(s.rules[i]), nil
+}
+
+// CreateRule stores r under a new id.
+func (s *MemoryStore) CreateRule(r Rule) (Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nameTaken(r.Name, 0) {
+		return Rule{}, ErrNameTaken
+	}
+	s.lastRule++
+	r.ID = s.lastRule
+	r.CreatedAt = time.Now().UTC()
+	r.UpdatedAt = r.CreatedAt
+	r = cloneRule(r)
+	s.rules = append(s.rules, r)
+	return cloneRule(r), nil
+}
+
+// UpdateRule replaces the rule with r.ID.
+func (s *MemoryStore) UpdateRule(r Rule) (Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(r.ID)
+	if i < 0 {
+		return Rule{}, ErrNotFound
+	}
+	if s.nameTaken(r.Name, r.ID) {
+		return Rule{}, ErrNameTaken
+	}
+	r.CreatedAt = s.rules[i].CreatedAt
+	r.UpdatedAt = time.Now().UTC()
+	s.rules[i] = cloneRule(r)
+	return cloneRule(r), nil
+}
+
+// DeleteRule removes the rule with the given id. Its history is kept.
+func (s *MemoryStore) DeleteRule(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.rules = slices.Delete(s.rules, i, i+1)
+	for j := range s.alerts {
+		if s.alerts[j].RuleID == id {
+			s.alerts[j].RuleID = 0
+		}
+	}
+	return nil
+}
+
+// InsertAlert appends an alert to the history and returns its id.
+func (s *MemoryStore) InsertAlert(a Alert) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAlert++
+	a.ID = s.lastAlert
+	s.alerts = append(s.alerts, a)
+	return a.ID, nil
+}
+
+// ResolveAlert marks an alert as resolved with its final count.
+func (s *MemoryStore) ResolveAlert(id, count int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.alerts {
+		if s.alerts[i].ID == id {
+			s.alerts[i].State = StateResolved
+			s.alerts[i].Count = count
+			s.alerts[i].ResolvedAt = &at
+			return nil
+		}
+	}
+	return nil
+}
+
+// ListAlerts returns matching alerts, newest first.
+func (s *MemoryStore) ListAlerts(filter AlertFilter) ([]Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var alerts []Alert
+	for i := len(s.alerts) - 1; i >= 0 && len(alerts) < filter.PageSize(); i-- {
+		a := s.alerts[i]
+		if (filter.RuleID != 0 && a.RuleID != filter.RuleID) || (filter.State != "" && a.State != filter.State) {
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+func (s *MemoryStore) index(id int) int {
+	return slices.IndexFunc(s.rules, func(r Rule) bool { return r.ID == id })
+}
+
+func (s *MemoryStore) nameTaken(name string, except int) bool {
+	return slices.ContainsFunc(s.rules, func(r Rule) bool { return r.Name == name && r.ID != except })
+}
+
+// cloneRule copies the slices and map so callers cannot modify stored rules.
+func cloneRule(r Rule) Rule {
+	r.Levels = slices.Clone(r.Levels)
+	r.Types = slices.Clone(r.Types)
+	r.Attributes = maps.Clone(r.Attributes)
+	return r
+}
@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRules(t *testing.T) {
+	s := NewMemoryStore()
+	r := NewRule()
+	r.Name = "errors"
+	r.Levels = []string{"ERROR"}
+	created, err := s.CreateRule(r)
+	if err != nil || created.ID != 1 || created.CreatedAt.IsZero() {
+		t.Fatalf("CreateRule() = %+v, %v", created, err)
+	}
+	if _, err := s.CreateRule(r); err != ErrNameTaken {
+		t.Errorf("duplicate CreateRule() error = %v, want ErrNameTaken", err)
+	}
+
+	// Callers must not be able to change stored rules through shared slices.
+	created.Levels[0] = "DEBUG"
+	got, _ := s.GetRule(created.ID)
+	if got.Levels[0] != "ERROR" {
+		t.Errorf("stored rule modified through returned copy: %+v", got)
+	}
+
+	got.Threshold = 3
+	updated, err := s.UpdateRule(got)
+	if err != nil || updated.Threshold != 3 || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("UpdateRule() = %+v, %v", updated, err)
+	}
+	if _, err := s.UpdateRule(Rule{ID: 99, Name: "x"}); err != ErrNotFound {
+		t.Errorf("UpdateRule() of unknown id error = %v", err)
+	}
+}
+
+func TestMemoryStoreAlerts(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		s.InsertAlert(Alert{RuleID: i % 2, RuleName: "r", State: StateFiring, FiredAt: now})
+	}
+	if err := s.ResolveAlert(1, 7, now); err != nil {
+		t.Fatalf("ResolveAlert() error: %v", err)
+	}
+
+	firing, _ := s.ListAlerts(AlertFilter{State: StateFiring})
+	if len(firing) != 2 || firing[0].ID != 3 {
+		t.Errorf("firing alerts = %+v, want ids 3, 2", firing)
+	}
+	limited, _ := s.ListAlerts(AlertFilter{Limit: 1})
+	if len(limited) != 1 || limited[0].ID != 3 {
+		t.Errorf("limited alerts = %+v", limited)
+	}
+
+	if err := s.DeleteRule(1); err != ErrNotFound {
+		t.Errorf("DeleteRule() of unknown id error = %v", err)
+	}
+	all, _ := s.ListAlerts(AlertFilter{RuleID: 1})
+	if len(all) != 2 || all[1].Count != 7 || all[1].State != StateResolved {
+		t.Errorf("alerts for rule 1 = %+v", all)
+	}
+}
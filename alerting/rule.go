@@ -0,0 +1,167 @@
+// Package alerting evaluates alert rules against the live log stream and
+// records when they fire and resolve.
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Alert states.
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+const (
+	// DefaultWindow is used for rules that do not set a window.
+	DefaultWindow = 5 * time.Minute
+
+	// MaxWindow bounds how far back a rule may count matches.
+	MaxWindow = 24 * time.Hour
+
+	// MaxNameLength matches the width of the name columns.
+	MaxNameLength = 100
+)
+
+var (
+	// ErrNotFound is returned for a rule id that does not exist.
+	ErrNotFound = errors.New("alert rule not found")
+
+	// ErrNameTaken is returned when another rule already has the name.
+	ErrNameTaken = errors.New("alert rule name already in use")
+)
+
+// Rule fires when more than Threshold log entries matching its filter arrive
+// within Window, and resolves once the count drops back to Threshold or
+// below. A Threshold of 0 fires on any single match, e.g. a pattern rule.
+type Rule struct {
+	ID         int               `json:"id"`
+	Name       string            `json:"name"`
+	Levels     []string          `json:"levels,omitempty"`
+	Types      []string          `json:"types,omitempty"`
+	MinLevel   string            `json:"min_level,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Pattern is a case-insensitive regular expression matched against the
+	// message, like search mode regex.
+	Pattern   string          `json:"pattern,omitempty"`
+	Threshold int             `json:"threshold"`
+	Window    models.Duration `json:"window"`
+	Enabled   bool            `json:"enabled"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// NewRule returns a rule with the defaults applied, ready to be decoded into.
+func NewRule() Rule {
+	return Rule{Window: models.Duration(DefaultWindow), Enabled: true}
+}
+
+// Validate checks the rule against the current registry.
+func (r Rule) Validate() error {
+	if r.Name == "" || len(r.Name) > MaxNameLength {
+		return fmt.Errorf("name must be 1-%d characters", MaxNameLength)
+	}
+	for _, l := range r.Levels {
+		if !models.ValidLevel(l) {
+			return fmt.Errorf("invalid level %q", l)
+		}
+	}
+	for _, t := range r.Types {
+		if !models.ValidType(t) {
+			return fmt.Errorf("invalid type %q", t)
+		}
+	}
+	if r.MinLevel != "" && !models.ValidLevel(r.MinLevel) {
+		return fmt.Errorf("invalid min_level %q", r.MinLevel)
+	}
+	if _, err := r.compile(); err != nil {
+		return err
+	}
+	if r.Threshold < 0 {
+		return errors.New("threshold must not be negative")
+	}
+	if w := time.Duration(r.Window); w < time.Second || w > MaxWindow {
+		return fmt.Errorf("window must be between 1s and %s", MaxWindow)
+	}
+	return nil
+}
+
+// Filter returns the log filter selecting the entries the rule counts,
+// excluding the pattern.
+func (r Rule) Filter() models.LogFilter {
+	return models.LogFilter{
+		Levels:     r.Levels,
+		Types:      r.Types,
+		MinLevel:   r.MinLevel,
+		Attributes: r.Attributes,
+	}
+}
+
+func (r Rule) compile() (*regexp.Regexp, error) {
+	if r.Pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("(?i)" + r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// Alert is one firing of a rule, kept as history after it resolves.
+type Alert struct {
+	ID       int    `json:"id"`
+	RuleID   int    `json:"rule_id,omitempty"`
+	RuleName string `json:"rule_name"`
+	State    string `json:"state"`
+	// Count is the number of matching entries seen while firing.
+	Count int `json:"count"`
+	// Message and LogID identify the entry that made the rule fire.
+	Message    string     `json:"message"`
+	LogID      int        `json:"log_id,omitempty"`
+	FiredAt    time.Time  `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// AlertFilter selects alert history entries, newest first.
+type AlertFilter struct {
+	RuleID int
+	State  string
+	Limit  int
+}
+
+// PageSize returns Limit bounded like models.LogFilter.PageSize.
+func (f AlertFilter) PageSize() int {
+	return models.LogFilter{Limit: f.Limit}.PageSize()
+}
+
+// LoadRules reads a JSON array of rules from path. Omitted fields take the
+// defaults of NewRule.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rules := make([]Rule, len(raw))
+	for i, msg := range raw {
+		rules[i] = NewRule()
+		if err := json.Unmarshal(msg, &rules[i]); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+		if err := rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %w", path, rules[i].Name, err)
+		}
+	}
+	return rules, nil
+}
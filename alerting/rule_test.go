@@ -0,0 +1,71 @@
+package alerting
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestRuleValidate(t *testing.T) {
+	valid := NewRule()
+	valid.Name = "errors"
+	valid.Levels = []string{"ERROR"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *Rule)
+		want   string
+	}{
+		{"missing name", func(r *Rule) { r.Name = "" }, "name"},
+		{"unknown level", func(r *Rule) { r.Levels = []string{"SEVERE"} }, "invalid level"},
+		{"unknown type", func(r *Rule) { r.Types = []string{"QUEUE"} }, "invalid type"},
+		{"unknown min level", func(r *Rule) { r.MinLevel = "SEVERE" }, "invalid min_level"},
+		{"bad pattern", func(r *Rule) { r.Pattern = "(" }, "invalid pattern"},
+		{"negative threshold", func(r *Rule) { r.Threshold = -1 }, "threshold"},
+		{"zero window", func(r *Rule) { r.Window = 0 }, "window"},
+		{"huge window", func(r *Rule) { r.Window = models.Duration(48 * time.Hour) }, "window"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := valid
+			tc.modify(&r)
+			if err := r.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`[
+		{"name": "database errors", "levels": ["ERROR"], "types": ["DATABASE"], "threshold": 20},
+		{"name": "panics", "pattern": "panic", "window": "1m", "enabled": false}
+	]`), 0o644)
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("LoadRules() error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("LoadRules() = %+v", rules)
+	}
+	if !rules[0].Enabled || time.Duration(rules[0].Window) != DefaultWindow || rules[0].Threshold != 20 {
+		t.Errorf("first rule = %+v, want defaults applied", rules[0])
+	}
+	if rules[1].Enabled || time.Duration(rules[1].Window) != time.Minute {
+		t.Errorf("second rule = %+v", rules[1])
+	}
+
+	os.WriteFile(path, []byte(`[{"name": "bad", "levels": ["SEVERE"]}]`), 0o644)
+	if _, err := LoadRules(path); err == nil || !strings.Contains(err.Error(), `rule "bad"`) {
+		t.Errorf("LoadRules() error = %v, want it to name the invalid rule", err)
+	}
+}
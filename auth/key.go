@@ -0,0 +1,184 @@
+// Package auth implements scoped API keys and the HTTP middleware that
+// checks them.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Scopes grant access to groups of routes. ScopeAdmin implies the others.
+const (
+	ScopeIngest = "ingest"
+	ScopeRead   = "read"
+	ScopeAdmin  = "admin"
+)
+
+// Scopes lists the valid scopes.
+var Scopes = []string{ScopeIngest, ScopeRead, ScopeAdmin}
+
+const (
+	tokenPrefix = "golog_"
+	// PrefixLength is the number of leading token characters stored in the
+	// clear so that keys and failed attempts can be told apart.
+	PrefixLength = len(tokenPrefix) + 8
+
+	// MaxNameLength bounds key names.
+	MaxNameLength = 100
+)
+
+// ErrNotFound is returned for unknown key ids and tokens.
+var ErrNotFound = errors.New("api key not found")
+
+// Key is a stored API key. Only the SHA-256 hash of the token is kept.
+type Key struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// Tenant confines the key to one tenant's logs: entries it ingests are
+	// stored under the tenant and it reads only that tenant's entries. Keys
+	// without a tenant see every tenant.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Failure is a rejected request.
+type Failure struct {
+	ID int `json:"id"`
+	// KeyPrefix identifies the presented key, if any, without storing it.
+	KeyPrefix  string    `json:"key_prefix"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Store persists keys and failed attempts.
+type Store interface {
+	CreateKey(k Key) (Key, error)
+	// KeyByHash returns the key with the given token hash, including
+	// revoked keys, or ErrNotFound.
+	KeyByHash(hash string) (Key, error)
+	ListKeys() ([]Key, error)
+	// RevokeKey marks a key revoked. Revoking a revoked key keeps the
+	// original time.
+	RevokeKey(id int) error
+	RecordFailure(f Failure) error
+	ListFailures(limit int) ([]Failure, error)
+}
+
+// NewKey creates a key with a random token. The token is returned only
+// here; the key stores its hash. An empty tenant creates a key that is not
+// tenant-scoped.
+func NewKey(name, tenant string, scopes []string) (Key, string, error) {
+	k := Key{Name: strings.TrimSpace(name), Scopes: scopes, Tenant: tenant}
+	if err := k.Validate(); err != nil {
+		return Key{}, "", err
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return Key{}, "", err
+	}
+	token := tokenPrefix + hex.EncodeToString(b)
+	k.Prefix = prefix(token)
+	k.Hash = HashToken(token)
+	return k, token, nil
+}
+
+// Validate checks the name, scopes and tenant.
+func (k Key) Validate() error {
+	if k.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(k.Name) > MaxNameLength {
+		return fmt.Errorf("name exceeds maximum length of %d characters", MaxNameLength)
+	}
+	if len(k.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, s := range k.Scopes {
+		if !slices.Contains(Scopes, s) {
+			return fmt.Errorf("invalid scope %q: must be one of %s", s, strings.Join(Scopes, ", "))
+		}
+	}
+	if err := models.ValidateTenant(k.Tenant); err != nil {
+		return err
+	}
+	// Admin keys manage every key, so a tenant-scoped one could simply
+	// create an unscoped key for itself.
+	if k.Tenant != "" && slices.Contains(k.Scopes, ScopeAdmin) {
+		return errors.New("tenant-scoped keys cannot have the admin scope")
+	}
+	return nil
+}
+
+// HashToken returns the hex SHA-256 hash stored for a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasScope reports whether the key grants scope.
+func (k Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// Revoked reports whether the key has been revoked.
+func (k Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// ParseScopes splits a comma-separated scope list.
+func ParseScopes(v string) ([]string, error) {
+	var scopes []string
+	for _, s := range strings.Split(v, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		if !slices.Contains(Scopes, s) {
+			return nil, fmt.Errorf("invalid scope %q: must be one of %s", s, strings.Join(Scopes, ", "))
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+// EnsureKey stores token as a key with the given name and scopes unless a
+// key with the same token exists. It lets a deployment configure a
+// bootstrap admin key.
+func EnsureKey(store Store, name, token string, scopes []string) error {
+	hash := HashToken(token)
+	if _, err := store.KeyByHash(hash); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	k := Key{Name: name, Prefix: prefix(token), Hash: hash, Scopes: scopes}
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	_, err := store.CreateKey(k)
+	return err
+}
+
+// prefix returns the part of a token that is safe to store.
+func prefix(token string) string {
+	if len(token) > PrefixLength {
+		return token[:PrefixLength]
+	}
+	return token
+}
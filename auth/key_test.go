@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewKey(t *testing.T) {
+	k, token, err := NewKey(" ci ", "", []string{ScopeIngest})
+	if err != nil {
+		t.Fatalf("NewKey() error: %v", err)
+	}
+	if !strings.HasPrefix(token, "golog_") || len(token) != len("golog_")+48 {
+		t.Errorf("token = %q", token)
+	}
+	if k.Name != "ci" || k.Prefix != token[:PrefixLength] || k.Hash != HashToken(token) || strings.Contains(k.Hash, token) {
+		t.Errorf("key = %+v", k)
+	}
+
+	_, other, _ := NewKey("ci", "", []string{ScopeIngest})
+	if other == token {
+		t.Error("NewKey() returned the same token twice")
+	}
+
+	if k, _, err := NewKey("team a", "team-a", []string{ScopeIngest, ScopeRead}); err != nil || k.Tenant != "team-a" {
+		t.Errorf("NewKey() with tenant = %+v, %v", k, err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tenant string
+		scopes []string
+		want   string
+	}{
+		{"", "", []string{ScopeRead}, "name"},
+		{"x", "", nil, "scope"},
+		{"x", "", []string{"write"}, "invalid scope"},
+		{strings.Repeat("x", MaxNameLength+1), "", []string{ScopeRead}, "maximum length"},
+		{"x", "Team A", []string{ScopeRead}, "invalid tenant"},
+		{"x", "team-a", []string{ScopeAdmin}, "admin scope"},
+	} {
+		if _, _, err := NewKey(tc.name, tc.tenant, tc.scopes); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("NewKey(%q, %v) error = %v, want it to mention %q", tc.name, tc.scopes, err, tc.want)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	ingest := Key{Scopes: []string{ScopeIngest}}
+	if !ingest.HasScope(ScopeIngest) || ingest.HasScope(ScopeRead) || ingest.HasScope(ScopeAdmin) {
+		t.Errorf("ingest key scopes wrong")
+	}
+	admin := Key{Scopes: []string{ScopeAdmin}}
+	if !admin.HasScope(ScopeIngest) || !admin.HasScope(ScopeRead) {
+		t.Errorf("admin key should imply all scopes")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes(" Read, ingest,,read")
+	if err != nil || strings.Join(scopes, ",") != "read,ingest" {
+		t.Errorf("ParseScopes() = %v, %v", scopes, err)
+	}
+	if _, err := ParseScopes("read,write"); err == nil {
+		t.Error("ParseScopes() accepted an unknown scope")
+	}
+}
+
+func TestEnsureKey(t *testing.T) {
+	s := NewMemoryStore()
+	for i := 0; i < 2; i++ {
+		if err := EnsureKey(s, "bootstrap", "golog_bootstrap_token", []string{ScopeAdmin}); err != nil {
+			t.Fatalf("EnsureKey() error: %v", err)
+		}
+	}
+	keys, _ := s.ListKeys()
+	if len(keys) != 1 || keys[0].Prefix != "golog_bootstra" || keys[0].Hash != HashToken("golog_bootstrap_token") {
+		t.Errorf("keys = %+v, want one bootstrap key", keys)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// maxMemoryFailures bounds the failures kept by MemoryStore; the oldest
+// are discarded first.
+const maxMemoryFailures = 1000
+
+// MemoryStore keeps keys and failed attempts in memory for the memory
+// driver; everything is lost on restart.
+type MemoryStore struct {
+	mu          sync.Mutex
+	keys        []Key
+	failures    []Failure
+	lastKey     int
+	lastFailure int
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// CreateKey stores k under a new id.
+func (s *MemoryStore) CreateKey(k Key) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastKey++
+	k.ID = s.lastKey
+	k.CreatedAt = time.Now().UTC()
+	k.Scopes = slices.Clone(k.Scopes)
+	s.keys = append(s.keys, k)
+	return cloneKey(k), nil
+}
+
+// KeyByHash returns the key with the given token hash.
+func (s *MemoryStore) KeyByHash(hash string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return cloneKey(k), nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
+
+// ListKeys returns all keys ordered by id.
+func (s *MemoryStore) ListKeys() ([]Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]Key, len(s.keys))
+	for i, k := range s.keys {
+		keys[i] = cloneKey(k)
+	}
+	return keys, nil
+}
+
+// RevokeKey marks the key with the given id revoked.
+func (s *MemoryStore) RevokeKey(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.keys {
+		if s.keys[i].ID == id {
+			if s.keys[i].RevokedAt == nil {
+				now := time.Now().UTC()
+				s.keys[i].RevokedAt = &now
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// RecordFailure stores f.
+func (s *MemoryStore) RecordFailure(f Failure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastFailure++
+	f.ID = s.lastFailure
+	s.failures = append(s.failures, f)
+	if len(s.failures) > maxMemoryFailures {
+		s.failures = s.failures[1:]
+	}
+	return nil
+}
+
+// ListFailures returns up to limit failures, newest first.
+func (s *MemoryStore) ListFailures(limit int) ([]Failure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limit = models.LogFilter{Limit: limit}.PageSize()
+	var out []Failure
+	for i := len(s.failures) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, s.failures[i])
+	}
+	return out, nil
+}
+
+func cloneKey(k Key) Key {
+	k.Scopes = slices.Clone(k.Scopes)
+	if k.RevokedAt != nil {
+		t := *k.RevokedAt
+		k.RevokedAt = &t
+	}
+	return k
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreKeys(t *testing.T) {
+	s := NewMemoryStore()
+	k, _, _ := NewKey("ci", "", []string{ScopeIngest})
+	created, err := s.CreateKey(k)
+	if err != nil || created.ID != 1 || created.CreatedAt.IsZero() {
+		t.Fatalf("CreateKey() = %+v, %v", created, err)
+	}
+
+	got, err := s.KeyByHash(k.Hash)
+	if err != nil || got.ID != 1 {
+		t.Fatalf("KeyByHash() = %+v, %v", got, err)
+	}
+	got.Scopes[0] = ScopeAdmin
+	if again, _ := s.KeyByHash(k.Hash); again.Scopes[0] != ScopeIngest {
+		t.Error("stored key modified through returned copy")
+	}
+	if _, err := s.KeyByHash("unknown"); err != ErrNotFound {
+		t.Errorf("KeyByHash() of unknown hash error = %v", err)
+	}
+
+	if err := s.RevokeKey(1); err != nil {
+		t.Fatalf("RevokeKey() error: %v", err)
+	}
+	first, _ := s.KeyByHash(k.Hash)
+	time.Sleep(time.Millisecond)
+	s.RevokeKey(1)
+	second, _ := s.KeyByHash(k.Hash)
+	if !first.Revoked() || !second.RevokedAt.Equal(*first.RevokedAt) {
+		t.Errorf("RevokeKey() = %v then %v, want the first time kept", first.RevokedAt, second.RevokedAt)
+	}
+	if err := s.RevokeKey(9); err != ErrNotFound {
+		t.Errorf("RevokeKey() of unknown id error = %v", err)
+	}
+}
+
+func TestMemoryStoreFailures(t *testing.T) {
+	s := NewMemoryStore()
+	for i := 0; i < maxMemoryFailures+5; i++ {
+		s.RecordFailure(Failure{Reason: "invalid API key"})
+	}
+	failures, _ := s.ListFailures(2)
+	if len(failures) != 2 || failures[0].ID != maxMemoryFailures+5 {
+		t.Errorf("ListFailures() = %+v", failures)
+	}
+	all, _ := s.ListFailures(maxMemoryFailures * 2)
+	if len(all) != 500 {
+		t.Errorf("ListFailures() returned %d, want the 500 page limit", len(all))
+	}
+	if len(s.failures) != maxMemoryFailures {
+		t.Errorf("kept %d failures, want %d", len(s.failures), maxMemoryFailures)
+	}
+}
@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheTTL is how long a looked-up key is reused before the store is asked
+// again. Keys revoked by another process, such as the CLI, stop working
+// within this time; keys revoked through the Authenticator stop at once.
+const cacheTTL = 10 * time.Second
+
+// Rejected requests are recorded in the background. failureQueueSize bounds
+// the ones waiting to be recorded, and each remote address gets at most
+// failuresPerAddress recorded per failureWindow; the rest are dropped and
+// counted, so that unauthenticated clients cannot flood the store.
+const (
+	failureQueueSize   = 1000
+	failuresPerAddress = 10
+	failureWindow      = time.Minute
+	maxFailureAddrs    = 10000
+)
+
+// HeaderAPIKey is an alternative to "Authorization: Bearer <key>".
+const HeaderAPIKey = "X-API-Key"
+
+// QueryAPIKey is the query parameter carrying the key on the paths passed
+// to QueryKey, for clients such as EventSource that cannot set headers.
+const QueryAPIKey = "api_key"
+
+type contextKey struct{}
+
+// Authenticator checks API keys against a Store.
+type Authenticator struct {
+	store Store
+	now   func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+	// recent counts the failures recorded per remote address since
+	// windowStart.
+	recent      map[string]int
+	windowStart time.Time
+
+	failures chan Failure
+	dropped  atomic.Int64
+	wg       sync.WaitGroup
+}
+
+type cachedKey struct {
+	key     Key
+	expires time.Time
+}
+
+// NewAuthenticator creates an Authenticator backed by store. Call Start to
+// begin recording rejected requests.
+func NewAuthenticator(store Store) *Authenticator {
+	return &Authenticator{
+		store:    store,
+		now:      time.Now,
+		cache:    make(map[string]cachedKey),
+		recent:   make(map[string]int),
+		failures: make(chan Failure, failureQueueSize),
+	}
+}
+
+// Start records rejected requests in the store until ctx is done. Queued
+// failures are still recorded then.
+func (a *Authenticator) Start(ctx context.Context) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		for {
+			select {
+			case f := <-a.failures:
+				a.record(f)
+			case <-ctx.Done():
+				for len(a.failures) > 0 {
+					a.record(<-a.failures)
+				}
+				if dropped := a.Dropped(); dropped > 0 {
+					log.Printf("Dropped %d failed authentications without recording them", dropped)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the goroutine started by Start has returned.
+func (a *Authenticator) Wait() {
+	a.wg.Wait()
+}
+
+// Dropped returns the number of rejected requests that were not recorded
+// because the queue was full or their address exceeded its limit.
+func (a *Authenticator) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// Require returns middleware that rejects requests without a valid,
+// unrevoked key granting scope: 401 when the key is missing or invalid, 403
+// when it lacks the scope. Rejections are recorded in the store in the
+// background, see Start. The key
+// is available to handlers through FromContext.
+func (a *Authenticator) Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFromRequest(r)
+			if token == "" {
+				a.reject(w, r, token, http.StatusUnauthorized, "missing API key")
+				return
+			}
+			key, err := a.lookup(token)
+			switch {
+			case errors.Is(err, ErrNotFound):
+				a.reject(w, r, token, http.StatusUnauthorized, "invalid API key")
+				return
+			case err != nil:
+				log.Printf("Error looking up API key: %v", err)
+				http.Error(w, "authentication unavailable", http.StatusServiceUnavailable)
+				return
+			case key.Revoked():
+				a.reject(w, r, token, http.StatusUnauthorized, "revoked API key")
+				return
+			case !key.HasScope(scope):
+				a.reject(w, r, token, http.StatusForbidden, "API key lacks the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, key)))
+		})
+	}
+}
+
+// FromContext returns the key that authenticated the request.
+func FromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(contextKey{}).(Key)
+	return k, ok
+}
+
+// TenantFromContext returns the tenant of the key that authenticated the
+// request, or "" when the request was not authenticated with a
+// tenant-scoped key.
+func TenantFromContext(ctx context.Context) string {
+	k, _ := FromContext(ctx)
+	return k.Tenant
+}
+
+// QueryKey returns middleware that removes the api_key query parameter from
+// every request, so that keys never reach the access log, and passes it on
+// as the X-API-Key header on paths only. Install it before the logger.
+func QueryKey(paths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if !q.Has(QueryAPIKey) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token := q.Get(QueryAPIKey)
+			q.Del(QueryAPIKey)
+			r = r.Clone(r.Context())
+			r.URL.RawQuery = q.Encode()
+			r.RequestURI = r.URL.RequestURI()
+			if slices.Contains(paths, r.URL.Path) && r.Header.Get("Authorization") == "" && r.Header.Get(HeaderAPIKey) == "" {
+				r.Header.Set(HeaderAPIKey, token)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tokenFromRequest reads the key from the Authorization or X-API-Key
+// header.
+func tokenFromRequest(r *http.Request) string {
+	if v := r.Header.Get("Authorization"); v != "" {
+		scheme, token, ok := strings.Cut(v, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.Header.Get(HeaderAPIKey)
+}
+
+func (a *Authenticator) lookup(token string) (Key, error) {
+	hash := HashToken(token)
+	now := a.now()
+
+	a.mu.Lock()
+	c, ok := a.cache[hash]
+	a.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.key, nil
+	}
+
+	key, err := a.store.KeyByHash(hash)
+	if err != nil {
+		return key, err
+	}
+	a.mu.Lock()
+	for h, c := range a.cache {
+		if !now.Before(c.expires) {
+			delete(a.cache, h)
+		}
+	}
+	a.cache[hash] = cachedKey{key: key, expires: now.Add(cacheTTL)}
+	a.mu.Unlock()
+	return key, nil
+}
+
+func (a *Authenticator) reject(w http.ResponseWriter, r *http.Request, token string, status int, reason string) {
+	f := Failure{
+		KeyPrefix:  prefix(token),
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Reason:     reason,
+		CreatedAt:  a.now().UTC(),
+	}
+	if !a.allowFailure(r.RemoteAddr, f.CreatedAt) {
+		a.dropped.Add(1)
+	} else {
+		select {
+		case a.failures <- f:
+		default:
+			a.dropped.Add(1)
+		}
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="golog"`)
+	}
+	http.Error(w, reason, status)
+}
+
+// allowFailure reports whether a failure from addr may be recorded under
+// the per-address limit.
+func (a *Authenticator) allowFailure(addr string, now time.Time) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !now.Before(a.windowStart.Add(failureWindow)) {
+		clear(a.recent)
+		a.windowStart = now
+	}
+	n, ok := a.recent[addr]
+	if n >= failuresPerAddress || !ok && len(a.recent) >= maxFailureAddrs {
+		return false
+	}
+	a.recent[addr] = n + 1
+	return true
+}
+
+func (a *Authenticator) record(f Failure) {
+	if err := a.store.RecordFailure(f); err != nil {
+		log.Printf("Error recording failed authentication: %v", err)
+	}
+}
+
+// CreateKey creates and stores a key, returning it with its token.
+func (a *Authenticator) CreateKey(name, tenant string, scopes []string) (Key, string, error) {
+	k, token, err := NewKey(name, tenant, scopes)
+	if err != nil {
+		return Key{}, "", err
+	}
+	k, err = a.store.CreateKey(k)
+	return k, token, err
+}
+
+// Keys returns all keys, including revoked ones.
+func (a *Authenticator) Keys() ([]Key, error) {
+	return a.store.ListKeys()
+}
+
+// RevokeKey revokes a key; it is rejected from the next request on.
+func (a *Authenticator) RevokeKey(id int) error {
+	if err := a.store.RevokeKey(id); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	clear(a.cache)
+	a.mu.Unlock()
+	return nil
+}
+
+// Failures returns the most recent rejected requests.
+func (a *Authenticator) Failures(limit int) ([]Failure, error) {
+	return a.store.ListFailures(limit)
+}
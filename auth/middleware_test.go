@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestAuthenticator(t *testing.T) (*Authenticator, *MemoryStore, map[string]string) {
+	t.Helper()
+	s := NewMemoryStore()
+	a := NewAuthenticator(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		a.Wait()
+	})
+	tokens := map[string]string{}
+	for _, scope := range Scopes {
+		_, token, err := a.CreateKey(scope+" key", "", []string{scope})
+		if err != nil {
+			t.Fatalf("CreateKey() error: %v", err)
+		}
+		tokens[scope] = token
+	}
+	return a, s, tokens
+}
+
+// waitFailures waits until the store holds n failures.
+func waitFailures(t *testing.T, s *MemoryStore, n int) []Failure {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		failures, _ := s.ListFailures(0)
+		if len(failures) >= n {
+			return failures
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("recorded %d failures, want %d: %+v", len(failures), n, failures)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	a, s, tokens := newTestAuthenticator(t)
+	var seen Key
+	h := a.Require(ScopeRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no key", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"query", func(r *http.Request) { r.URL.RawQuery = "api_key=" + tokens[ScopeRead] }, http.StatusUnauthorized},
+		{"unknown key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer golog_nope") }, http.StatusUnauthorized},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("u", tokens[ScopeRead]) }, http.StatusUnauthorized},
+		{"wrong scope", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+tokens[ScopeIngest]) }, http.StatusForbidden},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+tokens[ScopeRead]) }, http.StatusOK},
+		{"header", func(r *http.Request) { r.Header.Set(HeaderAPIKey, tokens[ScopeRead]) }, http.StatusOK},
+		{"admin", func(r *http.Request) { r.Header.Set(HeaderAPIKey, tokens[ScopeAdmin]) }, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/logs", nil)
+			tc.setup(req)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if rr.Code != tc.status {
+				t.Errorf("status = %d, want %d (body: %s)", rr.Code, tc.status, rr.Body.String())
+			}
+			if tc.status == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+	if seen.Name != "admin key" {
+		t.Errorf("FromContext() = %+v, want the last accepted key", seen)
+	}
+
+	failures := waitFailures(t, s, 5)
+	if len(failures) != 5 {
+		t.Fatalf("recorded %d failures, want 5: %+v", len(failures), failures)
+	}
+	f := failures[0]
+	if f.Reason != "API key lacks the read scope" || f.KeyPrefix != tokens[ScopeIngest][:PrefixLength] || f.Method != "GET" || f.Path != "/api/logs" || f.RemoteAddr == "" {
+		t.Errorf("failure = %+v", f)
+	}
+}
+
+func TestFailureLimit(t *testing.T) {
+	a, s, _ := newTestAuthenticator(t)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	h := a.Require(ScopeRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	call := func(addr string) {
+		req := httptest.NewRequest("GET", "/api/logs", nil)
+		req.RemoteAddr = addr
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	for i := 0; i < failuresPerAddress+5; i++ {
+		call(fmt.Sprintf("198.51.100.7:%d", 40000+i))
+	}
+	call("198.51.100.8:40000")
+	waitFailures(t, s, failuresPerAddress+1)
+	if got := a.Dropped(); got != 5 {
+		t.Errorf("Dropped() = %d, want 5", got)
+	}
+
+	// The limit starts over in the next window.
+	now = now.Add(failureWindow)
+	call("198.51.100.7:40000")
+	waitFailures(t, s, failuresPerAddress+2)
+	if got := a.Dropped(); got != 5 {
+		t.Errorf("Dropped() = %d after the window, want 5", got)
+	}
+}
+
+func TestQueryKey(t *testing.T) {
+	a, _, tokens := newTestAuthenticator(t)
+	var uri string
+	h := QueryKey("/api/logs/stream")(a.Require(ScopeRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.RequestURI
+	})))
+
+	call := func(target string) int {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := call("/api/logs/stream?level=ERROR&api_key=" + tokens[ScopeRead]); code != http.StatusOK {
+		t.Fatalf("status = %d on the stream, want 200", code)
+	}
+	if uri != "/api/logs/stream?level=ERROR" {
+		t.Errorf("request URI = %q, want the key removed", uri)
+	}
+	if code := call("/api/logs?api_key=" + tokens[ScopeRead]); code != http.StatusUnauthorized {
+		t.Errorf("status = %d on another path, want 401", code)
+	}
+}
+
+func TestRevokedKeyIsRejected(t *testing.T) {
+	a, s, tokens := newTestAuthenticator(t)
+	h := a.Require(ScopeIngest)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	call := func() int {
+		req := httptest.NewRequest("POST", "/api/logs", nil)
+		req.Header.Set(HeaderAPIKey, tokens[ScopeIngest])
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := call(); code != http.StatusOK {
+		t.Fatalf("status = %d before revocation", code)
+	}
+	keys, _ := a.Keys()
+	if err := a.RevokeKey(keys[0].ID); err != nil {
+		t.Fatalf("RevokeKey() error: %v", err)
+	}
+	if code := call(); code != http.StatusUnauthorized {
+		t.Errorf("status = %d after revocation, want 401", code)
+	}
+
+	// A key revoked directly in the store, e.g. by the CLI, is rejected once
+	// the cached lookup expires.
+	_, token, _ := a.CreateKey("other", "", []string{ScopeIngest})
+	tokens[ScopeIngest] = token
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	if code := call(); code != http.StatusOK {
+		t.Fatalf("status = %d for new key", code)
+	}
+	key, _ := s.KeyByHash(HashToken(token))
+	s.RevokeKey(key.ID)
+	if code := call(); code != http.StatusOK {
+		t.Errorf("status = %d within the cache TTL, want the cached key", code)
+	}
+	now = now.Add(cacheTTL)
+	if code := call(); code != http.StatusUnauthorized {
+		t.Errorf("status = %d after the cache TTL, want 401", code)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mstgnz/golog/config"
+	"github.com/mstgnz/golog/export"
+	"github.com/mstgnz/golog/models"
+)
+
+// exportSource is the part of a log store the export command reads from.
+type exportSource interface {
+	ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error
+}
+
+// exportCommand runs "golog-cli export ..." and returns the exit code.
+func exportCommand(cfg *config.Config, args []string) int {
+	store, closeStore, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s store: %v\n", cfg.DBDriver, err)
+		return 1
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := runExport(ctx, store, args, os.Stdout, os.Stderr, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runExport writes the matching logs to the -o file, or to stdout, and
+// reports the number exported to stderr.
+func runExport(ctx context.Context, src exportSource, args []string, stdout, stderr io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	filterFlags := addFilterFlags(fs)
+	format := fs.String("format", "", fmt.Sprintf("Output format (%s); defaults to the -o extension, else ndjson", strings.Join(export.Formats(), ", ")))
+	output := fs.String("o", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	filter, err := filterFlags.filter(now)
+	if err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = formatFromPath(*output)
+	}
+
+	out := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc, err := export.NewWriter(*format, out)
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	err = src.ExportLogs(ctx, filter, func(l models.Log) error {
+		n++
+		return enc.Write(l)
+	})
+	if err == nil {
+		err = enc.Close()
+	}
+	if err == nil && *output != "" {
+		err = out.(*os.File).Sync()
+	}
+	if err != nil {
+		if *output != "" {
+			os.Remove(*output)
+		}
+		return fmt.Errorf("export failed after %d logs: %w", n, err)
+	}
+	fmt.Fprintf(stderr, "Exported %d logs\n", n)
+	return nil
+}
+
+// formatFromPath picks the export format matching a file name.
+func formatFromPath(path string) string {
+	for _, format := range export.Formats() {
+		if strings.HasSuffix(path, export.Extension(format)) {
+			return format
+		}
+	}
+	if strings.HasSuffix(path, ".gz") {
+		return export.FormatColumnar
+	}
+	return export.FormatNDJSON
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/export"
+	"github.com/mstgnz/golog/models"
+)
+
+type fakeExport struct {
+	logs []models.Log
+	err  error
+	last models.LogFilter
+}
+
+func (f *fakeExport) ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error {
+	f.last = filter
+	for _, l := range f.logs {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func TestRunExport(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	src := &fakeExport{logs: []models.Log{
+		{ID: 1, Timestamp: now.Add(-time.Hour), Level: "ERROR", Type: "API", Message: "a"},
+		{ID: 2, Timestamp: now.Add(-time.Minute), Level: "ERROR", Type: "API", Message: "b"},
+	}}
+	var stdout, stderr bytes.Buffer
+
+	if err := runExport(context.Background(), src, []string{"-level", "ERROR", "-since", "-2h"}, &stdout, &stderr, now); err != nil {
+		t.Fatalf("runExport() error: %v", err)
+	}
+	if f := src.last; f.Levels[0] != "ERROR" || !f.Since.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("filter = %+v", f)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"message":"a"`) {
+		t.Errorf("stdout = %q, want NDJSON", stdout.String())
+	}
+	if stderr.String() != "Exported 2 logs\n" {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "errors.csv")
+	stdout.Reset()
+	if err := runExport(context.Background(), src, []string{"-o", path}, &stdout, &stderr, now); err != nil {
+		t.Fatalf("runExport(-o) error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "id,timestamp,") || stdout.Len() != 0 {
+		t.Errorf("file = %q, %v; stdout = %q", data, err, stdout.String())
+	}
+
+	src.err = errors.New("connection reset")
+	failed := filepath.Join(t.TempDir(), "failed.ndjson")
+	if err := runExport(context.Background(), src, []string{"-o", failed}, &stdout, &stderr, now); err == nil || !strings.Contains(err.Error(), "after 2 logs") {
+		t.Errorf("runExport() error = %v", err)
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("partial export was kept: %v", err)
+	}
+
+	if err := runExport(context.Background(), src, []string{"-format", "xml"}, &stdout, &stderr, now); err == nil {
+		t.Error("runExport(-format xml) succeeded, want an error")
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"":                    export.FormatNDJSON,
+		"logs.csv":            export.FormatCSV,
+		"logs.ndjson":         export.FormatNDJSON,
+		"logs.columnar.gz":    export.FormatColumnar,
+		"march.gz":            export.FormatColumnar,
+		"/tmp/logs.jsonlines": export.FormatNDJSON,
+	} {
+		if got := formatFromPath(path); got != want {
+			t.Errorf("formatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
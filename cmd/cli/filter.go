@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// filterFlags are the filter flags of subcommands that query stored logs.
+type filterFlags struct {
+	levels, types, minLevel *string
+	since, until            *string
+	grep, grepMode, tenant  *string
+	timeField               *string
+	attrs                   map[string]string
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filterFlags {
+	reg := models.CurrentRegistry()
+	f := &filterFlags{
+		levels:    fs.String("level", "", fmt.Sprintf("Filter logs by level, comma-separated (%s)", strings.Join(reg.LevelNames(), ", "))),
+		types:     fs.String("type", "", fmt.Sprintf("Filter logs by type, comma-separated (%s)", strings.Join(reg.Types(), ", "))),
+		minLevel:  fs.String("min-level", "", "Only include logs at or above this level"),
+		since:     fs.String("since", "", "Only include logs at or after this time (RFC3339 or relative, e.g. -15m)"),
+		until:     fs.String("until", "", "Only include logs before this time (RFC3339 or relative, e.g. -5m)"),
+		grep:      fs.String("grep", "", "Search message text"),
+		grepMode:  fs.String("grep-mode", models.SearchFullText, "Search mode for -grep (fts, substring, regex)"),
+		tenant:    fs.String("tenant", "", "Only include logs of this tenant"),
+		timeField: fs.String("time-field", models.TimeFieldTimestamp, "Time that -since, -until and the ordering apply to (timestamp or received_at)"),
+		attrs:     map[string]string{},
+	}
+	fs.Func("attr", "Filter logs by attribute as key=value (repeatable)", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", v)
+		}
+		f.attrs[key] = value
+		return nil
+	})
+	return f
+}
+
+// filter validates the flags and builds the filter, resolving relative
+// times against now.
+func (f *filterFlags) filter(now time.Time) (models.LogFilter, error) {
+	filter := models.LogFilter{
+		Levels:     splitList(*f.levels),
+		Types:      splitList(*f.types),
+		MinLevel:   *f.minLevel,
+		Attributes: f.attrs,
+		Query:      *f.grep,
+		SearchMode: *f.grepMode,
+		Tenant:     *f.tenant,
+		TimeField:  *f.timeField,
+	}
+	for _, l := range append(filter.Levels, filter.MinLevel) {
+		if l != "" && !models.ValidLevel(l) {
+			return filter, fmt.Errorf("invalid level %q", l)
+		}
+	}
+	for _, t := range filter.Types {
+		if !models.ValidType(t) {
+			return filter, fmt.Errorf("invalid type %q", t)
+		}
+	}
+	if err := models.ValidateTenant(filter.Tenant); err != nil {
+		return filter, err
+	}
+	if !models.ValidTimeField(filter.TimeField) {
+		return filter, fmt.Errorf("invalid -time-field %q: must be %s or %s", filter.TimeField, models.TimeFieldTimestamp, models.TimeFieldReceivedAt)
+	}
+	if err := filter.ValidateSearch(); err != nil {
+		return filter, fmt.Errorf("invalid -grep: %w", err)
+	}
+	var err error
+	if *f.since != "" {
+		if filter.Since, err = models.ParseTime(*f.since, now); err != nil {
+			return filter, fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+	if *f.until != "" {
+		if filter.Until, err = models.ParseTime(*f.until, now); err != nil {
+			return filter, fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+
+	"github.com/mstgnz/golog/config"
+	"github.com/mstgnz/golog/export"
+	"github.com/mstgnz/golog/models"
+)
+
+// maxReportedRejects caps the rejected records listed individually.
+const maxReportedRejects = 10
+
+// importSink is the part of a log store the import command writes to.
+type importSink interface {
+	ImportLogs(entries []models.Log, notify bool) (int, error)
+}
+
+// importState records the progress of an import so that an interrupted one
+// can resume. Records counts the input records consumed by committed
+// batches, including rejected ones.
+type importState struct {
+	Input    string `json:"input"`
+	Records  int    `json:"records"`
+	Imported int    `json:"imported"`
+	Rejected int    `json:"rejected"`
+	Complete bool   `json:"complete"`
+}
+
+// importCommand runs "golog-cli import ..." and returns the exit code.
+func importCommand(cfg *config.Config, args []string) int {
+	store, closeStore, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s store: %v\n", cfg.DBDriver, err)
+		return 1
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := runImport(ctx, store, args, os.Stdin, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runImport reads the file named by the single argument, or stdin for "-",
+// and inserts its entries in batches, saving the progress after each one.
+func runImport(ctx context.Context, dst importSink, args []string, stdin io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(out)
+	format := fs.String("format", "", "Input format (csv, ndjson, columnar, text); detected from the content by default")
+	pattern := fs.String("pattern", "", "Regular expression parsing text lines; named groups timestamp, level, type, tenant and message fill those fields, others become attributes")
+	timeLayout := fs.String("time-layout", "", "Go time layout of text and CSV timestamps, e.g. '02/Jan/2006:15:04:05 -0700'")
+	tenant := fs.String("tenant", "", "Store all entries under this tenant")
+	batchSize := fs.Int("batch", 1000, "Entries per insert transaction")
+	quiet := fs.Bool("quiet", false, "Do not notify live streams, alerts and webhooks of imported entries")
+	statePath := fs.String("state", "", "Progress file for resuming (default <file>.import-state; none for stdin)")
+	restart := fs.Bool("restart", false, "Ignore saved progress and import from the start")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: golog-cli import [flags] <file|->")
+	}
+	input := fs.Arg(0)
+	if *batchSize < 1 {
+		return errors.New("-batch must be positive")
+	}
+	if err := models.ValidateTenant(*tenant); err != nil {
+		return err
+	}
+	opts := export.ReadOptions{Format: *format, TimeLayout: *timeLayout}
+	if *pattern != "" {
+		re, err := regexp.Compile(*pattern)
+		if err != nil {
+			return fmt.Errorf("invalid -pattern: %w", err)
+		}
+		opts.Pattern = re
+	}
+	if *statePath == "" && input != "-" {
+		*statePath = input + ".import-state"
+	}
+
+	state := importState{Input: input}
+	if *statePath != "" && !*restart {
+		saved, err := loadImportState(*statePath)
+		if err != nil {
+			return err
+		}
+		if saved != nil {
+			if saved.Complete {
+				fmt.Fprintf(out, "%s was already imported (%d logs); use -restart to import it again\n", input, saved.Imported)
+				return nil
+			}
+			state = *saved
+			fmt.Fprintf(out, "Resuming after %d records\n", state.Records)
+		}
+	}
+
+	src := stdin
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+	r, err := export.NewReader(src, opts)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < state.Records; i++ {
+		if _, err := r.Read(); err == io.EOF {
+			return fmt.Errorf("%s has only %d records but %d were already imported; use -restart", input, i, state.Records)
+		} else if err != nil && !errors.As(err, new(*export.RecordError)) {
+			return err
+		}
+	}
+
+	// records and rejected count what was read since the last commit.
+	var batch []models.Log
+	var records, rejected int
+	flush := func() error {
+		n, err := dst.ImportLogs(batch, !*quiet)
+		if err != nil {
+			return fmt.Errorf("importing batch after record %d: %w", state.Records, err)
+		}
+		state.Records += records
+		state.Imported += n
+		state.Rejected += rejected
+		batch, records, rejected = batch[:0], 0, 0
+		return saveImportState(*statePath, state)
+	}
+	reject := func(err error) {
+		if state.Rejected+rejected < maxReportedRejects {
+			fmt.Fprintf(out, "Skipping %v\n", err)
+		}
+		rejected++
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("interrupted after %d records; run the same command to resume", state.Records)
+		}
+		l, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		records++
+		var rerr *export.RecordError
+		switch {
+		case errors.As(err, &rerr):
+			reject(err)
+			continue
+		case err != nil:
+			return err
+		}
+		if *tenant != "" {
+			l.Tenant = *tenant
+		}
+		if err := l.Validate(); err != nil {
+			reject(fmt.Errorf("line %d: %w", r.Line(), err))
+			continue
+		}
+		batch = append(batch, l)
+		if len(batch) == *batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	state.Complete = true
+	if err := saveImportState(*statePath, state); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Imported %d logs from %s (%s), rejected %d\n", state.Imported, input, r.Format(), state.Rejected)
+	return nil
+}
+
+// loadImportState returns the saved progress, or nil if there is none.
+func loadImportState(path string) (*importState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state importState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// saveImportState replaces the progress file atomically. It does nothing
+// without a path.
+func saveImportState(path string, state importState) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+)
+
+type fakeSink struct {
+	logs    []models.Log
+	batches int
+	notify  bool
+	failAt  int
+}
+
+func (f *fakeSink) ImportLogs(entries []models.Log, notify bool) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	f.batches++
+	if f.batches == f.failAt {
+		return 0, errors.New("connection lost")
+	}
+	f.notify = notify
+	f.logs = append(f.logs, entries...)
+	return len(entries), nil
+}
+
+func writeInput(t *testing.T, name string, n int) string {
+	t.Helper()
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if i == 3 {
+			b.WriteString("{\"level\":\"LOUD\",\"message\":\"bad level\"}\n")
+			continue
+		}
+		fmt.Fprintf(&b, "{\"timestamp\":\"2023-06-01T12:00:%02dZ\",\"level\":\"INFO\",\"type\":\"API\",\"message\":\"entry %d\"}\n", i, i)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunImport(t *testing.T) {
+	path := writeInput(t, "archive.ndjson", 10)
+	sink := &fakeSink{}
+	var out bytes.Buffer
+
+	if err := runImport(context.Background(), sink, []string{"-batch", "4", "-quiet", "-tenant", "billing", path}, nil, &out); err != nil {
+		t.Fatalf("runImport() error: %v", err)
+	}
+	if len(sink.logs) != 9 || sink.batches != 3 || sink.notify {
+		t.Errorf("imported %d logs in %d batches (notify %v)", len(sink.logs), sink.batches, sink.notify)
+	}
+	if l := sink.logs[0]; l.Tenant != "billing" || l.Timestamp.Second() != 1 || l.Message != "entry 1" {
+		t.Errorf("first entry = %+v", l)
+	}
+	if !strings.Contains(out.String(), "Skipping line 3: invalid level") || !strings.Contains(out.String(), "Imported 9 logs") || !strings.Contains(out.String(), "rejected 1") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	out.Reset()
+	if err := runImport(context.Background(), sink, []string{path}, nil, &out); err != nil || !strings.Contains(out.String(), "already imported") || len(sink.logs) != 9 {
+		t.Errorf("second run = %v, %q", err, out.String())
+	}
+}
+
+func TestRunImportResumes(t *testing.T) {
+	path := writeInput(t, "archive.ndjson", 10)
+	sink := &fakeSink{failAt: 2}
+	var out bytes.Buffer
+
+	if err := runImport(context.Background(), sink, []string{"-batch", "4", path}, nil, &out); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Fatalf("runImport() error = %v, want the failed batch", err)
+	}
+	if len(sink.logs) != 4 {
+		t.Fatalf("imported %d logs before the failure, want 4", len(sink.logs))
+	}
+
+	out.Reset()
+	if err := runImport(context.Background(), sink, []string{"-batch", "4", path}, nil, &out); err != nil {
+		t.Fatalf("resumed runImport() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Resuming after 5 records") || len(sink.logs) != 9 || sink.logs[4].Message != "entry 6" || !sink.notify {
+		t.Errorf("resumed import: %q, %d logs", out.String(), len(sink.logs))
+	}
+
+	if err := runImport(context.Background(), sink, []string{"-restart", path}, nil, &out); err != nil || len(sink.logs) != 18 {
+		t.Errorf("restarted import = %v, %d logs", err, len(sink.logs))
+	}
+}
+
+func TestRunImportStdinText(t *testing.T) {
+	sink := &fakeSink{}
+	var out bytes.Buffer
+	in := strings.NewReader("2023-06-01 12:00:00 ERROR disk full\n2023-06-01 12:00:01 INFO recovered\n")
+
+	if err := runImport(context.Background(), sink, []string{"-"}, in, &out); err != nil {
+		t.Fatalf("runImport() error: %v", err)
+	}
+	if len(sink.logs) != 2 || sink.logs[0].Level != models.LevelError || !strings.Contains(out.String(), "(text)") {
+		t.Errorf("imported %+v: %q", sink.logs, out.String())
+	}
+
+	for _, args := range [][]string{{}, {"-pattern", "(", "-"}, {"-batch", "0", "-"}} {
+		if err := runImport(context.Background(), sink, args, strings.NewReader(""), &out); err == nil {
+			t.Errorf("runImport(%q) succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mstgnz/golog/auth"
+	"github.com/mstgnz/golog/config"
+	"github.com/mstgnz/golog/database"
+	"github.com/mstgnz/golog/sqlitestore"
+)
+
+const keysUsage = `usage: golog-cli keys <command>
+
+Commands:
+  create -name NAME [-scopes ingest,read,admin] [-tenant T]   create a key and print its token
+  list                                                         list keys
+  revoke ID                                                    revoke a key
+  failures [-limit N]                                          list recent failed authentication attempts`
+
+// keysCommand runs "golog-cli keys ..." and returns the exit code.
+func keysCommand(cfg *config.Config, args []string) int {
+	store, closeStore, err := openKeyStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s store: %v\n", cfg.DBDriver, err)
+		return 1
+	}
+	defer closeStore()
+
+	if err := runKeys(store, args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// openKeyStore opens the API key store of the driver selected by
+// cfg.DBDriver.
+func openKeyStore(cfg *config.Config) (auth.Store, func(), error) {
+	switch cfg.DBDriver {
+	case config.DriverSQLite:
+		store, err := sqlitestore.Open(cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store.KeyStore(), func() { store.Close() }, nil
+	case config.DriverMemory:
+		return nil, nil, fmt.Errorf("the %s driver keeps keys inside the server; set ADMIN_API_KEY and use /api/admin/keys", cfg.DBDriver)
+	default:
+		if err := database.Connect(); err != nil {
+			return nil, nil, err
+		}
+		return database.NewKeyStore(), database.Close, nil
+	}
+}
+
+func runKeys(store auth.Store, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		fs.SetOutput(out)
+		name := fs.String("name", "", "Key name (required)")
+		scopeList := fs.String("scopes", auth.ScopeIngest, "Comma-separated scopes: "+strings.Join(auth.Scopes, ", "))
+		tenant := fs.String("tenant", "", "Confine the key to this tenant's logs")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		scopes, err := auth.ParseScopes(*scopeList)
+		if err != nil {
+			return err
+		}
+		k, token, err := auth.NewKey(*name, *tenant, scopes)
+		if err != nil {
+			return err
+		}
+		if k, err = store.CreateKey(k); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created key %d %q with scopes %s\n", k.ID, k.Name, strings.Join(k.Scopes, ", "))
+		if k.Tenant != "" {
+			fmt.Fprintf(out, "Tenant: %s\n", k.Tenant)
+		}
+		fmt.Fprintf(out, "Token: %s\n", token)
+		fmt.Fprintln(out, "Store the token now; it cannot be shown again.")
+		return nil
+
+	case "list":
+		keys, err := store.ListKeys()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tTENANT\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.Revoked() {
+				revoked = k.RevokedAt.Local().Format(time.DateTime)
+			}
+			tenant := k.Tenant
+			if tenant == "" {
+				tenant = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), tenant, k.CreatedAt.Local().Format(time.DateTime), revoked)
+		}
+		return w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: golog-cli keys revoke ID")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil || id < 1 {
+			return fmt.Errorf("invalid key id %q", args[1])
+		}
+		if err := store.RevokeKey(id); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Revoked key %d\n", id)
+		return nil
+
+	case "failures":
+		fs := flag.NewFlagSet("keys failures", flag.ContinueOnError)
+		fs.SetOutput(out)
+		limit := fs.Int("limit", 20, "Number of attempts to show")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		failures, err := store.ListFailures(*limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tREMOTE\tREQUEST\tKEY\tREASON")
+		for _, f := range failures {
+			key := f.KeyPrefix
+			if key == "" {
+				key = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s %s\t%s\t%s\n", f.CreatedAt.Local().Format(time.DateTime), f.RemoteAddr, f.Method, f.Path, key, f.Reason)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], keysUsage)
+	}
+}
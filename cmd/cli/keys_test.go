@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mstgnz/golog/auth"
+)
+
+func TestRunKeys(t *testing.T) {
+	store := auth.NewMemoryStore()
+	var out bytes.Buffer
+
+	if err := runKeys(store, []string{"create", "-name", "ci", "-scopes", "ingest,read", "-tenant", "billing"}, &out); err != nil {
+		t.Fatalf("keys create: %v", err)
+	}
+	token := regexp.MustCompile(`Token: (\S+)`).FindStringSubmatch(out.String())
+	if token == nil {
+		t.Fatalf("keys create output has no token: %s", out.String())
+	}
+	k, err := store.KeyByHash(auth.HashToken(token[1]))
+	if err != nil || k.Name != "ci" || k.Tenant != "billing" || !k.HasScope(auth.ScopeRead) || k.HasScope(auth.ScopeAdmin) {
+		t.Errorf("stored key = %+v, %v", k, err)
+	}
+
+	out.Reset()
+	if err := runKeys(store, []string{"revoke", "1"}, &out); err != nil {
+		t.Fatalf("keys revoke: %v", err)
+	}
+	out.Reset()
+	if err := runKeys(store, []string{"list"}, &out); err != nil {
+		t.Fatalf("keys list: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "ingest,read  billing") || strings.HasSuffix(lines[1], "-") {
+		t.Errorf("keys list output:\n%s", out.String())
+	}
+
+	store.RecordFailure(auth.Failure{RemoteAddr: "10.0.0.1:5123", Method: "GET", Path: "/api/logs", Reason: "missing API key"})
+	out.Reset()
+	if err := runKeys(store, []string{"failures", "-limit", "5"}, &out); err != nil {
+		t.Fatalf("keys failures: %v", err)
+	}
+	if !strings.Contains(out.String(), "GET /api/logs") || !strings.Contains(out.String(), "missing API key") {
+		t.Errorf("keys failures output:\n%s", out.String())
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"rotate"},
+		{"create", "-scopes", "read"},
+		{"create", "-name", "x", "-scopes", "write"},
+		{"create", "-name", "x", "-scopes", "admin", "-tenant", "billing"},
+		{"revoke"},
+		{"revoke", "abc"},
+		{"revoke", "9"},
+	} {
+		if err := runKeys(store, args, &out); err == nil {
+			t.Errorf("keys %v succeeded, want an error", args)
+		}
+	}
+}
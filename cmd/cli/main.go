@@ -7,31 +7,112 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/mstgnz/golog/config"
 	"github.com/mstgnz/golog/database"
 	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/sqlitestore"
 )
 
+// logSource is the part of a log store the CLI reads from.
+type logSource interface {
+	GetLogs(filter models.LogFilter) ([]models.Log, error)
+	ListenForLogs(ctx context.Context, ch chan<- models.Log) error
+	statsSource
+	exportSource
+	importSink
+}
+
 func main() {
-	levelFilter := flag.String("level", "", "Filter logs by level (INFO, WARNING, ERROR, DEBUG)")
-	typeFilter := flag.String("type", "", "Filter logs by type (SYSTEM, AUTH, DATABASE, USER, API)")
+	// The registry is needed before flags are defined so that -help lists the
+	// configured levels and types.
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	models.SetRegistry(cfg.Registry)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "keys":
+			os.Exit(keysCommand(cfg, os.Args[2:]))
+		case "migrate":
+			os.Exit(migrateCommand(cfg, os.Args[2:]))
+		case "stats":
+			os.Exit(statsCommand(cfg, os.Args[2:]))
+		case "export":
+			os.Exit(exportCommand(cfg, os.Args[2:]))
+		case "import":
+			os.Exit(importCommand(cfg, os.Args[2:]))
+		}
+	}
+
+	levelFilter := flag.String("level", "", fmt.Sprintf("Filter logs by level, comma-separated (%s)", strings.Join(cfg.Registry.LevelNames(), ", ")))
+	typeFilter := flag.String("type", "", fmt.Sprintf("Filter logs by type, comma-separated (%s)", strings.Join(cfg.Registry.Types(), ", ")))
+	minLevel := flag.String("min-level", "", "Only show logs at or above this level")
+	sinceFlag := flag.String("since", "", "Only show logs at or after this time (RFC3339 or relative, e.g. -15m)")
+	untilFlag := flag.String("until", "", "Only show logs before this time (RFC3339 or relative, e.g. -5m)")
+	grepFlag := flag.String("grep", "", "Search message text")
+	grepMode := flag.String("grep-mode", models.SearchFullText, "Search mode for -grep (fts, substring, regex)")
+	timeField := flag.String("time-field", models.TimeFieldTimestamp, "Time that -since, -until and the ordering apply to (timestamp or received_at)")
+	attrFilter := map[string]string{}
+	flag.Func("attr", "Filter logs by attribute as key=value (repeatable)", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", v)
+		}
+		attrFilter[key] = value
+		return nil
+	})
 	flag.Parse()
 
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	levels := splitList(*levelFilter)
+	for _, l := range append(levels, *minLevel) {
+		if l != "" && !cfg.Registry.ValidLevel(l) {
+			log.Fatalf("Invalid level %q: must be one of %s", l, strings.Join(cfg.Registry.LevelNames(), ", "))
+		}
+	}
+	types := splitList(*typeFilter)
+	for _, t := range types {
+		if !cfg.Registry.ValidType(t) {
+			log.Fatalf("Invalid -type %q: must be one of %s", t, strings.Join(cfg.Registry.Types(), ", "))
+		}
+	}
+
+	if !models.ValidTimeField(*timeField) {
+		log.Fatalf("Invalid -time-field %q: must be %s or %s", *timeField, models.TimeFieldTimestamp, models.TimeFieldReceivedAt)
 	}
 
-	if err := database.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	now := time.Now()
+	var since, until time.Time
+	if *sinceFlag != "" {
+		t, err := models.ParseTime(*sinceFlag, now)
+		if err != nil {
+			log.Fatalf("Invalid -since: %v", err)
+		}
+		since = t
+	}
+	if *untilFlag != "" {
+		t, err := models.ParseTime(*untilFlag, now)
+		if err != nil {
+			log.Fatalf("Invalid -until: %v", err)
+		}
+		until = t
+	}
+
+	store, closeStore, err := openStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open %s store: %v", cfg.DBDriver, err)
 	}
-	defer database.Close()
+	defer closeStore()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	store := database.NewStore()
 	logChan := make(chan models.Log)
 
 	if err := store.ListenForLogs(ctx, logChan); err != nil {
@@ -42,8 +123,18 @@ func main() {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	filter := models.LogFilter{
-		Level: *levelFilter,
-		Type:  *typeFilter,
+		Levels:     levels,
+		Types:      types,
+		MinLevel:   *minLevel,
+		Since:      since,
+		Until:      until,
+		TimeField:  *timeField,
+		Attributes: attrFilter,
+		Query:      *grepFlag,
+		SearchMode: *grepMode,
+	}
+	if err := filter.ValidateSearch(); err != nil {
+		log.Fatalf("Invalid -grep: %v", err)
 	}
 
 	logs, err := store.GetLogs(filter)
@@ -52,11 +143,26 @@ func main() {
 	}
 
 	fmt.Println("=== GoLog - Real-time Log Monitoring ===")
-	if *levelFilter != "" {
-		fmt.Printf("Level filter: %s\n", *levelFilter)
+	if len(levels) > 0 {
+		fmt.Printf("Level filter: %s\n", strings.Join(levels, ", "))
+	}
+	if *minLevel != "" {
+		fmt.Printf("Minimum level: %s\n", *minLevel)
+	}
+	if len(types) > 0 {
+		fmt.Printf("Type filter: %s\n", strings.Join(types, ", "))
+	}
+	if !since.IsZero() {
+		fmt.Printf("Since: %s\n", since.Format(time.RFC3339))
+	}
+	if !until.IsZero() {
+		fmt.Printf("Until: %s\n", until.Format(time.RFC3339))
+	}
+	if *grepFlag != "" {
+		fmt.Printf("Search (%s): %s\n", *grepMode, *grepFlag)
 	}
-	if *typeFilter != "" {
-		fmt.Printf("Type filter: %s\n", *typeFilter)
+	if len(attrFilter) > 0 {
+		fmt.Printf("Attribute filter:%s\n", formatAttributes(toAny(attrFilter)))
 	}
 	fmt.Println("======================================")
 
@@ -64,7 +170,7 @@ func main() {
 		fmt.Println("No logs found")
 	} else {
 		for i := len(logs) - 1; i >= 0; i-- {
-			printLog(logs[i])
+			printLog(highlight(logs[i], filter))
 		}
 	}
 
@@ -73,9 +179,8 @@ func main() {
 
 	go func() {
 		for logEntry := range logChan {
-			if (*levelFilter == "" || logEntry.Level == *levelFilter) &&
-				(*typeFilter == "" || logEntry.Type == *typeFilter) {
-				printLog(logEntry)
+			if filter.Matches(logEntry) {
+				printLog(highlight(logEntry, filter))
 			}
 		}
 	}()
@@ -84,23 +189,99 @@ func main() {
 	fmt.Println("\nShutting down...")
 }
 
+// openStore connects to the database selected by cfg.DBDriver. The memory
+// driver lives inside the server process, so the CLI cannot read from it.
+func openStore(cfg *config.Config) (logSource, func(), error) {
+	switch cfg.DBDriver {
+	case config.DriverSQLite:
+		store, err := sqlitestore.Open(cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, func() { store.Close() }, nil
+	case config.DriverMemory:
+		return nil, nil, fmt.Errorf("the %s driver keeps logs inside the server; use the web dashboard or API", cfg.DBDriver)
+	default:
+		if err := database.Connect(); err != nil {
+			return nil, nil, err
+		}
+		return database.NewStore(), database.Close, nil
+	}
+}
+
 func printLog(logEntry models.Log) {
 	timestamp := logEntry.Timestamp.Format("2006-01-02 15:04:05")
 
-	var levelColor string
-	switch logEntry.Level {
-	case models.LevelInfo:
-		levelColor = "\033[32m"
-	case models.LevelWarning:
-		levelColor = "\033[33m"
-	case models.LevelError:
-		levelColor = "\033[31m"
-	case models.LevelDebug:
-		levelColor = "\033[36m"
+	fmt.Printf("[%s] %s%s\033[0m [%s]: %s%s\n",
+		timestamp, levelColor(logEntry.Level), logEntry.Level, logEntry.Type, logEntry.Message, formatAttributes(logEntry.Attributes))
+}
+
+// levelColor picks a color by comparing the level's severity with the
+// built-in levels, so configured levels such as FATAL or TRACE are colored
+// like their nearest neighbour.
+func levelColor(level string) string {
+	reg := models.CurrentRegistry()
+	severity, ok := reg.Severity(level)
+	if !ok {
+		return "\033[0m"
+	}
+	atLeast := func(name string) bool {
+		s, ok := reg.Severity(name)
+		return ok && severity >= s
+	}
+	switch {
+	case atLeast(models.LevelError):
+		return "\033[31m"
+	case atLeast(models.LevelWarning):
+		return "\033[33m"
+	case atLeast(models.LevelInfo):
+		return "\033[32m"
 	default:
-		levelColor = "\033[0m"
+		return "\033[36m"
 	}
+}
 
-	fmt.Printf("[%s] %s%s\033[0m [%s]: %s\n",
-		timestamp, levelColor, logEntry.Level, logEntry.Type, logEntry.Message)
+// highlight marks search matches in the message with reverse video.
+func highlight(logEntry models.Log, filter models.LogFilter) models.Log {
+	if marked := filter.Highlight(logEntry.Message, "\033[7m", "\033[27m"); marked != "" {
+		logEntry.Message = marked
+	}
+	return logEntry
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func toAny(m map[string]string) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// formatAttributes renders attributes as sorted key=value pairs.
+func formatAttributes(attrs map[string]any) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", k, models.AttributeString(attrs[k]))
+	}
+	return b.String()
 }
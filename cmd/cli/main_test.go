@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,3 +99,38 @@ func TestCLIFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestLevelColor(t *testing.T) {
+	defer models.SetRegistry(models.CurrentRegistry())
+
+	levels := append([]models.LevelDef{{Name: "TRACE", Severity: 0}}, models.DefaultLevels...)
+	levels = append(levels, models.LevelDef{Name: "FATAL", Severity: 50})
+	reg, err := models.NewRegistry(levels, models.DefaultTypes)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	models.SetRegistry(reg)
+
+	tests := map[string]string{
+		"FATAL":   "\033[31m",
+		"ERROR":   "\033[31m",
+		"WARNING": "\033[33m",
+		"INFO":    "\033[32m",
+		"TRACE":   "\033[36m",
+		"UNKNOWN": "\033[0m",
+	}
+	for level, want := range tests {
+		if got := levelColor(level); got != want {
+			t.Errorf("levelColor(%s) = %q, want %q", level, got, want)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if got := splitList(" ERROR, WARNING,,FATAL "); strings.Join(got, "|") != "ERROR|WARNING|FATAL" {
+		t.Errorf("splitList() = %v", got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList(\"\") = %v, want nil", got)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mstgnz/golog/config"
+	"github.com/mstgnz/golog/database"
+)
+
+const migrateUsage = `usage: golog-cli migrate <command>
+
+Commands:
+  up [-to VERSION]   apply pending migrations, up to VERSION if given
+  down [-steps N]    revert the last N applied migrations (default 1)
+  status             list migrations and when they were applied`
+
+// migrator applies and reverts schema migrations.
+type migrator interface {
+	Up(to int) (int, error)
+	Down(steps int) (int, error)
+	Status() ([]database.MigrationStatus, error)
+}
+
+type dbMigrator struct{ db *sql.DB }
+
+func (m dbMigrator) Up(to int) (int, error)      { return database.MigrateUp(m.db, to) }
+func (m dbMigrator) Down(steps int) (int, error) { return database.MigrateDown(m.db, steps) }
+func (m dbMigrator) Status() ([]database.MigrationStatus, error) {
+	return database.MigrationStatuses(m.db)
+}
+
+// migrateCommand runs "golog-cli migrate ..." and returns the exit code.
+func migrateCommand(cfg *config.Config, args []string) int {
+	if cfg.DBDriver != config.DriverPostgres {
+		fmt.Fprintf(os.Stderr, "the %s driver creates its schema automatically; migrate only applies to %s\n", cfg.DBDriver, config.DriverPostgres)
+		return 2
+	}
+	if err := database.Connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s store: %v\n", cfg.DBDriver, err)
+		return 1
+	}
+	defer database.Close()
+
+	if err := runMigrate(dbMigrator{database.DB}, args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func runMigrate(m migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "up":
+		fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		fs.SetOutput(out)
+		to := fs.Int("to", 0, "Stop after this version")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		n, err := m.Up(*to)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migrations\n", n)
+		return nil
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		fs.SetOutput(out)
+		steps := fs.Int("steps", 1, "Number of migrations to revert")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+		n, err := m.Down(*steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Reverted %d migrations\n", n)
+		return nil
+
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			switch {
+			case s.Unknown:
+				applied = s.AppliedAt.Local().Format(time.DateTime) + " (unknown to this build)"
+			case !s.AppliedAt.IsZero():
+				applied = s.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/database"
+)
+
+type fakeMigrator struct {
+	to, steps int
+}
+
+func (f *fakeMigrator) Up(to int) (int, error) {
+	f.to = to
+	return 2, nil
+}
+
+func (f *fakeMigrator) Down(steps int) (int, error) {
+	f.steps = steps
+	return steps, nil
+}
+
+func (f *fakeMigrator) Status() ([]database.MigrationStatus, error) {
+	applied := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	return []database.MigrationStatus{
+		{Version: 1, Name: "init", AppliedAt: applied},
+		{Version: 2, Name: "partition_logs"},
+	}, nil
+}
+
+func TestRunMigrate(t *testing.T) {
+	m := &fakeMigrator{}
+	var out bytes.Buffer
+
+	if err := runMigrate(m, []string{"up", "-to", "3"}, &out); err != nil || m.to != 3 || !strings.Contains(out.String(), "Applied 2 migrations") {
+		t.Errorf("migrate up: %v, to = %d, output %q", err, m.to, out.String())
+	}
+	out.Reset()
+	if err := runMigrate(m, []string{"down"}, &out); err != nil || m.steps != 1 {
+		t.Errorf("migrate down: %v, steps = %d", err, m.steps)
+	}
+	if err := runMigrate(m, []string{"down", "-steps", "0"}, &out); err == nil {
+		t.Error("migrate down accepted -steps 0")
+	}
+
+	out.Reset()
+	if err := runMigrate(m, []string{"status"}, &out); err != nil {
+		t.Fatalf("migrate status: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "0001     init") || !strings.Contains(lines[1], "2024-03-01 12:00:00") || !strings.HasSuffix(lines[2], "pending") {
+		t.Errorf("migrate status output:\n%s", out.String())
+	}
+
+	if err := runMigrate(m, nil, &out); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("migrate without command error = %v, want usage", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/config"
+	"github.com/mstgnz/golog/models"
+)
+
+// histogramWidth is the length of the longest bar.
+const histogramWidth = 50
+
+// statsSource is the part of a log store the stats command reads from.
+type statsSource interface {
+	Stats(q models.StatsQuery) ([]models.StatsBucket, error)
+}
+
+// statsCommand runs "golog-cli stats ..." and returns the exit code.
+func statsCommand(cfg *config.Config, args []string) int {
+	store, closeStore, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s store: %v\n", cfg.DBDriver, err)
+		return 1
+	}
+	defer closeStore()
+
+	if err := runStats(store, args, os.Stdout, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func runStats(src statsSource, args []string, out io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(out)
+	filterFlags := addFilterFlags(fs)
+	interval := fs.Duration("interval", time.Minute, "Length of each bar")
+	by := fs.String("by", "", "Draw one histogram per level and/or type, e.g. level or level,type")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	filter, err := filterFlags.filter(now)
+	if err != nil {
+		return err
+	}
+	if filter.Until.IsZero() {
+		filter.Until = now
+	}
+	if filter.Since.IsZero() {
+		filter.Since = filter.Until.Add(-time.Hour)
+	}
+
+	q := models.StatsQuery{Filter: filter, Interval: *interval, GroupBy: splitList(*by)}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	buckets, err := src.Stats(q)
+	if err != nil {
+		return err
+	}
+	renderHistogram(out, q, buckets)
+	return nil
+}
+
+// renderHistogram draws one bar per interval, including empty ones, with a
+// separate histogram per group.
+func renderHistogram(out io.Writer, q models.StatsQuery, buckets []models.StatsBucket) {
+	var groups []string
+	counts := make(map[string]map[time.Time]int64)
+	var peak, total int64
+	for _, b := range buckets {
+		label := strings.Trim(b.Level+"/"+b.Type, "/")
+		if counts[label] == nil {
+			groups = append(groups, label)
+			counts[label] = make(map[time.Time]int64)
+		}
+		counts[label][b.Start] += b.Count
+		peak = max(peak, counts[label][b.Start])
+		total += b.Count
+	}
+	if len(groups) == 0 {
+		fmt.Fprintln(out, "No logs found")
+		return
+	}
+
+	layout := "2006-01-02 15:04"
+	if q.Interval%time.Minute != 0 {
+		layout = time.DateTime
+	}
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if g != "" {
+			fmt.Fprintf(out, "%s\n", g)
+		}
+		for t := q.BucketStart(q.Filter.Since); t.Before(q.Filter.Until); t = t.Add(q.Interval) {
+			n := counts[g][t]
+			width := int(n * histogramWidth / peak)
+			if n > 0 && width == 0 {
+				width = 1
+			}
+			fmt.Fprintf(out, "%s  %-*s %d\n", t.Local().Format(layout), histogramWidth, strings.Repeat("#", width), n)
+		}
+	}
+	fmt.Fprintf(out, "\nTotal: %d\n", total)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+type fakeStats struct {
+	buckets []models.StatsBucket
+	last    models.StatsQuery
+}
+
+func (f *fakeStats) Stats(q models.StatsQuery) ([]models.StatsBucket, error) {
+	f.last = q
+	return f.buckets, nil
+}
+
+func TestRunStats(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 3, 30, 0, time.UTC)
+	src := &fakeStats{buckets: []models.StatsBucket{
+		{Start: now.Truncate(time.Minute).Add(-2 * time.Minute), Count: 10},
+		{Start: now.Truncate(time.Minute), Count: 5},
+	}}
+	var out bytes.Buffer
+
+	if err := runStats(src, []string{"-level", "ERROR", "-since", "-3m"}, &out, now); err != nil {
+		t.Fatalf("runStats() error: %v", err)
+	}
+	if q := src.last; q.Interval != time.Minute || q.Filter.Levels[0] != "ERROR" || !q.Filter.Until.Equal(now) {
+		t.Errorf("query = %+v", q)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	// 10:00 to 10:03, a blank line and the total.
+	if len(lines) != 6 {
+		t.Fatalf("output has %d lines:\n%s", len(lines), out.String())
+	}
+	full := strings.Repeat("#", histogramWidth)
+	if !strings.Contains(lines[1], full+" 10") || !strings.Contains(lines[2], strings.Repeat(" ", histogramWidth)+" 0") ||
+		!strings.Contains(lines[3], strings.Repeat("#", histogramWidth/2)+" ") || lines[5] != "Total: 15" {
+		t.Errorf("histogram:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[0], now.Add(-3*time.Minute).Truncate(time.Minute).Local().Format("2006-01-02 15:04")) {
+		t.Errorf("first bar %q does not start at -since", lines[0])
+	}
+}
+
+func TestRunStatsGrouped(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	src := &fakeStats{buckets: []models.StatsBucket{
+		{Start: now.Add(-time.Hour), Level: "ERROR", Type: "API", Count: 3},
+		{Start: now.Add(-time.Hour), Level: "INFO", Type: "API", Count: 1},
+	}}
+	var out bytes.Buffer
+
+	if err := runStats(src, []string{"-interval", "1h", "-by", "level,type", "-since", "-2h"}, &out, now); err != nil {
+		t.Fatalf("runStats() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "ERROR/API\n") || !strings.Contains(out.String(), "INFO/API\n") {
+		t.Errorf("grouped histogram:\n%s", out.String())
+	}
+
+	src.buckets = nil
+	out.Reset()
+	if err := runStats(src, nil, &out, now); err != nil || !strings.Contains(out.String(), "No logs found") {
+		t.Errorf("empty stats: %v, output %q", err, out.String())
+	}
+	if err := runStats(src, []string{"-by", "tenant"}, &out, now); err == nil {
+		t.Error("runStats() accepted -by tenant")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,9 +11,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/auth"
 	"github.com/mstgnz/golog/config"
 	"github.com/mstgnz/golog/database"
+	"github.com/mstgnz/golog/email"
 	"github.com/mstgnz/golog/handlers"
+	"github.com/mstgnz/golog/memstore"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/retention"
+	"github.com/mstgnz/golog/sqlitestore"
+	"github.com/mstgnz/golog/syslog"
+	"github.com/mstgnz/golog/tenant"
+	"github.com/mstgnz/golog/wal"
+	"github.com/mstgnz/golog/webhook"
 )
 
 func main() {
@@ -20,22 +32,86 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	models.SetRegistry(cfg.Registry)
 
-	if err := database.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	b, err := openBackend(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open %s store: %v", cfg.DBDriver, err)
 	}
-	defer database.Close()
+	defer b.close()
+	store := b.logs
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	store := database.NewStore()
 	srv := handlers.NewServer(store)
+	srv.SetAllowedOrigins(cfg.CORSOrigins)
+	srv.SetTimestampPolicy(cfg.Timestamps)
+
+	authenticator, err := setupAuth(cfg, b.keys)
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+	if authenticator != nil {
+		authenticator.Start(ctx)
+		srv.SetAuth(authenticator)
+	}
+	if cfg.TenantQuota > 0 || len(cfg.TenantQuotas) > 0 {
+		srv.SetQuotas(tenant.NewQuotas(cfg.TenantQuota, cfg.TenantQuotas))
+	}
+
+	engine, err := startAlerting(ctx, cfg, b.alerts)
+	if err != nil {
+		log.Fatalf("Failed to start alerting: %v", err)
+	}
+	srv.SetAlerting(engine)
+
+	notifier, err := startWebhooks(ctx, cfg, b.deadLetters)
+	if err != nil {
+		log.Fatalf("Failed to start webhooks: %v", err)
+	}
+	if notifier != nil {
+		engine.AddNotifier(notifier)
+		srv.SetWebhooks(notifier)
+	}
+
+	mailer, err := startEmail(ctx, cfg, store)
+	if err != nil {
+		log.Fatalf("Failed to start email notifications: %v", err)
+	}
+	if mailer != nil {
+		engine.AddNotifier(mailer)
+	}
+
+	purger, err := startRetention(ctx, cfg, b.retention)
+	if err != nil {
+		log.Fatalf("Failed to start retention: %v", err)
+	}
+	if purger != nil {
+		srv.SetRetention(purger)
+	}
+	var partitioner *database.Partitioner
+	if cfg.DBDriver == config.DriverPostgres {
+		partitioner = startPartitions(ctx, cfg, purger)
+	}
+
+	buffer, err := startIngestBuffer(ctx, cfg, store, b.permanent)
+	if err != nil {
+		log.Fatalf("Failed to open ingestion buffer: %v", err)
+	}
+	if buffer != nil {
+		srv.SetIngestBuffer(buffer)
+	}
 
 	if err := srv.StartLogListener(ctx); err != nil {
 		log.Fatalf("Failed to start log listener: %v", err)
 	}
 
+	syslogServer := syslog.NewServer(srv, cfg.Timestamps)
+	if err := syslogServer.ListenAndServe(ctx, cfg.SyslogUDPAddr, cfg.SyslogTCPAddr); err != nil {
+		log.Fatalf("Failed to start syslog receiver: %v", err)
+	}
+
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Port),
 		Handler: srv.SetupRoutes(),
@@ -60,6 +136,237 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server shutdown failed: %v", err)
 	}
+	cancel()
+	syslogServer.Wait()
+	if authenticator != nil {
+		authenticator.Wait()
+	}
+	if notifier != nil {
+		notifier.Wait()
+	}
+	if mailer != nil {
+		mailer.Wait()
+	}
+	if purger != nil {
+		purger.Wait()
+	}
+	if partitioner != nil {
+		partitioner.Wait()
+	}
+	if buffer != nil {
+		buffer.Wait()
+		if err := buffer.Close(); err != nil {
+			log.Printf("Error closing ingestion buffer: %v", err)
+		}
+	}
 
 	log.Println("Server gracefully stopped")
 }
+
+// backend groups the stores of the driver selected by cfg.DBDriver.
+type backend struct {
+	logs        handlers.LogStore
+	alerts      alerting.Store
+	deadLetters webhook.DeadLetterStore
+	keys        auth.Store
+	retention   retention.Store
+	// permanent tells errors that retrying an insert cannot fix from
+	// transient ones; nil when inserts do not fail.
+	permanent func(error) bool
+	close     func()
+}
+
+// openBackend creates the stores selected by cfg.DBDriver.
+func openBackend(cfg *config.Config) (*backend, error) {
+	switch cfg.DBDriver {
+	case config.DriverMemory:
+		log.Printf("Using in-memory store (capacity %d); logs are lost on restart", cfg.MemstoreCapacity)
+		store := memstore.New(cfg.MemstoreCapacity)
+		return &backend{
+			logs:        store,
+			alerts:      alerting.NewMemoryStore(),
+			deadLetters: webhook.NewMemoryDeadLetters(),
+			keys:        auth.NewMemoryStore(),
+			retention:   store,
+			close:       func() {},
+		}, nil
+	case config.DriverSQLite:
+		store, err := sqlitestore.Open(cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		return &backend{
+			logs:        store,
+			alerts:      store.AlertStore(),
+			deadLetters: store.DeadLetterStore(),
+			keys:        store.KeyStore(),
+			retention:   store,
+			permanent:   store.Permanent,
+			close:       func() { store.Close() },
+		}, nil
+	default:
+		if err := database.Connect(); err != nil {
+			return nil, err
+		}
+		if err := prepareSchema(cfg, database.DB); err != nil {
+			database.Close()
+			return nil, err
+		}
+		store := database.NewStore()
+		return &backend{
+			logs:        store,
+			alerts:      database.NewAlertStore(),
+			deadLetters: database.NewDeadLetterStore(),
+			keys:        database.NewKeyStore(),
+			retention:   store,
+			permanent:   store.Permanent,
+			close:       database.Close,
+		}, nil
+	}
+}
+
+// prepareSchema applies pending migrations when MIGRATE_ON_BOOT is set and
+// otherwise checks that there are none.
+func prepareSchema(cfg *config.Config, db *sql.DB) error {
+	if cfg.MigrateOnBoot {
+		return database.Migrate(db)
+	}
+	pending, err := database.PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations are pending; run golog-cli migrate up or set MIGRATE_ON_BOOT=true", pending)
+	}
+	return nil
+}
+
+// setupAuth returns the authenticator for AUTH_ENABLED, storing
+// ADMIN_API_KEY as an admin key when set. It returns nil when
+// authentication is disabled.
+func setupAuth(cfg *config.Config, store auth.Store) (*auth.Authenticator, error) {
+	if !cfg.AuthEnabled {
+		if cfg.AdminAPIKey != "" {
+			log.Println("Warning: ADMIN_API_KEY is ignored because AUTH_ENABLED is false")
+		}
+		return nil, nil
+	}
+	if cfg.AdminAPIKey != "" {
+		if err := auth.EnsureKey(store, "ADMIN_API_KEY", cfg.AdminAPIKey, []string{auth.ScopeAdmin}); err != nil {
+			return nil, err
+		}
+	} else if cfg.DBDriver == config.DriverMemory {
+		log.Println("Warning: AUTH_ENABLED with the memory driver and no ADMIN_API_KEY; no request can be authenticated")
+	}
+	return auth.NewAuthenticator(store), nil
+}
+
+// startAlerting loads the alert rules, seeding them from ALERT_RULES_FILE,
+// and starts evaluating them.
+func startAlerting(ctx context.Context, cfg *config.Config, store alerting.Store) (*alerting.Engine, error) {
+	engine := alerting.NewEngine(store)
+	if cfg.AlertRulesFile != "" {
+		rules, err := alerting.LoadRules(cfg.AlertRulesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := engine.Seed(rules); err != nil {
+			return nil, err
+		}
+	}
+	if err := engine.Load(); err != nil {
+		return nil, err
+	}
+	go engine.Run(ctx)
+	return engine, nil
+}
+
+// startWebhooks starts delivering to the destinations in WEBHOOKS_FILE. It
+// returns nil when no file is configured.
+func startWebhooks(ctx context.Context, cfg *config.Config, deadLetters webhook.DeadLetterStore) (*webhook.Notifier, error) {
+	if cfg.WebhooksFile == "" {
+		return nil, nil
+	}
+	dests, err := webhook.LoadDestinations(cfg.WebhooksFile)
+	if err != nil {
+		return nil, err
+	}
+	n, err := webhook.NewNotifier(dests, deadLetters)
+	if err != nil {
+		return nil, err
+	}
+	n.Start(ctx)
+	log.Printf("Delivering webhooks to %d destinations", len(dests))
+	return n, nil
+}
+
+// startEmail starts the alert emails and digests configured in EMAIL_FILE.
+// It returns nil when no file is configured.
+func startEmail(ctx context.Context, cfg *config.Config, store email.LogReader) (*email.Notifier, error) {
+	if cfg.EmailFile == "" {
+		return nil, nil
+	}
+	emailCfg, err := email.LoadConfig(cfg.EmailFile)
+	if err != nil {
+		return nil, err
+	}
+	n, err := email.NewNotifier(*emailCfg, store)
+	if err != nil {
+		return nil, err
+	}
+	n.Start(ctx)
+	log.Printf("Sending email through %s with %d digests", emailCfg.SMTP.Host, len(emailCfg.Digests))
+	return n, nil
+}
+
+// startRetention starts purging according to the policy in RETENTION_FILE.
+// It returns nil when no file is configured.
+func startRetention(ctx context.Context, cfg *config.Config, store retention.Store) (*retention.Purger, error) {
+	if cfg.RetentionFile == "" {
+		return nil, nil
+	}
+	policy, err := retention.LoadPolicy(cfg.RetentionFile)
+	if err != nil {
+		return nil, err
+	}
+	p := retention.NewPurger(store, policy)
+	p.Start(ctx)
+	if policy.DryRun {
+		log.Println("Retention policy is a dry run; no entries will be deleted")
+	}
+	return p, nil
+}
+
+// startIngestBuffer opens the write-ahead log in INGEST_BUFFER_DIR and
+// starts writing its entries to store. It returns nil when no directory is
+// configured.
+func startIngestBuffer(ctx context.Context, cfg *config.Config, store wal.Store, permanent func(error) bool) (*wal.Buffer, error) {
+	if cfg.IngestBufferDir == "" {
+		return nil, nil
+	}
+	b, err := wal.Open(cfg.IngestBufferDir, store, wal.Options{
+		MaxBytes:  int64(cfg.IngestBufferMaxMB) << 20,
+		BatchSize: cfg.IngestBufferBatch,
+		Permanent: permanent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.Start(ctx)
+	log.Printf("Buffering ingested logs in %s (max %d MB)", cfg.IngestBufferDir, cfg.IngestBufferMaxMB)
+	return b, nil
+}
+
+// startPartitions maintains the partitions of the PostgreSQL logs table.
+// Partitions are dropped only when the retention policy expires every entry
+// in them; a dry-run policy never drops any.
+func startPartitions(ctx context.Context, cfg *config.Config, purger *retention.Purger) *database.Partitioner {
+	p := database.NewPartitioner(database.Period(cfg.PartitionPeriod), cfg.PartitionPremake)
+	if purger != nil && !purger.Policy().DryRun {
+		if horizon, ok := purger.Policy().Horizon(); ok {
+			p.SetRetention(horizon, cfg.PartitionDetach)
+		}
+	}
+	p.Start(ctx)
+	return p
+}
@@ -3,13 +3,24 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/auth"
 	"github.com/mstgnz/golog/config"
 	"github.com/mstgnz/golog/database"
+	"github.com/mstgnz/golog/handlers"
+	"github.com/mstgnz/golog/memstore"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/webhook"
 )
 
 func TestMainIntegration(t *testing.T) {
@@ -71,3 +82,188 @@ func TestMainIntegration(t *testing.T) {
 	// For testing, we'll just simulate the server startup
 	t.Log("Server started successfully")
 }
+
+func TestOpenBackendMemory(t *testing.T) {
+	b, err := openBackend(&config.Config{DBDriver: config.DriverMemory, MemstoreCapacity: 10})
+	if err != nil {
+		t.Fatalf("openBackend() error: %v", err)
+	}
+	defer b.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rules := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(rules, []byte(`[{"name":"postgres mentions","pattern":"postgres"}]`), 0o644)
+	engine, err := startAlerting(ctx, &config.Config{AlertRulesFile: rules}, b.alerts)
+	if err != nil {
+		t.Fatalf("startAlerting() error: %v", err)
+	}
+
+	server := handlers.NewServer(b.logs)
+	server.SetAlerting(engine)
+	if err := server.StartLogListener(ctx); err != nil {
+		t.Fatalf("StartLogListener() error: %v", err)
+	}
+	srv := httptest.NewServer(server.SetupRoutes())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/logs", "application/json",
+		strings.NewReader(`{"level":"INFO","type":"SYSTEM","message":"no postgres needed"}`))
+	if err != nil {
+		t.Fatalf("POST /api/logs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /api/logs status = %d, want 200", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/logs")
+	if err != nil {
+		t.Fatalf("GET /api/logs: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "no postgres needed") {
+		t.Errorf("GET /api/logs body = %s, want inserted entry", body)
+	}
+
+	// The seeded rule fires once the entry reaches the engine.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		alerts, _ := engine.Alerts(alerting.AlertFilter{State: alerting.StateFiring})
+		if len(alerts) == 1 && alerts[0].RuleName == "postgres mentions" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("firing alerts = %+v, want the seeded rule", alerts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartWebhooks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n, err := startWebhooks(ctx, &config.Config{}, webhook.NewMemoryDeadLetters())
+	if n != nil || err != nil {
+		t.Fatalf("startWebhooks() without a file = %v, %v; want nil, nil", n, err)
+	}
+
+	received := make(chan string, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer hook.Close()
+
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	os.WriteFile(path, []byte(`[{"name":"errors","url":"`+hook.URL+`","filter":{"levels":["ERROR"]}}]`), 0o644)
+	n, err = startWebhooks(ctx, &config.Config{WebhooksFile: path}, webhook.NewMemoryDeadLetters())
+	if err != nil {
+		t.Fatalf("startWebhooks() error: %v", err)
+	}
+	n.Observe(models.Log{Level: "ERROR", Message: "disk full"})
+
+	select {
+	case body := <-received:
+		if !strings.Contains(body, "disk full") {
+			t.Errorf("webhook body = %s", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	cancel()
+	n.Wait()
+}
+
+func TestStartEmail(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := memstore.New(10)
+
+	n, err := startEmail(ctx, &config.Config{}, store)
+	if n != nil || err != nil {
+		t.Fatalf("startEmail() without a file = %v, %v; want nil, nil", n, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "email.json")
+	os.WriteFile(path, []byte(`{"smtp": {"host": "localhost", "from": "golog@example.com"}, "alerts": {"to": ["ops@example.com"]}}`), 0o644)
+	n, err = startEmail(ctx, &config.Config{EmailFile: path}, store)
+	if err != nil || n == nil {
+		t.Fatalf("startEmail() = %v, %v", n, err)
+	}
+	cancel()
+	n.Wait()
+
+	os.WriteFile(path, []byte(`{"smtp": {"host": "localhost", "from": "golog@example.com"}}`), 0o644)
+	if _, err := startEmail(context.Background(), &config.Config{EmailFile: path}, store); err == nil {
+		t.Error("startEmail() accepted a config without alerts or digests")
+	}
+}
+
+func TestStartRetention(t *testing.T) {
+	store := memstore.New(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p, err := startRetention(ctx, &config.Config{}, store)
+	if p != nil || err != nil {
+		t.Fatalf("startRetention() without a file = %v, %v; want nil, nil", p, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "retention.json")
+	os.WriteFile(path, []byte(`{"max_age": "30d", "dry_run": true}`), 0o644)
+	p, err = startRetention(ctx, &config.Config{RetentionFile: path}, store)
+	if err != nil || p == nil {
+		t.Fatalf("startRetention() = %v, %v", p, err)
+	}
+	cancel()
+	p.Wait()
+
+	os.WriteFile(path, []byte(`{}`), 0o644)
+	if _, err := startRetention(context.Background(), &config.Config{RetentionFile: path}, store); err == nil {
+		t.Error("startRetention() accepted an empty policy")
+	}
+}
+
+func TestPrepareSchemaWithoutMigrateOnBoot(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).AddRow(1, "init", time.Now()))
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = prepareSchema(&config.Config{MigrateOnBoot: false}, db)
+	if err == nil || !strings.Contains(err.Error(), "migrate up") {
+		t.Errorf("prepareSchema() error = %v, want pending migrations reported", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetupAuth(t *testing.T) {
+	store := auth.NewMemoryStore()
+	if a, err := setupAuth(&config.Config{AdminAPIKey: "golog_secret"}, store); a != nil || err != nil {
+		t.Fatalf("setupAuth() with auth disabled = %v, %v", a, err)
+	}
+
+	cfg := &config.Config{AuthEnabled: true, AdminAPIKey: "golog_secret"}
+	for i := 0; i < 2; i++ {
+		if a, err := setupAuth(cfg, store); a == nil || err != nil {
+			t.Fatalf("setupAuth() = %v, %v", a, err)
+		}
+	}
+	keys, _ := store.ListKeys()
+	if len(keys) != 1 || !keys[0].HasScope(auth.ScopeAdmin) || keys[0].Hash != auth.HashToken("golog_secret") {
+		t.Errorf("keys = %+v, want one admin key", keys)
+	}
+}
@@ -1,21 +1,103 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/tenant"
+)
+
+// Supported values for DB_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
 )
 
 // Config holds the application configuration
 type Config struct {
+	DBDriver   string
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
 	Port       int
+
+	// MemstoreCapacity is the number of entries kept by the memory driver.
+	MemstoreCapacity int
+
+	// SQLitePath is the database file used by the sqlite driver.
+	SQLitePath string
+
+	// SyslogUDPAddr and SyslogTCPAddr enable the syslog receiver when set,
+	// e.g. ":514".
+	SyslogUDPAddr string
+	SyslogTCPAddr string
+
+	// AlertRulesFile is an optional JSON file of alert rules created at
+	// startup when no rule with the same name exists.
+	AlertRulesFile string
+
+	// WebhooksFile is an optional JSON file of webhook destinations.
+	WebhooksFile string
+
+	// EmailFile is an optional JSON file configuring SMTP alert emails and
+	// digests.
+	EmailFile string
+
+	// RetentionFile is an optional JSON file of the retention policy.
+	// Without it entries are kept forever.
+	RetentionFile string
+
+	// MigrateOnBoot applies pending PostgreSQL migrations at startup.
+	// Without it the server refuses to start on an outdated schema.
+	MigrateOnBoot bool
+
+	// PartitionPeriod ("day" or "week") is the range of each partition of
+	// the PostgreSQL logs table, and PartitionPremake the number of future
+	// partitions kept ready. PartitionDetach detaches expired partitions
+	// instead of dropping them.
+	PartitionPeriod  string
+	PartitionPremake int
+	PartitionDetach  bool
+
+	// AuthEnabled requires an API key on API routes. AdminAPIKey, when
+	// set, is stored as an admin key at startup so that a fresh deployment
+	// can create further keys.
+	AuthEnabled bool
+	AdminAPIKey string
+
+	// CORSOrigins lists the origins allowed to call the API from a browser.
+	CORSOrigins []string
+
+	// TenantQuota is the number of entries each tenant may ingest per
+	// minute, 0 for unlimited. TenantQuotas overrides it for individual
+	// tenants ("name:limit,...").
+	TenantQuota  int
+	TenantQuotas map[string]int
+
+	// Timestamps bounds client-supplied timestamps, configured with
+	// TIMESTAMP_POLICY (clamp or reject), TIMESTAMP_MAX_FUTURE and
+	// TIMESTAMP_MAX_PAST (0 for unbounded).
+	Timestamps models.TimestampPolicy
+
+	// IngestBufferDir enables the write-ahead log that buffers ingested
+	// entries until they are written to the database. IngestBufferMaxMB
+	// bounds its size and IngestBufferBatch the entries written at once.
+	IngestBufferDir   string
+	IngestBufferMaxMB int
+	IngestBufferBatch int
+
+	// Registry holds the accepted levels and types, configured with
+	// LOG_LEVELS ("NAME:SEVERITY,...") and LOG_TYPES ("NAME,...").
+	Registry *models.Registry
 }
 
 // Load loads the configuration from environment variables
@@ -31,16 +113,150 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	driver := getEnv("DB_DRIVER", DriverPostgres)
+	if driver != DriverPostgres && driver != DriverMemory && driver != DriverSQLite {
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q", driver)
+	}
+
+	capacity, err := strconv.Atoi(getEnv("MEMSTORE_CAPACITY", "10000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MEMSTORE_CAPACITY: %w", err)
+	}
+
+	registry, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	authEnabled, err := strconv.ParseBool(getEnv("AUTH_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTH_ENABLED: %w", err)
+	}
+	origins := splitList(getEnv("CORS_ORIGINS", "*"))
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("CORS_ORIGINS must list at least one origin")
+	}
+
+	quota, err := strconv.Atoi(getEnv("TENANT_QUOTA", "0"))
+	if err != nil || quota < 0 {
+		return nil, fmt.Errorf("invalid TENANT_QUOTA: must be a non-negative integer")
+	}
+	quotas, err := tenant.ParseLimits(getEnv("TENANT_QUOTAS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TENANT_QUOTAS: %w", err)
+	}
+
+	migrate, err := strconv.ParseBool(getEnv("MIGRATE_ON_BOOT", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MIGRATE_ON_BOOT: %w", err)
+	}
+	period := getEnv("PARTITION_PERIOD", "day")
+	if period != "day" && period != "week" {
+		return nil, fmt.Errorf("invalid PARTITION_PERIOD %q: must be day or week", period)
+	}
+	premake, err := strconv.Atoi(getEnv("PARTITION_PREMAKE", "3"))
+	if err != nil || premake < 1 {
+		return nil, fmt.Errorf("invalid PARTITION_PREMAKE: must be a positive integer")
+	}
+	detach, err := strconv.ParseBool(getEnv("PARTITION_DETACH", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PARTITION_DETACH: %w", err)
+	}
+
+	timestamps, err := loadTimestampPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	bufferMaxMB, err := strconv.Atoi(getEnv("INGEST_BUFFER_MAX_MB", "256"))
+	if err != nil || bufferMaxMB < 1 {
+		return nil, fmt.Errorf("invalid INGEST_BUFFER_MAX_MB: must be a positive integer")
+	}
+	bufferBatch, err := strconv.Atoi(getEnv("INGEST_BUFFER_BATCH", "1000"))
+	if err != nil || bufferBatch < 1 {
+		return nil, fmt.Errorf("invalid INGEST_BUFFER_BATCH: must be a positive integer")
+	}
+
 	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "golog"),
-		Port:       port,
+		DBDriver:          driver,
+		DBHost:            getEnv("DB_HOST", "localhost"),
+		DBPort:            getEnv("DB_PORT", "5432"),
+		DBUser:            getEnv("DB_USER", "postgres"),
+		DBPassword:        getEnv("DB_PASSWORD", "postgres"),
+		DBName:            getEnv("DB_NAME", "golog"),
+		Port:              port,
+		MemstoreCapacity:  capacity,
+		SQLitePath:        getEnv("SQLITE_PATH", "golog.db"),
+		SyslogUDPAddr:     getEnv("SYSLOG_UDP_ADDR", ""),
+		SyslogTCPAddr:     getEnv("SYSLOG_TCP_ADDR", ""),
+		AlertRulesFile:    getEnv("ALERT_RULES_FILE", ""),
+		WebhooksFile:      getEnv("WEBHOOKS_FILE", ""),
+		EmailFile:         getEnv("EMAIL_FILE", ""),
+		RetentionFile:     getEnv("RETENTION_FILE", ""),
+		MigrateOnBoot:     migrate,
+		PartitionPeriod:   period,
+		PartitionPremake:  premake,
+		PartitionDetach:   detach,
+		AuthEnabled:       authEnabled,
+		AdminAPIKey:       getEnv("ADMIN_API_KEY", ""),
+		CORSOrigins:       origins,
+		TenantQuota:       quota,
+		TenantQuotas:      quotas,
+		Timestamps:        timestamps,
+		IngestBufferDir:   getEnv("INGEST_BUFFER_DIR", ""),
+		IngestBufferMaxMB: bufferMaxMB,
+		IngestBufferBatch: bufferBatch,
+		Registry:          registry,
 	}, nil
 }
 
+func loadTimestampPolicy() (models.TimestampPolicy, error) {
+	p := models.TimestampPolicy{Mode: getEnv("TIMESTAMP_POLICY", models.TimestampClamp)}
+	var err error
+	if p.MaxFuture, err = time.ParseDuration(getEnv("TIMESTAMP_MAX_FUTURE", "5m")); err != nil {
+		return p, fmt.Errorf("invalid TIMESTAMP_MAX_FUTURE: %w", err)
+	}
+	if p.MaxPast, err = time.ParseDuration(getEnv("TIMESTAMP_MAX_PAST", "0")); err != nil {
+		return p, fmt.Errorf("invalid TIMESTAMP_MAX_PAST: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return p, fmt.Errorf("invalid timestamp policy: %w", err)
+	}
+	return p, nil
+}
+
+func loadRegistry() (*models.Registry, error) {
+	levels := models.DefaultLevels
+	if v := getEnv("LOG_LEVELS", ""); v != "" {
+		parsed, err := models.ParseLevels(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVELS: %w", err)
+		}
+		levels = parsed
+	}
+	types := models.DefaultTypes
+	if v := getEnv("LOG_TYPES", ""); v != "" {
+		types = models.ParseTypes(v)
+	}
+
+	registry, err := models.NewRegistry(levels, types)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVELS/LOG_TYPES: %w", err)
+	}
+	return registry, nil
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // Helper function to get environment variables with default values
 func getEnv(key, defaultValue string) string {
 	value, exists := os.LookupEnv(key)
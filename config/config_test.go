@@ -3,6 +3,9 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
 )
 
 func TestGetEnv(t *testing.T) {
@@ -35,6 +38,7 @@ func TestLoad(t *testing.T) {
 		"DB_PASSWORD": os.Getenv("DB_PASSWORD"),
 		"DB_NAME":     os.Getenv("DB_NAME"),
 		"PORT":        os.Getenv("PORT"),
+		"DB_DRIVER":   os.Getenv("DB_DRIVER"),
 	}
 
 	// Restore environment after test
@@ -55,6 +59,7 @@ func TestLoad(t *testing.T) {
 	os.Setenv("DB_PASSWORD", "test-password")
 	os.Setenv("DB_NAME", "test-db")
 	os.Setenv("PORT", "9090")
+	os.Unsetenv("DB_DRIVER")
 
 	// Load config
 	cfg, err := Load()
@@ -82,6 +87,10 @@ func TestLoad(t *testing.T) {
 		t.Errorf("cfg.Port = %d; want 9090", cfg.Port)
 	}
 
+	if cfg.DBDriver != DriverPostgres {
+		t.Errorf("cfg.DBDriver = %s; want %s", cfg.DBDriver, DriverPostgres)
+	}
+
 	// Test with invalid port
 	os.Setenv("PORT", "invalid")
 	_, err = Load()
@@ -89,3 +98,299 @@ func TestLoad(t *testing.T) {
 		t.Error("Load() with invalid PORT should return error")
 	}
 }
+
+func TestLoadDriver(t *testing.T) {
+	t.Setenv("PORT", "8080")
+
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("MEMSTORE_CAPACITY", "500")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.DBDriver != DriverMemory || cfg.MemstoreCapacity != 500 {
+		t.Errorf("cfg = %+v; want memory driver with capacity 500", cfg)
+	}
+
+	t.Setenv("DB_DRIVER", DriverSQLite)
+	t.Setenv("SQLITE_PATH", "/var/lib/golog/logs.db")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.DBDriver != DriverSQLite || cfg.SQLitePath != "/var/lib/golog/logs.db" {
+		t.Errorf("cfg = %+v; want sqlite driver with configured path", cfg)
+	}
+
+	t.Setenv("DB_DRIVER", "mysql")
+	if _, err := Load(); err == nil {
+		t.Error("Load() with unsupported DB_DRIVER should return error")
+	}
+}
+
+func TestLoadSyslog(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("SYSLOG_UDP_ADDR", ":5514")
+	t.Setenv("SYSLOG_TCP_ADDR", "127.0.0.1:6514")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.SyslogUDPAddr != ":5514" || cfg.SyslogTCPAddr != "127.0.0.1:6514" {
+		t.Errorf("cfg = %+v; want syslog addresses from the environment", cfg)
+	}
+}
+
+func TestLoadAlertRulesFile(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("ALERT_RULES_FILE", "/etc/golog/rules.json")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.AlertRulesFile != "/etc/golog/rules.json" {
+		t.Errorf("AlertRulesFile = %q", cfg.AlertRulesFile)
+	}
+}
+
+func TestLoadWebhooksFile(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("WEBHOOKS_FILE", "/etc/golog/webhooks.json")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.WebhooksFile != "/etc/golog/webhooks.json" {
+		t.Errorf("WebhooksFile = %q", cfg.WebhooksFile)
+	}
+}
+
+func TestLoadEmailFile(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("EMAIL_FILE", "/etc/golog/email.json")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.EmailFile != "/etc/golog/email.json" {
+		t.Errorf("EmailFile = %q", cfg.EmailFile)
+	}
+}
+
+func TestLoadRetentionFile(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("RETENTION_FILE", "/etc/golog/retention.json")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.RetentionFile != "/etc/golog/retention.json" {
+		t.Errorf("RetentionFile = %q", cfg.RetentionFile)
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.AuthEnabled || len(cfg.CORSOrigins) != 1 || cfg.CORSOrigins[0] != "*" {
+		t.Errorf("defaults: AuthEnabled = %v, CORSOrigins = %v", cfg.AuthEnabled, cfg.CORSOrigins)
+	}
+
+	t.Setenv("AUTH_ENABLED", "true")
+	t.Setenv("ADMIN_API_KEY", "golog_bootstrap")
+	t.Setenv("CORS_ORIGINS", "https://logs.example.com, https://admin.example.com")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.AuthEnabled || cfg.AdminAPIKey != "golog_bootstrap" || len(cfg.CORSOrigins) != 2 || cfg.CORSOrigins[1] != "https://admin.example.com" {
+		t.Errorf("Load() = %+v", cfg)
+	}
+
+	t.Setenv("AUTH_ENABLED", "maybe")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted AUTH_ENABLED=maybe")
+	}
+	t.Setenv("AUTH_ENABLED", "false")
+	t.Setenv("CORS_ORIGINS", " , ")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted an empty CORS_ORIGINS")
+	}
+}
+
+func TestLoadPartitions(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverPostgres)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.PartitionPeriod != "day" || cfg.PartitionPremake != 3 || cfg.PartitionDetach || !cfg.MigrateOnBoot {
+		t.Errorf("defaults = %q, %d, %v, %v", cfg.PartitionPeriod, cfg.PartitionPremake, cfg.PartitionDetach, cfg.MigrateOnBoot)
+	}
+
+	t.Setenv("PARTITION_PERIOD", "week")
+	t.Setenv("PARTITION_PREMAKE", "2")
+	t.Setenv("PARTITION_DETACH", "true")
+	t.Setenv("MIGRATE_ON_BOOT", "false")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.PartitionPeriod != "week" || cfg.PartitionPremake != 2 || !cfg.PartitionDetach || cfg.MigrateOnBoot {
+		t.Errorf("Load() = %q, %d, %v", cfg.PartitionPeriod, cfg.PartitionPremake, cfg.PartitionDetach)
+	}
+
+	for key, value := range map[string]string{"PARTITION_PERIOD": "month", "PARTITION_PREMAKE": "0", "PARTITION_DETACH": "maybe", "MIGRATE_ON_BOOT": "maybe"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() accepted %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestLoadTenantQuotas(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+	t.Setenv("TENANT_QUOTA", "6000")
+	t.Setenv("TENANT_QUOTAS", "billing:100,internal:0")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.TenantQuota != 6000 || len(cfg.TenantQuotas) != 2 || cfg.TenantQuotas["billing"] != 100 {
+		t.Errorf("TenantQuota = %d, TenantQuotas = %v", cfg.TenantQuota, cfg.TenantQuotas)
+	}
+
+	t.Setenv("TENANT_QUOTAS", "billing=100")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted a malformed TENANT_QUOTAS")
+	}
+	t.Setenv("TENANT_QUOTAS", "")
+	t.Setenv("TENANT_QUOTA", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted a negative TENANT_QUOTA")
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+
+	t.Setenv("LOG_LEVELS", "")
+	t.Setenv("LOG_TYPES", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.Registry.ValidLevel("INFO") || !cfg.Registry.ValidType("API") {
+		t.Error("default registry should contain the built-in levels and types")
+	}
+
+	t.Setenv("LOG_LEVELS", "trace:0,DEBUG:10,INFO:20,WARNING:30,ERROR:40,FATAL:50")
+	t.Setenv("LOG_TYPES", "SYSTEM, payments, QUEUE")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.Registry.ValidLevel("TRACE") || !cfg.Registry.ValidType("PAYMENTS") || cfg.Registry.ValidType("API") {
+		t.Errorf("registry = levels %v, types %v", cfg.Registry.LevelNames(), cfg.Registry.Types())
+	}
+
+	t.Setenv("LOG_LEVELS", "INFO:high")
+	if _, err := Load(); err == nil {
+		t.Error("Load() with a non-numeric severity should return error")
+	}
+	t.Setenv("LOG_LEVELS", "")
+	t.Setenv("LOG_TYPES", "BAD TYPE")
+	if _, err := Load(); err == nil {
+		t.Error("Load() with an invalid type name should return error")
+	}
+}
+
+func TestLoadTimestampPolicy(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverMemory)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := models.TimestampPolicy{Mode: models.TimestampClamp, MaxFuture: 5 * time.Minute}
+	if cfg.Timestamps != want {
+		t.Errorf("default Timestamps = %+v, want %+v", cfg.Timestamps, want)
+	}
+
+	t.Setenv("TIMESTAMP_POLICY", "reject")
+	t.Setenv("TIMESTAMP_MAX_FUTURE", "1m")
+	t.Setenv("TIMESTAMP_MAX_PAST", "720h")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want = models.TimestampPolicy{Mode: models.TimestampReject, MaxFuture: time.Minute, MaxPast: 720 * time.Hour}
+	if cfg.Timestamps != want {
+		t.Errorf("Timestamps = %+v, want %+v", cfg.Timestamps, want)
+	}
+
+	for key, value := range map[string]string{"TIMESTAMP_POLICY": "drop", "TIMESTAMP_MAX_FUTURE": "soon", "TIMESTAMP_MAX_PAST": "-1h"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() accepted %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestLoadIngestBuffer(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_DRIVER", DriverPostgres)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.IngestBufferDir != "" || cfg.IngestBufferMaxMB != 256 || cfg.IngestBufferBatch != 1000 {
+		t.Errorf("defaults = %q, %d, %d", cfg.IngestBufferDir, cfg.IngestBufferMaxMB, cfg.IngestBufferBatch)
+	}
+
+	t.Setenv("INGEST_BUFFER_DIR", "/var/lib/golog/buffer")
+	t.Setenv("INGEST_BUFFER_MAX_MB", "64")
+	t.Setenv("INGEST_BUFFER_BATCH", "500")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.IngestBufferDir != "/var/lib/golog/buffer" || cfg.IngestBufferMaxMB != 64 || cfg.IngestBufferBatch != 500 {
+		t.Errorf("Load() = %q, %d, %d", cfg.IngestBufferDir, cfg.IngestBufferMaxMB, cfg.IngestBufferBatch)
+	}
+
+	for key, value := range map[string]string{"INGEST_BUFFER_MAX_MB": "0", "INGEST_BUFFER_BATCH": "many"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() accepted %s=%s", key, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	ruleColumns  = "id, name, levels, types, min_level, attributes, pattern, threshold, window_seconds, enabled, created_at, updated_at"
+	alertColumns = "id, rule_id, rule_name, state, count, message, log_id, fired_at, resolved_at"
+
+	uniqueViolation = "23505"
+)
+
+// AlertStore keeps alert rules and history in the alert_rules and
+// alert_history tables.
+type AlertStore struct {
+	db *sql.DB
+}
+
+// NewAlertStore creates an AlertStore using the connection established by
+// Connect.
+func NewAlertStore() *AlertStore {
+	return &AlertStore{db: DB}
+}
+
+// ListRules returns all rules ordered by id.
+func (s *AlertStore) ListRules() ([]alerting.Rule, error) {
+	rows, err := s.db.Query("SELECT " + ruleColumns + " FROM alert_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []alerting.Rule
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule returns the rule with the given id.
+func (s *AlertStore) GetRule(id int) (alerting.Rule, error) {
+	r, err := scanRule(s.db.QueryRow("SELECT "+ruleColumns+" FROM alert_rules WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, alerting.ErrNotFound
+	}
+	return r, err
+}
+
+// CreateRule inserts r and returns it with its id and timestamps.
+func (s *AlertStore) CreateRule(r alerting.Rule) (alerting.Rule, error) {
+	attrs, err := marshalRuleAttributes(r.Attributes)
+	if err != nil {
+		return r, err
+	}
+	err = s.db.QueryRow(
+		`INSERT INTO alert_rules (name, levels, types, min_level, attributes, pattern, threshold, window_seconds, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`,
+		r.Name, textArray(r.Levels), textArray(r.Types), r.MinLevel, attrs, r.Pattern, r.Threshold, windowSeconds(r.Window), r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+	return r, ruleError(err)
+}
+
+// UpdateRule replaces the rule with r.ID.
+func (s *AlertStore) UpdateRule(r alerting.Rule) (alerting.Rule, error) {
+	attrs, err := marshalRuleAttributes(r.Attributes)
+	if err != nil {
+		return r, err
+	}
+	err = s.db.QueryRow(
+		`UPDATE alert_rules SET name = $1, levels = $2, types = $3, min_level = $4, attributes = $5, pattern = $6,
+		threshold = $7, window_seconds = $8, enabled = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 RETURNING created_at, updated_at`,
+		r.Name, textArray(r.Levels), textArray(r.Types), r.MinLevel, attrs, r.Pattern, r.Threshold, windowSeconds(r.Window), r.Enabled, r.ID,
+	).Scan(&r.CreatedAt, &r.UpdatedAt)
+	return r, ruleError(err)
+}
+
+// DeleteRule removes the rule with the given id. Its history is kept with
+// rule_id cleared.
+func (s *AlertStore) DeleteRule(id int) error {
+	res, err := s.db.Exec("DELETE FROM alert_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return alerting.ErrNotFound
+	}
+	return nil
+}
+
+// InsertAlert records a firing alert and returns its id.
+func (s *AlertStore) InsertAlert(a alerting.Alert) (int, error) {
+	var id int
+	err := s.db.QueryRow(
+		`INSERT INTO alert_history (rule_id, rule_name, state, count, message, log_id, fired_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		nullInt(a.RuleID), a.RuleName, a.State, a.Count, a.Message, nullInt(a.LogID), a.FiredAt,
+	).Scan(&id)
+	return id, err
+}
+
+// ResolveAlert marks an alert as resolved with its final count.
+func (s *AlertStore) ResolveAlert(id, count int, at time.Time) error {
+	_, err := s.db.Exec(
+		"UPDATE alert_history SET state = $1, count = $2, resolved_at = $3 WHERE id = $4",
+		alerting.StateResolved, count, at, id,
+	)
+	return err
+}
+
+// ListAlerts returns matching alerts, newest first.
+func (s *AlertStore) ListAlerts(filter alerting.AlertFilter) ([]alerting.Alert, error) {
+	var args queryArgs
+	where := " WHERE 1=1"
+	if filter.RuleID != 0 {
+		where += " AND rule_id = " + args.add(filter.RuleID)
+	}
+	if filter.State != "" {
+		where += " AND state = " + args.add(filter.State)
+	}
+	query := "SELECT " + alertColumns + " FROM alert_history" + where +
+		" ORDER BY fired_at DESC, id DESC LIMIT " + args.add(filter.PageSize())
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []alerting.Alert
+	for rows.Next() {
+		var a alerting.Alert
+		var ruleID, logID sql.NullInt64
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&a.ID, &ruleID, &a.RuleName, &a.State, &a.Count, &a.Message, &logID, &a.FiredAt, &resolvedAt); err != nil {
+			return nil, err
+		}
+		a.RuleID = int(ruleID.Int64)
+		a.LogID = int(logID.Int64)
+		if resolvedAt.Valid {
+			a.ResolvedAt = &resolvedAt.Time
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func scanRule(row rowScanner) (alerting.Rule, error) {
+	var r alerting.Rule
+	var attrs []byte
+	var window int64
+	err := row.Scan(&r.ID, &r.Name, pq.Array(&r.Levels), pq.Array(&r.Types), &r.MinLevel, &attrs,
+		&r.Pattern, &r.Threshold, &window, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return r, err
+	}
+	r.Window = models.Duration(time.Duration(window) * time.Second)
+	if len(attrs) > 0 {
+		if err := json.Unmarshal(attrs, &r.Attributes); err != nil {
+			return r, fmt.Errorf("decoding attributes of alert rule %d: %w", r.ID, err)
+		}
+		if len(r.Attributes) == 0 {
+			r.Attributes = nil
+		}
+	}
+	return r, nil
+}
+
+func marshalRuleAttributes(attrs map[string]string) (string, error) {
+	if len(attrs) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(attrs)
+	return string(b), err
+}
+
+// textArray binds a nil slice as an empty array rather than NULL.
+func textArray(values []string) any {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
+
+func windowSeconds(d models.Duration) int64 {
+	return int64(time.Duration(d) / time.Second)
+}
+
+// ruleError maps database errors to the errors alerting.Store callers
+// expect.
+func ruleError(err error) error {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return alerting.ErrNotFound
+	case errors.As(err, &pqErr) && pqErr.Code == uniqueViolation:
+		return alerting.ErrNameTaken
+	}
+	return err
+}
+
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
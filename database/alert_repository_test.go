@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/mstgnz/golog/alerting"
+)
+
+func newTestAlertStore(t *testing.T) (*AlertStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &AlertStore{db: db}, mock
+}
+
+var ruleRowColumns = []string{"id", "name", "levels", "types", "min_level", "attributes", "pattern", "threshold", "window_seconds", "enabled", "created_at", "updated_at"}
+
+func TestListRules(t *testing.T) {
+	store, mock := newTestAlertStore(t)
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, name, levels, types, min_level, attributes, pattern, threshold, window_seconds, enabled, created_at, updated_at FROM alert_rules ORDER BY id`).
+		WillReturnRows(sqlmock.NewRows(ruleRowColumns).
+			AddRow(1, "database errors", "{ERROR}", "{DATABASE}", "", []byte(`{"region":"eu"}`), "", 20, 300, true, now, now))
+
+	rules, err := store.ListRules()
+	if err != nil {
+		t.Fatalf("ListRules() error: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("ListRules() = %+v", rules)
+	}
+	r := rules[0]
+	if r.Levels[0] != "ERROR" || r.Types[0] != "DATABASE" || r.Attributes["region"] != "eu" ||
+		time.Duration(r.Window) != 5*time.Minute || r.Threshold != 20 || !r.Enabled {
+		t.Errorf("rule = %+v", r)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateRule(t *testing.T) {
+	store, mock := newTestAlertStore(t)
+	r := alerting.NewRule()
+	r.Name = "panics"
+	r.Pattern = "panic"
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO alert_rules`).
+		WithArgs("panics", "{}", "{}", "", "{}", "panic", 0, int64(300), true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, now, now))
+	mock.ExpectQuery(`INSERT INTO alert_rules`).
+		WillReturnError(&pq.Error{Code: uniqueViolation})
+
+	created, err := store.CreateRule(r)
+	if err != nil || created.ID != 4 {
+		t.Fatalf("CreateRule() = %+v, %v", created, err)
+	}
+	if _, err := store.CreateRule(r); err != alerting.ErrNameTaken {
+		t.Errorf("duplicate CreateRule() error = %v, want ErrNameTaken", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateAndDeleteMissingRule(t *testing.T) {
+	store, mock := newTestAlertStore(t)
+	mock.ExpectQuery(`UPDATE alert_rules SET .* WHERE id = \$10`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(`DELETE FROM alert_rules WHERE id = \$1`).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	r := alerting.NewRule()
+	r.ID, r.Name = 9, "gone"
+	if _, err := store.UpdateRule(r); err != alerting.ErrNotFound {
+		t.Errorf("UpdateRule() error = %v, want ErrNotFound", err)
+	}
+	if err := store.DeleteRule(9); err != alerting.ErrNotFound {
+		t.Errorf("DeleteRule() error = %v, want ErrNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAlertHistory(t *testing.T) {
+	store, mock := newTestAlertStore(t)
+	fired := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`INSERT INTO alert_history`).
+		WithArgs(3, "panics", alerting.StateFiring, 1, "panic: boom", nil, fired).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectExec(`UPDATE alert_history SET state = \$1, count = \$2, resolved_at = \$3 WHERE id = \$4`).
+		WithArgs(alerting.StateResolved, 5, fired.Add(time.Minute), 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id, rule_id, rule_name, state, count, message, log_id, fired_at, resolved_at FROM alert_history WHERE 1=1 AND state = \$1 ORDER BY fired_at DESC, id DESC LIMIT \$2`).
+		WithArgs(alerting.StateFiring, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_id", "rule_name", "state", "count", "message", "log_id", "fired_at", "resolved_at"}).
+			AddRow(12, nil, "deleted rule", alerting.StateFiring, 2, "x", 40, fired, nil))
+
+	id, err := store.InsertAlert(alerting.Alert{RuleID: 3, RuleName: "panics", State: alerting.StateFiring, Count: 1, Message: "panic: boom", FiredAt: fired})
+	if err != nil || id != 11 {
+		t.Fatalf("InsertAlert() = %d, %v", id, err)
+	}
+	if err := store.ResolveAlert(11, 5, fired.Add(time.Minute)); err != nil {
+		t.Fatalf("ResolveAlert() error: %v", err)
+	}
+	alerts, err := store.ListAlerts(alerting.AlertFilter{State: alerting.StateFiring})
+	if err != nil {
+		t.Fatalf("ListAlerts() error: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].RuleID != 0 || alerts[0].LogID != 40 || alerts[0].ResolvedAt != nil {
+		t.Errorf("ListAlerts() = %+v", alerts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
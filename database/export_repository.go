@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// exportFetchSize is the number of rows fetched from the export cursor at a
+// time.
+const exportFetchSize = 1000
+
+// ExportLogs calls fn with every entry matching the filter, oldest first by
+// the filter's time field, ignoring its pagination fields. Rows are read in
+// batches from a server-side cursor within a read-only transaction, so the
+// export is a consistent snapshot and only one batch is held in memory. An
+// error from fn stops the export and is returned.
+func (s *Store) ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error {
+	var args queryArgs
+	where := whereClause(filter, &args)
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DECLARE export_logs NO SCROLL CURSOR FOR SELECT "+logColumns+" FROM logs"+where+" ORDER BY "+filter.TimeColumn()+", id", args...); err != nil {
+		return err
+	}
+	fetch := fmt.Sprintf("FETCH %d FROM export_logs", exportFetchSize)
+	for {
+		n, err := fetchExportBatch(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "CLOSE export_logs"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// fetchExportBatch passes the rows of one FETCH to fn and returns how many
+// there were.
+func fetchExportBatch(ctx context.Context, tx *sql.Tx, fetch string, fn func(models.Log) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return n, err
+		}
+		if err := fn(l); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
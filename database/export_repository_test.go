@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mstgnz/golog/models"
+)
+
+func TestExportLogs(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("FetchesInBatches", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`DECLARE export_logs NO SCROLL CURSOR FOR SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND tenant = \$1 AND timestamp >= \$2 ORDER BY timestamp, id`).
+			WithArgs("billing", since).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		full := sqlmock.NewRows(logRowColumns)
+		for i := 1; i <= exportFetchSize; i++ {
+			full.AddRow(i, since, "INFO", "API", "ok", []byte(`{}`), "billing", since)
+		}
+		mock.ExpectQuery(`FETCH 1000 FROM export_logs`).WillReturnRows(full)
+		mock.ExpectQuery(`FETCH 1000 FROM export_logs`).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(exportFetchSize+1, since, "ERROR", "API", "failed", []byte(`{"code":500}`), "billing", since))
+		mock.ExpectExec(`CLOSE export_logs`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		var got []models.Log
+		err := store.ExportLogs(context.Background(), models.LogFilter{Tenant: "billing", Since: since, Limit: 5}, func(l models.Log) error {
+			got = append(got, l)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ExportLogs() error: %v", err)
+		}
+		if len(got) != exportFetchSize+1 || got[len(got)-1].Attributes["code"] == nil {
+			t.Errorf("exported %d entries, last %+v", len(got), got[len(got)-1])
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("CallbackErrorStops", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`DECLARE export_logs NO SCROLL CURSOR FOR SELECT .* FROM logs WHERE 1=1 ORDER BY timestamp, id`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`FETCH 1000 FROM export_logs`).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, since, "INFO", "API", "ok", []byte(`{}`), "", since).
+				AddRow(2, since, "INFO", "API", "ok", []byte(`{}`), "", since))
+		mock.ExpectRollback()
+
+		errClosed := errors.New("connection closed")
+		calls := 0
+		err := store.ExportLogs(context.Background(), models.LogFilter{}, func(models.Log) error {
+			calls++
+			return errClosed
+		})
+		if !errors.Is(err, errClosed) || calls != 1 {
+			t.Errorf("ExportLogs() = %v after %d calls, want the callback error after 1", err, calls)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// ImportLogs inserts archived entries in a single transaction, keeping their
+// timestamps; entries without one are stamped with the current time. Unless
+// notify is set, the inserts do not trigger NOTIFY, so live streams, alerts
+// and webhooks do not see them. It returns the number of rows inserted.
+func (s *Store) ImportLogs(entries []models.Log, notify bool) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if !notify {
+		if _, err := tx.Exec("SET LOCAL golog.notify = 'off'"); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now()
+	var n int64
+	for start := 0; start < len(entries); start += insertBatchSize {
+		chunk := entries[start:min(start+insertBatchSize, len(entries))]
+
+		var args queryArgs
+		values := make([]string, len(chunk))
+		for i, e := range chunk {
+			attrs, err := marshalAttributes(e.Attributes)
+			if err != nil {
+				return 0, err
+			}
+			values[i] = fmt.Sprintf("(%s, %s, %s, %s, %s, %s)", args.add(eventTime(e, now)), args.add(e.Level), args.add(e.Type), args.add(e.Message), args.add(attrs), args.add(e.Tenant))
+		}
+
+		res, err := tx.Exec("INSERT INTO logs (timestamp, level, type, message, attributes, tenant) VALUES "+strings.Join(values, ", "), args...)
+		if err != nil {
+			return 0, err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		n += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mstgnz/golog/models"
+)
+
+func TestImportLogs(t *testing.T) {
+	ts := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	entries := []models.Log{
+		{Timestamp: ts, Level: "ERROR", Type: "API", Message: "a", Attributes: map[string]any{"code": 500}},
+		{Level: "INFO", Type: "SYSTEM", Message: "b", Tenant: "billing"},
+	}
+
+	t.Run("Quiet", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL golog.notify = 'off'`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO logs \(timestamp, level, type, message, attributes, tenant\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\), \(\$7, \$8, \$9, \$10, \$11, \$12\)$`).
+			WithArgs(ts, "ERROR", "API", "a", `{"code":500}`, "", sqlmock.AnyArg(), "INFO", "SYSTEM", "b", "{}", "billing").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		n, err := store.ImportLogs(entries, false)
+		if err != nil || n != 2 {
+			t.Fatalf("ImportLogs() = %d, %v", n, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("Notify", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO logs`).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		if n, err := store.ImportLogs(entries, true); err != nil || n != 2 {
+			t.Fatalf("ImportLogs() = %d, %v", n, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
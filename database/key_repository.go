@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/mstgnz/golog/auth"
+	"github.com/mstgnz/golog/models"
+)
+
+const keyColumns = "id, name, prefix, key_hash, scopes, created_at, revoked_at, tenant"
+
+// KeyStore keeps API keys and failed authentication attempts in the
+// api_keys and auth_failures tables.
+type KeyStore struct {
+	db *sql.DB
+}
+
+// NewKeyStore creates a KeyStore using the connection established by
+// Connect.
+func NewKeyStore() *KeyStore {
+	return &KeyStore{db: DB}
+}
+
+// CreateKey inserts k and returns it with its id and creation time.
+func (s *KeyStore) CreateKey(k auth.Key) (auth.Key, error) {
+	err := s.db.QueryRow(
+		"INSERT INTO api_keys (name, prefix, key_hash, scopes, tenant) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		k.Name, k.Prefix, k.Hash, textArray(k.Scopes), k.Tenant,
+	).Scan(&k.ID, &k.CreatedAt)
+	return k, err
+}
+
+// KeyByHash returns the key with the given token hash.
+func (s *KeyStore) KeyByHash(hash string) (auth.Key, error) {
+	k, err := scanKey(s.db.QueryRow("SELECT "+keyColumns+" FROM api_keys WHERE key_hash = $1", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, auth.ErrNotFound
+	}
+	return k, err
+}
+
+// ListKeys returns all keys ordered by id.
+func (s *KeyStore) ListKeys() ([]auth.Key, error) {
+	rows, err := s.db.Query("SELECT " + keyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []auth.Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeKey marks the key with the given id revoked.
+func (s *KeyStore) RevokeKey(id int) error {
+	res, err := s.db.Exec("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return auth.ErrNotFound
+	}
+	return nil
+}
+
+// RecordFailure stores a rejected request.
+func (s *KeyStore) RecordFailure(f auth.Failure) error {
+	_, err := s.db.Exec(
+		`INSERT INTO auth_failures (key_prefix, remote_addr, method, path, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		f.KeyPrefix, f.RemoteAddr, f.Method, f.Path, f.Reason, f.CreatedAt,
+	)
+	return err
+}
+
+// ListFailures returns up to limit rejected requests, newest first.
+func (s *KeyStore) ListFailures(limit int) ([]auth.Failure, error) {
+	rows, err := s.db.Query(
+		"SELECT id, key_prefix, remote_addr, method, path, reason, created_at FROM auth_failures ORDER BY id DESC LIMIT $1",
+		models.LogFilter{Limit: limit}.PageSize(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []auth.Failure
+	for rows.Next() {
+		var f auth.Failure
+		if err := rows.Scan(&f.ID, &f.KeyPrefix, &f.RemoteAddr, &f.Method, &f.Path, &f.Reason, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+func scanKey(row rowScanner) (auth.Key, error) {
+	var k auth.Key
+	var revokedAt sql.NullTime
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, pq.Array(&k.Scopes), &k.CreatedAt, &revokedAt, &k.Tenant)
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, err
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mstgnz/golog/auth"
+)
+
+func newTestKeyStore(t *testing.T) (*KeyStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &KeyStore{db: db}, mock
+}
+
+var keyRowColumns = []string{"id", "name", "prefix", "key_hash", "scopes", "created_at", "revoked_at", "tenant"}
+
+func TestKeyStore(t *testing.T) {
+	store, mock := newTestKeyStore(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO api_keys \(name, prefix, key_hash, scopes, tenant\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id, created_at`).
+		WithArgs("ci", "golog_12345678", "abc", `{"ingest","read"}`, "billing").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+	mock.ExpectQuery(`SELECT id, name, prefix, key_hash, scopes, created_at, revoked_at, tenant FROM api_keys WHERE key_hash = \$1`).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows(keyRowColumns).AddRow(3, "ci", "golog_12345678", "abc", "{ingest,read}", now, now, "billing"))
+	mock.ExpectQuery(`FROM api_keys WHERE key_hash = \$1`).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(keyRowColumns))
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = COALESCE\(revoked_at, CURRENT_TIMESTAMP\) WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE api_keys`).
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	k, err := store.CreateKey(auth.Key{Name: "ci", Prefix: "golog_12345678", Hash: "abc", Scopes: []string{"ingest", "read"}, Tenant: "billing"})
+	if err != nil || k.ID != 3 {
+		t.Fatalf("CreateKey() = %+v, %v", k, err)
+	}
+	k, err = store.KeyByHash("abc")
+	if err != nil || len(k.Scopes) != 2 || !k.HasScope(auth.ScopeRead) || !k.Revoked() || k.Tenant != "billing" {
+		t.Errorf("KeyByHash() = %+v, %v", k, err)
+	}
+	if _, err := store.KeyByHash("missing"); err != auth.ErrNotFound {
+		t.Errorf("KeyByHash() of unknown hash error = %v", err)
+	}
+	if err := store.RevokeKey(3); err != nil {
+		t.Errorf("RevokeKey() error: %v", err)
+	}
+	if err := store.RevokeKey(9); err != auth.ErrNotFound {
+		t.Errorf("RevokeKey() of unknown id error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestKeyStoreFailures(t *testing.T) {
+	store, mock := newTestKeyStore(t)
+	now := time.Now()
+
+	mock.ExpectExec(`INSERT INTO auth_failures \(key_prefix, remote_addr, method, path, reason, created_at\)`).
+		WithArgs("golog_1234", "10.0.0.1:5123", "POST", "/api/logs", "invalid API key", now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT id, key_prefix, remote_addr, method, path, reason, created_at FROM auth_failures ORDER BY id DESC LIMIT \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key_prefix", "remote_addr", "method", "path", "reason", "created_at"}).
+			AddRow(1, "golog_1234", "10.0.0.1:5123", "POST", "/api/logs", "invalid API key", now))
+
+	err := store.RecordFailure(auth.Failure{
+		KeyPrefix: "golog_1234", RemoteAddr: "10.0.0.1:5123", Method: "POST", Path: "/api/logs", Reason: "invalid API key", CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("RecordFailure() error: %v", err)
+	}
+	failures, err := store.ListFailures(20)
+	if err != nil || len(failures) != 1 || failures[0].Reason != "invalid API key" {
+		t.Errorf("ListFailures() = %+v, %v", failures, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,8 +18,7 @@ import (
 )
 
 const (
-	defaultLimit = 100
-	maxLimit     = 500
+	logColumns = "id, timestamp, level, type, message, attributes, tenant, received_at"
 )
 
 // Store wraps a *sql.DB and provides log operations.
@@ -30,31 +34,26 @@ func NewStore() *Store {
 
 // GetLogs retrieves log entries with optional filtering and pagination.
 func (s *Store) GetLogs(filter models.LogFilter) ([]models.Log, error) {
-	query := "SELECT id, timestamp, level, type, message FROM logs WHERE 1=1"
-	args := []any{}
-	argCount := 1
+	var args queryArgs
+	where := whereClause(filter, &args)
 
-	if filter.Level != "" {
-		query += fmt.Sprintf(" AND level = $%d", argCount)
-		args = append(args, filter.Level)
-		argCount++
-	}
-	if filter.Type != "" {
-		query += fmt.Sprintf(" AND type = $%d", argCount)
-		args = append(args, filter.Type)
-		argCount++
+	columns := logColumns
+	headline := filter.Query != "" && filter.EffectiveSearchMode() == models.SearchFullText
+	if headline {
+		columns += fmt.Sprintf(", ts_headline('simple', message, plainto_tsquery('simple', %s), '%s')", args.add(filter.Query), headlineOptions)
 	}
 
-	limit := filter.Limit
-	if limit <= 0 {
-		limit = defaultLimit
-	}
-	if limit > maxLimit {
-		limit = maxLimit
+	timeColumn, order := filter.TimeColumn(), "DESC"
+	if filter.Cursor != nil {
+		op := "<"
+		if filter.Cursor.Backward {
+			op, order = ">", "ASC"
+		}
+		where += fmt.Sprintf(" AND (%s, id) %s (%s, %s)", timeColumn, op, args.add(filter.Cursor.Timestamp), args.add(filter.Cursor.ID))
 	}
 
-	query += fmt.Sprintf(" ORDER BY timestamp DESC LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, limit, filter.Offset)
+	query := "SELECT " + columns + " FROM logs" + where +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s OFFSET %s", timeColumn, order, order, args.add(filter.PageSize()), args.add(filter.Offset))
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -65,28 +64,251 @@ func (s *Store) GetLogs(filter models.LogFilter) ([]models.Log, error) {
 	var logs []models.Log
 	for rows.Next() {
 		var l models.Log
-		if err := rows.Scan(&l.ID, &l.Timestamp, &l.Level, &l.Type, &l.Message); err != nil {
+		if headline {
+			var snippet string
+			l, err = scanLog(rows, &snippet)
+			l.Snippet = snippet
+		} else {
+			l, err = scanLog(rows)
+			if err == nil && filter.Query != "" {
+				l.Snippet = filter.Highlight(l.Message, "<mark>", "</mark>")
+			}
+		}
+		if err != nil {
 			return nil, err
 		}
 		logs = append(logs, l)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor != nil && filter.Cursor.Backward {
+		slices.Reverse(logs)
+	}
+	return logs, nil
+}
+
+// headlineOptions makes ts_headline mark matches the same way as
+// LogFilter.Highlight does for substring and regex searches.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2"
+
+// queryArgs collects positional query parameters.
+type queryArgs []any
+
+// add appends v and returns its placeholder.
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// whereClause renders the filter conditions shared by log queries.
+func whereClause(filter models.LogFilter, args *queryArgs) string {
+	where := " WHERE 1=1"
+
+	if filter.Tenant != "" {
+		where += " AND tenant = " + args.add(filter.Tenant)
+	}
+
+	if levels := filter.LevelSet(); levels != nil {
+		where += " AND " + anyOf("level", levels, args)
+	}
+	if types := filter.TypeSet(); types != nil {
+		where += " AND " + anyOf("type", types, args)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND " + filter.TimeColumn() + " >= " + args.add(filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where += " AND " + filter.TimeColumn() + " < " + args.add(filter.Until)
+	}
+
+	keys := make([]string, 0, len(filter.Attributes))
+	for k := range filter.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		where += fmt.Sprintf(" AND attributes->>%s = %s", args.add(k), args.add(filter.Attributes[k]))
+	}
+
+	if filter.Query != "" {
+		switch filter.EffectiveSearchMode() {
+		case models.SearchSubstring:
+			where += " AND message ILIKE " + args.add("%"+likeEscaper.Replace(filter.Query)+"%")
+		case models.SearchRegex:
+			where += " AND message ~* " + args.add(filter.Query)
+		default:
+			where += " AND message_tsv @@ plainto_tsquery('simple', " + args.add(filter.Query) + ")"
+		}
+	}
 
-	return logs, rows.Err()
+	return where
 }
 
-// InsertLog inserts a new log entry and returns its ID.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// anyOf matches column against a set of values; an empty set matches
+// nothing.
+func anyOf(column string, values []string, args *queryArgs) string {
+	switch len(values) {
+	case 0:
+		return "FALSE"
+	case 1:
+		return column + " = " + args.add(values[0])
+	default:
+		return column + " = ANY(" + args.add(pq.Array(values)) + ")"
+	}
+}
+
+// InsertLog inserts a new log entry and returns its ID. The entry keeps its
+// timestamp and receive time; either defaults to the current time.
 func (s *Store) InsertLog(logEntry models.Log) (int, error) {
+	attrs, err := marshalAttributes(logEntry.Attributes)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
 	var id int
-	err := s.db.QueryRow(
-		"INSERT INTO logs (level, type, message) VALUES ($1, $2, $3) RETURNING id",
-		logEntry.Level, logEntry.Type, logEntry.Message,
+	err = s.db.QueryRow(
+		"INSERT INTO logs (timestamp, received_at, level, type, message, attributes, tenant) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		eventTime(logEntry, now), receiptTime(logEntry, now), logEntry.Level, logEntry.Type, logEntry.Message, attrs, logEntry.Tenant,
 	).Scan(&id)
 	return id, err
 }
 
+// eventTime returns the timestamp of l, or now when it has none.
+func eventTime(l models.Log, now time.Time) time.Time {
+	if l.Timestamp.IsZero() {
+		return now
+	}
+	return l.Timestamp
+}
+
+// receiptTime returns when l was received, or now when that is not known
+// yet, e.g. for entries that did not pass through an ingestion buffer.
+func receiptTime(l models.Log, now time.Time) time.Time {
+	if l.ReceivedAt.IsZero() {
+		return now
+	}
+	return l.ReceivedAt
+}
+
+// insertBatchSize bounds the rows per INSERT statement so that the seven
+// parameters per row stay well below PostgreSQL's 65535 parameter limit.
+const insertBatchSize = 1000
+
+// Permanent reports whether an insert failed because of the entries rather
+// than the database, so that retrying it cannot succeed: invalid data
+// (SQLSTATE class 22), a violated constraint such as a timestamp no
+// partition covers (23), or an entry exceeding a limit (54).
+func (s *Store) Permanent(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "22", "23", "54":
+		return true
+	}
+	return false
+}
+
+// InsertLogs inserts entries in a single transaction using multi-row INSERTs
+// and returns their IDs in input order. Either all entries are stored or
+// none are.
+func (s *Store) InsertLogs(entries []models.Log) ([]int, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	ids := make([]int, 0, len(entries))
+	for start := 0; start < len(entries); start += insertBatchSize {
+		chunk := entries[start:min(start+insertBatchSize, len(entries))]
+
+		var args queryArgs
+		values := make([]string, len(chunk))
+		for i, e := range chunk {
+			attrs, err := marshalAttributes(e.Attributes)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s)", args.add(eventTime(e, now)), args.add(receiptTime(e, now)), args.add(e.Level), args.add(e.Type), args.add(e.Message), args.add(attrs), args.add(e.Tenant))
+		}
+
+		rows, err := tx.Query("INSERT INTO logs (timestamp, received_at, level, type, message, attributes, tenant) VALUES "+strings.Join(values, ", ")+" RETURNING id", args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ids) != len(entries) {
+		return nil, fmt.Errorf("inserted %d rows, expected %d", len(ids), len(entries))
+	}
+	return ids, tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLog reads a row selected with logColumns followed by any extra columns.
+func scanLog(row rowScanner, extra ...any) (models.Log, error) {
+	var l models.Log
+	var attrs []byte
+	dest := append([]any{&l.ID, &l.Timestamp, &l.Level, &l.Type, &l.Message, &attrs, &l.Tenant, &l.ReceivedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return l, err
+	}
+	if len(attrs) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(attrs))
+		dec.UseNumber()
+		if err := dec.Decode(&l.Attributes); err != nil {
+			return l, fmt.Errorf("decoding attributes of log %d: %w", l.ID, err)
+		}
+		if len(l.Attributes) == 0 {
+			l.Attributes = nil
+		}
+	}
+	return l, nil
+}
+
+func marshalAttributes(attrs map[string]any) (string, error) {
+	if len(attrs) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return "", fmt.Errorf("encoding attributes: %w", err)
+	}
+	return string(b), nil
+}
+
 // ListenForLogs subscribes to PostgreSQL NOTIFY on log_channel and forwards
-// each notification as a Log to ch. The goroutine stops and closes ch when ctx
-// is cancelled.
+// each notification as a Log to ch. Notifications only carry a compact,
+// possibly truncated copy of the row, so entries are re-read by ID before
+// being forwarded; notifications arriving in a burst are hydrated with a
+// single query. The goroutine stops and closes ch when ctx is cancelled.
 func (s *Store) ListenForLogs(ctx context.Context, logChan chan<- models.Log) error {
 	listener := pq.NewListener(s.dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
 		if err != nil {
@@ -104,22 +326,93 @@ func (s *Store) ListenForLogs(ctx context.Context, logChan chan<- models.Log) er
 		defer listener.Close()
 		defer close(logChan)
 		for {
+			var batch []models.Log
 			select {
 			case n := <-listener.Notify:
-				if n == nil {
-					continue
-				}
-				var logEntry models.Log
-				if err := json.Unmarshal([]byte(n.Extra), &logEntry); err != nil {
-					log.Printf("Error unmarshaling notification: %v\n", err)
-					continue
-				}
-				logChan <- logEntry
+				batch = appendNotification(batch, n)
 			case <-ctx.Done():
 				return
 			}
+
+		drain:
+			for len(batch) < hydrateBatchSize {
+				select {
+				case n := <-listener.Notify:
+					batch = appendNotification(batch, n)
+				default:
+					break drain
+				}
+			}
+
+			for _, logEntry := range s.hydrate(ctx, batch) {
+				select {
+				case logChan <- logEntry:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
 	}()
 
 	return nil
 }
+
+// hydrateBatchSize caps how many notifications are looked up in one query.
+const hydrateBatchSize = 100
+
+// appendNotification decodes a notification payload and appends it to batch.
+// A nil notification signals a listener reconnect and is skipped.
+func appendNotification(batch []models.Log, n *pq.Notification) []models.Log {
+	if n == nil {
+		return batch
+	}
+	var logEntry models.Log
+	dec := json.NewDecoder(strings.NewReader(n.Extra))
+	dec.UseNumber()
+	if err := dec.Decode(&logEntry); err != nil {
+		log.Printf("Error unmarshaling notification: %v\n", err)
+		return batch
+	}
+	return append(batch, logEntry)
+}
+
+// hydrate replaces the compact notification payloads in batch with the full
+// rows. Entries that cannot be loaded are passed through as notified.
+func (s *Store) hydrate(ctx context.Context, batch []models.Log) []models.Log {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ids := make([]int64, len(batch))
+	for i, l := range batch {
+		ids[i] = int64(l.ID)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+logColumns+" FROM logs WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		log.Printf("Error loading notified logs: %v\n", err)
+		return batch
+	}
+	defer rows.Close()
+
+	full := make(map[int]models.Log, len(batch))
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			log.Printf("Error loading notified logs: %v\n", err)
+			return batch
+		}
+		full[l.ID] = l
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error loading notified logs: %v\n", err)
+		return batch
+	}
+
+	for i, l := range batch {
+		if f, ok := full[l.ID]; ok {
+			batch[i] = f
+		}
+	}
+	return batch
+}
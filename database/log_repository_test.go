@@ -1,13 +1,19 @@
 package database
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/mstgnz/golog/models"
 )
 
+var logRowColumns = []string{"id", "timestamp", "level", "type", "message", "attributes", "tenant", "received_at"}
+
 func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
 	t.Helper()
 	db, mock, err := sqlmock.New()
@@ -22,11 +28,11 @@ func TestGetLogs(t *testing.T) {
 	t.Run("NoFilters", func(t *testing.T) {
 		store, mock := newTestStore(t)
 
-		mock.ExpectQuery(`SELECT id, timestamp, level, type, message FROM logs WHERE 1=1 ORDER BY timestamp DESC LIMIT \$1 OFFSET \$2`).
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 ORDER BY timestamp DESC, id DESC LIMIT \$1 OFFSET \$2`).
 			WithArgs(100, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "level", "type", "message"}).
-				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed").
-				AddRow(2, time.Now(), "INFO", "SYSTEM", "System started"))
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed", []byte(`{}`), "", time.Now()).
+				AddRow(2, time.Now(), "INFO", "SYSTEM", "System started", []byte(`{}`), "", time.Now()))
 
 		logs, err := store.GetLogs(models.LogFilter{})
 		if err != nil {
@@ -43,10 +49,10 @@ func TestGetLogs(t *testing.T) {
 	t.Run("LevelFilter", func(t *testing.T) {
 		store, mock := newTestStore(t)
 
-		mock.ExpectQuery(`SELECT id, timestamp, level, type, message FROM logs WHERE 1=1 AND level = \$1 ORDER BY timestamp DESC LIMIT \$2 OFFSET \$3`).
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND level = \$1 ORDER BY timestamp DESC, id DESC LIMIT \$2 OFFSET \$3`).
 			WithArgs("ERROR", 100, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "level", "type", "message"}).
-				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed"))
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed", []byte(`{}`), "", time.Now()))
 
 		logs, err := store.GetLogs(models.LogFilter{Level: "ERROR"})
 		if err != nil {
@@ -66,10 +72,10 @@ func TestGetLogs(t *testing.T) {
 	t.Run("TypeFilter", func(t *testing.T) {
 		store, mock := newTestStore(t)
 
-		mock.ExpectQuery(`SELECT id, timestamp, level, type, message FROM logs WHERE 1=1 AND type = \$1 ORDER BY timestamp DESC LIMIT \$2 OFFSET \$3`).
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND type = \$1 ORDER BY timestamp DESC, id DESC LIMIT \$2 OFFSET \$3`).
 			WithArgs("DATABASE", 100, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "level", "type", "message"}).
-				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed"))
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed", []byte(`{}`), "", time.Now()))
 
 		logs, err := store.GetLogs(models.LogFilter{Type: "DATABASE"})
 		if err != nil {
@@ -89,10 +95,10 @@ func TestGetLogs(t *testing.T) {
 	t.Run("BothFilters", func(t *testing.T) {
 		store, mock := newTestStore(t)
 
-		mock.ExpectQuery(`SELECT id, timestamp, level, type, message FROM logs WHERE 1=1 AND level = \$1 AND type = \$2 ORDER BY timestamp DESC LIMIT \$3 OFFSET \$4`).
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND level = \$1 AND type = \$2 ORDER BY timestamp DESC, id DESC LIMIT \$3 OFFSET \$4`).
 			WithArgs("ERROR", "DATABASE", 100, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "level", "type", "message"}).
-				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed"))
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection failed", []byte(`{}`), "", time.Now()))
 
 		logs, err := store.GetLogs(models.LogFilter{Level: "ERROR", Type: "DATABASE"})
 		if err != nil {
@@ -106,13 +112,165 @@ func TestGetLogs(t *testing.T) {
 		}
 	})
 
+	t.Run("LevelSetAndMinLevel", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND level = ANY\(\$1\) AND type = ANY\(\$2\) ORDER BY timestamp DESC, id DESC LIMIT \$3 OFFSET \$4`).
+			WithArgs(`{"WARNING","ERROR"}`, `{"AUTH","API"}`, 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns))
+
+		_, err := store.GetLogs(models.LogFilter{MinLevel: "WARNING", Types: []string{"AUTH", "API"}})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("EmptyLevelSet", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND FALSE ORDER BY timestamp DESC, id DESC LIMIT \$1 OFFSET \$2`).
+			WithArgs(100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns))
+
+		_, err := store.GetLogs(models.LogFilter{Level: "DEBUG", MinLevel: "ERROR"})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("AttributeFilter", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND attributes->>\$1 = \$2 AND attributes->>\$3 = \$4 ORDER BY timestamp DESC, id DESC LIMIT \$5 OFFSET \$6`).
+			WithArgs("request_id", "abc", "user_id", "42", 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "INFO", "API", "Request handled", []byte(`{"user_id": 42, "request_id": "abc"}`), "", time.Now()))
+
+		logs, err := store.GetLogs(models.LogFilter{Attributes: map[string]string{"user_id": "42", "request_id": "abc"}})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 1 {
+			t.Fatalf("GetLogs() = %d logs, want 1", len(logs))
+		}
+		if got := models.AttributeString(logs[0].Attributes["user_id"]); got != "42" {
+			t.Errorf("user_id attribute = %q, want 42", got)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("TimeRange", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		since := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+		until := since.Add(15 * time.Minute)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND level = \$1 AND timestamp >= \$2 AND timestamp < \$3 ORDER BY timestamp DESC, id DESC LIMIT \$4 OFFSET \$5`).
+			WithArgs("ERROR", since, until, 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, since.Add(time.Minute), "ERROR", "DATABASE", "Connection failed", []byte(`{}`), "", since.Add(time.Minute)))
+
+		logs, err := store.GetLogs(models.LogFilter{Level: "ERROR", Since: since, Until: until})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 1 {
+			t.Errorf("GetLogs() = %d logs, want 1", len(logs))
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("TenantFilter", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND tenant = \$1 AND level = \$2 ORDER BY timestamp DESC, id DESC LIMIT \$3 OFFSET \$4`).
+			WithArgs("billing", "ERROR", 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "ERROR", "API", "Charge failed", []byte(`{}`), "billing", time.Now()))
+
+		logs, err := store.GetLogs(models.LogFilter{Level: "ERROR", Tenant: "billing"})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 1 || logs[0].Tenant != "billing" {
+			t.Errorf("GetLogs() = %+v, want one billing entry", logs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("FullTextSearch", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at, ts_headline\('simple', message, plainto_tsquery\('simple', \$2\), '.+'\) FROM logs WHERE 1=1 AND message_tsv @@ plainto_tsquery\('simple', \$1\) ORDER BY timestamp DESC, id DESC LIMIT \$3 OFFSET \$4`).
+			WithArgs("connection timeout", "connection timeout", 100, 0).
+			WillReturnRows(sqlmock.NewRows(append(logRowColumns, "ts_headline")).
+				AddRow(1, time.Now(), "ERROR", "DATABASE", "Connection timeout", []byte(`{}`), "", time.Now(), "<mark>Connection</mark> <mark>timeout</mark>"))
+
+		logs, err := store.GetLogs(models.LogFilter{Query: "connection timeout"})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 1 || logs[0].Snippet != "<mark>Connection</mark> <mark>timeout</mark>" {
+			t.Errorf("GetLogs() = %+v, want one log with highlighted snippet", logs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("SubstringSearch", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND message ILIKE \$1 ORDER BY timestamp DESC, id DESC LIMIT \$2 OFFSET \$3`).
+			WithArgs(`%100\%\_done%`, 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(1, time.Now(), "INFO", "SYSTEM", "job 100%_done", []byte(`{}`), "", time.Now()))
+
+		logs, err := store.GetLogs(models.LogFilter{Query: "100%_done", SearchMode: models.SearchSubstring})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 1 || logs[0].Snippet != "job <mark>100%_done</mark>" {
+			t.Errorf("GetLogs() = %+v, want one log with highlighted snippet", logs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("RegexSearch", func(t *testing.T) {
+		store, mock := newTestStore(t)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND level = \$1 AND message ~\* \$2 ORDER BY timestamp DESC, id DESC LIMIT \$3 OFFSET \$4`).
+			WithArgs("ERROR", `panic: .+`, 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns))
+
+		if _, err := store.GetLogs(models.LogFilter{Level: "ERROR", Query: `panic: .+`, SearchMode: models.SearchRegex}); err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
 	t.Run("Pagination", func(t *testing.T) {
 		store, mock := newTestStore(t)
 
-		mock.ExpectQuery(`SELECT id, timestamp, level, type, message FROM logs WHERE 1=1 ORDER BY timestamp DESC LIMIT \$1 OFFSET \$2`).
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 ORDER BY timestamp DESC, id DESC LIMIT \$1 OFFSET \$2`).
 			WithArgs(10, 20).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "level", "type", "message"}).
-				AddRow(21, time.Now(), "INFO", "SYSTEM", "paged"))
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(21, time.Now(), "INFO", "SYSTEM", "paged", []byte(`{}`), "", time.Now()))
 
 		logs, err := store.GetLogs(models.LogFilter{Limit: 10, Offset: 20})
 		if err != nil {
@@ -126,12 +284,78 @@ func TestGetLogs(t *testing.T) {
 		}
 	})
 
+	t.Run("ReceivedAt", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		since := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+		ts := since.Add(time.Hour)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND received_at >= \$1 AND \(received_at, id\) < \(\$2, \$3\) ORDER BY received_at DESC, id DESC LIMIT \$4 OFFSET \$5`).
+			WithArgs(since, ts, 9, 100, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(8, since.Add(-time.Hour), "INFO", "SYSTEM", "late", []byte(`{}`), "", since.Add(time.Minute)))
+
+		logs, err := store.GetLogs(models.LogFilter{Since: since, TimeField: models.TimeFieldReceivedAt, Cursor: &models.Cursor{Timestamp: ts, ID: 9}})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 1 || !logs[0].ReceivedAt.Equal(since.Add(time.Minute)) || !logs[0].Timestamp.Equal(since.Add(-time.Hour)) {
+			t.Errorf("GetLogs() = %+v", logs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("CursorForward", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		ts := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND \(timestamp, id\) < \(\$1, \$2\) ORDER BY timestamp DESC, id DESC LIMIT \$3 OFFSET \$4`).
+			WithArgs(ts, 50, 2, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(49, ts, "INFO", "SYSTEM", "b", []byte(`{}`), "", ts).
+				AddRow(48, ts.Add(-time.Second), "INFO", "SYSTEM", "a", []byte(`{}`), "", ts.Add(-time.Second)))
+
+		logs, err := store.GetLogs(models.LogFilter{Limit: 2, Cursor: &models.Cursor{Timestamp: ts, ID: 50}})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 2 || logs[0].ID != 49 || logs[1].ID != 48 {
+			t.Errorf("GetLogs() returned unexpected order: %+v", logs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("CursorBackward", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		ts := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 AND \(timestamp, id\) > \(\$1, \$2\) ORDER BY timestamp ASC, id ASC LIMIT \$3 OFFSET \$4`).
+			WithArgs(ts, 50, 2, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns).
+				AddRow(51, ts, "INFO", "SYSTEM", "c", []byte(`{}`), "", ts).
+				AddRow(52, ts.Add(time.Second), "INFO", "SYSTEM", "d", []byte(`{}`), "", ts.Add(time.Second)))
+
+		logs, err := store.GetLogs(models.LogFilter{Limit: 2, Cursor: &models.Cursor{Timestamp: ts, ID: 50, Backward: true}})
+		if err != nil {
+			t.Fatalf("GetLogs() error: %v", err)
+		}
+		if len(logs) != 2 || logs[0].ID != 52 || logs[1].ID != 51 {
+			t.Errorf("GetLogs() should return newest first, got %+v", logs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
 	t.Run("LimitCappedAtMax", func(t *testing.T) {
 		store, mock := newTestStore(t)
 
-		mock.ExpectQuery(`SELECT id, timestamp, level, type, message FROM logs WHERE 1=1 ORDER BY timestamp DESC LIMIT \$1 OFFSET \$2`).
-			WithArgs(maxLimit, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "level", "type", "message"}))
+		mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE 1=1 ORDER BY timestamp DESC, id DESC LIMIT \$1 OFFSET \$2`).
+			WithArgs(models.MaxLimit, 0).
+			WillReturnRows(sqlmock.NewRows(logRowColumns))
 
 		_, err := store.GetLogs(models.LogFilter{Limit: 9999})
 		if err != nil {
@@ -146,10 +370,18 @@ func TestGetLogs(t *testing.T) {
 func TestInsertLog(t *testing.T) {
 	store, mock := newTestStore(t)
 
-	logEntry := models.Log{Level: "ERROR", Type: "DATABASE", Message: "Connection failed"}
+	logEntry := models.Log{
+		Timestamp:  time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		ReceivedAt: time.Date(2024, 1, 15, 10, 30, 2, 0, time.UTC),
+		Level:      "ERROR",
+		Type:       "DATABASE",
+		Message:    "Connection failed",
+		Attributes: map[string]any{"duration_ms": 5000},
+		Tenant:     "billing",
+	}
 
-	mock.ExpectQuery(`INSERT INTO logs \(level, type, message\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
-		WithArgs(logEntry.Level, logEntry.Type, logEntry.Message).
+	mock.ExpectQuery(`INSERT INTO logs \(timestamp, received_at, level, type, message, attributes, tenant\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING id`).
+		WithArgs(logEntry.Timestamp, logEntry.ReceivedAt, logEntry.Level, logEntry.Type, logEntry.Message, `{"duration_ms":5000}`, "billing").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	id, err := store.InsertLog(logEntry)
@@ -163,3 +395,123 @@ func TestInsertLog(t *testing.T) {
 		t.Errorf("Unfulfilled expectations: %v", err)
 	}
 }
+
+func TestInsertLogs(t *testing.T) {
+	store, mock := newTestStore(t)
+
+	entries := []models.Log{
+		{Level: "INFO", Type: "SYSTEM", Message: "one"},
+		{Timestamp: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), Level: "ERROR", Type: "API", Message: "two", Attributes: map[string]any{"status": 500}, Tenant: "search"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO logs \(timestamp, received_at, level, type, message, attributes, tenant\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\), \(\$8, \$9, \$10, \$11, \$12, \$13, \$14\) RETURNING id`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "INFO", "SYSTEM", "one", "{}", "", entries[1].Timestamp, sqlmock.AnyArg(), "ERROR", "API", "two", `{"status":500}`, "search").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(8))
+	mock.ExpectCommit()
+
+	got, err := store.InsertLogs(entries)
+	if err != nil {
+		t.Fatalf("InsertLogs() error: %v", err)
+	}
+	if fmt.Sprint(got) != "[7 8]" {
+		t.Errorf("InsertLogs() ids = %v, want [7 8]", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInsertLogsRollsBackOnError(t *testing.T) {
+	store, mock := newTestStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO logs`).WillReturnError(fmt.Errorf("constraint violation"))
+	mock.ExpectRollback()
+
+	if _, err := store.InsertLogs([]models.Log{{Level: "INFO", Type: "SYSTEM", Message: "one"}}); err == nil {
+		t.Fatal("InsertLogs() should fail when the insert fails")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	store := &Store{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "23514"}, true},
+		{fmt.Errorf("inserting: %w", &pq.Error{Code: "22P02"}), true},
+		{&pq.Error{Code: "54000"}, true},
+		{&pq.Error{Code: "57P01"}, false},
+		{&pq.Error{Code: "40001"}, false},
+		{fmt.Errorf("dial tcp: connection refused"), false},
+	}
+	for _, tc := range tests {
+		if got := store.Permanent(tc.err); got != tc.want {
+			t.Errorf("Permanent(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestAppendNotification(t *testing.T) {
+	batch := appendNotification(nil, nil)
+	if len(batch) != 0 {
+		t.Fatalf("nil notification should be skipped, got %v", batch)
+	}
+
+	batch = appendNotification(batch, &pq.Notification{Extra: `not json`})
+	if len(batch) != 0 {
+		t.Fatalf("malformed notification should be skipped, got %v", batch)
+	}
+
+	batch = appendNotification(batch, &pq.Notification{Extra: `{"id":7,"timestamp":"2024-01-15T10:30:00Z","level":"ERROR","type":"API","message":"trunc","tenant":"billing"}`})
+	if len(batch) != 1 || batch[0].ID != 7 || batch[0].Level != "ERROR" || batch[0].Tenant != "billing" {
+		t.Errorf("appendNotification() = %+v, want decoded entry with id 7", batch)
+	}
+}
+
+func TestHydrate(t *testing.T) {
+	store, mock := newTestStore(t)
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	longMessage := strings.Repeat("x", models.MaxMessageLength)
+
+	batch := []models.Log{
+		{ID: 3, Timestamp: ts, Level: "ERROR", Type: "API", Message: longMessage[:1000]},
+		{ID: 4, Timestamp: ts, Level: "INFO", Type: "SYSTEM", Message: "deleted before lookup"},
+	}
+
+	mock.ExpectQuery(`SELECT id, timestamp, level, type, message, attributes, tenant, received_at FROM logs WHERE id = ANY\(\$1\)`).
+		WithArgs("{3,4}").
+		WillReturnRows(sqlmock.NewRows(logRowColumns).
+			AddRow(3, ts, "ERROR", "API", longMessage, []byte(`{"request_id":"abc"}`), "", ts))
+
+	got := store.hydrate(context.Background(), batch)
+	if len(got) != 2 {
+		t.Fatalf("hydrate() returned %d entries, want 2", len(got))
+	}
+	if got[0].Message != longMessage || got[0].Attributes["request_id"] != "abc" {
+		t.Errorf("first entry was not hydrated with the full row")
+	}
+	if got[1].Message != "deleted before lookup" {
+		t.Errorf("missing row should fall back to the notification payload, got %q", got[1].Message)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestHydrateQueryError(t *testing.T) {
+	store, mock := newTestStore(t)
+	batch := []models.Log{{ID: 1, Message: "compact"}}
+
+	mock.ExpectQuery(`SELECT .+ FROM logs WHERE id = ANY\(\$1\)`).WillReturnError(fmt.Errorf("connection reset"))
+
+	got := store.hydrate(context.Background(), batch)
+	if len(got) != 1 || got[0].Message != "compact" {
+		t.Errorf("hydrate() on error = %+v, want notification payload", got)
+	}
+}
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the schema as numbered pairs of files,
+// <version>_<name>.up.sql and <version>_<name>.down.sql, applied in order.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID keys the advisory lock that keeps servers starting at the
+// same time from migrating concurrently.
+const migrationLockID = 4_712_002
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus describes one migration. AppliedAt is zero while it is
+// pending; Unknown marks a version recorded in the database that this build
+// does not have, i.e. one applied by a newer golog.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+	Unknown   bool
+}
+
+func loadMigrations() ([]migration, error) {
+	paths, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*migration)
+	for _, p := range paths {
+		base := strings.TrimPrefix(p, "migrations/")
+		stem, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		v, name, ok2 := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(v)
+		if !ok || !ok2 || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.up.sql or .down.sql", base)
+		}
+		data, err := migrationFiles.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = string(data)
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// Migrate applies all pending migrations.
+func Migrate(db *sql.DB) error {
+	_, err := MigrateUp(db, 0)
+	return err
+}
+
+// MigrateUp applies the pending migrations up to and including version to,
+// or all of them when to is 0, each in its own transaction. It returns the
+// number applied.
+func MigrateUp(db *sql.DB, to int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if v := newestUnknown(migrations, applied); v != 0 {
+			return fmt.Errorf("the database has migration %d, which is newer than this build", v)
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.version]; ok || (to > 0 && m.version > to) {
+				continue
+			}
+			if err := runMigration(conn, m.up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+			}
+			log.Printf("Applied migration %04d_%s", m.version, m.name)
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and
+// returns the number reverted.
+func MigrateDown(db *sql.DB, steps int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if v := newestUnknown(migrations, applied); v != 0 {
+			return fmt.Errorf("the database has migration %d, which is newer than this build", v)
+		}
+		for i := len(migrations) - 1; i >= 0 && n < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.version]; !ok {
+				continue
+			}
+			if err := runMigration(conn, m.down, "DELETE FROM schema_migrations WHERE version = $1", m.version); err != nil {
+				return fmt.Errorf("reverting migration %04d_%s: %w", m.version, m.name, err)
+			}
+			log.Printf("Reverted migration %04d_%s", m.version, m.name)
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// MigrationStatuses lists the known migrations, and any unknown ones found
+// in the database, by version.
+func MigrationStatuses(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		rows, err := conn.QueryContext(context.Background(), "SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		recorded := make(map[int]MigrationStatus)
+		for rows.Next() {
+			var s MigrationStatus
+			if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+				return err
+			}
+			recorded[s.Version] = s
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			s := MigrationStatus{Version: m.version, Name: m.name}
+			if r, ok := recorded[m.version]; ok {
+				s.AppliedAt = r.AppliedAt
+				delete(recorded, m.version)
+			}
+			statuses = append(statuses, s)
+		}
+		for _, r := range recorded {
+			r.Unknown = true
+			statuses = append(statuses, r)
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// PendingMigrations returns the number of migrations not yet applied.
+func PendingMigrations(db *sql.DB) (int, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range statuses {
+		if s.AppliedAt.IsZero() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// withMigrationLock runs fn on one connection holding the migration lock,
+// after creating schema_migrations if needed. Advisory locks belong to a
+// session, so everything must use that connection.
+func withMigrationLock(db *sql.DB, fn func(*sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedMigrations(conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// newestUnknown returns the newest applied version that is not among
+// migrations, or 0.
+func newestUnknown(migrations []migration, applied map[int]bool) int {
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.version] = true
+	}
+	newest := 0
+	for v := range applied {
+		if !known[v] && v > newest {
+			newest = v
+		}
+	}
+	return newest
+}
+
+// runMigration runs script and records the change in one transaction.
+func runMigration(conn *sql.Conn, script, record string, args ...any) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
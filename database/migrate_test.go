@@ -0,0 +1,193 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations() error: %v", err)
+	}
+	if len(migrations) < 2 || migrations[0].version != 1 || migrations[0].name != "init" {
+		t.Fatalf("loadMigrations() = %+v", migrations)
+	}
+	for i, m := range migrations {
+		if m.version != i+1 || strings.TrimSpace(m.up) == "" || strings.TrimSpace(m.down) == "" {
+			t.Errorf("migration %d = version %d, want consecutive versions with up and down SQL", i, m.version)
+		}
+	}
+}
+
+func newTestMigrationDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+// expectLocked expects the lock and table setup of withMigrationLock and
+// returns a function expecting the unlock.
+func expectLocked(mock sqlmock.Sqlmock) func() {
+	mock.ExpectExec("SELECT pg_advisory_lock($1)").WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createMigrationsTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	return func() {
+		mock.ExpectExec("SELECT pg_advisory_unlock($1)").WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int) {
+	rows := sqlmock.NewRows([]string{"version"})
+	for _, v := range versions {
+		rows.AddRow(v)
+	}
+	mock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(rows)
+}
+
+func TestMigrateUp(t *testing.T) {
+	migrations, _ := loadMigrations()
+	db, mock := newTestMigrationDB(t)
+
+	unlock := expectLocked(mock)
+	expectApplied(mock, 1)
+	for _, m := range migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(m.up).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)").
+			WithArgs(m.version, m.name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	unlock()
+
+	n, err := MigrateUp(db, 0)
+	if err != nil || n != len(migrations)-1 {
+		t.Fatalf("MigrateUp() = %d, %v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUpTo(t *testing.T) {
+	migrations, _ := loadMigrations()
+	db, mock := newTestMigrationDB(t)
+
+	unlock := expectLocked(mock)
+	expectApplied(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(migrations[0].up).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)").
+		WithArgs(1, "init").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	unlock()
+
+	if n, err := MigrateUp(db, 1); err != nil || n != 1 {
+		t.Fatalf("MigrateUp(1) = %d, %v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUpRollsBackOnError(t *testing.T) {
+	migrations, _ := loadMigrations()
+	db, mock := newTestMigrationDB(t)
+
+	unlock := expectLocked(mock)
+	expectApplied(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(migrations[0].up).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	unlock()
+
+	_, err := MigrateUp(db, 0)
+	if err == nil || !strings.Contains(err.Error(), "0001_init") {
+		t.Errorf("MigrateUp() error = %v, want it to name the migration", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	db, mock := newTestMigrationDB(t)
+
+	unlock := expectLocked(mock)
+	expectApplied(mock, 1, 2, 999)
+	unlock()
+
+	if _, err := MigrateUp(db, 0); err == nil || !strings.Contains(err.Error(), "999") {
+		t.Errorf("MigrateUp() error = %v, want it to refuse migration 999", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	migrations, _ := loadMigrations()
+	last := migrations[len(migrations)-1]
+	db, mock := newTestMigrationDB(t)
+
+	unlock := expectLocked(mock)
+	var all []int
+	for _, m := range migrations {
+		all = append(all, m.version)
+	}
+	expectApplied(mock, all...)
+	mock.ExpectBegin()
+	mock.ExpectExec(last.down).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version = $1").
+		WithArgs(last.version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	unlock()
+
+	if n, err := MigrateDown(db, 1); err != nil || n != 1 {
+		t.Fatalf("MigrateDown(1) = %d, %v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrationStatuses(t *testing.T) {
+	migrations, _ := loadMigrations()
+	db, mock := newTestMigrationDB(t)
+	applied := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	unlock := expectLocked(mock)
+	mock.ExpectQuery("SELECT version, name, applied_at FROM schema_migrations ORDER BY version").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+			AddRow(1, "init", applied).
+			AddRow(999, "future", applied))
+	unlock()
+
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		t.Fatalf("MigrationStatuses() error: %v", err)
+	}
+	if len(statuses) != len(migrations)+1 {
+		t.Fatalf("MigrationStatuses() = %+v", statuses)
+	}
+	if !statuses[0].AppliedAt.Equal(applied) || !statuses[1].AppliedAt.IsZero() {
+		t.Errorf("statuses = %+v, want only the first applied", statuses)
+	}
+	if last := statuses[len(statuses)-1]; !last.Unknown || last.Version != 999 || last.Name != "future" {
+		t.Errorf("last status = %+v, want the unknown migration", last)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
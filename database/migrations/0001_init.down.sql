@@ -0,0 +1,4 @@
+-- Remove everything golog created. pg_trgm is left installed because other
+-- schemas may use it.
+DROP TABLE IF EXISTS auth_failures, api_keys, webhook_dead_letters, alert_history, alert_rules, logs;
+DROP FUNCTION IF EXISTS notify_log_change();
@@ -0,0 +1,128 @@
+-- The schema formerly applied by hand from init.sql. Every statement is
+-- idempotent so that databases initialized that way are adopted as they are.
+CREATE TABLE IF NOT EXISTS logs (
+    id SERIAL PRIMARY KEY,
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    level VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    message_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED
+);
+
+-- Upgrade tables created before these columns were introduced
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS message_tsv TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED;
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Levels are configurable (LOG_LEVELS) and may be as long as types. Widening
+-- a VARCHAR does not rewrite the table.
+ALTER TABLE logs ALTER COLUMN level TYPE VARCHAR(50);
+
+-- Full-text search (q) uses the tsvector index; substring and regex search use trigrams
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_logs_message_tsv ON logs USING GIN (message_tsv);
+CREATE INDEX IF NOT EXISTS idx_logs_message_trgm ON logs USING GIN (message gin_trgm_ops);
+
+-- Time-range queries, ORDER BY timestamp DESC and keyset pagination on
+-- (timestamp, id) all use this index
+DROP INDEX IF EXISTS idx_logs_timestamp;
+CREATE INDEX IF NOT EXISTS idx_logs_timestamp_id ON logs (timestamp DESC, id DESC);
+
+-- Queries from tenant-scoped API keys always filter on tenant
+CREATE INDEX IF NOT EXISTS idx_logs_tenant_timestamp_id ON logs (tenant, timestamp DESC, id DESC);
+
+-- Create function to notify on new log entries. pg_notify payloads are limited
+-- to 8000 bytes, so only a compact summary is sent; listeners load the full
+-- row by id. The tenant is included so that the stream never delivers an
+-- entry to another tenant's subscribers, even when the row cannot be loaded.
+CREATE OR REPLACE FUNCTION notify_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('log_channel', json_build_object(
+        'id', NEW.id,
+        'timestamp', NEW.timestamp,
+        'level', NEW.level,
+        'type', NEW.type,
+        'message', left(NEW.message, 1000),
+        'tenant', NEW.tenant
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create trigger to call the notify function
+DROP TRIGGER IF EXISTS log_notify_trigger ON logs;
+CREATE TRIGGER log_notify_trigger
+AFTER INSERT ON logs
+FOR EACH ROW
+EXECUTE FUNCTION notify_log_change();
+
+-- Alert rules and the history of alerts they raised. History outlives
+-- deleted rules.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    levels TEXT[] NOT NULL DEFAULT '{}',
+    types TEXT[] NOT NULL DEFAULT '{}',
+    min_level VARCHAR(32) NOT NULL DEFAULT '',
+    attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    pattern TEXT NOT NULL DEFAULT '',
+    threshold INTEGER NOT NULL DEFAULT 0,
+    window_seconds INTEGER NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_history (
+    id SERIAL PRIMARY KEY,
+    rule_id INTEGER REFERENCES alert_rules (id) ON DELETE SET NULL,
+    rule_name VARCHAR(100) NOT NULL,
+    state VARCHAR(16) NOT NULL,
+    count INTEGER NOT NULL,
+    message TEXT NOT NULL,
+    log_id INTEGER,
+    fired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_history_fired_at ON alert_history (fired_at DESC, id DESC);
+
+-- Webhook deliveries that failed after all retries
+CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+    id SERIAL PRIMARY KEY,
+    destination VARCHAR(100) NOT NULL,
+    url TEXT NOT NULL,
+    event VARCHAR(16) NOT NULL,
+    payload TEXT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- API keys, stored as SHA-256 hashes of the token, and rejected requests
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    tenant VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS auth_failures (
+    id SERIAL PRIMARY KEY,
+    key_prefix VARCHAR(16) NOT NULL,
+    remote_addr TEXT NOT NULL,
+    method VARCHAR(16) NOT NULL,
+    path TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,36 @@
+-- Copy all partitions back into a single logs table. Detached partitions are
+-- left alone. id stays BIGINT, which the earlier schema handles as well.
+DROP TRIGGER IF EXISTS log_notify_trigger ON logs;
+ALTER TABLE logs RENAME TO logs_partitioned;
+ALTER TABLE logs_partitioned RENAME CONSTRAINT logs_pkey TO logs_partitioned_pkey;
+DROP INDEX idx_logs_id, idx_logs_message_tsv, idx_logs_message_trgm, idx_logs_tenant_timestamp_id;
+ALTER SEQUENCE logs_id_seq OWNED BY NONE;
+
+CREATE TABLE logs (
+    id BIGINT PRIMARY KEY DEFAULT nextval('logs_id_seq'),
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    level VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    message_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED
+);
+
+ALTER SEQUENCE logs_id_seq OWNED BY logs.id;
+
+INSERT INTO logs (id, timestamp, level, type, message, attributes, tenant)
+SELECT id, timestamp, level, type, message, attributes, tenant
+FROM logs_partitioned;
+
+DROP TABLE logs_partitioned;
+
+CREATE INDEX idx_logs_message_tsv ON logs USING GIN (message_tsv);
+CREATE INDEX idx_logs_message_trgm ON logs USING GIN (message gin_trgm_ops);
+CREATE INDEX idx_logs_timestamp_id ON logs (timestamp DESC, id DESC);
+CREATE INDEX idx_logs_tenant_timestamp_id ON logs (tenant, timestamp DESC, id DESC);
+
+CREATE TRIGGER log_notify_trigger
+AFTER INSERT ON logs
+FOR EACH ROW
+EXECUTE FUNCTION notify_log_change();
@@ -0,0 +1,56 @@
+-- Range-partition logs by timestamp. Existing rows are copied into
+-- logs_legacy, which covers everything before the start of tomorrow (UTC);
+-- the server creates the daily or weekly partitions that follow and drops
+-- logs_legacy once retention has expired all of it. Rows outside every
+-- partition land in logs_default.
+DROP TRIGGER IF EXISTS log_notify_trigger ON logs;
+ALTER TABLE logs RENAME TO logs_unpartitioned;
+ALTER TABLE logs_unpartitioned RENAME CONSTRAINT logs_pkey TO logs_unpartitioned_pkey;
+
+-- Keep the id sequence so that ids continue where they left off
+ALTER SEQUENCE logs_id_seq OWNED BY NONE;
+ALTER SEQUENCE logs_id_seq AS BIGINT;
+
+-- The primary key of a partitioned table must include the partition key
+CREATE TABLE logs (
+    id BIGINT NOT NULL DEFAULT nextval('logs_id_seq'),
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    level VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    message_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED,
+    PRIMARY KEY (timestamp, id)
+) PARTITION BY RANGE (timestamp);
+
+ALTER SEQUENCE logs_id_seq OWNED BY logs.id;
+
+DO $$
+BEGIN
+    EXECUTE format('CREATE TABLE logs_legacy PARTITION OF logs FOR VALUES FROM (MINVALUE) TO (%L)',
+        (date_trunc('day', now() AT TIME ZONE 'UTC') + interval '1 day') AT TIME ZONE 'UTC');
+END
+$$;
+CREATE TABLE logs_default PARTITION OF logs DEFAULT;
+
+INSERT INTO logs (id, timestamp, level, type, message, attributes, tenant)
+SELECT id, COALESCE(timestamp, CURRENT_TIMESTAMP), level, type, message, attributes, tenant
+FROM logs_unpartitioned;
+
+DROP TABLE logs_unpartitioned;
+
+-- Indexes on a partitioned table are created on every partition. The
+-- primary key serves ORDER BY timestamp DESC, id DESC and keyset
+-- pagination; listeners load rows by id alone.
+CREATE INDEX idx_logs_id ON logs (id);
+CREATE INDEX idx_logs_message_tsv ON logs USING GIN (message_tsv);
+CREATE INDEX idx_logs_message_trgm ON logs USING GIN (message gin_trgm_ops);
+CREATE INDEX idx_logs_tenant_timestamp_id ON logs (tenant, timestamp DESC, id DESC);
+
+CREATE TRIGGER log_notify_trigger
+AFTER INSERT ON logs
+FOR EACH ROW
+EXECUTE FUNCTION notify_log_change();
+
+ALTER TABLE alert_history ALTER COLUMN log_id TYPE BIGINT;
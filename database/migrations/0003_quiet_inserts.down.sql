@@ -0,0 +1,14 @@
+CREATE OR REPLACE FUNCTION notify_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('log_channel', json_build_object(
+        'id', NEW.id,
+        'timestamp', NEW.timestamp,
+        'level', NEW.level,
+        'type', NEW.type,
+        'message', left(NEW.message, 1000),
+        'tenant', NEW.tenant
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,19 @@
+-- Let a transaction insert rows without notifying listeners, e.g. when
+-- backfilling archived logs, by running SET LOCAL golog.notify = 'off' first.
+CREATE OR REPLACE FUNCTION notify_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('golog.notify', true) = 'off' THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('log_channel', json_build_object(
+        'id', NEW.id,
+        'timestamp', NEW.timestamp,
+        'level', NEW.level,
+        'type', NEW.type,
+        'message', left(NEW.message, 1000),
+        'tenant', NEW.tenant
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
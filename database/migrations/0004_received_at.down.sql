@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_logs_received_at_id;
+ALTER TABLE logs DROP COLUMN received_at;
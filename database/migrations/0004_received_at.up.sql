@@ -0,0 +1,10 @@
+-- Keep the time an entry was received apart from the event time sent by the
+-- client. Existing rows were stamped on receipt, so both times are equal.
+ALTER TABLE logs ADD COLUMN received_at TIMESTAMP WITH TIME ZONE;
+UPDATE logs SET received_at = timestamp;
+ALTER TABLE logs ALTER COLUMN received_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE logs ALTER COLUMN received_at SET NOT NULL;
+
+-- Serves ORDER BY received_at DESC, id DESC and keyset pagination by receive
+-- time.
+CREATE INDEX idx_logs_received_at_id ON logs (received_at DESC, id DESC);
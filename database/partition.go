@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Period is the time range covered by one partition of the logs table.
+type Period string
+
+// Supported partition periods. Weeks start on Monday; both are aligned to
+// UTC.
+const (
+	Daily  Period = "day"
+	Weekly Period = "week"
+)
+
+// PartitionCheckInterval is how often the Partitioner creates and drops
+// partitions.
+const PartitionCheckInterval = time.Hour
+
+func (p Period) start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if p == Weekly {
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+func (p Period) next(t time.Time) time.Time {
+	if p == Weekly {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// Partition is one partition of the logs table. From is zero for a partition
+// without lower bound; the default partition has neither bound.
+type Partition struct {
+	Name    string
+	From    time.Time
+	To      time.Time
+	Default bool
+}
+
+// listPartitions reads the bounds back from the catalog. They are cast in
+// the same query so that the session time zone does not matter.
+const listPartitions = `SELECT c.relname,
+       pg_get_expr(c.relpartbound, c.oid) = 'DEFAULT',
+       (regexp_match(pg_get_expr(c.relpartbound, c.oid), 'FROM \(''(.*?)''\)'))[1]::timestamptz,
+       (regexp_match(pg_get_expr(c.relpartbound, c.oid), 'TO \(''(.*?)''\)'))[1]::timestamptz
+FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'logs'::regclass
+ORDER BY c.relname`
+
+// Partitioner creates partitions of the logs table ahead of time and drops
+// the ones retention has expired entirely.
+type Partitioner struct {
+	db      *sql.DB
+	period  Period
+	premake int
+	horizon time.Duration
+	detach  bool
+	wg      sync.WaitGroup
+}
+
+// NewPartitioner creates a Partitioner keeping premake partitions of period
+// ready after the current one, using the connection established by Connect.
+func NewPartitioner(period Period, premake int) *Partitioner {
+	return &Partitioner{db: DB, period: period, premake: premake}
+}
+
+// SetRetention drops partitions whose entries are all older than horizon,
+// or detaches them to be archived by hand when detach is set.
+func (p *Partitioner) SetRetention(horizon time.Duration, detach bool) {
+	p.horizon = horizon
+	p.detach = detach
+}
+
+// Partitions lists the partitions of the logs table.
+func (p *Partitioner) Partitions() ([]Partition, error) {
+	rows, err := p.db.Query(listPartitions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []Partition
+	for rows.Next() {
+		var part Partition
+		var isDefault sql.NullBool
+		var from, to sql.NullTime
+		if err := rows.Scan(&part.Name, &isDefault, &from, &to); err != nil {
+			return nil, err
+		}
+		part.Default = isDefault.Bool
+		part.From = from.Time
+		part.To = to.Time
+		parts = append(parts, part)
+	}
+	return parts, rows.Err()
+}
+
+// Maintain creates the partitions from the current period up to premake
+// periods ahead and drops or detaches expired ones.
+func (p *Partitioner) Maintain(now time.Time) error {
+	parts, err := p.Partitions()
+	if err != nil {
+		return fmt.Errorf("listing partitions: %w", err)
+	}
+
+	// New partitions start where the last one ends, so that they never
+	// overlap partitions created with another period.
+	var upper time.Time
+	var def string
+	for _, part := range parts {
+		if part.Default {
+			def = part.Name
+		}
+		if part.To.After(upper) {
+			upper = part.To
+		}
+	}
+	start := p.period.start(now)
+	for i := 0; i <= p.premake; i++ {
+		end := p.period.next(start)
+		from := start
+		if upper.After(from) {
+			from = upper
+		}
+		if from.Before(end) {
+			if err := p.create(def, from, end); err != nil {
+				return err
+			}
+			upper = end
+		}
+		start = end
+	}
+
+	if p.horizon <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-p.horizon)
+	for _, part := range parts {
+		if part.Default || part.To.IsZero() || part.To.After(cutoff) {
+			continue
+		}
+		if err := p.expire(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// create adds the partition [from, to). Postgres refuses to create it while
+// the default partition def holds rows in that range, which happens when
+// entries are accepted further ahead than the premade partitions reach, so
+// those rows are moved into the new partition in the same transaction.
+func (p *Partitioner) create(def string, from, to time.Time) error {
+	name := "logs_p" + from.Format("20060102")
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF logs FOR VALUES FROM (%s) TO (%s)",
+		pq.QuoteIdentifier(name), pq.QuoteLiteral(from.Format(time.RFC3339)), pq.QuoteLiteral(to.Format(time.RFC3339)))
+
+	var moved int64
+	if def == "" {
+		if _, err := p.db.Exec(stmt); err != nil {
+			return fmt.Errorf("creating partition %s: %w", name, err)
+		}
+	} else {
+		var err error
+		if moved, err = p.createFromDefault(def, stmt, from, to); err != nil {
+			return fmt.Errorf("creating partition %s: %w", name, err)
+		}
+	}
+	log.Printf("Created partition %s for %s to %s", name, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	if moved > 0 {
+		log.Printf("Moved %d entries from %s to partition %s", moved, def, name)
+	}
+	return nil
+}
+
+func (p *Partitioner) createFromDefault(def, stmt string, from, to time.Time) (int64, error) {
+	var exists bool
+	if err := p.db.QueryRow(fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE timestamp >= $1 AND timestamp < $2)",
+		pq.QuoteIdentifier(def)), from, to).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		_, err := p.db.Exec(stmt)
+		return 0, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The moved rows are not new, so listeners are not notified of them.
+	if _, err := tx.Exec("SET LOCAL golog.notify = 'off'"); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("ALTER TABLE logs DETACH PARTITION " + pq.QuoteIdentifier(def)); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(stmt); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(fmt.Sprintf("WITH moved AS (DELETE FROM %s WHERE timestamp >= $1 AND timestamp < $2 RETURNING %s) INSERT INTO logs (%s) SELECT %s FROM moved",
+		pq.QuoteIdentifier(def), logColumns, logColumns, logColumns), from, to)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("ALTER TABLE logs ATTACH PARTITION " + pq.QuoteIdentifier(def) + " DEFAULT"); err != nil {
+		return 0, err
+	}
+	return moved, tx.Commit()
+}
+
+func (p *Partitioner) expire(part Partition) error {
+	stmt, action := "DROP TABLE %s", "Dropped"
+	if p.detach {
+		stmt, action = "ALTER TABLE logs DETACH PARTITION %s", "Detached"
+	}
+	if _, err := p.db.Exec(fmt.Sprintf(stmt, pq.QuoteIdentifier(part.Name))); err != nil {
+		return fmt.Errorf("expiring partition %s: %w", part.Name, err)
+	}
+	log.Printf("%s expired partition %s ending %s", action, part.Name, part.To.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// Start maintains the partitions immediately and then every
+// PartitionCheckInterval until ctx is done.
+func (p *Partitioner) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(PartitionCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := p.Maintain(time.Now()); err != nil {
+				log.Printf("Error maintaining partitions: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the goroutine started by Start has returned.
+func (p *Partitioner) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var partitionColumns = []string{"relname", "is_default", "from", "to"}
+
+func date(day int) time.Time {
+	return time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodStart(t *testing.T) {
+	tests := []struct {
+		period Period
+		t      time.Time
+		want   time.Time
+	}{
+		{Daily, date(6).Add(13 * time.Hour), date(6)},
+		{Weekly, date(6).Add(13 * time.Hour), date(4)},
+		{Weekly, date(10).Add(23 * time.Hour), date(4)},
+		{Weekly, date(11), date(11)},
+		{Daily, time.Date(2024, 3, 6, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), date(5)},
+	}
+	for _, tc := range tests {
+		if got := tc.period.start(tc.t); !got.Equal(tc.want) {
+			t.Errorf("%s start(%v) = %v, want %v", tc.period, tc.t, got, tc.want)
+		}
+	}
+}
+
+func newTestPartitioner(t *testing.T, period Period, premake int) (*Partitioner, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Partitioner{db: db, period: period, premake: premake}, mock
+}
+
+func expectCreate(mock sqlmock.Sqlmock, name string, from, to time.Time) {
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS \"" + name + "\" PARTITION OF logs FOR VALUES FROM ('" +
+		from.Format(time.RFC3339) + "') TO ('" + to.Format(time.RFC3339) + "')")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectDefaultRows(mock sqlmock.Sqlmock, from, to time.Time, exists bool) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM "logs_default" WHERE timestamp >= $1 AND timestamp < $2)`)).
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+}
+
+func TestMaintainCreatesPartitions(t *testing.T) {
+	t.Run("Daily", func(t *testing.T) {
+		p, mock := newTestPartitioner(t, Daily, 2)
+		mock.ExpectQuery(`SELECT c.relname`).
+			WillReturnRows(sqlmock.NewRows(partitionColumns).
+				AddRow("logs_default", true, nil, nil).
+				AddRow("logs_legacy", false, nil, date(7)))
+		expectDefaultRows(mock, date(7), date(8), false)
+		expectCreate(mock, "logs_p20240307", date(7), date(8))
+		expectDefaultRows(mock, date(8), date(9), false)
+		expectCreate(mock, "logs_p20240308", date(8), date(9))
+
+		if err := p.Maintain(date(6).Add(12 * time.Hour)); err != nil {
+			t.Fatalf("Maintain() error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("WeeklyAfterLegacy", func(t *testing.T) {
+		p, mock := newTestPartitioner(t, Weekly, 1)
+		mock.ExpectQuery(`SELECT c.relname`).
+			WillReturnRows(sqlmock.NewRows(partitionColumns).
+				AddRow("logs_legacy", false, nil, date(7)))
+		expectCreate(mock, "logs_p20240307", date(7), date(11))
+		expectCreate(mock, "logs_p20240311", date(11), date(18))
+
+		if err := p.Maintain(date(6)); err != nil {
+			t.Fatalf("Maintain() error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestMaintainMovesRowsFromDefault(t *testing.T) {
+	// Entries timestamped past the premade partitions land in logs_default,
+	// and the partition covering them can only be created once they are
+	// moved out.
+	p, mock := newTestPartitioner(t, Daily, 0)
+	mock.ExpectQuery(`SELECT c.relname`).
+		WillReturnRows(sqlmock.NewRows(partitionColumns).
+			AddRow("logs_default", true, nil, nil).
+			AddRow("logs_p20240306", false, date(6), date(7)))
+	expectDefaultRows(mock, date(7), date(8), true)
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL golog.notify = 'off'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE logs DETACH PARTITION "logs_default"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectCreate(mock, "logs_p20240307", date(7), date(8))
+	mock.ExpectExec(regexp.QuoteMeta(`WITH moved AS (DELETE FROM "logs_default" WHERE timestamp >= $1 AND timestamp < $2 RETURNING `+logColumns+`) INSERT INTO logs (`+logColumns+`) SELECT `+logColumns+` FROM moved`)).
+		WithArgs(date(7), date(8)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE logs ATTACH PARTITION "logs_default" DEFAULT`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := p.Maintain(date(7).Add(time.Hour)); err != nil {
+		t.Fatalf("Maintain() error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMaintainExpiresPartitions(t *testing.T) {
+	for _, detach := range []bool{false, true} {
+		p, mock := newTestPartitioner(t, Daily, 0)
+		p.SetRetention(30*24*time.Hour, detach)
+		mock.ExpectQuery(`SELECT c.relname`).
+			WillReturnRows(sqlmock.NewRows(partitionColumns).
+				AddRow("logs_default", true, nil, nil).
+				AddRow("logs_p20240101", false, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)).
+				AddRow("logs_p20240306", false, date(6), date(7)))
+		if detach {
+			mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE logs DETACH PARTITION "logs_p20240101"`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		} else {
+			mock.ExpectExec(regexp.QuoteMeta(`DROP TABLE "logs_p20240101"`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+
+		if err := p.Maintain(date(6)); err != nil {
+			t.Fatalf("Maintain() error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("detach=%v: unfulfilled expectations: %v", detach, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mstgnz/golog/retention"
+)
+
+// CountLogs counts the entries matching c.
+func (s *Store) CountLogs(c retention.Condition) (int64, error) {
+	var args queryArgs
+	var n int64
+	err := s.db.QueryRow("SELECT COUNT(*) FROM logs"+retentionWhere(c, &args), args...).Scan(&n)
+	return n, err
+}
+
+// DeleteLogs deletes up to limit of the oldest entries matching c. The
+// subquery keeps each statement to one bounded batch.
+func (s *Store) DeleteLogs(c retention.Condition, limit int) (int64, error) {
+	var args queryArgs
+	where := retentionWhere(c, &args)
+	query := fmt.Sprintf("DELETE FROM logs WHERE id IN (SELECT id FROM logs%s ORDER BY timestamp, id LIMIT %s)", where, args.add(limit))
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func retentionWhere(c retention.Condition, args *queryArgs) string {
+	where := " WHERE 1=1"
+	if !c.Before.IsZero() {
+		where += " AND timestamp < " + args.add(c.Before)
+	}
+	if c.Levels != nil {
+		where += " AND " + anyOf("level", c.Levels, args)
+	}
+	if c.Types != nil {
+		where += " AND " + anyOf("type", c.Types, args)
+	}
+	if len(c.ExceptLevels) > 0 {
+		where += " AND level <> ALL(" + args.add(pq.Array(c.ExceptLevels)) + ")"
+	}
+	if len(c.ExceptTypes) > 0 {
+		where += " AND type <> ALL(" + args.add(pq.Array(c.ExceptTypes)) + ")"
+	}
+	return where
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/mstgnz/golog/retention"
+)
+
+func TestRetention(t *testing.T) {
+	store, mock := newTestStore(t)
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM logs WHERE 1=1$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	if n, err := store.CountLogs(retention.Condition{}); err != nil || n != 42 {
+		t.Errorf("CountLogs() = %d, %v", n, err)
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM logs WHERE 1=1 AND timestamp < \$1 AND type = \$2 AND level <> ALL\(\$3\)`).
+		WithArgs(before, "AUTH", pq.Array([]string{"DEBUG", "ERROR"})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	if n, err := store.CountLogs(retention.Condition{Before: before, Types: []string{"AUTH"}, ExceptLevels: []string{"DEBUG", "ERROR"}}); err != nil || n != 7 {
+		t.Errorf("CountLogs() = %d, %v", n, err)
+	}
+
+	mock.ExpectExec(`DELETE FROM logs WHERE id IN \(SELECT id FROM logs WHERE 1=1 AND timestamp < \$1 AND level <> ALL\(\$2\) AND type <> ALL\(\$3\) ORDER BY timestamp, id LIMIT \$4\)`).
+		WithArgs(before, pq.Array([]string{"DEBUG"}), pq.Array([]string{"AUTH"}), 500).
+		WillReturnResult(sqlmock.NewResult(0, 500))
+	n, err := store.DeleteLogs(retention.Condition{Before: before, ExceptLevels: []string{"DEBUG"}, ExceptTypes: []string{"AUTH"}}, 500)
+	if err != nil || n != 500 {
+		t.Errorf("DeleteLogs() = %d, %v", n, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
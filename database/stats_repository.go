@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Stats counts the entries matching the query's filter per interval. Empty
+// intervals are omitted.
+func (s *Store) Stats(q models.StatsQuery) ([]models.StatsBucket, error) {
+	var args queryArgs
+	where := whereClause(q.Filter, &args)
+	secs := args.add(int64(q.Interval / time.Second))
+
+	// Only level and type pass StatsQuery.Validate, so they are safe to use
+	// as column names. Rows are ordered like models.SortStats.
+	columns, order := "", "bucket"
+	for _, g := range q.GroupBy {
+		columns += ", " + g
+	}
+	for _, g := range []string{models.GroupByLevel, models.GroupByType} {
+		if q.Grouped(g) {
+			order += ", " + g
+		}
+	}
+	query := fmt.Sprintf("SELECT to_timestamp(floor(extract(epoch FROM %s) / %s) * %s) AS bucket%s, COUNT(*) FROM logs%s GROUP BY bucket%s ORDER BY %s",
+		q.Filter.TimeColumn(), secs, secs, columns, where, columns, order)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []models.StatsBucket
+	for rows.Next() {
+		var b models.StatsBucket
+		dest := []any{&b.Start}
+		for _, g := range q.GroupBy {
+			if g == models.GroupByLevel {
+				dest = append(dest, &b.Level)
+			} else {
+				dest = append(dest, &b.Type)
+			}
+		}
+		if err := rows.Scan(append(dest, &b.Count)...); err != nil {
+			return nil, err
+		}
+		b.Start = b.Start.UTC()
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mstgnz/golog/models"
+)
+
+func TestStats(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+
+	t.Run("Ungrouped", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectQuery(`SELECT to_timestamp\(floor\(extract\(epoch FROM timestamp\) / \$3\) \* \$3\) AS bucket, COUNT\(\*\) FROM logs WHERE 1=1 AND timestamp >= \$1 AND timestamp < \$2 GROUP BY bucket ORDER BY bucket`).
+			WithArgs(since, until, int64(60)).
+			WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+				AddRow(since, 3).
+				AddRow(since.Add(time.Minute), 5))
+
+		buckets, err := store.Stats(models.StatsQuery{Filter: models.LogFilter{Since: since, Until: until}, Interval: time.Minute})
+		if err != nil {
+			t.Fatalf("Stats() error: %v", err)
+		}
+		if len(buckets) != 2 || buckets[1].Count != 5 || !buckets[1].Start.Equal(since.Add(time.Minute)) {
+			t.Errorf("Stats() = %+v", buckets)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("GroupedAndFiltered", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectQuery(`SELECT to_timestamp\(floor\(extract\(epoch FROM timestamp\) / \$5\) \* \$5\) AS bucket, type, level, COUNT\(\*\) FROM logs WHERE 1=1 AND tenant = \$1 AND level = \$2 AND timestamp >= \$3 AND timestamp < \$4 GROUP BY bucket, type, level ORDER BY bucket, level, type`).
+			WithArgs("billing", "ERROR", since, until, int64(300)).
+			WillReturnRows(sqlmock.NewRows([]string{"bucket", "type", "level", "count"}).
+				AddRow(since, "API", "ERROR", 7))
+
+		buckets, err := store.Stats(models.StatsQuery{
+			Filter:   models.LogFilter{Tenant: "billing", Levels: []string{"ERROR"}, Since: since, Until: until},
+			Interval: 5 * time.Minute,
+			GroupBy:  []string{models.GroupByType, models.GroupByLevel},
+		})
+		if err != nil {
+			t.Fatalf("Stats() error: %v", err)
+		}
+		want := models.StatsBucket{Start: since, Level: "ERROR", Type: "API", Count: 7}
+		if len(buckets) != 1 || buckets[0] != want {
+			t.Errorf("Stats() = %+v, want %+v", buckets, want)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
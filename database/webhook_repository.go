@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/webhook"
+)
+
+// DeadLetterStore keeps failed webhook deliveries in the
+// webhook_dead_letters table.
+type DeadLetterStore struct {
+	db *sql.DB
+}
+
+// NewDeadLetterStore creates a DeadLetterStore using the connection
+// established by Connect.
+func NewDeadLetterStore() *DeadLetterStore {
+	return &DeadLetterStore{db: DB}
+}
+
+// InsertDeadLetter records a failed delivery.
+func (s *DeadLetterStore) InsertDeadLetter(d webhook.DeadLetter) error {
+	_, err := s.db.Exec(
+		`INSERT INTO webhook_dead_letters (destination, url, event, payload, error, attempts, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		d.Destination, d.URL, d.Event, d.Payload, d.Error, d.Attempts, d.CreatedAt,
+	)
+	return err
+}
+
+// ListDeadLetters returns up to limit failed deliveries, newest first.
+func (s *DeadLetterStore) ListDeadLetters(limit int) ([]webhook.DeadLetter, error) {
+	rows, err := s.db.Query(
+		"SELECT id, destination, url, event, payload, error, attempts, created_at FROM webhook_dead_letters ORDER BY id DESC LIMIT $1",
+		models.LogFilter{Limit: limit}.PageSize(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []webhook.DeadLetter
+	for rows.Next() {
+		var d webhook.DeadLetter
+		if err := rows.Scan(&d.ID, &d.Destination, &d.URL, &d.Event, &d.Payload, &d.Error, &d.Attempts, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
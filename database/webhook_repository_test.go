@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mstgnz/golog/webhook"
+)
+
+func TestDeadLetterStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+	store := &DeadLetterStore{db: db}
+
+	now := time.Now()
+	mock.ExpectExec(`INSERT INTO webhook_dead_letters \(destination, url, event, payload, error, attempts, created_at\)`).
+		WithArgs("oncall", "https://hooks.example.com", "alert", `{"event":"alert"}`, "unexpected status 500", 5, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT id, destination, url, event, payload, error, attempts, created_at FROM webhook_dead_letters ORDER BY id DESC LIMIT \$1`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "destination", "url", "event", "payload", "error", "attempts", "created_at"}).
+			AddRow(1, "oncall", "https://hooks.example.com", "alert", `{"event":"alert"}`, "unexpected status 500", 5, now))
+
+	err = store.InsertDeadLetter(webhook.DeadLetter{
+		Destination: "oncall", URL: "https://hooks.example.com", Event: "alert",
+		Payload: `{"event":"alert"}`, Error: "unexpected status 500", Attempts: 5, CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("InsertDeadLetter() error: %v", err)
+	}
+	letters, err := store.ListDeadLetters(0)
+	if err != nil || len(letters) != 1 || letters[0].Attempts != 5 {
+		t.Errorf("ListDeadLetters() = %+v, %v", letters, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+// Package email sends alert notifications and periodic digests of log
+// activity over SMTP.
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Connection security modes for SMTP.Security.
+const (
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+	SecurityNone     = "none"
+)
+
+// Defaults for unset fields.
+const (
+	DefaultPort    = 587
+	DefaultTimeout = 30 * time.Second
+	DefaultTop     = 10
+	DefaultEvery   = 24 * time.Hour
+)
+
+// Config is the contents of EMAIL_FILE.
+type Config struct {
+	SMTP SMTP `json:"smtp"`
+
+	// Alerts sends an email for every alert that fires or resolves.
+	Alerts *AlertEmail `json:"alerts,omitempty"`
+
+	// Digests are periodic summaries of matching log entries.
+	Digests []Digest `json:"digests,omitempty"`
+}
+
+// SMTP describes the mail server.
+type SMTP struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+	// Security is starttls (the default), tls for implicit TLS, usually on
+	// port 465, or none.
+	Security string `json:"security,omitempty"`
+	// Username and Password enable PLAIN authentication when set.
+	Username string          `json:"username,omitempty"`
+	Password string          `json:"password,omitempty"`
+	From     string          `json:"from"`
+	Timeout  models.Duration `json:"timeout,omitempty"`
+}
+
+// AlertEmail configures immediate alert emails. Subject and Body are
+// templates rendered with an alerting.Event.
+type AlertEmail struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject,omitempty"`
+	Body    string   `json:"body,omitempty"`
+}
+
+// Digest configures a periodic summary. Subject and Body are templates
+// rendered with a Summary.
+type Digest struct {
+	Name string   `json:"name"`
+	To   []string `json:"to"`
+
+	// Every is the digest period, e.g. "1h" or "24h". Periods are aligned to
+	// UTC and shifted by Offset, so every 24h with offset 8h covers 08:00 to
+	// 08:00 UTC and is sent at 08:00.
+	Every  models.Duration `json:"every,omitempty"`
+	Offset models.Duration `json:"offset,omitempty"`
+
+	// Filter selects the entries to count; only ERROR entries when nil.
+	Filter *models.LogFilter `json:"filter,omitempty"`
+	// Top is the number of most frequent messages listed.
+	Top int `json:"top,omitempty"`
+	// SkipEmpty suppresses digests for periods without matching entries.
+	SkipEmpty bool `json:"skip_empty,omitempty"`
+
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+}
+
+func (s SMTP) withDefaults() SMTP {
+	if s.Port == 0 {
+		s.Port = DefaultPort
+	}
+	if s.Security == "" {
+		s.Security = SecurityStartTLS
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = models.Duration(DefaultTimeout)
+	}
+	return s
+}
+
+func (d Digest) withDefaults() Digest {
+	if d.Every <= 0 {
+		d.Every = models.Duration(DefaultEvery)
+	}
+	if d.Top <= 0 {
+		d.Top = DefaultTop
+	}
+	if d.Filter == nil {
+		d.Filter = &models.LogFilter{Level: "ERROR"}
+	}
+	if d.Subject == "" {
+		d.Subject = defaultDigestSubject
+	}
+	if d.Body == "" {
+		d.Body = defaultDigestBody
+	}
+	return d
+}
+
+// Validate checks the SMTP settings, recipients, filters and templates.
+func (c Config) Validate() error {
+	if c.SMTP.Host == "" {
+		return errors.New("smtp.host is required")
+	}
+	if c.SMTP.Port < 0 || c.SMTP.Port > 65535 {
+		return fmt.Errorf("invalid smtp.port %d", c.SMTP.Port)
+	}
+	switch c.SMTP.Security {
+	case "", SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return fmt.Errorf("smtp.security must be %s, %s or %s", SecurityStartTLS, SecurityTLS, SecurityNone)
+	}
+	if _, err := mail.ParseAddress(c.SMTP.From); err != nil {
+		return fmt.Errorf("invalid smtp.from %q: %w", c.SMTP.From, err)
+	}
+	if c.Alerts == nil && len(c.Digests) == 0 {
+		return errors.New("configure alerts, digests or both")
+	}
+
+	if a := c.Alerts; a != nil {
+		if err := validateRecipients(a.To); err != nil {
+			return fmt.Errorf("alerts: %w", err)
+		}
+		if _, _, err := parseTemplates(a.Subject, a.Body); err != nil {
+			return fmt.Errorf("alerts: %w", err)
+		}
+	}
+
+	names := make(map[string]bool, len(c.Digests))
+	for _, d := range c.Digests {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("digest %q: %w", d.Name, err)
+		}
+		if names[d.Name] {
+			return fmt.Errorf("duplicate digest %q", d.Name)
+		}
+		names[d.Name] = true
+	}
+	return nil
+}
+
+func (d Digest) validate() error {
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := validateRecipients(d.To); err != nil {
+		return err
+	}
+	if d.Every < 0 || (d.Every > 0 && time.Duration(d.Every) < time.Minute) {
+		return errors.New("every must be at least 1m")
+	}
+	if d.Offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	if f := d.Filter; f != nil {
+		for _, l := range f.LevelSet() {
+			if !models.ValidLevel(l) {
+				return fmt.Errorf("invalid level %q", l)
+			}
+		}
+		for _, t := range f.TypeSet() {
+			if !models.ValidType(t) {
+				return fmt.Errorf("invalid type %q", t)
+			}
+		}
+		if f.MinLevel != "" && !models.ValidLevel(f.MinLevel) {
+			return fmt.Errorf("invalid min_level %q", f.MinLevel)
+		}
+		if err := f.ValidateSearch(); err != nil {
+			return err
+		}
+	}
+	if _, _, err := parseTemplates(d.Subject, d.Body); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateRecipients(to []string) error {
+	if len(to) == 0 {
+		return errors.New("at least one recipient is required")
+	}
+	for _, addr := range to {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+func parseTemplates(subject, body string) (*template.Template, *template.Template, error) {
+	s, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	b, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid body template: %w", err)
+	}
+	return s, b, nil
+}
+
+// LoadConfig reads the email configuration from path. Environment variables
+// in the SMTP username and password are expanded, so credentials need not
+// be stored in the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.SMTP.Username = os.ExpandEnv(cfg.SMTP.Username)
+	cfg.SMTP.Password = os.ExpandEnv(cfg.SMTP.Password)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,76 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		SMTP:    SMTP{Host: "smtp.example.com", From: "GoLog <golog@example.com>"},
+		Alerts:  &AlertEmail{To: []string{"oncall@example.com"}},
+		Digests: []Digest{{Name: "daily", To: []string{"managers@example.com"}}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"missing host", func(c *Config) { c.SMTP.Host = "" }, "smtp.host"},
+		{"bad security", func(c *Config) { c.SMTP.Security = "ssl" }, "smtp.security"},
+		{"bad from", func(c *Config) { c.SMTP.From = "golog" }, "smtp.from"},
+		{"nothing to send", func(c *Config) { c.Alerts, c.Digests = nil, nil }, "configure"},
+		{"no alert recipients", func(c *Config) { c.Alerts = &AlertEmail{} }, "recipient"},
+		{"bad alert template", func(c *Config) { c.Alerts.Body = "{{.Nope" }, "body template"},
+		{"digest name", func(c *Config) { c.Digests[0].Name = "" }, "name"},
+		{"bad recipient", func(c *Config) { c.Digests[0].To = []string{"nobody"} }, "invalid recipient"},
+		{"short period", func(c *Config) { c.Digests[0].Every = 1 }, "every"},
+		{"bad level", func(c *Config) { c.Digests[0].Filter = &models.LogFilter{Level: "SEVERE"} }, "invalid level"},
+		{"duplicate digest", func(c *Config) { c.Digests = append(c.Digests, c.Digests[0]) }, "duplicate"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := valid
+			a := *valid.Alerts
+			c.Alerts = &a
+			c.Digests = append([]Digest(nil), valid.Digests...)
+			tc.modify(&c)
+			if err := c.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("GOLOG_TEST_SMTP_PASSWORD", "s3cret")
+	path := filepath.Join(t.TempDir(), "email.json")
+	os.WriteFile(path, []byte(`{
+		"smtp": {"host": "smtp.example.com", "username": "golog", "password": "${GOLOG_TEST_SMTP_PASSWORD}", "from": "golog@example.com"},
+		"digests": [{"name": "hourly", "to": ["ops@example.com"], "every": "1h", "top": 5}]
+	}`), 0o644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.SMTP.Password != "s3cret" || len(cfg.Digests) != 1 || cfg.Digests[0].Top != 5 {
+		t.Errorf("LoadConfig() = %+v", cfg)
+	}
+	if s := cfg.SMTP.withDefaults(); s.Port != DefaultPort || s.Security != SecurityStartTLS {
+		t.Errorf("defaults = %+v", s)
+	}
+
+	os.WriteFile(path, []byte(`{"smtp": {"host": "smtp.example.com", "from": "golog@example.com"}}`), 0o644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("LoadConfig() accepted a config without alerts or digests")
+	}
+}
@@ -0,0 +1,150 @@
+package email
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// MaxDigestEntries bounds the number of entries read to find a digest's top
+// messages. When a period has more, Top is built from the newest ones and
+// the summary is marked Truncated; the counts are always exact.
+const MaxDigestEntries = 100000
+
+// LogReader is the part of the log store digests are built from.
+type LogReader interface {
+	GetLogs(filter models.LogFilter) ([]models.Log, error)
+	Stats(q models.StatsQuery) ([]models.StatsBucket, error)
+}
+
+// Summary is the data a digest's templates are rendered with.
+type Summary struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+	Total int
+	// Types and Levels are sorted by count, highest first.
+	Types  []Count
+	Levels []Count
+	// Top holds the most frequent messages.
+	Top       []MessageCount
+	Truncated bool
+}
+
+// Count is the number of entries with a given type or level.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// MessageCount is the number of entries with the same type and message.
+type MessageCount struct {
+	Type     string
+	Level    string
+	Message  string
+	Count    int
+	LastSeen time.Time
+}
+
+// Summarize counts the entries matching filter received in [start, end),
+// keeping the top most frequent messages. Windows use the receive time so
+// that entries arriving late with an earlier event time are still counted
+// in the next digest. Counts come from the store's stats; only the top
+// messages are found by paging through the entries newest first.
+func Summarize(store LogReader, filter models.LogFilter, start, end time.Time, top int) (Summary, error) {
+	s := Summary{Start: start, End: end}
+	filter.TimeField = models.TimeFieldReceivedAt
+	filter.Since = start
+	filter.Until = end
+	filter.Limit = models.MaxLimit
+	filter.Offset = 0
+	filter.Cursor = nil
+
+	buckets, err := store.Stats(models.StatsQuery{
+		Filter:   filter,
+		Interval: max(end.Sub(start).Truncate(time.Second), time.Second),
+		GroupBy:  []string{models.GroupByLevel, models.GroupByType},
+	})
+	if err != nil {
+		return s, err
+	}
+	types := make(map[string]int)
+	levels := make(map[string]int)
+	for _, b := range buckets {
+		s.Total += int(b.Count)
+		types[b.Type] += int(b.Count)
+		levels[b.Level] += int(b.Count)
+	}
+	s.Types = sortCounts(types)
+	s.Levels = sortCounts(levels)
+	if s.Total == 0 || top <= 0 {
+		return s, nil
+	}
+
+	messages := make(map[[2]string]*MessageCount)
+	read := 0
+	for {
+		logs, err := store.GetLogs(filter)
+		if err != nil {
+			return s, err
+		}
+		for _, l := range logs {
+			if read == MaxDigestEntries {
+				s.Truncated = true
+				break
+			}
+			read++
+			key := [2]string{l.Type, l.Message}
+			m, ok := messages[key]
+			if !ok {
+				m = &MessageCount{Type: l.Type, Level: l.Level, Message: l.Message}
+				messages[key] = m
+			}
+			m.Count++
+			if l.Timestamp.After(m.LastSeen) {
+				m.LastSeen = l.Timestamp
+			}
+		}
+		if s.Truncated || len(logs) < models.MaxLimit {
+			break
+		}
+		c := filter.CursorAfter(logs[len(logs)-1], false)
+		filter.Cursor = &c
+	}
+
+	for _, m := range messages {
+		s.Top = append(s.Top, *m)
+	}
+	sort.Slice(s.Top, func(i, j int) bool {
+		a, b := s.Top[i], s.Top[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+	if len(s.Top) > top {
+		s.Top = s.Top[:top]
+	}
+	return s, nil
+}
+
+func sortCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+// next returns the end of the digest period following now.
+func (d Digest) next(now time.Time) time.Time {
+	every, offset := time.Duration(d.Every), time.Duration(d.Offset)
+	return now.Add(-offset).Truncate(every).Add(every).Add(offset)
+}
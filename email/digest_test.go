@@ -0,0 +1,143 @@
+package email
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// fakeReader serves logs newest first, applying the filter and cursor the
+// way the stores do.
+type fakeReader struct {
+	logs    []models.Log
+	queries int
+}
+
+func (r *fakeReader) GetLogs(filter models.LogFilter) ([]models.Log, error) {
+	r.queries++
+	var out []models.Log
+	for _, l := range r.logs {
+		if filter.Cursor != nil && !filter.Cursor.Before(filter.TimeOf(l), l.ID) {
+			continue
+		}
+		if filter.Matches(l) {
+			out = append(out, l)
+		}
+		if len(out) == filter.PageSize() {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeReader) Stats(q models.StatsQuery) ([]models.StatsBucket, error) {
+	counts := make(map[models.StatsBucket]int64)
+	for _, l := range r.logs {
+		if q.Filter.Matches(l) {
+			counts[q.Key(l)]++
+		}
+	}
+	var buckets []models.StatsBucket
+	for b, n := range counts {
+		b.Count = n
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// newFakeReader creates entries received one second apart ending just
+// before end, newest first. Entries without a timestamp get the receive
+// time.
+func newFakeReader(end time.Time, entries ...models.Log) *fakeReader {
+	r := &fakeReader{}
+	for i, l := range entries {
+		l.ID = len(entries) - i
+		l.ReceivedAt = end.Add(-time.Duration(i+1) * time.Second)
+		if l.Timestamp.IsZero() {
+			l.Timestamp = l.ReceivedAt
+		}
+		r.logs = append(r.logs, l)
+	}
+	return r
+}
+
+func TestSummarize(t *testing.T) {
+	end := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	var entries []models.Log
+	for i := 0; i < 600; i++ {
+		entries = append(entries, models.Log{Level: "ERROR", Type: "DATABASE", Message: "connection refused"})
+	}
+	for i := 0; i < 3; i++ {
+		entries = append(entries, models.Log{Level: "ERROR", Type: "API", Message: fmt.Sprintf("timeout %d", i%2)})
+	}
+	entries = append(entries, models.Log{Level: "INFO", Type: "API", Message: "not an error"})
+	r := newFakeReader(end, entries...)
+
+	s, err := Summarize(r, models.LogFilter{Level: "ERROR"}, end.Add(-24*time.Hour), end, 2)
+	if err != nil {
+		t.Fatalf("Summarize() error: %v", err)
+	}
+	if r.queries != 2 {
+		t.Errorf("queries = %d, want 2 pages", r.queries)
+	}
+	if s.Total != 603 || s.Truncated {
+		t.Errorf("Total = %d, Truncated = %v", s.Total, s.Truncated)
+	}
+	if len(s.Types) != 2 || s.Types[0] != (Count{"DATABASE", 600}) || s.Types[1] != (Count{"API", 3}) {
+		t.Errorf("Types = %+v", s.Types)
+	}
+	if len(s.Levels) != 1 || s.Levels[0] != (Count{"ERROR", 603}) {
+		t.Errorf("Levels = %+v", s.Levels)
+	}
+	if len(s.Top) != 2 || s.Top[0].Message != "connection refused" || s.Top[1].Message != "timeout 0" || s.Top[1].Count != 2 {
+		t.Errorf("Top = %+v", s.Top)
+	}
+}
+
+func TestSummarizeRange(t *testing.T) {
+	end := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	r := newFakeReader(end.Add(time.Hour),
+		models.Log{Level: "ERROR", Type: "API", Message: "after the period"},
+	)
+	s, err := Summarize(r, models.LogFilter{Level: "ERROR"}, end.Add(-time.Hour), end, 5)
+	if err != nil || s.Total != 0 || len(s.Top) != 0 {
+		t.Errorf("Summarize() = %+v, %v; want an empty summary", s, err)
+	}
+	if r.queries != 0 {
+		t.Errorf("queries = %d, want no entries read for an empty period", r.queries)
+	}
+}
+
+func TestSummarizeLateArrival(t *testing.T) {
+	end := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	late := end.Add(-3 * time.Hour)
+	r := newFakeReader(end,
+		models.Log{Level: "ERROR", Type: "API", Message: "buffered on the client", Timestamp: late},
+	)
+	s, err := Summarize(r, models.LogFilter{Level: "ERROR"}, end.Add(-time.Hour), end, 5)
+	if err != nil || s.Total != 1 || len(s.Top) != 1 || !s.Top[0].LastSeen.Equal(late) {
+		t.Errorf("Summarize() = %+v, %v; want the late entry counted", s, err)
+	}
+}
+
+func TestDigestNext(t *testing.T) {
+	tests := []struct {
+		every, offset time.Duration
+		now, want     string
+	}{
+		{time.Hour, 0, "2024-01-15T10:20:00Z", "2024-01-15T11:00:00Z"},
+		{time.Hour, 0, "2024-01-15T10:00:00Z", "2024-01-15T11:00:00Z"},
+		{24 * time.Hour, 0, "2024-01-15T10:20:00Z", "2024-01-16T00:00:00Z"},
+		{24 * time.Hour, 8 * time.Hour, "2024-01-15T07:59:00Z", "2024-01-15T08:00:00Z"},
+		{24 * time.Hour, 8 * time.Hour, "2024-01-15T08:00:00Z", "2024-01-16T08:00:00Z"},
+	}
+	for _, tc := range tests {
+		d := Digest{Every: models.Duration(tc.every), Offset: models.Duration(tc.offset)}
+		now, _ := time.Parse(time.RFC3339, tc.now)
+		if got := d.next(now).Format(time.RFC3339); got != tc.want {
+			t.Errorf("next(%s) every %v offset %v = %s, want %s", tc.now, tc.every, tc.offset, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages through one SMTP server, opening a connection per
+// message.
+type Mailer struct {
+	smtp SMTP
+	// tlsConfig overrides the TLS settings; tests use it to trust their
+	// server's certificate.
+	tlsConfig *tls.Config
+}
+
+// NewMailer creates a Mailer for the given server.
+func NewMailer(s SMTP) *Mailer {
+	return &Mailer{smtp: s.withDefaults()}
+}
+
+// Send delivers msg to all of its recipients.
+func (m *Mailer) Send(msg Message) error {
+	data, err := m.format(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(m.smtp.Timeout)
+	addr := net.JoinHostPort(m.smtp.Host, strconv.Itoa(m.smtp.Port))
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if m.smtp.Security == SecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, m.tls())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, m.smtp.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if m.smtp.Security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS; set security to none to send unencrypted")
+		}
+		if err := c.StartTLS(m.tls()); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if m.smtp.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection to anything but localhost.
+		auth := smtp.PlainAuth("", m.smtp.Username, m.smtp.Password, m.smtp.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(m.smtp.From)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+		if err := c.Rcpt(addr.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", addr.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (m *Mailer) tls() *tls.Config {
+	if m.tlsConfig != nil {
+		return m.tlsConfig
+	}
+	return &tls.Config{ServerName: m.smtp.Host}
+}
+
+// format renders msg as an RFC 5322 message with a quoted-printable UTF-8
+// body.
+func (m *Mailer) format(msg Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+	header("From", m.smtp.From)
+	header("To", strings.Join(msg.To, ", "))
+	// Collapsing whitespace keeps a rendered subject on one header line.
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(msg.Subject), " ")))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(m.smtp.From))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domain := "golog"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
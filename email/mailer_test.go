@@ -0,0 +1,233 @@
+package email
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type received struct {
+	from string
+	to   []string
+	auth string
+	tls  bool
+	msg  *mail.Message
+	body string
+}
+
+// fakeSMTP is a minimal local SMTP server that records the messages it is
+// given. It offers STARTTLS when tlsConfig is set.
+type fakeSMTP struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	mu        sync.Mutex
+	messages  []received
+}
+
+func newFakeSMTP(t *testing.T, tlsConfig *tls.Config) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln, tlsConfig: tlsConfig}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) smtp() SMTP {
+	return SMTP{Host: "127.0.0.1", Port: s.ln.Addr().(*net.TCPAddr).Port, Security: SecurityNone, From: "golog@example.com"}
+}
+
+func (s *fakeSMTP) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	// conn is replaced after STARTTLS, so close whichever is current.
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	var cur received
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.tlsConfig != nil && !cur.tls {
+				reply("250-fake")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-fake")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, cur.tls = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			_, creds, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(creds)
+			cur.auth = string(decoded)
+			reply("235 accepted")
+		case "MAIL":
+			cur.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			cur.to = append(cur.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(data.String()))
+			if err != nil {
+				reply("554 bad message")
+				continue
+			}
+			body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+			cur.msg, cur.body = msg, string(body)
+			s.mu.Lock()
+			s.messages = append(s.messages, cur)
+			s.mu.Unlock()
+			cur = received{tls: cur.tls}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// testTLS returns a server certificate for 127.0.0.1 and a client config
+// trusting it.
+func testTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	return &tls.Config{Certificates: ts.TLS.Certificates}, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+func TestSendPlain(t *testing.T) {
+	srv := newFakeSMTP(t, nil)
+	m := NewMailer(srv.smtp())
+
+	err := m.Send(Message{
+		To:      []string{"Ops <ops@example.com>", "dev@example.com"},
+		Subject: "Daily\nreport ✓",
+		Body:    "3 errors\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	got := srv.received()
+	if len(got) != 1 {
+		t.Fatalf("received %d messages, want 1", len(got))
+	}
+	r := got[0]
+	if r.from != "golog@example.com" || strings.Join(r.to, ",") != "ops@example.com,dev@example.com" || r.tls || r.auth != "" {
+		t.Errorf("envelope = %+v", r)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(r.msg.Header.Get("Subject"))
+	if subject != "Daily report ✓" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if r.body != "3 errors\r\nline two\r\n" {
+		t.Errorf("body = %q", r.body)
+	}
+	if r.msg.Header.Get("Message-ID") == "" || !strings.HasSuffix(r.msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q", r.msg.Header.Get("Message-ID"))
+	}
+}
+
+func TestSendStartTLSWithAuth(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+	srv := newFakeSMTP(t, serverTLS)
+	cfg := srv.smtp()
+	cfg.Security = SecurityStartTLS
+	cfg.Username = "golog"
+	cfg.Password = "s3cret"
+	m := NewMailer(cfg)
+	m.tlsConfig = clientTLS
+
+	if err := m.Send(Message{To: []string{"ops@example.com"}, Subject: "hi", Body: "hello"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	got := srv.received()
+	if len(got) != 1 || !got[0].tls || got[0].auth != "\x00golog\x00s3cret" {
+		t.Errorf("received = %+v", got)
+	}
+}
+
+func TestSendStartTLSRequired(t *testing.T) {
+	srv := newFakeSMTP(t, nil)
+	cfg := srv.smtp()
+	cfg.Security = SecurityStartTLS
+	m := NewMailer(cfg)
+
+	err := m.Send(Message{To: []string{"ops@example.com"}, Subject: "hi", Body: "hello"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send() error = %v, want missing STARTTLS", err)
+	}
+	if len(srv.received()) != 0 {
+		t.Error("message was sent without TLS")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	m := NewMailer(SMTP{Host: "localhost", From: "golog@example.com"})
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	data, err := m.format(Message{To: []string{"ops@example.com"}, Subject: "s", Body: "b"}, now)
+	if err != nil {
+		t.Fatalf("format() error: %v", err)
+	}
+	if !strings.Contains(string(data), "Date: Mon, 15 Jan 2024 10:00:00 +0000\r\n") {
+		t.Errorf("message = %q", data)
+	}
+}
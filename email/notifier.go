@@ -0,0 +1,187 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+)
+
+// alertQueueSize bounds the alert emails waiting to be sent; further alerts
+// are dropped and logged.
+const alertQueueSize = 100
+
+const (
+	defaultAlertSubject = `[golog] {{.Alert.State}}: {{.Rule.Name}}`
+	defaultAlertBody    = `Alert "{{.Rule.Name}}" is {{.Alert.State}}.
+
+Matches: {{.Alert.Count}}
+Fired at: {{.Alert.FiredAt.UTC.Format "2006-01-02 15:04:05 MST"}}
+{{with .Alert.ResolvedAt}}Resolved at: {{.UTC.Format "2006-01-02 15:04:05 MST"}}
+{{end}}{{with .Log}}
+Latest entry: [{{.Level}}] [{{.Type}}] {{.Message}}
+{{end}}`
+
+	defaultDigestSubject = `[golog] {{.Name}}: {{.Total}} entries`
+	defaultDigestBody    = `{{.Total}} entries from {{.Start.UTC.Format "2006-01-02 15:04"}} to {{.End.UTC.Format "2006-01-02 15:04 MST"}}.
+{{if .Types}}
+By type:
+{{range .Types}}  {{printf "%-12s %8d" .Name .Count}}
+{{end}}{{end}}{{if .Top}}
+Top messages:
+{{range .Top}}  {{printf "%8d" .Count}}  [{{.Type}}] {{.Message}}
+{{end}}{{end}}{{if .Truncated}}
+Top messages are based on the newest entries only.
+{{end}}`
+)
+
+// Notifier sends alert emails and scheduled digests. It implements
+// alerting.Notifier.
+type Notifier struct {
+	mailer  *Mailer
+	store   LogReader
+	alerts  *alertEmail
+	digests []*digest
+	queue   chan alerting.Event
+	now     func() time.Time
+	wg      sync.WaitGroup
+}
+
+type alertEmail struct {
+	to            []string
+	subject, body *template.Template
+}
+
+type digest struct {
+	Digest
+	subject, body *template.Template
+}
+
+// NewNotifier validates cfg and creates a Notifier reading digests from
+// store. Call Start to begin sending.
+func NewNotifier(cfg Config, store LogReader) (*Notifier, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	n := &Notifier{
+		mailer: NewMailer(cfg.SMTP),
+		store:  store,
+		queue:  make(chan alerting.Event, alertQueueSize),
+		now:    time.Now,
+	}
+	if a := cfg.Alerts; a != nil {
+		subject, body := a.Subject, a.Body
+		if subject == "" {
+			subject = defaultAlertSubject
+		}
+		if body == "" {
+			body = defaultAlertBody
+		}
+		st, bt, _ := parseTemplates(subject, body)
+		n.alerts = &alertEmail{to: a.To, subject: st, body: bt}
+	}
+	for _, d := range cfg.Digests {
+		d = d.withDefaults()
+		st, bt, _ := parseTemplates(d.Subject, d.Body)
+		n.digests = append(n.digests, &digest{Digest: d, subject: st, body: bt})
+	}
+	return n, nil
+}
+
+// Start sends queued alert emails and runs the digest schedules until ctx
+// is done.
+func (n *Notifier) Start(ctx context.Context) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		for {
+			select {
+			case ev := <-n.queue:
+				if err := n.sendAlert(ev); err != nil {
+					log.Printf("Error sending alert email for %q: %v", ev.Rule.Name, err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for _, d := range n.digests {
+		n.wg.Add(1)
+		go func(d *digest) {
+			defer n.wg.Done()
+			for {
+				end := d.next(n.now())
+				timer := time.NewTimer(end.Sub(n.now()))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				if err := n.sendDigest(d, end); err != nil {
+					log.Printf("Error sending digest %q: %v", d.Name, err)
+				}
+			}
+		}(d)
+	}
+}
+
+// Wait blocks until the goroutines started by Start have returned.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// Notify queues an alert email. It never blocks the alerting engine.
+func (n *Notifier) Notify(ev alerting.Event) {
+	if n.alerts == nil {
+		return
+	}
+	select {
+	case n.queue <- ev:
+	default:
+		log.Printf("Alert email queue full, dropping %s alert for %q", ev.Alert.State, ev.Rule.Name)
+	}
+}
+
+func (n *Notifier) sendAlert(ev alerting.Event) error {
+	msg, err := render(n.alerts.to, n.alerts.subject, n.alerts.body, ev)
+	if err != nil {
+		return err
+	}
+	return n.mailer.Send(msg)
+}
+
+// sendDigest summarizes the period ending at end and emails it.
+func (n *Notifier) sendDigest(d *digest, end time.Time) error {
+	start := end.Add(-time.Duration(d.Every))
+	s, err := Summarize(n.store, *d.Filter, start, end, d.Top)
+	if err != nil {
+		return fmt.Errorf("querying logs: %w", err)
+	}
+	if s.Total == 0 && d.SkipEmpty {
+		return nil
+	}
+	s.Name = d.Name
+	msg, err := render(d.To, d.subject, d.body, s)
+	if err != nil {
+		return err
+	}
+	return n.mailer.Send(msg)
+}
+
+func render(to []string, subject, body *template.Template, data any) (Message, error) {
+	var s, b bytes.Buffer
+	if err := subject.Execute(&s, data); err != nil {
+		return Message{}, fmt.Errorf("rendering subject: %w", err)
+	}
+	if err := body.Execute(&b, data); err != nil {
+		return Message{}, fmt.Errorf("rendering body: %w", err)
+	}
+	return Message{To: to, Subject: s.String(), Body: b.String()}, nil
+}
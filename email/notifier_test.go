@@ -0,0 +1,131 @@
+package email
+
+import (
+	"context"
+	"mime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+func subject(r received) string {
+	s, _ := new(mime.WordDecoder).DecodeHeader(r.msg.Header.Get("Subject"))
+	return s
+}
+
+func TestAlertEmail(t *testing.T) {
+	srv := newFakeSMTP(t, nil)
+	n, err := NewNotifier(Config{
+		SMTP:   srv.smtp(),
+		Alerts: &AlertEmail{To: []string{"oncall@example.com"}},
+	}, &fakeReader{})
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n.Start(ctx)
+	defer func() {
+		cancel()
+		n.Wait()
+	}()
+
+	firedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	n.Notify(alerting.Event{
+		Rule:  alerting.Rule{Name: "database errors"},
+		Alert: alerting.Alert{State: alerting.StateFiring, Count: 21, FiredAt: firedAt},
+		Log:   &models.Log{Level: "ERROR", Type: "DATABASE", Message: "connection refused"},
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(srv.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("alert email was not sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	r := srv.received()[0]
+	if got := subject(r); got != "[golog] firing: database errors" {
+		t.Errorf("Subject = %q", got)
+	}
+	for _, want := range []string{"Matches: 21", "Fired at: 2024-01-15 10:30:00 UTC", "[ERROR] [DATABASE] connection refused"} {
+		if !strings.Contains(r.body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, r.body)
+		}
+	}
+}
+
+func TestDigestEmail(t *testing.T) {
+	srv := newFakeSMTP(t, nil)
+	end := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	reader := newFakeReader(end,
+		models.Log{Level: "ERROR", Type: "DATABASE", Message: "connection refused"},
+		models.Log{Level: "ERROR", Type: "DATABASE", Message: "connection refused"},
+		models.Log{Level: "ERROR", Type: "AUTH", Message: "invalid token"},
+		models.Log{Level: "WARNING", Type: "AUTH", Message: "slow login"},
+	)
+	n, err := NewNotifier(Config{
+		SMTP: srv.smtp(),
+		Digests: []Digest{
+			{Name: "daily errors", To: []string{"managers@example.com"}},
+			{
+				Name:      "auth",
+				To:        []string{"security@example.com"},
+				Filter:    &models.LogFilter{Type: "AUTH"},
+				Subject:   "{{.Total}} auth entries",
+				Body:      "{{range .Levels}}{{.Name}}={{.Count}} {{end}}",
+				SkipEmpty: true,
+			},
+		},
+	}, reader)
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+
+	if err := n.sendDigest(n.digests[0], end); err != nil {
+		t.Fatalf("sendDigest() error: %v", err)
+	}
+	r := srv.received()[0]
+	if got := subject(r); got != "[golog] daily errors: 3 entries" || r.to[0] != "managers@example.com" {
+		t.Errorf("Subject = %q, to = %v", got, r.to)
+	}
+	for _, want := range []string{
+		"3 entries from 2024-01-14 00:00 to 2024-01-15 00:00 UTC",
+		"DATABASE            2",
+		"AUTH                1",
+		"       2  [DATABASE] connection refused",
+	} {
+		if !strings.Contains(r.body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, r.body)
+		}
+	}
+
+	if err := n.sendDigest(n.digests[1], end); err != nil {
+		t.Fatalf("sendDigest() error: %v", err)
+	}
+	if r := srv.received()[1]; subject(r) != "2 auth entries" || strings.TrimSpace(r.body) != "ERROR=1 WARNING=1" {
+		t.Errorf("custom digest = %q %q", subject(r), r.body)
+	}
+
+	// Nothing matches a week later, so the second digest is skipped.
+	n.sendDigest(n.digests[1], end.Add(7*24*time.Hour))
+	if got := len(srv.received()); got != 2 {
+		t.Errorf("received %d messages, want the empty digest skipped", got)
+	}
+}
+
+func TestNotifyWithoutAlerts(t *testing.T) {
+	n, err := NewNotifier(Config{
+		SMTP:    SMTP{Host: "localhost", From: "golog@example.com"},
+		Digests: []Digest{{Name: "d", To: []string{"a@example.com"}}},
+	}, &fakeReader{})
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+	n.Notify(alerting.Event{})
+	if len(n.queue) != 0 {
+		t.Error("alert was queued without alert emails configured")
+	}
+}
@@ -0,0 +1,398 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// FormatText is plain-text log lines parsed with a pattern. It can only be
+// read.
+const FormatText = "text"
+
+// DefaultPattern matches lines such as
+// "2024-01-15 10:30:00 ERROR connection refused" or
+// "2024-01-15T10:30:00Z [warn]: disk almost full".
+var DefaultPattern = regexp.MustCompile(`^(?P<timestamp>\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)\s+\[?(?P<level>[A-Za-z]+)\]?:?\s+(?P<message>.*)$`)
+
+// timeLayouts are tried in order when ReadOptions.TimeLayout is empty.
+// Layouts without a zone are read in the local time zone.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05,999999999",
+}
+
+// levelAliases maps common spellings onto the built-in levels.
+var levelAliases = map[string]string{
+	"WARN":  models.LevelWarning,
+	"ERR":   models.LevelError,
+	"TRACE": models.LevelDebug,
+}
+
+// ReadOptions configures NewReader.
+type ReadOptions struct {
+	// Format is one of the export formats or FormatText; empty detects it
+	// from the content.
+	Format string
+	// Pattern parses text lines; DefaultPattern when nil. The named groups
+	// timestamp, level, type, tenant and message fill those fields, any
+	// other named group becomes an attribute. Without a message group the
+	// whole line is the message.
+	Pattern *regexp.Regexp
+	// TimeLayout parses the timestamps of text and CSV records; empty tries
+	// RFC3339 and the common variants with a space or without a zone.
+	TimeLayout string
+}
+
+// RecordError reports a record that could not be decoded. Reading can
+// continue with the next record.
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader decodes log entries one record at a time.
+type Reader struct {
+	format string
+	opts   ReadOptions
+	next   func() (models.Log, error)
+	line   int
+}
+
+// NewReader returns a Reader for r, which may be gzip-compressed.
+func NewReader(r io.Reader, opts ReadOptions) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReaderSize(gz, 64<<10)
+	}
+	if opts.Format == "" {
+		opts.Format = detect(br)
+	}
+	if opts.Pattern == nil {
+		opts.Pattern = DefaultPattern
+	}
+
+	rd := &Reader{format: opts.Format, opts: opts}
+	switch opts.Format {
+	case FormatNDJSON:
+		rd.next = rd.ndjson(br)
+	case FormatCSV:
+		next, err := rd.csv(br)
+		if err != nil {
+			return nil, err
+		}
+		rd.next = next
+	case FormatColumnar:
+		rd.next = rd.columnar(br)
+	case FormatText:
+		rd.next = rd.text(br)
+	default:
+		return nil, fmt.Errorf("unknown import format %q: must be csv, ndjson, columnar or text", opts.Format)
+	}
+	return rd, nil
+}
+
+// Line returns the line on which the last record read starts.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Format returns the format being read.
+func (r *Reader) Format() string {
+	return r.format
+}
+
+// Read returns the next entry. It returns io.EOF after the last record and
+// a *RecordError for a record that cannot be decoded. Every call, including
+// one returning a RecordError, consumes one record.
+func (r *Reader) Read() (models.Log, error) {
+	return r.next()
+}
+
+// detect guesses the format from the first line: row groups are columnar,
+// other JSON objects NDJSON, a header with timestamp and message columns CSV,
+// and anything else text.
+func detect(br *bufio.Reader) string {
+	head, _ := br.Peek(br.Size())
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	line = bytes.TrimSpace(line)
+	switch {
+	case bytes.HasPrefix(line, []byte(`{"rows":`)):
+		return FormatColumnar
+	case bytes.HasPrefix(line, []byte("{")):
+		return FormatNDJSON
+	}
+	if header, err := csv.NewReader(bytes.NewReader(line)).Read(); err == nil && len(header) > 1 {
+		var ts, msg bool
+		for _, h := range header {
+			ts = ts || strings.EqualFold(strings.TrimSpace(h), "timestamp")
+			msg = msg || strings.EqualFold(strings.TrimSpace(h), "message")
+		}
+		if ts && msg {
+			return FormatCSV
+		}
+	}
+	return FormatText
+}
+
+// lines returns a function reading one line at a time, without the line
+// ending, and its line number. Blank lines are skipped.
+func lines(br *bufio.Reader) func() (string, int, error) {
+	n := 0
+	return func() (string, int, error) {
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				return "", n, err
+			}
+			n++
+			if line = strings.TrimRight(line, "\r\n"); strings.TrimSpace(line) != "" {
+				return line, n, nil
+			}
+			if err == io.EOF {
+				return "", n, io.EOF
+			}
+		}
+	}
+}
+
+// jsonRecord is an NDJSON line. The timestamp is parsed leniently so that
+// dumps from other tools can be read.
+type jsonRecord struct {
+	Timestamp  string         `json:"timestamp"`
+	Level      string         `json:"level"`
+	Type       string         `json:"type"`
+	Tenant     string         `json:"tenant"`
+	Message    string         `json:"message"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+func (r *Reader) ndjson(br *bufio.Reader) func() (models.Log, error) {
+	next := lines(br)
+	return func() (models.Log, error) {
+		line, n, err := next()
+		if err != nil {
+			return models.Log{}, err
+		}
+		r.line = n
+		var rec jsonRecord
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&rec); err != nil {
+			return models.Log{}, &RecordError{Line: n, Err: err}
+		}
+		l := models.Log{Level: rec.Level, Type: rec.Type, Tenant: rec.Tenant, Message: rec.Message, Attributes: rec.Attributes}
+		if err := r.fill(&l, rec.Timestamp); err != nil {
+			return l, &RecordError{Line: n, Err: err}
+		}
+		return l, nil
+	}
+}
+
+func (r *Reader) csv(br *bufio.Reader) (func() (models.Log, error), error) {
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	return func() (models.Log, error) {
+		record, err := cr.Read()
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				r.line = perr.StartLine
+				return models.Log{}, &RecordError{Line: perr.StartLine, Err: perr.Err}
+			}
+			return models.Log{}, err
+		}
+		line, _ := cr.FieldPos(0)
+		r.line = line
+
+		var l models.Log
+		var ts string
+		for i, v := range record {
+			if i >= len(header) {
+				break
+			}
+			switch header[i] {
+			case "id", "received_at":
+			case "timestamp":
+				ts = v
+			case "level":
+				l.Level = v
+			case "type":
+				l.Type = v
+			case "tenant":
+				l.Tenant = v
+			case "message":
+				l.Message = v
+			case "attributes":
+				if v == "" {
+					continue
+				}
+				dec := json.NewDecoder(strings.NewReader(v))
+				dec.UseNumber()
+				if err := dec.Decode(&l.Attributes); err != nil {
+					return l, &RecordError{Line: line, Err: fmt.Errorf("attributes: %w", err)}
+				}
+			default:
+				if v != "" {
+					setAttribute(&l, header[i], v)
+				}
+			}
+		}
+		if err := r.fill(&l, ts); err != nil {
+			return l, &RecordError{Line: line, Err: err}
+		}
+		return l, nil
+	}, nil
+}
+
+func (r *Reader) columnar(br *bufio.Reader) func() (models.Log, error) {
+	next := lines(br)
+	var group []models.Log
+	return func() (models.Log, error) {
+		for len(group) == 0 {
+			line, n, err := next()
+			if err != nil {
+				return models.Log{}, err
+			}
+			r.line = n
+			var g RowGroup
+			dec := json.NewDecoder(strings.NewReader(line))
+			dec.UseNumber()
+			if err := dec.Decode(&g); err != nil {
+				return models.Log{}, fmt.Errorf("row group on line %d: %w", n, err)
+			}
+			if group, err = g.Logs(); err != nil {
+				return models.Log{}, fmt.Errorf("row group on line %d: %w", n, err)
+			}
+		}
+		l := group[0]
+		group = group[1:]
+		l.ID, l.ReceivedAt = 0, time.Time{}
+		normalize(&l)
+		return l, nil
+	}
+}
+
+func (r *Reader) text(br *bufio.Reader) func() (models.Log, error) {
+	next := lines(br)
+	names := r.opts.Pattern.SubexpNames()
+	hasMessage := r.opts.Pattern.SubexpIndex("message") >= 0
+	return func() (models.Log, error) {
+		line, n, err := next()
+		if err != nil {
+			return models.Log{}, err
+		}
+		r.line = n
+		m := r.opts.Pattern.FindStringSubmatch(line)
+		if m == nil {
+			return models.Log{}, &RecordError{Line: n, Err: errors.New("line does not match the pattern")}
+		}
+
+		var l models.Log
+		var ts string
+		for i, name := range names {
+			switch name {
+			case "":
+			case "timestamp":
+				ts = m[i]
+			case "level":
+				l.Level = m[i]
+			case "type":
+				l.Type = m[i]
+			case "tenant":
+				l.Tenant = m[i]
+			case "message":
+				l.Message = m[i]
+			default:
+				if m[i] != "" {
+					setAttribute(&l, name, m[i])
+				}
+			}
+		}
+		if !hasMessage {
+			l.Message = line
+		}
+		if err := r.fill(&l, ts); err != nil {
+			return l, &RecordError{Line: n, Err: err}
+		}
+		return l, nil
+	}
+}
+
+// fill parses the timestamp, if any, and normalizes the entry.
+func (r *Reader) fill(l *models.Log, ts string) error {
+	if ts = strings.TrimSpace(ts); ts != "" {
+		t, err := parseTime(ts, r.opts.TimeLayout)
+		if err != nil {
+			return err
+		}
+		l.Timestamp = t
+	}
+	normalize(l)
+	return nil
+}
+
+func parseTime(v, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.ParseInLocation(layout, v, time.Local)
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", v)
+}
+
+// normalize upper-cases level and type, maps level aliases such as WARN and
+// fills in the registry's default type.
+func normalize(l *models.Log) {
+	l.Level = strings.ToUpper(strings.TrimSpace(l.Level))
+	if alias, ok := levelAliases[l.Level]; ok && !models.ValidLevel(l.Level) {
+		l.Level = alias
+	}
+	l.Type = strings.ToUpper(strings.TrimSpace(l.Type))
+	if l.Type == "" {
+		l.Type = models.CurrentRegistry().DefaultType()
+	}
+}
+
+func setAttribute(l *models.Log, key, value string) {
+	if l.Attributes == nil {
+		l.Attributes = make(map[string]any)
+	}
+	l.Attributes[key] = value
+}
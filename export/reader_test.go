@@ -0,0 +1,136 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// readAll returns the decoded entries and the lines of the RecordErrors.
+func readAll(t *testing.T, r *Reader) ([]models.Log, []int) {
+	t.Helper()
+	var logs []models.Log
+	var bad []int
+	for {
+		l, err := r.Read()
+		if err == io.EOF {
+			return logs, bad
+		}
+		var rerr *RecordError
+		if errors.As(err, &rerr) {
+			bad = append(bad, rerr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read() error: %v", err)
+		}
+		logs = append(logs, l)
+	}
+}
+
+func TestReadRoundTrip(t *testing.T) {
+	for _, format := range Formats() {
+		t.Run(format, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(write(t, format, testLogs)), ReadOptions{})
+			if err != nil {
+				t.Fatalf("NewReader() error: %v", err)
+			}
+			if r.Format() != format {
+				t.Errorf("detected %q, want %q", r.Format(), format)
+			}
+			logs, bad := readAll(t, r)
+			if len(logs) != len(testLogs) || len(bad) != 0 {
+				t.Fatalf("read %+v with bad records %v", logs, bad)
+			}
+			for i, l := range logs {
+				want := testLogs[i]
+				if l.ID != 0 || !l.Timestamp.Equal(want.Timestamp) || l.Message != want.Message || l.Tenant != want.Tenant || len(l.Attributes) != len(want.Attributes) {
+					t.Errorf("entry %d = %+v, want %+v without ID", i, l, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadGzipNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("{\"timestamp\":\"2024-03-01 10:00:00\",\"level\":\"warn\",\"message\":\"disk\"}\n\nnot json\n{\"level\":\"info\",\"type\":\"api\",\"message\":\"ok\"}"))
+	gz.Close()
+
+	r, err := NewReader(&buf, ReadOptions{})
+	if err != nil {
+		t.Fatalf("NewReader() error: %v", err)
+	}
+	logs, bad := readAll(t, r)
+	if r.Format() != FormatNDJSON || len(logs) != 2 || len(bad) != 1 || bad[0] != 3 {
+		t.Fatalf("read %q: %+v, bad %v", r.Format(), logs, bad)
+	}
+	want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	if l := logs[0]; !l.Timestamp.Equal(want) || l.Level != models.LevelWarning || l.Type != models.CurrentRegistry().DefaultType() {
+		t.Errorf("first entry = %+v", l)
+	}
+	if l := logs[1]; !l.Timestamp.IsZero() || l.Level != models.LevelInfo || l.Type != models.TypeAPI {
+		t.Errorf("second entry = %+v", l)
+	}
+}
+
+func TestReadCSVExtraColumns(t *testing.T) {
+	in := "Timestamp,Level,Message,host\n2024-03-01T10:00:00Z,ERROR,\"multi\nline\",web-1\nyesterday,INFO,x,\n"
+	r, err := NewReader(strings.NewReader(in), ReadOptions{})
+	if err != nil {
+		t.Fatalf("NewReader() error: %v", err)
+	}
+	logs, bad := readAll(t, r)
+	if r.Format() != FormatCSV || len(logs) != 1 || len(bad) != 1 || bad[0] != 4 {
+		t.Fatalf("read %q: %+v, bad %v", r.Format(), logs, bad)
+	}
+	if l := logs[0]; l.Message != "multi\nline" || l.Attributes["host"] != "web-1" {
+		t.Errorf("entry = %+v", l)
+	}
+}
+
+func TestReadText(t *testing.T) {
+	in := "2024-03-01 10:00:00,250 ERROR connection refused\n    at db.go:42\n2024-03-01T10:00:01Z [warn]: disk almost full\n"
+	r, err := NewReader(strings.NewReader(in), ReadOptions{})
+	if err != nil {
+		t.Fatalf("NewReader() error: %v", err)
+	}
+	logs, bad := readAll(t, r)
+	if r.Format() != FormatText || len(logs) != 2 || len(bad) != 1 || bad[0] != 2 {
+		t.Fatalf("read %q: %+v, bad %v", r.Format(), logs, bad)
+	}
+	if l := logs[0]; l.Level != models.LevelError || l.Message != "connection refused" || l.Timestamp.Nanosecond() != 250_000_000 {
+		t.Errorf("first entry = %+v", l)
+	}
+	if l := logs[1]; l.Level != models.LevelWarning || !l.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC)) {
+		t.Errorf("second entry = %+v", l)
+	}
+}
+
+func TestReadTextPattern(t *testing.T) {
+	opts := ReadOptions{
+		Format:     FormatText,
+		Pattern:    regexp.MustCompile(`^(?P<timestamp>\S+ \S+) (?P<host>\S+) (?P<type>\w+)\[(?P<level>\w+)\] (?P<message>.*)$`),
+		TimeLayout: "02/01/2006 15:04",
+	}
+	r, err := NewReader(strings.NewReader("15/03/2024 08:30 web-1 auth[error] bad password\n"), opts)
+	if err != nil {
+		t.Fatalf("NewReader() error: %v", err)
+	}
+	logs, bad := readAll(t, r)
+	if len(logs) != 1 || len(bad) != 0 {
+		t.Fatalf("read %+v, bad %v", logs, bad)
+	}
+	want := time.Date(2024, 3, 15, 8, 30, 0, 0, time.Local)
+	if l := logs[0]; !l.Timestamp.Equal(want) || l.Type != models.TypeAuth || l.Level != models.LevelError || l.Attributes["host"] != "web-1" || l.Message != "bad password" {
+		t.Errorf("entry = %+v", l)
+	}
+}
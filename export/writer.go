@@ -0,0 +1,218 @@
+// Package export writes log entries as CSV, NDJSON or a compressed columnar
+// format and reads them back, along with plain-text log files, for imports.
+// Writers and readers only buffer what they need to encode or decode, so any
+// number of entries can be streamed through them.
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	FormatCSV      = "csv"
+	FormatNDJSON   = "ndjson"
+	FormatColumnar = "columnar"
+
+	// RowGroupSize is the number of entries per block of the columnar format.
+	RowGroupSize = 10000
+)
+
+// csvHeader names the CSV columns. Attributes are encoded as a JSON object.
+var csvHeader = []string{"id", "timestamp", "level", "type", "tenant", "message", "attributes", "received_at"}
+
+// Formats lists the supported formats.
+func Formats() []string {
+	return []string{FormatCSV, FormatNDJSON, FormatColumnar}
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatColumnar:
+		return "application/gzip"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// Extension returns the file name extension of format.
+func Extension(format string) string {
+	switch format {
+	case FormatCSV:
+		return ".csv"
+	case FormatColumnar:
+		return ".columnar.gz"
+	default:
+		return ".ndjson"
+	}
+}
+
+// Writer encodes entries in one of the formats. Close must be called to
+// flush buffered output; it does not close the underlying writer.
+type Writer interface {
+	Write(l models.Log) error
+	Close() error
+}
+
+// NewWriter returns a Writer encoding entries to w in format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatColumnar:
+		return &columnarWriter{gz: gzip.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q: must be csv, ndjson or columnar", format)
+	}
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(l models.Log) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	attrs := ""
+	if len(l.Attributes) > 0 {
+		b, err := json.Marshal(l.Attributes)
+		if err != nil {
+			return fmt.Errorf("encoding attributes of log %d: %w", l.ID, err)
+		}
+		attrs = string(b)
+	}
+	return c.w.Write([]string{strconv.Itoa(l.ID), formatTimestamp(l.Timestamp), l.Level, l.Type, l.Tenant, l.Message, attrs, formatTimestamp(l.ReceivedAt)})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(l models.Log) error {
+	l.Snippet = ""
+	return n.enc.Encode(l)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// RowGroup is one block of the columnar format: a gzip stream of JSON
+// objects, one per line, each holding up to RowGroupSize entries stored
+// column by column. Attributes are null for entries without any. Files
+// written before received_at was recorded have no ReceivedAt column.
+type RowGroup struct {
+	Rows       int              `json:"rows"`
+	ID         []int            `json:"id"`
+	Timestamp  []string         `json:"timestamp"`
+	Level      []string         `json:"level"`
+	Type       []string         `json:"type"`
+	Tenant     []string         `json:"tenant"`
+	Message    []string         `json:"message"`
+	Attributes []map[string]any `json:"attributes"`
+	ReceivedAt []string         `json:"received_at,omitempty"`
+}
+
+// Logs returns the entries of the row group.
+func (g RowGroup) Logs() ([]models.Log, error) {
+	logs := make([]models.Log, g.Rows)
+	for _, n := range []int{len(g.ID), len(g.Timestamp), len(g.Level), len(g.Type), len(g.Tenant), len(g.Message), len(g.Attributes)} {
+		if n != g.Rows {
+			return nil, fmt.Errorf("row group of %d rows has a column of %d values", g.Rows, n)
+		}
+	}
+	if n := len(g.ReceivedAt); n != 0 && n != g.Rows {
+		return nil, fmt.Errorf("row group of %d rows has a column of %d values", g.Rows, n)
+	}
+	for i := range logs {
+		ts, err := time.Parse(time.RFC3339Nano, g.Timestamp[i])
+		if err != nil {
+			return nil, err
+		}
+		logs[i] = models.Log{ID: g.ID[i], Timestamp: ts, Level: g.Level[i], Type: g.Type[i], Tenant: g.Tenant[i], Message: g.Message[i], Attributes: g.Attributes[i]}
+		if len(g.ReceivedAt) > 0 {
+			if logs[i].ReceivedAt, err = time.Parse(time.RFC3339Nano, g.ReceivedAt[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return logs, nil
+}
+
+type columnarWriter struct {
+	gz    *gzip.Writer
+	group RowGroup
+}
+
+func (c *columnarWriter) Write(l models.Log) error {
+	g := &c.group
+	g.Rows++
+	g.ID = append(g.ID, l.ID)
+	g.Timestamp = append(g.Timestamp, formatTimestamp(l.Timestamp))
+	g.Level = append(g.Level, l.Level)
+	g.Type = append(g.Type, l.Type)
+	g.Tenant = append(g.Tenant, l.Tenant)
+	g.Message = append(g.Message, l.Message)
+	g.Attributes = append(g.Attributes, l.Attributes)
+	g.ReceivedAt = append(g.ReceivedAt, formatTimestamp(l.ReceivedAt))
+	if g.Rows == RowGroupSize {
+		return c.flush()
+	}
+	return nil
+}
+
+func (c *columnarWriter) flush() error {
+	if c.group.Rows == 0 {
+		return nil
+	}
+	b, err := json.Marshal(c.group)
+	if err != nil {
+		return err
+	}
+	if _, err := c.gz.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	c.group = RowGroup{}
+	return nil
+}
+
+func (c *columnarWriter) Close() error {
+	if err := c.flush(); err != nil {
+		return err
+	}
+	return c.gz.Close()
+}
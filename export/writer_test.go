@@ -0,0 +1,118 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+var testLogs = []models.Log{
+	{ID: 1, Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 500, time.UTC), Level: "ERROR", Type: "API", Message: "timeout, retrying\n\"upstream\"", Attributes: map[string]any{"request_id": "abc"}, ReceivedAt: time.Date(2024, 3, 1, 10, 5, 0, 0, time.UTC)},
+	{ID: 2, Timestamp: time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC), Level: "INFO", Type: "SYSTEM", Tenant: "billing", Message: "ok", Snippet: "<mark>ok</mark>", ReceivedAt: time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC)},
+}
+
+func write(t *testing.T, format string, logs []models.Log) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) error: %v", format, err)
+	}
+	for _, l := range logs {
+		if err := w.Write(l); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(write(t, FormatCSV, testLogs))).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "id,timestamp,level,type,tenant,message,attributes,received_at" {
+		t.Fatalf("records = %q", records)
+	}
+	if r := records[1]; r[1] != "2024-03-01T10:00:00.0000005Z" || r[5] != testLogs[0].Message || r[6] != `{"request_id":"abc"}` || r[7] != "2024-03-01T10:05:00Z" {
+		t.Errorf("first record = %q", r)
+	}
+	if r := records[2]; r[4] != "billing" || r[6] != "" {
+		t.Errorf("second record = %q", r)
+	}
+
+	if got := string(write(t, FormatCSV, nil)); got != "id,timestamp,level,type,tenant,message,attributes,received_at\n" {
+		t.Errorf("empty export = %q, want only the header", got)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(write(t, FormatNDJSON, testLogs))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	var l models.Log
+	if err := json.Unmarshal([]byte(lines[1]), &l); err != nil {
+		t.Fatalf("decoding line: %v", err)
+	}
+	if l.ID != 2 || l.Tenant != "billing" || l.Snippet != "" {
+		t.Errorf("second entry = %+v, want it without snippet", l)
+	}
+}
+
+func TestColumnar(t *testing.T) {
+	logs := make([]models.Log, RowGroupSize+1)
+	for i := range logs {
+		logs[i] = testLogs[i%2]
+		logs[i].ID = i + 1
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(write(t, FormatColumnar, logs)))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error: %v", err)
+	}
+	sc := bufio.NewScanner(gz)
+	sc.Buffer(nil, 64<<20)
+	var got []models.Log
+	var groups int
+	for sc.Scan() {
+		var g RowGroup
+		if err := json.Unmarshal(sc.Bytes(), &g); err != nil {
+			t.Fatalf("decoding row group: %v", err)
+		}
+		entries, err := g.Logs()
+		if err != nil {
+			t.Fatalf("Logs() error: %v", err)
+		}
+		got = append(got, entries...)
+		groups++
+	}
+	if groups != 2 || len(got) != len(logs) {
+		t.Fatalf("read %d entries in %d row groups, want %d in 2", len(got), groups, len(logs))
+	}
+	if first := got[0]; !first.Timestamp.Equal(testLogs[0].Timestamp) || first.Message != testLogs[0].Message || first.Attributes["request_id"] != "abc" || !first.ReceivedAt.Equal(testLogs[0].ReceivedAt) {
+		t.Errorf("first entry = %+v", first)
+	}
+	if second := got[1]; second.Tenant != "billing" || second.Attributes != nil {
+		t.Errorf("second entry = %+v", second)
+	}
+	if last := got[len(got)-1]; last.ID != len(logs) {
+		t.Errorf("last entry = %+v", last)
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("parquet", &bytes.Buffer{}); err == nil {
+		t.Error("NewWriter(parquet) succeeded, want an error")
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/auth"
+)
+
+// SetAlerting enables the alert rule API and feeds every streamed log entry
+// to the engine.
+func (s *Server) SetAlerting(engine *alerting.Engine) {
+	s.alerts = engine
+	s.AddObserver(engine.Observe)
+}
+
+func (s *Server) alertRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(s.require(auth.ScopeRead), denyTenantKeys)
+		r.Get("/", s.ListAlertsHandler)
+		r.Get("/rules", s.ListAlertRulesHandler)
+		r.Get("/rules/{id}", s.GetAlertRuleHandler)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(s.require(auth.ScopeAdmin))
+		r.Post("/rules", s.CreateAlertRuleHandler)
+		r.Put("/rules/{id}", s.UpdateAlertRuleHandler)
+		r.Delete("/rules/{id}", s.DeleteAlertRuleHandler)
+	})
+}
+
+// ListAlertsHandler returns alert history, newest first.
+//
+// Query parameters: state (firing or resolved), rule_id, limit (default
+// 100, max 500).
+func (s *Server) ListAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := alerting.AlertFilter{State: q.Get("state")}
+	if filter.State != "" && filter.State != alerting.StateFiring && filter.State != alerting.StateResolved {
+		http.Error(w, "state must be firing or resolved", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("rule_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "rule_id must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.RuleID = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	alerts, err := s.alerts.Alerts(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if alerts == nil {
+		alerts = []alerting.Alert{}
+	}
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+// ListAlertRulesHandler returns all alert rules.
+func (s *Server) ListAlertRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.alerts.Rules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []alerting.Rule{}
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// GetAlertRuleHandler returns one alert rule.
+func (s *Server) GetAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+	rule, err := s.alerts.Rule(id)
+	if err != nil {
+		ruleErrorResponse(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// CreateAlertRuleHandler creates an alert rule. Omitted fields default to an
+// enabled rule with a 5 minute window and a threshold of 0.
+func (s *Server) CreateAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeRule(w, r)
+	if !ok {
+		return
+	}
+	created, err := s.alerts.CreateRule(rule)
+	if err != nil {
+		ruleErrorResponse(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// UpdateAlertRuleHandler replaces an alert rule. A changed rule starts
+// counting from zero and resolves its firing alert.
+func (s *Server) UpdateAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := decodeRule(w, r)
+	if !ok {
+		return
+	}
+	rule.ID = id
+	updated, err := s.alerts.UpdateRule(rule)
+	if err != nil {
+		ruleErrorResponse(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteAlertRuleHandler deletes an alert rule; its history is kept.
+func (s *Server) DeleteAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.alerts.DeleteRule(id); err != nil {
+		ruleErrorResponse(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ruleID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		http.Error(w, "invalid rule id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeRule reads a rule from the request body and validates it, so that
+// invalid rules are reported as 400 rather than store errors.
+func decodeRule(w http.ResponseWriter, r *http.Request) (alerting.Rule, bool) {
+	rule := alerting.NewRule()
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return rule, false
+	}
+	if err := rule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return rule, false
+	}
+	return rule, true
+}
+
+func ruleErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, alerting.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, alerting.ErrNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+func newAlertingServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	engine := alerting.NewEngine(alerting.NewMemoryStore())
+	if err := engine.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	srv := newTestServer(&mockStore{})
+	srv.SetAlerting(engine)
+	return srv, srv.SetupRoutes()
+}
+
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAlertRuleCRUD(t *testing.T) {
+	_, h := newAlertingServer(t)
+
+	rr := do(h, "POST", "/api/alerts/rules", `{"name":"database errors","levels":["ERROR"],"types":["DATABASE"],"threshold":20,"window":"5m"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	var created alerting.Rule
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.ID != 1 || !created.Enabled || created.Threshold != 20 {
+		t.Errorf("created = %+v", created)
+	}
+
+	if rr := do(h, "POST", "/api/alerts/rules", `{"name":"database errors"}`); rr.Code != http.StatusConflict {
+		t.Errorf("duplicate name status = %d, want 409", rr.Code)
+	}
+
+	rr = do(h, "PUT", "/api/alerts/rules/1", `{"name":"database errors","levels":["ERROR"],"threshold":5,"enabled":false}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+
+	rr = do(h, "GET", "/api/alerts/rules/1", "")
+	var got alerting.Rule
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if rr.Code != http.StatusOK || got.Threshold != 5 || got.Enabled || len(got.Types) != 0 {
+		t.Errorf("get = %d %+v", rr.Code, got)
+	}
+
+	rr = do(h, "GET", "/api/alerts/rules", "")
+	var rules []alerting.Rule
+	json.Unmarshal(rr.Body.Bytes(), &rules)
+	if len(rules) != 1 {
+		t.Errorf("list = %s", rr.Body.String())
+	}
+
+	if rr := do(h, "DELETE", "/api/alerts/rules/1", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", rr.Code)
+	}
+	if rr := do(h, "GET", "/api/alerts/rules/1", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", rr.Code)
+	}
+}
+
+func TestAlertRuleErrors(t *testing.T) {
+	_, h := newAlertingServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"invalid json", "POST", "/api/alerts/rules", "{", http.StatusBadRequest},
+		{"invalid level", "POST", "/api/alerts/rules", `{"name":"x","levels":["SEVERE"]}`, http.StatusBadRequest},
+		{"invalid pattern", "POST", "/api/alerts/rules", `{"name":"x","pattern":"("}`, http.StatusBadRequest},
+		{"invalid id", "GET", "/api/alerts/rules/abc", "", http.StatusBadRequest},
+		{"unknown rule", "PUT", "/api/alerts/rules/9", `{"name":"x"}`, http.StatusNotFound},
+		{"unknown delete", "DELETE", "/api/alerts/rules/9", "", http.StatusNotFound},
+		{"invalid state", "GET", "/api/alerts?state=pending", "", http.StatusBadRequest},
+		{"invalid rule_id", "GET", "/api/alerts?rule_id=x", "", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if rr := do(h, tc.method, tc.target, tc.body); rr.Code != tc.status {
+				t.Errorf("status = %d, want %d (body: %s)", rr.Code, tc.status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAlertRoutesDisabled(t *testing.T) {
+	h := newTestServer(&mockStore{}).SetupRoutes()
+	if rr := do(h, "GET", "/api/alerts/rules", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without alerting", rr.Code)
+	}
+}
+
+func TestObserversSeeStreamedLogs(t *testing.T) {
+	listenCh := make(chan models.Log)
+	ms := &mockStore{
+		listenFn: func(ctx context.Context, ch chan<- models.Log) error {
+			go func() {
+				defer close(ch)
+				for {
+					select {
+					case l := <-listenCh:
+						ch <- l
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return nil
+		},
+	}
+	srv := newTestServer(ms)
+	seen := make(chan models.Log, 1)
+	srv.AddObserver(func(l models.Log) { seen <- l })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := srv.StartLogListener(ctx); err != nil {
+		t.Fatalf("StartLogListener: %v", err)
+	}
+
+	listenCh <- models.Log{ID: 7, Message: "observed"}
+	select {
+	case l := <-seen:
+		if l.ID != 7 {
+			t.Errorf("observer got %+v", l)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("observer was not called")
+	}
+}
+
+func TestListAlerts(t *testing.T) {
+	_, h := newAlertingServer(t)
+	rr := do(h, "GET", "/api/alerts?state=firing&limit=10", "")
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("list alerts = %d %s, want an empty array", rr.Code, rr.Body.String())
+	}
+}
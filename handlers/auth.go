@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mstgnz/golog/auth"
+)
+
+// SetAuth requires API keys on the API routes and enables the key
+// management API under /api/admin.
+func (s *Server) SetAuth(a *auth.Authenticator) {
+	s.auth = a
+}
+
+// SetAllowedOrigins sets the origins allowed by CORS; all origins are
+// allowed by default.
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.origins = origins
+}
+
+// require returns middleware enforcing scope, or a no-op when
+// authentication is disabled.
+func (s *Server) require(scope string) func(http.Handler) http.Handler {
+	if s.auth == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return s.auth.Require(scope)
+}
+
+func (s *Server) adminRoutes(r chi.Router) {
+	if s.auth != nil {
+		r.Get("/keys", s.ListKeysHandler)
+		r.Post("/keys", s.CreateKeyHandler)
+		r.Delete("/keys/{id}", s.RevokeKeyHandler)
+		r.Get("/auth-failures", s.ListAuthFailuresHandler)
+	}
+	if s.quotas != nil {
+		r.Get("/quotas", s.QuotaUsageHandler)
+	}
+	if s.retention != nil {
+		r.Get("/retention", s.RetentionStatusHandler)
+		r.Post("/retention/run", s.RunRetentionHandler)
+	}
+	if s.buffer != nil {
+		r.Get("/ingest-buffer", s.IngestBufferHandler)
+	}
+}
+
+// ListKeysHandler returns all API keys, including revoked ones. Tokens are
+// never returned.
+func (s *Server) ListKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.auth.Keys()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []auth.Key{}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// CreateKeyHandler creates an API key from {"name": ..., "scopes": [...]},
+// optionally with "tenant" to confine it to one tenant. The response is the
+// only place the token appears.
+func (s *Server) CreateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+		Tenant string   `json:"tenant"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := (auth.Key{Name: req.Name, Scopes: req.Scopes, Tenant: req.Tenant}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, token, err := s.auth.CreateKey(req.Name, req.Tenant, req.Scopes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, struct {
+		auth.Key
+		Token string `json:"token"`
+	}{key, token})
+}
+
+// RevokeKeyHandler revokes an API key.
+func (s *Server) RevokeKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		http.Error(w, "invalid key id", http.StatusBadRequest)
+		return
+	}
+	if err := s.auth.RevokeKey(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAuthFailuresHandler returns rejected requests, newest first.
+//
+// Query parameters: limit (default 100, max 500).
+func (s *Server) ListAuthFailuresHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	failures, err := s.auth.Failures(limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if failures == nil {
+		failures = []auth.Failure{}
+	}
+	writeJSON(w, http.StatusOK, failures)
+}
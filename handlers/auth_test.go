@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/auth"
+)
+
+func newAuthServer(t *testing.T) (http.Handler, map[string]string) {
+	t.Helper()
+	a := auth.NewAuthenticator(auth.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		a.Wait()
+	})
+	tokens := map[string]string{}
+	for _, scope := range auth.Scopes {
+		_, token, err := a.CreateKey(scope, "", []string{scope})
+		if err != nil {
+			t.Fatalf("CreateKey() error: %v", err)
+		}
+		tokens[scope] = token
+	}
+	engine := alerting.NewEngine(alerting.NewMemoryStore())
+	engine.Load()
+
+	srv := newTestServer(&mockStore{insertID: 1})
+	srv.SetAlerting(engine)
+	srv.SetAuth(a)
+	return srv.SetupRoutes(), tokens
+}
+
+func doAs(h http.Handler, token, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRouteScopes(t *testing.T) {
+	h, tokens := newAuthServer(t)
+	logBody := `{"level":"INFO","type":"SYSTEM","message":"hello"}`
+
+	tests := []struct {
+		method, target, body string
+		scope                string // the narrowest scope allowed; "" for public routes
+	}{
+		{"GET", "/api/logs", "", auth.ScopeRead},
+		{"POST", "/api/logs", logBody, auth.ScopeIngest},
+		{"POST", "/api/logs/bulk", logBody, auth.ScopeIngest},
+		{"POST", "/v1/logs", "{}", auth.ScopeIngest},
+		{"GET", "/api/alerts", "", auth.ScopeRead},
+		{"GET", "/api/alerts/rules", "", auth.ScopeRead},
+		{"POST", "/api/alerts/rules", `{"name":"r"}`, auth.ScopeAdmin},
+		{"GET", "/api/admin/keys", "", auth.ScopeAdmin},
+		{"GET", "/api/registry", "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			for _, scope := range append([]string{""}, auth.Scopes...) {
+				rr := doAs(h, tokens[scope], tc.method, tc.target, tc.body)
+				allowed := tc.scope == "" || scope == tc.scope || scope == auth.ScopeAdmin
+				switch {
+				case allowed && (rr.Code == http.StatusUnauthorized || rr.Code == http.StatusForbidden):
+					t.Errorf("key %q: status = %d, want access", scope, rr.Code)
+				case !allowed && scope == "" && rr.Code != http.StatusUnauthorized:
+					t.Errorf("no key: status = %d, want 401", rr.Code)
+				case !allowed && scope != "" && rr.Code != http.StatusForbidden:
+					t.Errorf("key %q: status = %d, want 403", scope, rr.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyManagement(t *testing.T) {
+	h, tokens := newAuthServer(t)
+	admin := tokens[auth.ScopeAdmin]
+
+	rr := doAs(h, admin, "POST", "/api/admin/keys", `{"name":"ci","scopes":["ingest"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		auth.Key
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.ID != 4 || created.Token == "" || strings.Contains(rr.Body.String(), auth.HashToken(created.Token)) {
+		t.Errorf("created = %s", rr.Body.String())
+	}
+	if rr := doAs(h, created.Token, "POST", "/api/logs", `{"level":"INFO","type":"SYSTEM","message":"hi"}`); rr.Code != http.StatusOK {
+		t.Errorf("new key status = %d", rr.Code)
+	}
+
+	if rr := doAs(h, admin, "POST", "/api/admin/keys", `{"name":"ci","scopes":["write"]}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid scope status = %d, want 400", rr.Code)
+	}
+
+	if rr := doAs(h, admin, "DELETE", "/api/admin/keys/4", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("revoke status = %d", rr.Code)
+	}
+	if rr := doAs(h, admin, "DELETE", "/api/admin/keys/99", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("revoke unknown status = %d, want 404", rr.Code)
+	}
+	if rr := doAs(h, created.Token, "POST", "/api/logs", `{}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key status = %d, want 401", rr.Code)
+	}
+
+	rr = doAs(h, admin, "GET", "/api/admin/keys", "")
+	var keys []auth.Key
+	json.Unmarshal(rr.Body.Bytes(), &keys)
+	if len(keys) != 4 || !keys[3].Revoked() {
+		t.Errorf("keys = %s", rr.Body.String())
+	}
+
+	// Failures are recorded in the background.
+	var failures []auth.Failure
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rr = doAs(h, admin, "GET", "/api/admin/auth-failures?limit=1", "")
+		failures = nil
+		json.Unmarshal(rr.Body.Bytes(), &failures)
+		if len(failures) == 1 && failures[0].Reason == "revoked API key" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(failures) != 1 || failures[0].Reason != "revoked API key" || failures[0].KeyPrefix != created.Prefix {
+		t.Errorf("failures = %s", rr.Body.String())
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	srv := newTestServer(&mockStore{})
+	srv.SetAllowedOrigins([]string{"https://logs.example.com"})
+	h := srv.SetupRoutes()
+
+	for origin, want := range map[string]string{
+		"https://logs.example.com": "https://logs.example.com",
+		"https://evil.example.com": "",
+	} {
+		req := httptest.NewRequest("GET", "/api/logs", nil)
+		req.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want %q", origin, got, want)
+		}
+		if rr.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("origin %s: credentials allowed", origin)
+		}
+	}
+}
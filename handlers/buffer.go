@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/wal"
+)
+
+// bufferRetryAfter is the Retry-After, in seconds, sent while the ingestion
+// buffer is full.
+const bufferRetryAfter = "5"
+
+// SetIngestBuffer makes the ingestion endpoints queue entries in b instead
+// of writing them to the store, and exposes its depth under
+// /api/admin/ingest-buffer.
+func (s *Server) SetIngestBuffer(b *wal.Buffer) {
+	s.buffer = b
+}
+
+// IngestBufferHandler returns the depth of the ingestion buffer and the
+// outcome of its recent writes to the store.
+func (s *Server) IngestBufferHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.buffer.Stats())
+}
+
+// enqueue appends entries to the ingestion buffer. Otherwise it writes the
+// error response, a 429 while the buffer is full, and returns false.
+func (s *Server) enqueue(w http.ResponseWriter, entries []models.Log) bool {
+	if len(entries) == 0 {
+		return true
+	}
+	err := s.buffer.Append(entries)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, wal.ErrFull):
+		w.Header().Set("Retry-After", bufferRetryAfter)
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		log.Printf("Error buffering logs: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
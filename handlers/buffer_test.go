@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/memstore"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/tenant"
+	"github.com/mstgnz/golog/wal"
+)
+
+func TestIngestBuffer(t *testing.T) {
+	ms := &mockStore{insertErr: errors.New("db down")}
+	srv := newTestServer(ms)
+	if rr := do(srv.SetupRoutes(), "GET", "/api/admin/ingest-buffer", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("status without buffer = %d, want 404", rr.Code)
+	}
+
+	store := memstore.New(10)
+	buf, err := wal.Open(t.TempDir(), store, wal.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Close()
+	srv.SetIngestBuffer(buf)
+	h := srv.SetupRoutes()
+
+	// The store is down, but the entries are accepted.
+	rr := do(h, "POST", "/api/logs", `{"level":"ERROR","type":"API","message":"one"}`)
+	if rr.Code != http.StatusAccepted || !json.Valid(rr.Body.Bytes()) {
+		t.Errorf("add status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	rr = do(h, "POST", "/api/logs/bulk", `{"level":"INFO","type":"API","message":"two"}
+{"level":"INFO","type":"API"}`)
+	var resp BulkResponse
+	if rr.Code != http.StatusAccepted || json.Unmarshal(rr.Body.Bytes(), &resp) != nil || resp.Accepted != 1 || resp.Rejected != 1 {
+		t.Errorf("bulk status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if ms.lastInsert.Message != "" || ms.lastBulk != nil {
+		t.Error("entries were written to the store instead of the buffer")
+	}
+
+	rr = do(h, "GET", "/api/admin/ingest-buffer", "")
+	var st wal.Stats
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &st) != nil || st.Entries != 2 {
+		t.Fatalf("buffer status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buf.Start(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Len() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("store has %d entries, want 2", store.Len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIngest(t *testing.T) {
+	ms := &mockStore{insertID: 1}
+	srv := newTestServer(ms)
+	srv.SetQuotas(tenant.NewQuotas(0, map[string]int{"billing": 1}))
+	entry := models.Log{Level: "INFO", Type: "SYSTEM", Message: "from syslog", Tenant: "billing"}
+	if err := srv.Ingest(entry); err != nil || ms.lastInsert.Message != "from syslog" {
+		t.Fatalf("Ingest() = %v, stored %+v", err, ms.lastInsert)
+	}
+	if err := srv.Ingest(entry); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Ingest() over quota = %v, want ErrQuotaExceeded", err)
+	}
+
+	// With a buffer the entry is queued even while the store is down.
+	ms = &mockStore{insertErr: errors.New("db down")}
+	srv = newTestServer(ms)
+	buf, err := wal.Open(t.TempDir(), memstore.New(10), wal.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Close()
+	srv.SetIngestBuffer(buf)
+	if err := srv.Ingest(entry); err != nil {
+		t.Fatalf("Ingest() with buffer = %v", err)
+	}
+	if st := buf.Stats(); st.Entries != 1 || ms.lastInsert.Message != "" {
+		t.Errorf("buffer has %d entries and store got %+v, want the entry buffered", st.Entries, ms.lastInsert)
+	}
+}
+
+func TestIngestBufferFull(t *testing.T) {
+	buf, err := wal.Open(t.TempDir(), memstore.New(10), wal.Options{MaxBytes: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Close()
+	srv := newTestServer(&mockStore{})
+	srv.SetIngestBuffer(buf)
+	h := srv.SetupRoutes()
+
+	body := `{"level":"INFO","type":"API","message":"a message long enough to fill the buffer"}`
+	if rr := do(h, "POST", "/api/logs", body); rr.Code != http.StatusAccepted {
+		t.Fatalf("first add status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	rr := do(h, "POST", "/api/logs", body)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("full buffer status = %d, Retry-After %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	req := httptest.NewRequest("POST", "/v1/logs", strings.NewReader(otlpJSONBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("OTLP status = %d, want 429", rr.Code)
+	}
+}
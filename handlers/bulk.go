@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/auth"
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	// MaxBulkEntries is the maximum number of entries accepted per bulk request.
+	MaxBulkEntries = 10000
+	// MaxBulkBytes limits the uncompressed size of a bulk request body.
+	MaxBulkBytes = 32 << 20
+
+	// maxLineBytes leaves room for a maximum-length message plus attributes.
+	maxLineBytes = 1 << 20
+)
+
+var errTooManyEntries = fmt.Errorf("too many entries: maximum is %d per request", MaxBulkEntries)
+
+// BulkResult reports the outcome for one entry of a bulk request. Line is the
+// 1-based NDJSON line or JSON array position.
+type BulkResult struct {
+	Line  int    `json:"line"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// BulkResponse is the body returned by BulkAddLogsHandler.
+type BulkResponse struct {
+	Accepted int          `json:"accepted"`
+	Rejected int          `json:"rejected"`
+	Results  []BulkResult `json:"results"`
+}
+
+// bulkEntry is a decoded entry or the reason it could not be decoded.
+type bulkEntry struct {
+	line int
+	log  models.Log
+	err  error
+}
+
+// BulkAddLogsHandler inserts many log entries in one request.
+//
+// The body is either NDJSON (one entry per line) or a JSON array, optionally
+// gzip-compressed with Content-Encoding: gzip. Valid entries are inserted in a
+// single transaction; invalid ones are reported per line without failing the
+// rest of the batch, and neither are entries whose timestamp the timestamp
+// policy rejects. Entries sent with a tenant-scoped API key are stored under
+// the key's tenant, and the whole batch counts against its quota. With an
+// ingestion buffer the entries are queued and the response is 202 Accepted
+// without IDs.
+func (s *Server) BulkAddLogsHandler(w http.ResponseWriter, r *http.Request) {
+	body := io.Reader(http.MaxBytesReader(w, r.Body, MaxBulkBytes))
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "invalid gzip body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = &limitedReader{r: gz, n: MaxBulkBytes}
+	}
+
+	entries, err := decodeBulk(body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.Is(err, errTooManyEntries) || errors.Is(err, errBodyTooLarge) || errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	resp := BulkResponse{Results: make([]BulkResult, len(entries))}
+	var valid []models.Log
+	var positions []int
+	tenant := auth.TenantFromContext(r.Context())
+	now := time.Now()
+	for i, e := range entries {
+		resp.Results[i].Line = e.line
+		if tenant != "" {
+			e.log.Tenant = tenant
+		}
+		if e.err == nil {
+			e.err = e.log.Validate()
+		}
+		if e.err == nil {
+			e.err = s.timestamps.Apply(&e.log, now)
+		}
+		if e.err != nil {
+			resp.Results[i].Error = e.err.Error()
+			resp.Rejected++
+			continue
+		}
+		valid = append(valid, e.log)
+		positions = append(positions, i)
+	}
+
+	if !s.allowIngest(w, valid) {
+		return
+	}
+	if s.buffer != nil {
+		if s.enqueue(w, valid) {
+			resp.Accepted = len(valid)
+			writeJSON(w, http.StatusAccepted, resp)
+		}
+		return
+	}
+	if len(valid) > 0 {
+		ids, err := s.store.InsertLogs(valid)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for j, id := range ids {
+			resp.Results[positions[j]].ID = id
+		}
+		resp.Accepted = len(ids)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding bulk response: %v", err)
+	}
+}
+
+// decodeBulk reads a JSON array or NDJSON body. Every NDJSON line holds an
+// object, so a leading '[' is enough to tell the two apart.
+func decodeBulk(body io.Reader) ([]bulkEntry, error) {
+	br := bufio.NewReader(body)
+	isArray := false
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			break
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		br.UnreadByte()
+		isArray = b == '['
+		break
+	}
+
+	var entries []bulkEntry
+	var err error
+	if isArray {
+		entries, err = decodeArray(br)
+	} else {
+		entries, err = decodeNDJSON(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no log entries in request body")
+	}
+	return entries, nil
+}
+
+func decodeArray(r io.Reader) ([]bulkEntry, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, wrapBodyError(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("expected a JSON array of log entries")
+	}
+
+	var entries []bulkEntry
+	for dec.More() {
+		if len(entries) == MaxBulkEntries {
+			return nil, errTooManyEntries
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, wrapBodyError(err)
+		}
+		entries = append(entries, decodeEntry(len(entries)+1, raw))
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, wrapBodyError(err)
+	}
+	return entries, nil
+}
+
+func decodeNDJSON(r io.Reader) ([]bulkEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+
+	var entries []bulkEntry
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(entries) == MaxBulkEntries {
+			return nil, errTooManyEntries
+		}
+		entries = append(entries, decodeEntry(line, text))
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("line %d exceeds %d bytes", line+1, maxLineBytes)
+		}
+		return nil, wrapBodyError(err)
+	}
+	return entries, nil
+}
+
+func decodeEntry(line int, data []byte) bulkEntry {
+	e := bulkEntry{line: line}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&e.log); err != nil {
+		e.err = fmt.Errorf("invalid JSON: %w", err)
+	}
+	return e
+}
+
+var errBodyTooLarge = fmt.Errorf("request body exceeds %d bytes", MaxBulkBytes)
+
+func wrapBodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.Is(err, errBodyTooLarge) || errors.As(err, &maxErr) {
+		return err
+	}
+	return fmt.Errorf("invalid request body: %w", err)
+}
+
+// limitedReader fails with errBodyTooLarge instead of silently truncating
+// once more than n bytes have been read.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
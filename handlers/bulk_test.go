@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestBulkAddLogsHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		statusCode   int
+		wantAccepted int
+		wantRejected int
+		wantResults  []BulkResult
+	}{
+		{
+			name: "ndjson",
+			body: `{"level":"INFO","type":"SYSTEM","message":"one"}
+{"level":"ERROR","type":"API","message":"two","attributes":{"status":500}}
+`,
+			statusCode:   http.StatusOK,
+			wantAccepted: 2,
+			wantResults:  []BulkResult{{Line: 1, ID: 10}, {Line: 2, ID: 11}},
+		},
+		{
+			name:         "json array",
+			body:         ` [{"level":"INFO","type":"SYSTEM","message":"one"},{"level":"DEBUG","type":"USER","message":"two"}]`,
+			statusCode:   http.StatusOK,
+			wantAccepted: 2,
+			wantResults:  []BulkResult{{Line: 1, ID: 10}, {Line: 2, ID: 11}},
+		},
+		{
+			name: "per-line errors",
+			body: `{"level":"INFO","type":"SYSTEM","message":"one"}
+
+{"level":"TRACE","type":"SYSTEM","message":"bad level"}
+not-json
+{"level":"INFO","type":"SYSTEM","message":"four"}`,
+			statusCode:   http.StatusOK,
+			wantAccepted: 2,
+			wantRejected: 2,
+			wantResults: []BulkResult{
+				{Line: 1, ID: 10},
+				{Line: 3, Error: "invalid level"},
+				{Line: 4, Error: "invalid JSON"},
+				{Line: 5, ID: 11},
+			},
+		},
+		{
+			name:         "array element errors",
+			body:         `[{"level":"INFO","type":"SYSTEM","message":""},{"level":"INFO","type":"SYSTEM","message":"ok"}]`,
+			statusCode:   http.StatusOK,
+			wantAccepted: 1,
+			wantRejected: 1,
+			wantResults:  []BulkResult{{Line: 1, Error: "message is required"}, {Line: 2, ID: 10}},
+		},
+		{
+			name:       "empty body",
+			body:       "\n\n",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "truncated array",
+			body:       `[{"level":"INFO","type":"SYSTEM","message":"one"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := &mockStore{insertID: 10}
+			srv := newTestServer(ms)
+			req := httptest.NewRequest("POST", "/api/logs/bulk", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			srv.BulkAddLogsHandler(rr, req)
+
+			if rr.Code != tc.statusCode {
+				t.Fatalf("status = %d, want %d (body: %s)", rr.Code, tc.statusCode, rr.Body.String())
+			}
+			if tc.statusCode != http.StatusOK {
+				return
+			}
+
+			var resp BulkResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Accepted != tc.wantAccepted || resp.Rejected != tc.wantRejected {
+				t.Errorf("accepted/rejected = %d/%d, want %d/%d", resp.Accepted, resp.Rejected, tc.wantAccepted, tc.wantRejected)
+			}
+			if len(ms.lastBulk) != tc.wantAccepted {
+				t.Errorf("store received %d entries, want %d", len(ms.lastBulk), tc.wantAccepted)
+			}
+			if len(resp.Results) != len(tc.wantResults) {
+				t.Fatalf("got %d results, want %d: %+v", len(resp.Results), len(tc.wantResults), resp.Results)
+			}
+			for i, want := range tc.wantResults {
+				got := resp.Results[i]
+				if got.Line != want.Line || got.ID != want.ID || !strings.HasPrefix(got.Error, want.Error) {
+					t.Errorf("results[%d] = %+v, want %+v", i, got, want)
+				}
+				if want.Error == "" && got.Error != "" {
+					t.Errorf("results[%d] unexpected error %q", i, got.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkAddLogsHandlerGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"level":"INFO","type":"SYSTEM","message":"compressed"}` + "\n"))
+	gz.Close()
+
+	ms := &mockStore{insertID: 1}
+	srv := newTestServer(ms)
+	req := httptest.NewRequest("POST", "/api/logs/bulk", &buf)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	srv.BulkAddLogsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+	}
+	if len(ms.lastBulk) != 1 || ms.lastBulk[0].Message != "compressed" {
+		t.Errorf("store received %+v", ms.lastBulk)
+	}
+
+	req = httptest.NewRequest("POST", "/api/logs/bulk", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	srv.BulkAddLogsHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid gzip: status = %d, want 400", rr.Code)
+	}
+}
+
+func TestBulkAddLogsHandlerTooManyEntries(t *testing.T) {
+	line := `{"level":"INFO","type":"SYSTEM","message":"x"}` + "\n"
+	body := strings.Repeat(line, MaxBulkEntries+1)
+
+	ms := &mockStore{}
+	srv := newTestServer(ms)
+	req := httptest.NewRequest("POST", "/api/logs/bulk", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	srv.BulkAddLogsHandler(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rr.Code)
+	}
+	if ms.lastBulk != nil {
+		t.Error("nothing should be inserted when the request is rejected")
+	}
+}
+
+func TestBulkAddLogsHandlerStoreError(t *testing.T) {
+	srv := newTestServer(&mockStore{insertErr: errors.New("db down")})
+	req := httptest.NewRequest("POST", "/api/logs/bulk", strings.NewReader(`{"level":"INFO","type":"SYSTEM","message":"x"}`))
+	rr := httptest.NewRecorder()
+	srv.BulkAddLogsHandler(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rr.Code)
+	}
+}
+
+func TestBulkAddLogsHandlerTimestampPolicy(t *testing.T) {
+	ms := &mockStore{insertID: 10}
+	srv := newTestServer(ms)
+	srv.SetTimestampPolicy(models.TimestampPolicy{Mode: models.TimestampReject, MaxPast: 24 * time.Hour})
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	body := `{"timestamp":"` + recent + `","level":"INFO","type":"SYSTEM","message":"recent"}
+{"timestamp":"2020-01-01T00:00:00Z","level":"INFO","type":"SYSTEM","message":"stale"}
+`
+	rr := httptest.NewRecorder()
+	srv.BulkAddLogsHandler(rr, httptest.NewRequest("POST", "/api/logs/bulk", strings.NewReader(body)))
+
+	var resp BulkResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v (body: %s)", err, rr.Body.String())
+	}
+	if resp.Accepted != 1 || resp.Rejected != 1 || !strings.Contains(resp.Results[1].Error, "in the past") {
+		t.Errorf("response = %+v, want the stale entry rejected", resp)
+	}
+	if len(ms.lastBulk) != 1 || ms.lastBulk[0].Timestamp.UTC().Format(time.RFC3339) != recent {
+		t.Errorf("stored %+v, want the recent entry with its timestamp", ms.lastBulk)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mstgnz/golog/export"
+)
+
+// ExportHandler streams every entry matching the filters as a file
+// download, oldest first, without the page size limit of GetLogsHandler.
+//
+// Query parameters: the filters of GetLogsHandler and format (csv, ndjson
+// or columnar, default ndjson). Pagination parameters are ignored.
+func (s *Server) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := scopeFilter(r, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = export.FormatNDJSON
+	}
+
+	out := &countingWriter{w: w}
+	enc, err := export.NewWriter(format, out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="golog-%s%s"`, time.Now().UTC().Format("20060102T150405Z"), export.Extension(format)))
+
+	err = s.store.ExportLogs(r.Context(), filter, enc.Write)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err == nil || r.Context().Err() != nil {
+		return
+	}
+	if out.n == 0 {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The status has been sent, so the only way to tell the client that the
+	// file is incomplete is to break the connection.
+	log.Printf("Error exporting logs after %d bytes: %v", out.n, err)
+	panic(http.ErrAbortHandler)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
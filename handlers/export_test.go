@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestExportHandler(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	ms := &mockStore{logs: []models.Log{
+		{ID: 1, Timestamp: ts, Level: "ERROR", Type: "API", Message: "a, b"},
+		{ID: 2, Timestamp: ts, Level: "ERROR", Type: "API", Message: "c"},
+	}}
+	h := newTestServer(ms).SetupRoutes()
+
+	rr := do(h, "GET", "/api/logs/export?format=csv&level=ERROR&since=2024-03-01T00:00:00Z&limit=1", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="golog-`) || !strings.HasSuffix(cd, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil || len(records) != 3 || records[1][5] != "a, b" {
+		t.Errorf("records = %q, %v", records, err)
+	}
+	if f := ms.lastFilter; f.Levels[0] != "ERROR" || f.Since.IsZero() {
+		t.Errorf("store filter = %+v", f)
+	}
+
+	rr = do(h, "GET", "/api/logs/export", "")
+	if ct := rr.Header().Get("Content-Type"); rr.Code != http.StatusOK || ct != "application/x-ndjson" || strings.Count(rr.Body.String(), "\n") != 2 {
+		t.Errorf("default export = %d %q: %s", rr.Code, ct, rr.Body.String())
+	}
+
+	for _, target := range []string{"/api/logs/export?format=parquet", "/api/logs/export?level=TRACE"} {
+		if rr := do(h, "GET", target, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", target, rr.Code)
+		}
+	}
+
+	ms.logs, ms.exportErr = nil, errors.New("connection refused")
+	rr = do(h, "GET", "/api/logs/export?format=csv", "")
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Content-Disposition") != "" {
+		t.Errorf("failed export = %d %v, want 500 without a download", rr.Code, rr.Header())
+	}
+}
+
+func TestExportScopedToTenant(t *testing.T) {
+	ms := &mockStore{}
+	srv, billing, _ := newTenantServer(t, ms)
+	h := srv.SetupRoutes()
+
+	if rr := doAs(h, billing, "GET", "/api/logs/export", ""); rr.Code != http.StatusOK || ms.lastFilter.Tenant != "billing" {
+		t.Errorf("status = %d, tenant = %q", rr.Code, ms.lastFilter.Tenant)
+	}
+	if rr := doAs(h, billing, "GET", "/api/logs/export?tenant=search", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("other tenant status = %d, want 403", rr.Code)
+	}
+}
@@ -3,27 +3,43 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/auth"
 	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/retention"
+	"github.com/mstgnz/golog/tenant"
+	"github.com/mstgnz/golog/wal"
+	"github.com/mstgnz/golog/webhook"
 )
 
 // LogStore is the interface for log persistence operations.
 type LogStore interface {
 	GetLogs(filter models.LogFilter) ([]models.Log, error)
 	InsertLog(logEntry models.Log) (int, error)
+	InsertLogs(entries []models.Log) ([]int, error)
 	ListenForLogs(ctx context.Context, ch chan<- models.Log) error
+	Stats(q models.StatsQuery) ([]models.StatsBucket, error)
+	ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error
 }
 
 // Client represents a connected SSE client.
 type Client struct {
 	send chan models.Log
+	// tenant, when set, limits the client to that tenant's entries.
+	tenant string
 }
 
 // Server holds application state and serves HTTP requests.
@@ -32,6 +48,18 @@ type Server struct {
 	clients   map[*Client]bool
 	clientsMu sync.Mutex
 	logChan   chan models.Log
+
+	// observers see every streamed entry before it is sent to SSE clients.
+	observers []func(models.Log)
+	alerts    *alerting.Engine
+	webhooks  *webhook.Notifier
+	auth      *auth.Authenticator
+	origins   []string
+	quotas    *tenant.Quotas
+	retention *retention.Purger
+	buffer    *wal.Buffer
+
+	timestamps models.TimestampPolicy
 }
 
 // NewServer creates a Server backed by the given store.
@@ -40,29 +68,65 @@ func NewServer(store LogStore) *Server {
 		store:   store,
 		clients: make(map[*Client]bool),
 		logChan: make(chan models.Log),
+		origins: []string{"*"},
 	}
 }
 
+// AddObserver registers fn to be called with every new log entry delivered
+// by the store's change feed. fn must not block. Observers must be added
+// before StartLogListener.
+func (s *Server) AddObserver(fn func(models.Log)) {
+	s.observers = append(s.observers, fn)
+}
+
+// SetTimestampPolicy bounds the timestamps clients send with their entries.
+// Without a policy any client timestamp is accepted.
+func (s *Server) SetTimestampPolicy(p models.TimestampPolicy) {
+	s.timestamps = p
+}
+
 // SetupRoutes registers all HTTP routes and returns the handler.
 func (s *Server) SetupRoutes() http.Handler {
 	r := chi.NewRouter()
+	// EventSource cannot set headers, so the stream also takes the key from
+	// the query; it is removed before the request is logged.
+	r.Use(auth.QueryKey("/api/logs/stream"))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	// API keys are sent in headers, so cross-origin requests never need
+	// cookies or other credentials.
 	r.Use(cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedOrigins: s.origins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "Content-Encoding", auth.HeaderAPIKey},
+		ExposedHeaders: []string{"Link", "Content-Disposition"},
+		MaxAge:         300,
 	}).Handler)
 
 	r.Route("/api", func(r chi.Router) {
-		r.Get("/logs", s.GetLogsHandler)
-		r.Post("/logs", s.AddLogHandler)
-		r.Get("/logs/stream", s.StreamLogsHandler)
+		r.With(s.require(auth.ScopeRead)).Get("/logs", s.GetLogsHandler)
+		r.With(s.require(auth.ScopeIngest)).Post("/logs", s.AddLogHandler)
+		r.With(s.require(auth.ScopeIngest)).Post("/logs/bulk", s.BulkAddLogsHandler)
+		r.With(s.require(auth.ScopeRead)).Get("/logs/stream", s.StreamLogsHandler)
+		r.With(s.require(auth.ScopeRead)).Get("/logs/stats", s.StatsHandler)
+		r.With(s.require(auth.ScopeRead)).Get("/logs/export", s.ExportHandler)
+		// The registry only lists level and type names, which the dashboard
+		// needs before the user has entered a key.
+		r.Get("/registry", s.RegistryHandler)
+		if s.alerts != nil {
+			r.Route("/alerts", s.alertRoutes)
+		}
+		if s.webhooks != nil {
+			r.With(s.require(auth.ScopeAdmin)).Get("/webhooks/dead-letters", s.ListDeadLettersHandler)
+		}
+		if s.auth != nil || s.quotas != nil || s.retention != nil || s.buffer != nil {
+			r.With(s.require(auth.ScopeAdmin)).Route("/admin", s.adminRoutes)
+		}
 	})
 
+	// OTLP/HTTP exporters append /v1/logs to the configured endpoint.
+	r.With(s.require(auth.ScopeIngest)).Post("/v1/logs", s.OTLPLogsHandler)
+
 	fileServer := http.FileServer(http.Dir("./web/static"))
 	r.Handle("/*", fileServer)
 
@@ -71,19 +135,21 @@ func (s *Server) SetupRoutes() http.Handler {
 
 // GetLogsHandler returns log entries with optional filtering and pagination.
 //
-// Query parameters: level, type, limit (default 100, max 500), offset (default 0).
+// Query parameters: level and type (comma-separated or repeated for several
+// values), min_level, limit (default 100, max 500), offset (default 0) or
+// cursor for keyset pagination, since/until (RFC3339 or relative like
+// -15m), attr.<key>=<value> to match structured attributes, q with mode
+// (fts, substring, regex) to search message text, and tenant. Tenant-scoped
+// API keys only see their tenant. time_field (timestamp or received_at)
+// selects the time that since/until and the ordering apply to.
 func (s *Server) GetLogsHandler(w http.ResponseWriter, r *http.Request) {
-	filter := models.LogFilter{
-		Level: r.URL.Query().Get("level"),
-		Type:  r.URL.Query().Get("type"),
-	}
-
-	if filter.Level != "" && !models.ValidLevels[filter.Level] {
-		http.Error(w, "invalid level", http.StatusBadRequest)
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if filter.Type != "" && !models.ValidTypes[filter.Type] {
-		http.Error(w, "invalid type", http.StatusBadRequest)
+	if err := scopeFilter(r, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
@@ -103,6 +169,18 @@ func (s *Server) GetLogsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		filter.Offset = n
 	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		if filter.Offset > 0 {
+			http.Error(w, "cursor and offset cannot be combined", http.StatusBadRequest)
+			return
+		}
+		var c models.Cursor
+		if err := c.UnmarshalText([]byte(v)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Cursor = &c
+	}
 
 	logs, err := s.store.GetLogs(filter)
 	if err != nil {
@@ -113,23 +191,80 @@ func (s *Server) GetLogsHandler(w http.ResponseWriter, r *http.Request) {
 		logs = []models.Log{}
 	}
 
+	if link := paginationLinks(r.URL, filter, logs); link != "" {
+		w.Header().Set("Link", link)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(logs); err != nil {
 		log.Printf("Error encoding logs response: %v", err)
 	}
 }
 
-// AddLogHandler inserts a new log entry.
+// paginationLinks builds an RFC 8288 Link header with next (older) and prev
+// (newer) cursors around the returned page.
+func paginationLinks(u *url.URL, filter models.LogFilter, logs []models.Log) string {
+	if len(logs) == 0 {
+		return ""
+	}
+	full := len(logs) == filter.PageSize()
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+
+	hasNext := full
+	hasPrev := filter.Cursor != nil || filter.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, full
+	}
+
+	link := func(c models.Cursor, rel string) string {
+		q := u.Query()
+		q.Del("offset")
+		q.Set("cursor", c.String())
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, u.Path, q.Encode(), rel)
+	}
+
+	var links []string
+	if hasNext {
+		links = append(links, link(filter.CursorAfter(logs[len(logs)-1], false), "next"))
+	}
+	if hasPrev {
+		links = append(links, link(filter.CursorAfter(logs[0], true), "prev"))
+	}
+	return strings.Join(links, ", ")
+}
+
+// AddLogHandler inserts a new log entry. Entries sent with a tenant-scoped
+// API key are stored under the key's tenant. The entry keeps the timestamp
+// sent by the client, subject to the timestamp policy, or is stamped with
+// the receive time. With an ingestion buffer the entry is queued and the
+// response is 202 Accepted without an ID.
 func (s *Server) AddLogHandler(w http.ResponseWriter, r *http.Request) {
 	var logEntry models.Log
-	if err := json.NewDecoder(r.Body).Decode(&logEntry); err != nil {
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&logEntry); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if t := auth.TenantFromContext(r.Context()); t != "" {
+		logEntry.Tenant = t
+	}
 	if err := logEntry.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := s.timestamps.Apply(&logEntry, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.allowIngest(w, []models.Log{logEntry}) {
+		return
+	}
+	if s.buffer != nil {
+		if s.enqueue(w, []models.Log{logEntry}) {
+			writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+		}
+		return
+	}
 
 	id, err := s.store.InsertLog(logEntry)
 	if err != nil {
@@ -143,9 +278,22 @@ func (s *Server) AddLogHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// RegistryHandler returns the configured levels (ordered by severity) and
+// types so that clients can build their filters.
+func (s *Server) RegistryHandler(w http.ResponseWriter, r *http.Request) {
+	reg := models.CurrentRegistry()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"levels": reg.Levels(),
+		"types":  reg.Types(),
+	}); err != nil {
+		log.Printf("Error encoding registry response: %v", err)
+	}
+}
+
 // StreamLogsHandler streams log entries to the client using Server-Sent Events.
 //
-// Query parameters: level, type (optional filters applied server-side).
+// Query parameters: the same filters as GetLogsHandler, applied server-side.
 func (s *Server) StreamLogsHandler(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -153,14 +301,21 @@ func (s *Server) StreamLogsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := scopeFilter(r, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	level := r.URL.Query().Get("level")
-	logType := r.URL.Query().Get("type")
-
-	client := &Client{send: make(chan models.Log, 256)}
+	client := &Client{send: make(chan models.Log, 256), tenant: filter.Tenant}
 	s.clientsMu.Lock()
 	s.clients[client] = true
 	s.clientsMu.Unlock()
@@ -178,7 +333,8 @@ func (s *Server) StreamLogsHandler(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			if (level == "" || logEntry.Level == level) && (logType == "" || logEntry.Type == logType) {
+			if filter.Matches(logEntry) {
+				logEntry.Snippet = filter.Highlight(logEntry.Message, "<mark>", "</mark>")
 				data, err := json.Marshal(logEntry)
 				if err != nil {
 					log.Printf("Error marshaling log entry: %v", err)
@@ -195,16 +351,112 @@ func (s *Server) StreamLogsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// parseFilter builds a LogFilter from the query parameters shared by the
+// list and stream endpoints.
+func parseFilter(q url.Values) (models.LogFilter, error) {
+	filter := models.LogFilter{
+		Levels:     listParam(q, "level"),
+		Types:      listParam(q, "type"),
+		MinLevel:   q.Get("min_level"),
+		Attributes: attributeFilters(q),
+		Query:      q.Get("q"),
+		SearchMode: q.Get("mode"),
+		Tenant:     q.Get("tenant"),
+		TimeField:  q.Get("time_field"),
+	}
+
+	if err := models.ValidateTenant(filter.Tenant); err != nil {
+		return filter, err
+	}
+	if !models.ValidTimeField(filter.TimeField) {
+		return filter, fmt.Errorf("invalid time_field %q: must be %s or %s", filter.TimeField, models.TimeFieldTimestamp, models.TimeFieldReceivedAt)
+	}
+
+	for _, l := range filter.Levels {
+		if !models.ValidLevel(l) {
+			return filter, fmt.Errorf("invalid level %q", l)
+		}
+	}
+	for _, t := range filter.Types {
+		if !models.ValidType(t) {
+			return filter, fmt.Errorf("invalid type %q", t)
+		}
+	}
+	if filter.MinLevel != "" && !models.ValidLevel(filter.MinLevel) {
+		return filter, fmt.Errorf("invalid min_level %q", filter.MinLevel)
+	}
+	if err := filter.ValidateSearch(); err != nil {
+		return filter, err
+	}
+
+	now := time.Now()
+	if v := q.Get("since"); v != "" {
+		t, err := models.ParseTime(v, now)
+		if err != nil {
+			return filter, fmt.Errorf("since: %w", err)
+		}
+		filter.Since = t
+	}
+	if v := q.Get("until"); v != "" {
+		t, err := models.ParseTime(v, now)
+		if err != nil {
+			return filter, fmt.Errorf("until: %w", err)
+		}
+		filter.Until = t
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, errors.New("since must be before until")
+	}
+
+	return filter, nil
+}
+
+// listParam collects the values of a parameter that may be repeated or
+// comma-separated, e.g. level=ERROR,WARNING or type=AUTH&type=API.
+func listParam(q url.Values, name string) []string {
+	var values []string
+	for _, v := range q[name] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// attributeFilters collects attr.<key>=<value> query parameters.
+func attributeFilters(q url.Values) map[string]string {
+	var attrs map[string]string
+	for key, values := range q {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[name] = values[0]
+	}
+	return attrs
+}
+
 // StartLogListener subscribes to the store's notification channel and broadcasts
-// each log entry to all connected SSE clients.
+// each log entry to the observers and the connected SSE clients of its tenant.
 func (s *Server) StartLogListener(ctx context.Context) error {
 	if err := s.store.ListenForLogs(ctx, s.logChan); err != nil {
 		return err
 	}
 	go func() {
 		for logEntry := range s.logChan {
+			for _, observe := range s.observers {
+				observe(logEntry)
+			}
 			s.clientsMu.Lock()
 			for client := range s.clients {
+				if client.tenant != "" && client.tenant != logEntry.Tenant {
+					continue
+				}
 				select {
 				case client.send <- logEntry:
 				default:
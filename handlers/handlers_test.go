@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -15,20 +16,55 @@ import (
 
 // mockStore implements LogStore for testing.
 type mockStore struct {
-	logs      []models.Log
-	insertID  int
-	insertErr error
-	listenFn  func(ctx context.Context, ch chan<- models.Log) error
+	logs       []models.Log
+	insertID   int
+	insertErr  error
+	listenFn   func(ctx context.Context, ch chan<- models.Log) error
+	lastFilter models.LogFilter
+	lastInsert models.Log
+	lastBulk   []models.Log
+	stats      []models.StatsBucket
+	lastStats  models.StatsQuery
+	exportErr  error
 }
 
 func (m *mockStore) GetLogs(filter models.LogFilter) ([]models.Log, error) {
+	m.lastFilter = filter
 	return m.logs, nil
 }
 
 func (m *mockStore) InsertLog(logEntry models.Log) (int, error) {
+	m.lastInsert = logEntry
 	return m.insertID, m.insertErr
 }
 
+func (m *mockStore) InsertLogs(entries []models.Log) ([]int, error) {
+	m.lastBulk = entries
+	if m.insertErr != nil {
+		return nil, m.insertErr
+	}
+	ids := make([]int, len(entries))
+	for i := range entries {
+		ids[i] = m.insertID + i
+	}
+	return ids, nil
+}
+
+func (m *mockStore) Stats(q models.StatsQuery) ([]models.StatsBucket, error) {
+	m.lastStats = q
+	return m.stats, nil
+}
+
+func (m *mockStore) ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error {
+	m.lastFilter = filter
+	for _, l := range m.logs {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return m.exportErr
+}
+
 func (m *mockStore) ListenForLogs(ctx context.Context, ch chan<- models.Log) error {
 	if m.listenFn != nil {
 		return m.listenFn(ctx, ch)
@@ -104,6 +140,50 @@ func TestGetLogsHandler(t *testing.T) {
 			query:      "offset=-5",
 			statusCode: http.StatusBadRequest,
 		},
+		{
+			name:       "time range accepted",
+			query:      "since=-15m&until=2099-01-01T00:00:00Z",
+			storeLogs:  []models.Log{},
+			statusCode: http.StatusOK,
+			wantCount:  0,
+		},
+		{
+			name:       "invalid since",
+			query:      "since=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "since after until",
+			query:      "since=2024-01-02T00:00:00Z&until=2024-01-01T00:00:00Z",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "search accepted",
+			query:      "q=connection+timeout&mode=substring",
+			storeLogs:  []models.Log{},
+			statusCode: http.StatusOK,
+			wantCount:  0,
+		},
+		{
+			name:       "invalid search mode",
+			query:      "q=timeout&mode=fuzzy",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid regex",
+			query:      "q=(unclosed&mode=regex",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid cursor",
+			query:      "cursor=garbage",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "cursor with offset",
+			query:      "offset=10&cursor=" + models.Cursor{Timestamp: fixedTime, ID: 1}.String(),
+			statusCode: http.StatusBadRequest,
+		},
 		{
 			name:       "pagination params accepted",
 			query:      "limit=10&offset=20",
@@ -136,6 +216,139 @@ func TestGetLogsHandler(t *testing.T) {
 	}
 }
 
+func TestGetLogsHandlerAttributeFilter(t *testing.T) {
+	ms := &mockStore{}
+	srv := newTestServer(ms)
+	req := httptest.NewRequest("GET", "/api/logs?level=ERROR&attr.user_id=42&attr.request_id=abc", nil)
+	rr := httptest.NewRecorder()
+	srv.GetLogsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+	}
+	want := map[string]string{"user_id": "42", "request_id": "abc"}
+	if len(ms.lastFilter.Attributes) != len(want) {
+		t.Fatalf("filter attributes = %v, want %v", ms.lastFilter.Attributes, want)
+	}
+	for k, v := range want {
+		if ms.lastFilter.Attributes[k] != v {
+			t.Errorf("filter attribute %s = %q, want %q", k, ms.lastFilter.Attributes[k], v)
+		}
+	}
+}
+
+func TestParseFilterLevels(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantErr   bool
+		wantLevel []string
+		wantType  []string
+		wantMin   string
+	}{
+		{query: "level=ERROR,WARNING&type=AUTH&type=API", wantLevel: []string{"ERROR", "WARNING"}, wantType: []string{"AUTH", "API"}},
+		{query: "level=ERROR", wantLevel: []string{"ERROR"}},
+		{query: "min_level=WARNING", wantMin: "WARNING"},
+		{query: "level=ERROR,TRACE", wantErr: true},
+		{query: "type=AUTH,,NETWORK", wantErr: true},
+		{query: "min_level=SEVERE", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tc.query)
+			filter, err := parseFilter(q)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseFilter() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if strings.Join(filter.Levels, ",") != strings.Join(tc.wantLevel, ",") ||
+				strings.Join(filter.Types, ",") != strings.Join(tc.wantType, ",") ||
+				filter.MinLevel != tc.wantMin {
+				t.Errorf("parseFilter() = levels %v, types %v, min %q", filter.Levels, filter.Types, filter.MinLevel)
+			}
+		})
+	}
+}
+
+func TestGetLogsHandlerLinkHeader(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	page := []models.Log{
+		{ID: 3, Timestamp: ts.Add(2 * time.Second), Level: "INFO", Type: "SYSTEM", Message: "c"},
+		{ID: 2, Timestamp: ts.Add(time.Second), Level: "INFO", Type: "SYSTEM", Message: "b"},
+	}
+
+	parseLinks := func(t *testing.T, header string) map[string]models.Cursor {
+		t.Helper()
+		links := map[string]models.Cursor{}
+		for _, part := range strings.Split(header, ", ") {
+			target, rel, ok := strings.Cut(part, ">; rel=")
+			if !ok {
+				t.Fatalf("malformed Link header part %q", part)
+			}
+			u, err := url.Parse(strings.TrimPrefix(target, "<"))
+			if err != nil {
+				t.Fatalf("invalid link URL: %v", err)
+			}
+			if u.Query().Has("offset") {
+				t.Errorf("link %q should not carry offset", target)
+			}
+			var c models.Cursor
+			if err := c.UnmarshalText([]byte(u.Query().Get("cursor"))); err != nil {
+				t.Fatalf("invalid cursor in link: %v", err)
+			}
+			links[strings.Trim(rel, `"`)] = c
+		}
+		return links
+	}
+
+	t.Run("first full page has only next", func(t *testing.T) {
+		srv := newTestServer(&mockStore{logs: page})
+		rr := httptest.NewRecorder()
+		srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?limit=2&level=INFO", nil))
+
+		links := parseLinks(t, rr.Header().Get("Link"))
+		next, ok := links["next"]
+		if !ok || next.ID != 2 || next.Backward {
+			t.Errorf("next cursor = %+v, want forward cursor at id 2", next)
+		}
+		if _, ok := links["prev"]; ok {
+			t.Error("first page should not have a prev link")
+		}
+	})
+
+	t.Run("cursor page has next and prev", func(t *testing.T) {
+		ms := &mockStore{logs: page}
+		srv := newTestServer(ms)
+		cursor := models.Cursor{Timestamp: ts.Add(3 * time.Second), ID: 4}
+		rr := httptest.NewRecorder()
+		srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?limit=2&cursor="+cursor.String(), nil))
+
+		if ms.lastFilter.Cursor == nil || ms.lastFilter.Cursor.ID != 4 {
+			t.Fatalf("store received cursor %+v, want id 4", ms.lastFilter.Cursor)
+		}
+		links := parseLinks(t, rr.Header().Get("Link"))
+		if prev, ok := links["prev"]; !ok || prev.ID != 3 || !prev.Backward {
+			t.Errorf("prev cursor = %+v, want backward cursor at id 3", prev)
+		}
+		if _, ok := links["next"]; !ok {
+			t.Error("full page should have a next link")
+		}
+	})
+
+	t.Run("partial last page has no next", func(t *testing.T) {
+		srv := newTestServer(&mockStore{logs: page[:1]})
+		cursor := models.Cursor{Timestamp: ts.Add(3 * time.Second), ID: 4}
+		rr := httptest.NewRecorder()
+		srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?limit=2&cursor="+cursor.String(), nil))
+
+		links := parseLinks(t, rr.Header().Get("Link"))
+		if _, ok := links["next"]; ok {
+			t.Error("partial page should not have a next link")
+		}
+	})
+}
+
 func TestAddLogHandler(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -152,6 +365,13 @@ func TestAddLogHandler(t *testing.T) {
 			statusCode: http.StatusOK,
 			wantID:     42,
 		},
+		{
+			name:       "valid log with attributes",
+			body:       `{"level":"INFO","type":"API","message":"hello","attributes":{"user_id":42,"request_id":"abc"}}`,
+			insertID:   7,
+			statusCode: http.StatusOK,
+			wantID:     7,
+		},
 		{
 			name:       "missing message",
 			body:       `{"level":"INFO","type":"SYSTEM","message":""}`,
@@ -198,6 +418,144 @@ func TestAddLogHandler(t *testing.T) {
 	}
 }
 
+func TestAddLogHandlerKeepsAttributes(t *testing.T) {
+	ms := &mockStore{insertID: 1}
+	srv := newTestServer(ms)
+	body := `{"level":"INFO","type":"API","message":"hello","attributes":{"user_id":9007199254740993,"ok":true}}`
+	req := httptest.NewRequest("POST", "/api/logs", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	srv.AddLogHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+	}
+	if got := models.AttributeString(ms.lastInsert.Attributes["user_id"]); got != "9007199254740993" {
+		t.Errorf("user_id = %s, want 9007199254740993", got)
+	}
+	if got := models.AttributeString(ms.lastInsert.Attributes["ok"]); got != "true" {
+		t.Errorf("ok = %s, want true", got)
+	}
+}
+
+func TestAddLogHandlerTimestampPolicy(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := "2024-01-15T10:30:00Z"
+	body := func(ts string) string {
+		return `{"timestamp":"` + ts + `","level":"INFO","type":"SYSTEM","message":"hello"}`
+	}
+	policy := models.TimestampPolicy{Mode: models.TimestampReject, MaxFuture: 5 * time.Minute}
+
+	t.Run("client timestamp kept", func(t *testing.T) {
+		ms := &mockStore{insertID: 1}
+		srv := newTestServer(ms)
+		srv.SetTimestampPolicy(policy)
+		rr := httptest.NewRecorder()
+		srv.AddLogHandler(rr, httptest.NewRequest("POST", "/api/logs", strings.NewReader(body(past))))
+		if rr.Code != http.StatusOK || ms.lastInsert.Timestamp.Format(time.RFC3339) != past {
+			t.Errorf("status %d, stored timestamp %v, want %s", rr.Code, ms.lastInsert.Timestamp, past)
+		}
+	})
+
+	t.Run("future timestamp rejected", func(t *testing.T) {
+		ms := &mockStore{insertID: 1}
+		srv := newTestServer(ms)
+		srv.SetTimestampPolicy(policy)
+		rr := httptest.NewRecorder()
+		srv.AddLogHandler(rr, httptest.NewRequest("POST", "/api/logs", strings.NewReader(body(future))))
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "in the future") {
+			t.Errorf("status %d (%s), want 400", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("missing timestamp stamped", func(t *testing.T) {
+		ms := &mockStore{insertID: 1}
+		srv := newTestServer(ms)
+		before := time.Now()
+		rr := httptest.NewRecorder()
+		srv.AddLogHandler(rr, httptest.NewRequest("POST", "/api/logs", strings.NewReader(`{"level":"INFO","type":"SYSTEM","message":"hello"}`)))
+		if rr.Code != http.StatusOK || ms.lastInsert.Timestamp.Before(before) {
+			t.Errorf("status %d, stored timestamp %v, want the receive time", rr.Code, ms.lastInsert.Timestamp)
+		}
+	})
+}
+
+func TestGetLogsHandlerTimeField(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	ms := &mockStore{logs: []models.Log{{ID: 2, Timestamp: ts, ReceivedAt: ts.Add(time.Hour), Level: "INFO", Type: "SYSTEM", Message: "late"}}}
+	srv := newTestServer(ms)
+	rr := httptest.NewRecorder()
+	srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?limit=1&time_field=received_at", nil))
+
+	if rr.Code != http.StatusOK || ms.lastFilter.TimeField != models.TimeFieldReceivedAt {
+		t.Fatalf("status %d, filter %+v", rr.Code, ms.lastFilter)
+	}
+	u, _ := url.Parse(strings.TrimSuffix(strings.TrimPrefix(rr.Header().Get("Link"), "<"), `>; rel="next"`))
+	var c models.Cursor
+	if err := c.UnmarshalText([]byte(u.Query().Get("cursor"))); err != nil || !c.Timestamp.Equal(ts.Add(time.Hour)) {
+		t.Errorf("next cursor = %+v, %v, want it at the receive time", c, err)
+	}
+	if u.Query().Get("time_field") != models.TimeFieldReceivedAt {
+		t.Errorf("next link %s should keep time_field", u)
+	}
+
+	rr = httptest.NewRecorder()
+	srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?time_field=created", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid time_field status = %d, want 400", rr.Code)
+	}
+}
+
+func TestRegistryHandler(t *testing.T) {
+	reg, err := models.NewRegistry(
+		[]models.LevelDef{{Name: "FATAL", Severity: 50}, {Name: "TRACE", Severity: 0}, {Name: "INFO", Severity: 20}},
+		[]string{"PAYMENTS", "QUEUE"},
+	)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	models.SetRegistry(reg)
+	defer models.SetRegistry(mustDefaultRegistry(t))
+
+	srv := newTestServer(&mockStore{})
+	rr := httptest.NewRecorder()
+	srv.RegistryHandler(rr, httptest.NewRequest("GET", "/api/registry", nil))
+
+	var resp struct {
+		Levels []models.LevelDef `json:"levels"`
+		Types  []string          `json:"types"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Levels) != 3 || resp.Levels[0].Name != "TRACE" || resp.Levels[2].Name != "FATAL" {
+		t.Errorf("levels = %+v, want ordered by severity", resp.Levels)
+	}
+	if strings.Join(resp.Types, ",") != "PAYMENTS,QUEUE" {
+		t.Errorf("types = %v", resp.Types)
+	}
+
+	// Filters and validation follow the registry.
+	rr = httptest.NewRecorder()
+	srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?level=TRACE&type=QUEUE", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("GET with configured level/type: status = %d, want 200", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	srv.GetLogsHandler(rr, httptest.NewRequest("GET", "/api/logs?type=API", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("GET with unregistered type: status = %d, want 400", rr.Code)
+	}
+}
+
+func mustDefaultRegistry(t *testing.T) *models.Registry {
+	t.Helper()
+	reg, err := models.NewRegistry(models.DefaultLevels, models.DefaultTypes)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	return reg
+}
+
 func TestStreamLogsHandler(t *testing.T) {
 	// listenCh lets the test inject log entries into the stream.
 	listenCh := make(chan models.Log, 1)
@@ -242,7 +600,7 @@ func TestStreamLogsHandler(t *testing.T) {
 	// Wait for client registration before sending.
 	time.Sleep(20 * time.Millisecond)
 
-	listenCh <- models.Log{ID: 1, Level: "INFO", Type: "SYSTEM", Message: "stream-test"}
+	listenCh <- models.Log{ID: 1, Level: "INFO", Type: "SYSTEM", Message: "stream-test", Attributes: map[string]any{"request_id": "abc"}}
 
 	<-handlerDone
 
@@ -250,6 +608,9 @@ func TestStreamLogsHandler(t *testing.T) {
 	if !strings.Contains(body, "stream-test") {
 		t.Errorf("SSE body = %q, want to contain 'stream-test'", body)
 	}
+	if !strings.Contains(body, `"attributes":{"request_id":"abc"}`) {
+		t.Errorf("SSE body = %q, want attributes to be preserved", body)
+	}
 	if !strings.HasPrefix(body, "data: ") {
 		t.Errorf("SSE body should start with 'data: ', got: %q", body)
 	}
@@ -285,7 +646,7 @@ func TestStreamLogsHandlerFilter(t *testing.T) {
 	reqCtx, reqCancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer reqCancel()
 
-	req := httptest.NewRequest("GET", "/api/logs/stream?level=ERROR", nil).WithContext(reqCtx)
+	req := httptest.NewRequest("GET", "/api/logs/stream?min_level=WARNING", nil).WithContext(reqCtx)
 	rr := httptest.NewRecorder()
 
 	handlerDone := make(chan struct{})
@@ -296,7 +657,7 @@ func TestStreamLogsHandlerFilter(t *testing.T) {
 
 	time.Sleep(20 * time.Millisecond)
 
-	// This should be filtered out (INFO != ERROR).
+	// This should be filtered out (INFO is below WARNING).
 	listenCh <- models.Log{ID: 1, Level: "INFO", Type: "SYSTEM", Message: "should-be-filtered"}
 	// This should pass through.
 	listenCh <- models.Log{ID: 2, Level: "ERROR", Type: "DATABASE", Message: "should-appear"}
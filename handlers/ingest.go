@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// ErrQuotaExceeded is returned by Ingest when the entry's tenant has used up
+// its ingestion quota.
+var ErrQuotaExceeded = errors.New("tenant ingestion quota exceeded")
+
+// Ingest stores an entry received other than over HTTP, such as by the
+// syslog receiver, the way the ingestion endpoints do: charged to its
+// tenant's quota and queued in the ingestion buffer when one is set. The
+// entry must already be validated.
+func (s *Server) Ingest(entry models.Log) error {
+	if s.quotas != nil {
+		if _, ok := s.quotas.Allow(map[string]int{entry.Tenant: 1}); !ok {
+			return ErrQuotaExceeded
+		}
+	}
+	if s.buffer != nil {
+		return s.buffer.Append([]models.Log{entry})
+	}
+	_, err := s.store.InsertLog(entry)
+	return err
+}
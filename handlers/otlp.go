@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/otlp"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// OTLPLogsHandler implements the OTLP/HTTP logs endpoint (POST /v1/logs) so
+// that OpenTelemetry exporters can send to golog directly. Requests may be
+// protobuf or JSON encoded and gzip-compressed; the response uses the same
+// encoding and reports records that failed validation or the timestamp
+// policy as a partial success.
+func (s *Server) OTLPLogsHandler(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		http.Error(w, "unsupported content type: use application/x-protobuf or application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := io.Reader(http.MaxBytesReader(w, r.Body, MaxBulkBytes))
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "invalid gzip body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = &limitedReader{r: gz, n: MaxBulkBytes}
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.Is(err, errBodyTooLarge) || errors.As(err, &maxErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req *collogspb.ExportLogsServiceRequest
+	if contentType == contentTypeProtobuf {
+		req, err = otlp.DecodeProtobuf(data)
+	} else {
+		req, err = otlp.DecodeJSON(data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := otlp.Convert(req)
+	assignTenant(r, res.Entries)
+	now := time.Now()
+	kept := res.Entries[:0]
+	for _, e := range res.Entries {
+		if err := s.timestamps.Apply(&e, now); err != nil {
+			if res.Rejected == 0 {
+				res.Error = err.Error()
+			}
+			res.Rejected++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	res.Entries = kept
+	if !s.allowIngest(w, res.Entries) {
+		return
+	}
+	if s.buffer != nil {
+		if !s.enqueue(w, res.Entries) {
+			return
+		}
+	} else if len(res.Entries) > 0 {
+		if _, err := s.store.InsertLogs(res.Entries); err != nil {
+			log.Printf("Error inserting OTLP logs: %v", err)
+			// 503 tells OTLP exporters to retry the export.
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if res.Rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(res.Rejected),
+			ErrorMessage:       res.Error,
+		}
+	}
+	writeOTLPResponse(w, contentType, resp)
+}
+
+func writeOTLPResponse(w http.ResponseWriter, contentType string, resp *collogspb.ExportLogsServiceResponse) {
+	w.Header().Set("Content-Type", contentType)
+	if contentType == contentTypeProtobuf {
+		b, err := proto.Marshal(resp)
+		if err != nil {
+			log.Printf("Error encoding OTLP response: %v", err)
+			return
+		}
+		w.Write(b)
+		return
+	}
+
+	out := map[string]any{}
+	if ps := resp.GetPartialSuccess(); ps != nil {
+		out["partialSuccess"] = map[string]any{
+			"rejectedLogRecords": ps.GetRejectedLogRecords(),
+			"errorMessage":       ps.GetErrorMessage(),
+		}
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		log.Printf("Error encoding OTLP response: %v", err)
+	}
+}
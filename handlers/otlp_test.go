@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const otlpJSONBody = `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"auth"}}]},
+"scopeLogs":[{"logRecords":[
+  {"severityNumber":17,"body":{"stringValue":"login failed"},"traceId":"5b8efff798038103d269b633813fc60c"},
+  {"severityNumber":9}
+]}]}]}`
+
+func TestOTLPLogsHandlerJSON(t *testing.T) {
+	ms := &mockStore{insertID: 1}
+	srv := newTestServer(ms)
+	req := httptest.NewRequest("POST", "/v1/logs", strings.NewReader(otlpJSONBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	srv.SetupRoutes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+	}
+	if len(ms.lastBulk) != 1 {
+		t.Fatalf("store received %d entries, want 1", len(ms.lastBulk))
+	}
+	got := ms.lastBulk[0]
+	if got.Level != "ERROR" || got.Type != "AUTH" || got.Attributes["trace_id"] != "5b8efff798038103d269b633813fc60c" {
+		t.Errorf("stored %+v", got)
+	}
+
+	var resp struct {
+		PartialSuccess struct {
+			RejectedLogRecords int64 `json:"rejectedLogRecords"`
+		} `json:"partialSuccess"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.PartialSuccess.RejectedLogRecords != 1 {
+		t.Errorf("rejectedLogRecords = %d, want 1", resp.PartialSuccess.RejectedLogRecords)
+	}
+}
+
+func TestOTLPLogsHandlerProtobuf(t *testing.T) {
+	export := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			ScopeLogs: []*logspb.ScopeLogs{{
+				LogRecords: []*logspb.LogRecord{{
+					SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+					Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "cache miss"}},
+				}},
+			}},
+		}},
+	}
+	data, err := proto.Marshal(export)
+	if err != nil {
+		t.Fatalf("proto.Marshal() error: %v", err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+
+	ms := &mockStore{insertID: 1}
+	srv := newTestServer(ms)
+	req := httptest.NewRequest("POST", "/v1/logs", &buf)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	srv.OTLPLogsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q", rr.Header().Get("Content-Type"))
+	}
+	var resp collogspb.ExportLogsServiceResponse
+	if err := proto.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.GetPartialSuccess() != nil {
+		t.Errorf("unexpected partial success %v", resp.GetPartialSuccess())
+	}
+	if len(ms.lastBulk) != 1 || ms.lastBulk[0].Level != "DEBUG" || ms.lastBulk[0].Message != "cache miss" {
+		t.Errorf("store received %+v", ms.lastBulk)
+	}
+}
+
+func TestOTLPLogsHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		store       *mockStore
+		statusCode  int
+	}{
+		{"unsupported content type", "text/plain", "hello", &mockStore{}, http.StatusUnsupportedMediaType},
+		{"malformed json", "application/json", "{", &mockStore{}, http.StatusBadRequest},
+		{"malformed protobuf", "application/x-protobuf", "\xff\xff", &mockStore{}, http.StatusBadRequest},
+		{"store failure", "application/json; charset=utf-8", otlpJSONBody, &mockStore{insertErr: errors.New("db down")}, http.StatusServiceUnavailable},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(tc.store)
+			req := httptest.NewRequest("POST", "/v1/logs", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rr := httptest.NewRecorder()
+			srv.OTLPLogsHandler(rr, req)
+
+			if rr.Code != tc.statusCode {
+				t.Errorf("status = %d, want %d (body: %s)", rr.Code, tc.statusCode, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mstgnz/golog/retention"
+)
+
+// SetRetention exposes the purger's status and manual runs under
+// /api/admin/retention.
+func (s *Server) SetRetention(p *retention.Purger) {
+	s.retention = p
+}
+
+// RetentionStatusHandler returns the retention policy and the outcome of
+// the last purge.
+func (s *Server) RetentionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.retention.Status())
+}
+
+// RunRetentionHandler purges now and returns the outcome. With
+// dry_run=true it only reports what would be deleted.
+func (s *Server) RunRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "invalid dry_run", http.StatusBadRequest)
+			return
+		}
+	}
+
+	run, err := s.retention.Run(r.Context(), dryRun)
+	switch {
+	case errors.Is(err, retention.ErrRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeJSON(w, http.StatusOK, run)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mstgnz/golog/memstore"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/retention"
+)
+
+func TestRetentionRoutes(t *testing.T) {
+	store := memstore.New(10)
+	store.InsertLog(models.Log{Level: "DEBUG", Type: "API", Message: "m"})
+	store.InsertLog(models.Log{Level: "INFO", Type: "API", Message: "m"})
+
+	srv := newTestServer(store)
+	if rr := do(srv.SetupRoutes(), "GET", "/api/admin/retention", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("status without retention = %d, want 404", rr.Code)
+	}
+
+	policy := retention.Policy{MaxRows: 1}
+	srv.SetRetention(retention.NewPurger(store, policy))
+	h := srv.SetupRoutes()
+
+	rr := do(h, "POST", "/api/admin/retention/run?dry_run=true", "")
+	var run retention.Run
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &run) != nil || !run.DryRun || run.Deleted != 1 {
+		t.Fatalf("dry run status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if store.Len() != 2 {
+		t.Errorf("dry run deleted entries; %d left", store.Len())
+	}
+
+	if rr := do(h, "POST", "/api/admin/retention/run", ""); rr.Code != http.StatusOK || store.Len() != 1 {
+		t.Errorf("run status = %d, %d entries left (body: %s)", rr.Code, store.Len(), rr.Body.String())
+	}
+	if rr := do(h, "POST", "/api/admin/retention/run?dry_run=maybe", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid dry_run status = %d, want 400", rr.Code)
+	}
+
+	rr = do(h, "GET", "/api/admin/retention", "")
+	var st retention.Status
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &st) != nil {
+		t.Fatalf("status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if st.Policy.MaxRows != 1 || st.LastRun == nil || st.LastRun.Deleted != 1 || st.LastRun.DryRun {
+		t.Errorf("status = %+v", st)
+	}
+	if st.Policy.Interval != models.Duration(retention.DefaultInterval) {
+		t.Errorf("policy interval = %v, want the default", st.Policy.Interval)
+	}
+}
+
+func TestRetentionRequiresAdmin(t *testing.T) {
+	ms := &mockStore{}
+	srv, billing, admin := newTenantServer(t, ms)
+	srv.SetRetention(retention.NewPurger(memstore.New(1), retention.Policy{MaxRows: 1}))
+	h := srv.SetupRoutes()
+
+	if rr := doAs(h, billing, "GET", "/api/admin/retention", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want 403", rr.Code)
+	}
+	if rr := doAs(h, admin, "GET", "/api/admin/retention", ""); rr.Code != http.StatusOK {
+		t.Errorf("admin status = %d", rr.Code)
+	}
+}
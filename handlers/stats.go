@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Defaults for GET /api/logs/stats.
+const (
+	defaultStatsInterval = time.Minute
+	defaultStatsWindow   = time.Hour
+)
+
+// StatsResponse is the body of GET /api/logs/stats.
+type StatsResponse struct {
+	Interval string               `json:"interval"`
+	Since    time.Time            `json:"since"`
+	Until    time.Time            `json:"until"`
+	GroupBy  []string             `json:"group_by"`
+	Buckets  []models.StatsBucket `json:"buckets"`
+}
+
+// StatsHandler counts log entries per time interval.
+//
+// Query parameters: the filters of GetLogsHandler, interval (a duration
+// such as 1m or 1h, default 1m) and group_by (level and/or type). since
+// defaults to an hour before until, which defaults to now. Intervals without
+// entries are omitted.
+func (s *Server) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := scopeFilter(r, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if filter.Until.IsZero() {
+		filter.Until = time.Now()
+	}
+	if filter.Since.IsZero() {
+		filter.Since = filter.Until.Add(-defaultStatsWindow)
+	}
+
+	query := models.StatsQuery{Filter: filter, Interval: defaultStatsInterval, GroupBy: listParam(q, "group_by")}
+	if v := q.Get("interval"); v != "" {
+		if query.Interval, err = time.ParseDuration(v); err != nil {
+			http.Error(w, "invalid interval: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := query.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := s.store.Stats(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if buckets == nil {
+		buckets = []models.StatsBucket{}
+	}
+	groupBy := query.GroupBy
+	if groupBy == nil {
+		groupBy = []string{}
+	}
+	writeJSON(w, http.StatusOK, StatsResponse{
+		Interval: query.Interval.String(),
+		Since:    filter.Since.UTC(),
+		Until:    filter.Until.UTC(),
+		GroupBy:  groupBy,
+		Buckets:  buckets,
+	})
+}
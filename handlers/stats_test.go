@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestStatsHandler(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	ms := &mockStore{stats: []models.StatsBucket{{Start: start, Level: "ERROR", Count: 4}}}
+	h := newTestServer(ms).SetupRoutes()
+
+	rr := do(h, "GET", "/api/logs/stats?level=ERROR&interval=5m&group_by=level&since=2024-03-01T10:00:00Z&until=2024-03-01T16:00:00Z", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	var resp StatsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Interval != "5m0s" || len(resp.GroupBy) != 1 || len(resp.Buckets) != 1 || resp.Buckets[0].Count != 4 {
+		t.Errorf("response = %+v", resp)
+	}
+	q := ms.lastStats
+	if q.Interval != 5*time.Minute || !q.Grouped(models.GroupByLevel) || q.Filter.Levels[0] != "ERROR" || !q.Filter.Until.Equal(start.Add(6*time.Hour)) {
+		t.Errorf("store query = %+v", q)
+	}
+
+	ms.stats = nil
+	rr = do(h, "GET", "/api/logs/stats", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("default status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if q := ms.lastStats; q.Interval != time.Minute || q.Filter.Until.Sub(q.Filter.Since) != time.Hour {
+		t.Errorf("default query = %+v, want the last hour by minute", q)
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Buckets == nil || resp.GroupBy == nil {
+		t.Errorf("empty response = %s, want empty arrays", rr.Body.String())
+	}
+
+	for _, target := range []string{
+		"/api/logs/stats?interval=soon",
+		"/api/logs/stats?interval=500ms",
+		"/api/logs/stats?group_by=tenant",
+		"/api/logs/stats?interval=1s&since=-1d",
+		"/api/logs/stats?level=TRACE",
+	} {
+		if rr := do(h, "GET", target, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", target, rr.Code)
+		}
+	}
+}
+
+func TestStatsScopedToTenant(t *testing.T) {
+	ms := &mockStore{}
+	srv, billing, _ := newTenantServer(t, ms)
+	h := srv.SetupRoutes()
+
+	if rr := doAs(h, billing, "GET", "/api/logs/stats", ""); rr.Code != http.StatusOK || ms.lastStats.Filter.Tenant != "billing" {
+		t.Errorf("status = %d, tenant = %q", rr.Code, ms.lastStats.Filter.Tenant)
+	}
+	if rr := doAs(h, billing, "GET", "/api/logs/stats?tenant=search", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("other tenant status = %d, want 403", rr.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/mstgnz/golog/auth"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/tenant"
+)
+
+// SetQuotas limits how many entries each tenant may ingest per minute and
+// exposes the current usage under /api/admin/quotas.
+func (s *Server) SetQuotas(q *tenant.Quotas) {
+	s.quotas = q
+}
+
+// scopeFilter restricts filter to the tenant of the caller's key. A
+// tenant-scoped key may not ask for another tenant.
+func scopeFilter(r *http.Request, filter *models.LogFilter) error {
+	t := auth.TenantFromContext(r.Context())
+	if t == "" {
+		return nil
+	}
+	if filter.Tenant != "" && filter.Tenant != t {
+		return fmt.Errorf("API key is scoped to tenant %q", t)
+	}
+	filter.Tenant = t
+	return nil
+}
+
+// assignTenant stores entries under the tenant of the caller's key. Keys
+// without a tenant keep the tenant given in each entry.
+func assignTenant(r *http.Request, entries []models.Log) {
+	if t := auth.TenantFromContext(r.Context()); t != "" {
+		for i := range entries {
+			entries[i].Tenant = t
+		}
+	}
+}
+
+// allowIngest charges entries to their tenants' quotas, writing 429 with
+// Retry-After, or 413 for a request larger than a whole quota, when they do
+// not fit.
+func (s *Server) allowIngest(w http.ResponseWriter, entries []models.Log) bool {
+	if s.quotas == nil || len(entries) == 0 {
+		return true
+	}
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[e.Tenant]++
+	}
+	retryAfter, ok := s.quotas.Allow(counts)
+	if ok {
+		return true
+	}
+	if retryAfter == 0 {
+		http.Error(w, "request exceeds the tenant's ingestion quota per minute", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "tenant ingestion quota exceeded", http.StatusTooManyRequests)
+	return false
+}
+
+// denyTenantKeys rejects tenant-scoped keys on routes that expose data
+// across tenants, such as alert rules and their history.
+func denyTenantKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.TenantFromContext(r.Context()) != "" {
+			http.Error(w, "not available to tenant-scoped API keys", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// QuotaUsageHandler returns each tenant's ingestion in the current minute.
+func (s *Server) QuotaUsageHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.quotas.Usage())
+}
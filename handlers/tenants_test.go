@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/auth"
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/tenant"
+)
+
+// newTenantServer returns a server with a key confined to tenant "billing"
+// and an unscoped admin key.
+func newTenantServer(t *testing.T, ms *mockStore) (*Server, string, string) {
+	t.Helper()
+	a := auth.NewAuthenticator(auth.NewMemoryStore())
+	_, billing, err := a.CreateKey("billing", "billing", []string{auth.ScopeIngest, auth.ScopeRead})
+	if err != nil {
+		t.Fatalf("CreateKey() error: %v", err)
+	}
+	_, admin, err := a.CreateKey("admin", "", []string{auth.ScopeAdmin})
+	if err != nil {
+		t.Fatalf("CreateKey() error: %v", err)
+	}
+	engine := alerting.NewEngine(alerting.NewMemoryStore())
+	engine.Load()
+
+	srv := newTestServer(ms)
+	srv.SetAlerting(engine)
+	srv.SetAuth(a)
+	return srv, billing, admin
+}
+
+func TestTenantScopedKeys(t *testing.T) {
+	ms := &mockStore{insertID: 1}
+	srv, billing, admin := newTenantServer(t, ms)
+	h := srv.SetupRoutes()
+
+	if rr := doAs(h, billing, "POST", "/api/logs", `{"level":"INFO","type":"API","message":"charged","tenant":"search"}`); rr.Code != http.StatusOK {
+		t.Fatalf("add status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if ms.lastInsert.Tenant != "billing" {
+		t.Errorf("stored tenant = %q, want the key's tenant", ms.lastInsert.Tenant)
+	}
+	if rr := doAs(h, billing, "POST", "/api/logs/bulk", `{"level":"INFO","type":"API","message":"a"}`); rr.Code != http.StatusOK || ms.lastBulk[0].Tenant != "billing" {
+		t.Errorf("bulk status = %d, tenant = %q", rr.Code, ms.lastBulk[0].Tenant)
+	}
+	if rr := doAs(h, admin, "POST", "/api/logs", `{"level":"INFO","type":"API","message":"m","tenant":"search"}`); rr.Code != http.StatusOK || ms.lastInsert.Tenant != "search" {
+		t.Errorf("unscoped key: status = %d, tenant = %q, want the entry's tenant", rr.Code, ms.lastInsert.Tenant)
+	}
+
+	if rr := doAs(h, billing, "GET", "/api/logs", ""); rr.Code != http.StatusOK || ms.lastFilter.Tenant != "billing" {
+		t.Errorf("list status = %d, filter tenant = %q", rr.Code, ms.lastFilter.Tenant)
+	}
+	if rr := doAs(h, billing, "GET", "/api/logs?tenant=search", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("other tenant status = %d, want 403", rr.Code)
+	}
+	if rr := doAs(h, admin, "GET", "/api/logs?tenant=search", ""); rr.Code != http.StatusOK || ms.lastFilter.Tenant != "search" {
+		t.Errorf("admin list status = %d, filter tenant = %q", rr.Code, ms.lastFilter.Tenant)
+	}
+	if rr := doAs(h, admin, "GET", "/api/logs?tenant=Search!", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid tenant status = %d, want 400", rr.Code)
+	}
+	if rr := doAs(h, billing, "GET", "/api/alerts", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("alerts status = %d, want 403 for a tenant-scoped key", rr.Code)
+	}
+
+	rr := doAs(h, admin, "POST", "/api/admin/keys", `{"name":"search","scopes":["read"],"tenant":"search"}`)
+	if rr.Code != http.StatusCreated || !strings.Contains(rr.Body.String(), `"tenant":"search"`) {
+		t.Errorf("create tenant key status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	if rr := doAs(h, admin, "POST", "/api/admin/keys", `{"name":"x","scopes":["admin"],"tenant":"search"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("tenant admin key status = %d, want 400", rr.Code)
+	}
+}
+
+func TestStreamScopedToTenant(t *testing.T) {
+	listenCh := make(chan models.Log, 2)
+	ms := &mockStore{
+		listenFn: func(ctx context.Context, ch chan<- models.Log) error {
+			go func() {
+				for {
+					select {
+					case l := <-listenCh:
+						ch <- l
+					case <-ctx.Done():
+						close(ch)
+						return
+					}
+				}
+			}()
+			return nil
+		},
+	}
+	srv, billing, _ := newTenantServer(t, ms)
+	h := srv.SetupRoutes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := srv.StartLogListener(ctx); err != nil {
+		t.Fatalf("StartLogListener: %v", err)
+	}
+
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer reqCancel()
+	req := httptest.NewRequest("GET", "/api/logs/stream", nil).WithContext(reqCtx)
+	req.Header.Set("Authorization", "Bearer "+billing)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(rr, req)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	listenCh <- models.Log{ID: 1, Level: "INFO", Type: "API", Message: "other-tenant", Tenant: "search"}
+	listenCh <- models.Log{ID: 2, Level: "INFO", Type: "API", Message: "own-tenant", Tenant: "billing"}
+	<-done
+
+	body := rr.Body.String()
+	if strings.Contains(body, "other-tenant") || !strings.Contains(body, "own-tenant") {
+		t.Errorf("stream body = %q, want only the key's tenant", body)
+	}
+}
+
+func TestTenantQuotas(t *testing.T) {
+	ms := &mockStore{insertID: 1}
+	srv, billing, admin := newTenantServer(t, ms)
+	srv.SetQuotas(tenant.NewQuotas(0, map[string]int{"billing": 2}))
+	h := srv.SetupRoutes()
+
+	entry := `{"level":"INFO","type":"API","message":"m"}`
+	if rr := doAs(h, billing, "POST", "/api/logs/bulk", entry+"\n"+entry); rr.Code != http.StatusOK {
+		t.Fatalf("bulk within quota status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+	rr := doAs(h, billing, "POST", "/api/logs", entry)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("over quota status = %d, Retry-After = %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if rr := doAs(h, billing, "POST", "/api/logs/bulk", entry+"\n"+entry+"\n"+entry); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("batch larger than quota status = %d, want 413", rr.Code)
+	}
+	if rr := doAs(h, admin, "POST", "/api/logs", entry); rr.Code != http.StatusOK {
+		t.Errorf("unlimited default tenant status = %d", rr.Code)
+	}
+
+	rr = doAs(h, admin, "GET", "/api/admin/quotas", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `{"tenant":"billing","used":2,"limit":2}`) {
+		t.Errorf("quota usage status = %d (body: %s)", rr.Code, rr.Body.String())
+	}
+}
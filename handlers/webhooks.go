@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mstgnz/golog/webhook"
+)
+
+// SetWebhooks enables the dead-letter API and sends every streamed log entry
+// to the notifier's log destinations.
+func (s *Server) SetWebhooks(n *webhook.Notifier) {
+	s.webhooks = n
+	s.AddObserver(n.Observe)
+}
+
+// ListDeadLettersHandler returns webhook deliveries that failed after all
+// retries, newest first.
+//
+// Query parameters: limit (default 100, max 500).
+func (s *Server) ListDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	letters, err := s.webhooks.DeadLetters(limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if letters == nil {
+		letters = []webhook.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mstgnz/golog/webhook"
+)
+
+func TestListDeadLetters(t *testing.T) {
+	dl := webhook.NewMemoryDeadLetters()
+	n, err := webhook.NewNotifier([]webhook.Destination{
+		{Name: "oncall", URL: "http://127.0.0.1:1", Alerts: true, QueueSize: 1},
+	}, dl)
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+	srv := newTestServer(&mockStore{})
+	srv.SetWebhooks(n)
+	h := srv.SetupRoutes()
+
+	dl.InsertDeadLetter(webhook.DeadLetter{Destination: "oncall", Event: webhook.EventAlert, Error: "queue full"})
+
+	rr := do(h, "GET", "/api/webhooks/dead-letters?limit=5", "")
+	var letters []webhook.DeadLetter
+	json.Unmarshal(rr.Body.Bytes(), &letters)
+	if rr.Code != http.StatusOK || len(letters) != 1 || letters[0].Destination != "oncall" {
+		t.Errorf("dead letters = %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do(h, "GET", "/api/webhooks/dead-letters?limit=0", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 status = %d, want 400", rr.Code)
+	}
+}
+
+func TestWebhookRoutesDisabled(t *testing.T) {
+	h := newTestServer(&mockStore{}).SetupRoutes()
+	if rr := do(h, "GET", "/api/webhooks/dead-letters", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without webhooks", rr.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package memstore
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// ExportLogs calls fn with every entry matching the filter, oldest first by
+// its time field, ignoring pagination. The matching entries are copied
+// before fn is called, so a slow consumer does not block inserts. An error
+// from fn or ctx stops the export and is returned.
+func (s *Store) ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error {
+	s.mu.RLock()
+	var logs []models.Log
+	for i := s.size - 1; i >= 0; i-- {
+		if l := s.at(i); filter.Matches(l) {
+			l.Attributes = maps.Clone(l.Attributes)
+			logs = append(logs, l)
+		}
+	}
+	s.mu.RUnlock()
+	slices.SortFunc(logs, newestFirst(filter))
+	slices.Reverse(logs)
+
+	for _, l := range logs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package memstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestExportLogs(t *testing.T) {
+	s := New(3)
+	insert(t, s,
+		models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "evicted"},
+		models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "a"},
+		models.Log{Level: models.LevelInfo, Type: models.TypeAPI, Message: "b"},
+		models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "c"},
+	)
+
+	var got []models.Log
+	err := s.ExportLogs(context.Background(), models.LogFilter{Level: models.LevelError, Limit: 1}, func(l models.Log) error {
+		got = append(got, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportLogs() error: %v", err)
+	}
+	if len(got) != 2 || got[0].Message != "a" || got[1].Message != "c" {
+		t.Errorf("ExportLogs() = %+v, want the retained errors oldest first", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.ExportLogs(ctx, models.LogFilter{}, func(models.Log) error { return nil }); err != context.Canceled {
+		t.Errorf("ExportLogs() with cancelled context = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package memstore
+
+import (
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/retention"
+)
+
+// CountLogs counts the entries matching c.
+func (s *Store) CountLogs(c retention.Condition) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var n int64
+	for i := 0; i < s.size; i++ {
+		if c.Matches(s.at(i)) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteLogs deletes up to limit of the oldest entries matching c and
+// compacts the ring.
+func (s *Store) DeleteLogs(c retention.Condition, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]int, 0, s.size)
+	var n int64
+	for i := s.size - 1; i >= 0; i-- { // oldest first
+		if n < int64(limit) && c.Matches(s.at(i)) {
+			n++
+			continue
+		}
+		kept = append(kept, i)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	compacted := make([]models.Log, len(s.ring))
+	for j, i := range kept {
+		compacted[j] = s.at(i)
+	}
+	s.ring = compacted
+	s.size = len(kept)
+	s.next = s.size % len(s.ring)
+	return n, nil
+}
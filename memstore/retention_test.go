@@ -0,0 +1,40 @@
+package memstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/retention"
+)
+
+func TestRetention(t *testing.T) {
+	s := New(4)
+	for i := 0; i < 6; i++ {
+		level := models.LevelInfo
+		if i%2 == 0 {
+			level = models.LevelDebug
+		}
+		insert(t, s, models.Log{Level: level, Type: models.TypeSystem, Message: fmt.Sprintf("msg %d", i)})
+	}
+
+	debug := retention.Condition{Levels: []string{models.LevelDebug}}
+	if n, _ := s.CountLogs(debug); n != 2 {
+		t.Errorf("CountLogs() = %d, want 2", n)
+	}
+	if n, _ := s.DeleteLogs(debug, 1); n != 1 {
+		t.Errorf("DeleteLogs() = %d, want 1", n)
+	}
+	logs, _ := s.GetLogs(models.LogFilter{})
+	if got := ids(logs); fmt.Sprint(got) != "[6 5 4]" {
+		t.Errorf("GetLogs() ids = %v, want the oldest DEBUG entry deleted", got)
+	}
+
+	// The freed slot is reused before the oldest entry is overwritten.
+	insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "msg 6"})
+	insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "msg 7"})
+	logs, _ = s.GetLogs(models.LogFilter{})
+	if got := ids(logs); fmt.Sprint(got) != "[8 7 6 5]" {
+		t.Errorf("GetLogs() ids = %v, want [8 7 6 5]", got)
+	}
+}
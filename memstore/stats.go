@@ -0,0 +1,24 @@
+package memstore
+
+import "github.com/mstgnz/golog/models"
+
+// Stats counts the entries matching the query's filter per interval. Empty
+// intervals are omitted.
+func (s *Store) Stats(q models.StatsQuery) ([]models.StatsBucket, error) {
+	s.mu.RLock()
+	counts := make(map[models.StatsBucket]int64)
+	for i := 0; i < s.size; i++ {
+		if l := s.at(i); q.Filter.Matches(l) {
+			counts[q.Key(l)]++
+		}
+	}
+	s.mu.RUnlock()
+
+	buckets := make([]models.StatsBucket, 0, len(counts))
+	for b, n := range counts {
+		b.Count = n
+		buckets = append(buckets, b)
+	}
+	models.SortStats(buckets)
+	return buckets, nil
+}
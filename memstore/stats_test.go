@@ -0,0 +1,44 @@
+package memstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestStats(t *testing.T) {
+	s := New(10)
+	insert(t, s,
+		models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "a"},
+		models.Log{Level: models.LevelError, Type: models.TypeDatabase, Message: "b"},
+		models.Log{Level: models.LevelInfo, Type: models.TypeAPI, Message: "c"},
+	)
+	now := time.Now()
+	q := models.StatsQuery{
+		Filter:   models.LogFilter{Since: now.Add(-time.Hour), Until: now.Add(time.Hour), MinLevel: models.LevelWarning},
+		Interval: 24 * time.Hour,
+		GroupBy:  []string{models.GroupByType},
+	}
+
+	buckets, err := s.Stats(q)
+	if err != nil {
+		t.Fatalf("Stats() error: %v", err)
+	}
+	// Entries inserted around midnight UTC may fall into two days.
+	var api, database int64
+	for _, b := range buckets {
+		if !b.Start.Equal(q.BucketStart(b.Start)) || b.Level != "" {
+			t.Errorf("bucket %+v is not a grouped daily bucket", b)
+		}
+		switch b.Type {
+		case models.TypeAPI:
+			api += b.Count
+		case models.TypeDatabase:
+			database += b.Count
+		}
+	}
+	if api != 1 || database != 1 {
+		t.Errorf("Stats() = %+v, want one API and one DATABASE error", buckets)
+	}
+}
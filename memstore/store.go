@@ -0,0 +1,209 @@
+// Package memstore provides an in-memory log store for running golog without
+// PostgreSQL, e.g. during development and in tests.
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	// DefaultCapacity is the number of entries kept when New is given a
+	// non-positive capacity.
+	DefaultCapacity = 10000
+
+	subscriberBuffer = 256
+)
+
+// Store keeps the most recent entries in a fixed-size ring buffer. Once the
+// buffer is full the oldest entry is overwritten.
+type Store struct {
+	mu     sync.RWMutex
+	ring   []models.Log
+	next   int
+	size   int
+	lastID int
+
+	subsMu sync.Mutex
+	subs   map[chan models.Log]struct{}
+}
+
+// New creates a Store holding up to capacity entries.
+func New(capacity int) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Store{
+		ring: make([]models.Log, capacity),
+		subs: make(map[chan models.Log]struct{}),
+	}
+}
+
+// GetLogs returns entries matching the filter, newest first by the filter's
+// time field, with the same pagination semantics as database.Store.
+func (s *Store) GetLogs(filter models.LogFilter) ([]models.Log, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+
+	// Entries are held in the order they were received, which is not the
+	// order of client timestamps, so matches are sorted before paging.
+	var logs []models.Log
+	for i := 0; i < s.size; i++ {
+		l := s.at(i)
+		if c := filter.Cursor; c != nil {
+			t := filter.TimeOf(l)
+			older := c.Before(t, l.ID)
+			newer := !older && !(t.Equal(c.Timestamp) && l.ID == c.ID)
+			if (backward && !newer) || (!backward && !older) {
+				continue
+			}
+		}
+		if filter.Matches(l) {
+			logs = append(logs, l)
+		}
+	}
+	slices.SortFunc(logs, newestFirst(filter))
+	if backward {
+		slices.Reverse(logs)
+	}
+
+	logs = logs[min(filter.Offset, len(logs)):]
+	logs = slices.Clone(logs[:min(filter.PageSize(), len(logs))])
+	for i := range logs {
+		logs[i].Attributes = maps.Clone(logs[i].Attributes)
+		logs[i].Snippet = filter.Highlight(logs[i].Message, "<mark>", "</mark>")
+	}
+
+	if backward {
+		slices.Reverse(logs)
+	}
+	return logs, nil
+}
+
+// newestFirst orders entries by the filter's time field and then id, newest
+// first.
+func newestFirst(filter models.LogFilter) func(a, b models.Log) int {
+	return func(a, b models.Log) int {
+		if c := filter.TimeOf(b).Compare(filter.TimeOf(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	}
+}
+
+// at returns the i-th newest entry.
+func (s *Store) at(i int) models.Log {
+	idx := (s.next - 1 - i + len(s.ring)) % len(s.ring)
+	return s.ring[idx]
+}
+
+// InsertLog stores the entry, assigning its ID, and notifies listeners.
+// Entries without a receive time are received now, and entries without a
+// timestamp get the receive time.
+func (s *Store) InsertLog(logEntry models.Log) (int, error) {
+	s.mu.Lock()
+	logEntry = s.add(logEntry)
+	s.mu.Unlock()
+
+	s.publish(logEntry)
+	return logEntry.ID, nil
+}
+
+// InsertLogs stores all entries and returns their IDs in input order.
+func (s *Store) InsertLogs(entries []models.Log) ([]int, error) {
+	stored := make([]models.Log, len(entries))
+	ids := make([]int, len(entries))
+
+	s.mu.Lock()
+	for i, e := range entries {
+		stored[i] = s.add(e)
+		ids[i] = stored[i].ID
+	}
+	s.mu.Unlock()
+
+	for _, l := range stored {
+		s.publish(l)
+	}
+	return ids, nil
+}
+
+// add writes the entry into the ring. The caller must hold s.mu.
+func (s *Store) add(logEntry models.Log) models.Log {
+	s.lastID++
+	logEntry.ID = s.lastID
+	if logEntry.ReceivedAt.IsZero() {
+		logEntry.ReceivedAt = time.Now().UTC()
+	}
+	if logEntry.Timestamp.IsZero() {
+		logEntry.Timestamp = logEntry.ReceivedAt
+	}
+	logEntry.Attributes = maps.Clone(logEntry.Attributes)
+	logEntry.Snippet = ""
+
+	s.ring[s.next] = logEntry
+	s.next = (s.next + 1) % len(s.ring)
+	if s.size < len(s.ring) {
+		s.size++
+	}
+	return logEntry
+}
+
+func (s *Store) publish(logEntry models.Log) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	for sub := range s.subs {
+		select {
+		case sub <- logEntry:
+		default:
+			log.Printf("memstore: dropping log %d for slow listener", logEntry.ID)
+		}
+	}
+}
+
+// ListenForLogs forwards every newly inserted entry to logChan. The goroutine
+// stops and closes logChan when ctx is cancelled.
+func (s *Store) ListenForLogs(ctx context.Context, logChan chan<- models.Log) error {
+	sub := make(chan models.Log, subscriberBuffer)
+	s.subsMu.Lock()
+	s.subs[sub] = struct{}{}
+	s.subsMu.Unlock()
+
+	go func() {
+		defer close(logChan)
+		defer func() {
+			s.subsMu.Lock()
+			delete(s.subs, sub)
+			s.subsMu.Unlock()
+		}()
+		for {
+			select {
+			case l := <-sub:
+				select {
+				case logChan <- l:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Len returns the number of entries currently held.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
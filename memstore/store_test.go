@@ -0,0 +1,217 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func insert(t *testing.T, s *Store, entries ...models.Log) {
+	t.Helper()
+	for _, e := range entries {
+		if _, err := s.InsertLog(e); err != nil {
+			t.Fatalf("InsertLog() error: %v", err)
+		}
+	}
+}
+
+func ids(logs []models.Log) []int {
+	out := make([]int, len(logs))
+	for i, l := range logs {
+		out[i] = l.ID
+	}
+	return out
+}
+
+func TestInsertAndGetLogs(t *testing.T) {
+	s := New(10)
+	insert(t, s,
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "System started"},
+		models.Log{Level: models.LevelError, Type: models.TypeDatabase, Message: "Connection timeout", Attributes: map[string]any{"user_id": 42}},
+		models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "Request failed", Tenant: "billing"},
+	)
+
+	tests := []struct {
+		name   string
+		filter models.LogFilter
+		want   []int
+	}{
+		{"all newest first", models.LogFilter{}, []int{3, 2, 1}},
+		{"level", models.LogFilter{Level: models.LevelError}, []int{3, 2}},
+		{"level and type", models.LogFilter{Level: models.LevelError, Type: models.TypeDatabase}, []int{2}},
+		{"attribute", models.LogFilter{Attributes: map[string]string{"user_id": "42"}}, []int{2}},
+		{"search", models.LogFilter{Query: "timeout"}, []int{2}},
+		{"tenant", models.LogFilter{Tenant: "billing"}, []int{3}},
+		{"limit", models.LogFilter{Limit: 2}, []int{3, 2}},
+		{"offset", models.LogFilter{Limit: 2, Offset: 2}, []int{1}},
+		{"since in the future", models.LogFilter{Since: time.Now().Add(time.Hour)}, []int{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs, err := s.GetLogs(tc.filter)
+			if err != nil {
+				t.Fatalf("GetLogs() error: %v", err)
+			}
+			if got := ids(logs); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("GetLogs() ids = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	s := New(10)
+	insert(t, s, models.Log{Level: models.LevelError, Type: models.TypeDatabase, Message: "Connection timeout"})
+
+	logs, err := s.GetLogs(models.LogFilter{Query: "timeout"})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Snippet != "Connection <mark>timeout</mark>" {
+		t.Errorf("GetLogs() = %+v, want highlighted snippet", logs)
+	}
+}
+
+func TestRingOverwritesOldest(t *testing.T) {
+	s := New(3)
+	for i := 0; i < 5; i++ {
+		insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: fmt.Sprintf("msg %d", i)})
+	}
+
+	if s.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", s.Len())
+	}
+	logs, _ := s.GetLogs(models.LogFilter{})
+	if got := ids(logs); fmt.Sprint(got) != "[5 4 3]" {
+		t.Errorf("GetLogs() ids = %v, want [5 4 3]", got)
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	s := New(10)
+	for i := 0; i < 5; i++ {
+		insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "m"})
+	}
+
+	first, _ := s.GetLogs(models.LogFilter{Limit: 2})
+	if got := ids(first); fmt.Sprint(got) != "[5 4]" {
+		t.Fatalf("first page = %v, want [5 4]", got)
+	}
+
+	next := models.LogFilter{}.CursorAfter(first[len(first)-1], false)
+	second, _ := s.GetLogs(models.LogFilter{Limit: 2, Cursor: &next})
+	if got := ids(second); fmt.Sprint(got) != "[3 2]" {
+		t.Fatalf("second page = %v, want [3 2]", got)
+	}
+
+	prev := models.LogFilter{}.CursorAfter(second[0], true)
+	back, _ := s.GetLogs(models.LogFilter{Limit: 2, Cursor: &prev})
+	if got := ids(back); fmt.Sprint(got) != "[5 4]" {
+		t.Errorf("prev page = %v, want [5 4]", got)
+	}
+}
+
+func TestClientTimestamps(t *testing.T) {
+	s := New(10)
+	base := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	insert(t, s,
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "a", Timestamp: base.Add(time.Minute)},
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "b", Timestamp: base},
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "c", Timestamp: base.Add(2 * time.Minute)},
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "d"},
+	)
+
+	byEvent, _ := s.GetLogs(models.LogFilter{Limit: 2, Offset: 1})
+	if got := ids(byEvent); fmt.Sprint(got) != "[3 1]" {
+		t.Fatalf("by timestamp = %v, want [3 1]", got)
+	}
+	if l := byEvent[0]; !l.Timestamp.Equal(base.Add(2*time.Minute)) || !l.ReceivedAt.After(l.Timestamp) {
+		t.Errorf("entry 3 = %+v, want the client timestamp kept", byEvent[0])
+	}
+
+	next := models.LogFilter{}.CursorAfter(byEvent[1], false)
+	rest, _ := s.GetLogs(models.LogFilter{Limit: 2, Cursor: &next})
+	if got := ids(rest); fmt.Sprint(got) != "[2]" {
+		t.Errorf("next page = %v, want [2]", got)
+	}
+	prev := models.LogFilter{}.CursorAfter(rest[0], true)
+	back, _ := s.GetLogs(models.LogFilter{Limit: 2, Cursor: &prev})
+	if got := ids(back); fmt.Sprint(got) != "[3 1]" {
+		t.Errorf("prev page = %v, want [3 1]", got)
+	}
+
+	byReceipt, _ := s.GetLogs(models.LogFilter{TimeField: models.TimeFieldReceivedAt, Since: base, Until: base.Add(time.Hour)})
+	if got := ids(byReceipt); len(got) != 0 {
+		t.Errorf("received in the client's time range = %v, want none", got)
+	}
+	byReceipt, _ = s.GetLogs(models.LogFilter{TimeField: models.TimeFieldReceivedAt})
+	if got := ids(byReceipt); fmt.Sprint(got) != "[4 3 2 1]" {
+		t.Errorf("by received_at = %v, want [4 3 2 1]", got)
+	}
+}
+
+func TestInsertCopiesAttributes(t *testing.T) {
+	s := New(10)
+	attrs := map[string]any{"user_id": 1}
+	insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeUser, Message: "m", Attributes: attrs})
+	attrs["user_id"] = 2
+
+	logs, _ := s.GetLogs(models.LogFilter{})
+	if logs[0].Attributes["user_id"] != 1 {
+		t.Errorf("stored attributes changed with caller's map: %v", logs[0].Attributes)
+	}
+}
+
+func TestInsertLogs(t *testing.T) {
+	s := New(10)
+	insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "first"})
+
+	got, err := s.InsertLogs([]models.Log{
+		{Level: models.LevelInfo, Type: models.TypeSystem, Message: "second"},
+		{Level: models.LevelError, Type: models.TypeAPI, Message: "third"},
+	})
+	if err != nil {
+		t.Fatalf("InsertLogs() error: %v", err)
+	}
+	if fmt.Sprint(got) != "[2 3]" {
+		t.Errorf("InsertLogs() ids = %v, want [2 3]", got)
+	}
+	logs, _ := s.GetLogs(models.LogFilter{})
+	if fmt.Sprint(ids(logs)) != "[3 2 1]" {
+		t.Errorf("GetLogs() ids = %v, want [3 2 1]", ids(logs))
+	}
+}
+
+func TestListenForLogs(t *testing.T) {
+	s := New(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan models.Log)
+	if err := s.ListenForLogs(ctx, ch); err != nil {
+		t.Fatalf("ListenForLogs() error: %v", err)
+	}
+
+	insert(t, s, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "live"})
+
+	select {
+	case l := <-ch:
+		if l.Message != "live" || l.ID != 1 {
+			t.Errorf("received %+v, want live entry with id 1", l)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for log")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
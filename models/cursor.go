@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Cursor marks a position in the (time, id) ordering used for keyset
+// pagination, where the time is the one selected by LogFilter.TimeField. It
+// is exchanged with clients as an opaque string.
+type Cursor struct {
+	Timestamp time.Time
+	ID        int
+	// Backward pages towards newer entries instead of older ones.
+	Backward bool
+}
+
+type cursorPayload struct {
+	Timestamp time.Time `json:"t"`
+	ID        int       `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// MarshalText encodes the cursor as URL-safe base64.
+func (c Cursor) MarshalText() ([]byte, error) {
+	data, err := json.Marshal(cursorPayload{Timestamp: c.Timestamp, ID: c.ID, Backward: c.Backward})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, base64.RawURLEncoding.EncodedLen(len(data)))
+	base64.RawURLEncoding.Encode(out, data)
+	return out, nil
+}
+
+// UnmarshalText decodes a cursor produced by MarshalText.
+func (c *Cursor) UnmarshalText(text []byte) error {
+	data := make([]byte, base64.RawURLEncoding.DecodedLen(len(text)))
+	n, err := base64.RawURLEncoding.Decode(data, text)
+	if err != nil {
+		return errors.New("invalid cursor")
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(data[:n], &p); err != nil || p.Timestamp.IsZero() {
+		return errors.New("invalid cursor")
+	}
+	*c = Cursor{Timestamp: p.Timestamp, ID: p.ID, Backward: p.Backward}
+	return nil
+}
+
+// String returns the encoded cursor.
+func (c Cursor) String() string {
+	text, err := c.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// Before reports whether the position (t, id) precedes the cursor.
+func (c Cursor) Before(t time.Time, id int) bool {
+	if t.Equal(c.Timestamp) {
+		return id < c.ID
+	}
+	return t.Before(c.Timestamp)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Timestamp: time.Date(2024, 1, 15, 10, 30, 0, 123456000, time.UTC), ID: 42, Backward: true}
+
+	var decoded Cursor
+	if err := decoded.UnmarshalText([]byte(c.String())); err != nil {
+		t.Fatalf("UnmarshalText() error: %v", err)
+	}
+	if !decoded.Timestamp.Equal(c.Timestamp) || decoded.ID != c.ID || decoded.Backward != c.Backward {
+		t.Errorf("decoded cursor = %+v, want %+v", decoded, c)
+	}
+}
+
+func TestCursorInvalid(t *testing.T) {
+	for _, text := range []string{"", "not base64!", "e30", "bm90IGpzb24"} {
+		var c Cursor
+		if err := c.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) expected error", text)
+		}
+	}
+}
+
+func TestCursorBefore(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	c := Cursor{Timestamp: ts, ID: 10}
+
+	tests := []struct {
+		name string
+		log  Log
+		want bool
+	}{
+		{"earlier timestamp", Log{Timestamp: ts.Add(-time.Second), ID: 99}, true},
+		{"later timestamp", Log{Timestamp: ts.Add(time.Second), ID: 1}, false},
+		{"same timestamp lower id", Log{Timestamp: ts, ID: 9}, true},
+		{"same timestamp same id", Log{Timestamp: ts, ID: 10}, false},
+		{"same timestamp higher id", Log{Timestamp: ts, ID: 11}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := c.Before(tc.log.Timestamp, tc.log.ID); got != tc.want {
+				t.Errorf("Before() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, DefaultLimit},
+		{-1, DefaultLimit},
+		{10, 10},
+		{MaxLimit + 1, MaxLimit},
+	}
+	for _, tc := range tests {
+		if got := (LogFilter{Limit: tc.limit}).PageSize(); got != tc.want {
+			t.Errorf("PageSize() with Limit=%d = %d, want %d", tc.limit, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationJSON(t *testing.T) {
+	var v struct {
+		Window Duration `json:"window"`
+	}
+	if err := json.Unmarshal([]byte(`{"window":"90s"}`), &v); err != nil || time.Duration(v.Window) != 90*time.Second {
+		t.Errorf("window string = %v, %v", time.Duration(v.Window), err)
+	}
+	if err := json.Unmarshal([]byte(`{"window":300}`), &v); err != nil || time.Duration(v.Window) != 5*time.Minute {
+		t.Errorf("window seconds = %v, %v", time.Duration(v.Window), err)
+	}
+	if err := json.Unmarshal([]byte(`{"window":"2d"}`), &v); err != nil || time.Duration(v.Window) != 48*time.Hour {
+		t.Errorf("window days = %v, %v", time.Duration(v.Window), err)
+	}
+	if err := json.Unmarshal([]byte(`{"window":"soon"}`), &v); err == nil {
+		t.Error("invalid window should fail")
+	}
+
+	data, _ := json.Marshal(Duration(5 * time.Minute))
+	if string(data) != `"5m0s"` {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
@@ -1,7 +1,13 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,25 +23,43 @@ const (
 	TypeUser     = "USER"
 	TypeAPI      = "API"
 
+	DefaultLimit = 100
+	MaxLimit     = 500
+
 	MaxMessageLength = 10000
-)
+	MaxAttributes    = 64
+	MaxAttributeKey  = 128
 
-var (
-	ValidLevels = map[string]bool{
-		LevelInfo: true, LevelWarning: true, LevelError: true, LevelDebug: true,
-	}
-	ValidTypes = map[string]bool{
-		TypeSystem: true, TypeAuth: true, TypeDatabase: true, TypeUser: true, TypeAPI: true,
-	}
+	MaxTenantLength = 64
 )
 
+// tenantPattern restricts tenant names to lower-case identifiers so they
+// are safe in URLs, config lists and notification payloads.
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 // Log represents a log entry
 type Log struct {
-	ID        int       `json:"id"`
-	Timestamp time.Time `json:"timestamp"`
-	Level     string    `json:"level"`
-	Type      string    `json:"type"`
-	Message   string    `json:"message"`
+	ID int `json:"id"`
+
+	// Timestamp is when the event happened, as sent by the client or, when
+	// it sent none, the receive time. ReceivedAt is set by the store.
+	Timestamp  time.Time `json:"timestamp"`
+	ReceivedAt time.Time `json:"received_at"`
+
+	Level   string `json:"level"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+
+	// Tenant is the project or team the entry belongs to. Entries ingested
+	// with a tenant-scoped API key always carry the key's tenant; an empty
+	// tenant is the default, shared one.
+	Tenant string `json:"tenant,omitempty"`
+
+	// Attributes holds structured context such as request or user IDs.
+	Attributes map[string]any `json:"attributes,omitempty"`
+
+	// Snippet is the highlighted part of Message that matched a search query.
+	Snippet string `json:"snippet,omitempty"`
 }
 
 // Validate checks that the log entry has valid field values.
@@ -46,11 +70,32 @@ func (l *Log) Validate() error {
 	if len(l.Message) > MaxMessageLength {
 		return errors.New("message exceeds maximum length")
 	}
-	if !ValidLevels[l.Level] {
-		return errors.New("invalid level: must be one of INFO, WARNING, ERROR, DEBUG")
+	reg := CurrentRegistry()
+	if !reg.ValidLevel(l.Level) {
+		return fmt.Errorf("invalid level: must be one of %s", strings.Join(reg.LevelNames(), ", "))
+	}
+	if !reg.ValidType(l.Type) {
+		return fmt.Errorf("invalid type: must be one of %s", strings.Join(reg.Types(), ", "))
+	}
+	if len(l.Attributes) > MaxAttributes {
+		return fmt.Errorf("too many attributes: maximum is %d", MaxAttributes)
 	}
-	if !ValidTypes[l.Type] {
-		return errors.New("invalid type: must be one of SYSTEM, AUTH, DATABASE, USER, API")
+	for k := range l.Attributes {
+		if k == "" || len(k) > MaxAttributeKey {
+			return fmt.Errorf("invalid attribute key %q", k)
+		}
+	}
+	return ValidateTenant(l.Tenant)
+}
+
+// ValidateTenant checks a tenant name. The empty name is valid and denotes
+// the default tenant.
+func ValidateTenant(tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+	if len(tenant) > MaxTenantLength || !tenantPattern.MatchString(tenant) {
+		return fmt.Errorf("invalid tenant %q: use up to %d lower-case letters, digits, '-' or '_'", tenant, MaxTenantLength)
 	}
 	return nil
 }
@@ -61,4 +106,168 @@ type LogFilter struct {
 	Type   string `json:"type"`
 	Limit  int    `json:"limit,omitempty"`
 	Offset int    `json:"offset,omitempty"`
+
+	// Cursor continues keyset pagination from a previous page and replaces
+	// Offset.
+	Cursor *Cursor `json:"cursor,omitempty"`
+
+	// Since (inclusive) and Until (exclusive) bound the entry time selected
+	// by TimeField, which also orders results. Zero values leave the range
+	// open.
+	Since     time.Time `json:"since,omitempty"`
+	Until     time.Time `json:"until,omitempty"`
+	TimeField string    `json:"time_field,omitempty"`
+
+	// Attributes matches entries whose attribute values equal the given
+	// strings, e.g. {"user_id": "42"} for the query attr.user_id=42.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Query searches the message text using SearchMode (fts, substring or
+	// regex; fts when empty).
+	Query      string `json:"q,omitempty"`
+	SearchMode string `json:"search_mode,omitempty"`
+
+	// Levels and Types match any of several values and are combined with
+	// Level and Type. MinLevel keeps only levels at least as severe as the
+	// given one according to the registry.
+	Levels   []string `json:"levels,omitempty"`
+	Types    []string `json:"types,omitempty"`
+	MinLevel string   `json:"min_level,omitempty"`
+
+	// Tenant restricts results to one tenant. It is set from the caller's
+	// API key rather than the request whenever the key is tenant-scoped.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// LevelSet returns the levels an entry may have, or nil when any level
+// matches. A non-nil empty slice matches nothing, e.g. level=DEBUG with
+// min_level=ERROR.
+func (f LogFilter) LevelSet() []string {
+	set := valueSet(f.Level, f.Levels)
+	if f.MinLevel == "" {
+		return set
+	}
+
+	reg := CurrentRegistry()
+	min, ok := reg.Severity(f.MinLevel)
+	allowed := []string{}
+	for _, l := range reg.Levels() {
+		if ok && l.Severity >= min && (set == nil || slices.Contains(set, l.Name)) {
+			allowed = append(allowed, l.Name)
+		}
+	}
+	return allowed
+}
+
+// TypeSet returns the types an entry may have, or nil when any type matches.
+func (f LogFilter) TypeSet() []string {
+	return valueSet(f.Type, f.Types)
+}
+
+func valueSet(single string, multi []string) []string {
+	if single == "" && len(multi) == 0 {
+		return nil
+	}
+	set := make([]string, 0, len(multi)+1)
+	if single != "" {
+		set = append(set, single)
+	}
+	for _, v := range multi {
+		if !slices.Contains(set, v) {
+			set = append(set, v)
+		}
+	}
+	return set
+}
+
+// PageSize returns Limit bounded to (0, MaxLimit], using DefaultLimit when
+// unset.
+func (f LogFilter) PageSize() int {
+	if f.Limit <= 0 {
+		return DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		return MaxLimit
+	}
+	return f.Limit
+}
+
+// Matches reports whether the log entry satisfies the filter. Pagination
+// fields are ignored.
+func (f LogFilter) Matches(l Log) bool {
+	if f.Tenant != "" && l.Tenant != f.Tenant {
+		return false
+	}
+	if levels := f.LevelSet(); levels != nil && !slices.Contains(levels, l.Level) {
+		return false
+	}
+	if types := f.TypeSet(); types != nil && !slices.Contains(types, l.Type) {
+		return false
+	}
+	if t := f.TimeOf(l); !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if t := f.TimeOf(l); !f.Until.IsZero() && !t.Before(f.Until) {
+		return false
+	}
+	for k, want := range f.Attributes {
+		v, ok := l.Attributes[k]
+		if !ok || v == nil || AttributeString(v) != want {
+			return false
+		}
+	}
+	if f.Query != "" && len(f.matchRanges(l.Message)) == 0 {
+		return false
+	}
+	return true
+}
+
+// AttributeString renders an attribute value the way PostgreSQL's ->>
+// operator does, so in-memory filtering agrees with database queries.
+func AttributeString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	case bool, int, int64:
+		return fmt.Sprint(val)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
+
+// ParseTime parses an absolute RFC3339 timestamp or a duration relative to
+// now such as "-15m", "-2h30m" or "-7d".
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("empty time value")
+	}
+	if value == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: want RFC3339 or a relative duration like -15m", value)
+		}
+		return now.AddDate(0, 0, n), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC3339 or a relative duration like -15m", value)
+	}
+	return now.Add(d), nil
 }
@@ -83,6 +83,28 @@ func TestLogValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid type",
 		},
+		{
+			name:    "valid attributes",
+			log:     Log{Level: LevelInfo, Type: TypeAPI, Message: "ok", Attributes: map[string]any{"user_id": 42}},
+			wantErr: false,
+		},
+		{
+			name:    "empty attribute key",
+			log:     Log{Level: LevelInfo, Type: TypeAPI, Message: "ok", Attributes: map[string]any{"": 1}},
+			wantErr: true,
+			errMsg:  "invalid attribute key",
+		},
+		{
+			name:    "valid tenant",
+			log:     Log{Level: LevelInfo, Type: TypeAPI, Message: "ok", Tenant: "billing-api"},
+			wantErr: false,
+		},
+		{
+			name:    "invalid tenant",
+			log:     Log{Level: LevelInfo, Type: TypeAPI, Message: "ok", Tenant: "Billing API"},
+			wantErr: true,
+			errMsg:  "invalid tenant",
+		},
 		{
 			name:    "message too long",
 			log:     Log{Level: LevelInfo, Type: TypeUser, Message: strings.Repeat("x", MaxMessageLength+1)},
@@ -149,3 +171,117 @@ func TestLogFilter(t *testing.T) {
 		t.Errorf("Type mismatch after JSON: got %s, want %s", unmarshaledFilter.Type, filter.Type)
 	}
 }
+
+func TestLogFilterMatches(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	entry := Log{
+		Timestamp: ts,
+		Level:     LevelError,
+		Type:      TypeAPI,
+		Message:   "request failed",
+		Tenant:    "billing",
+		Attributes: map[string]any{
+			"user_id":    float64(42),
+			"request_id": "abc",
+			"retry":      true,
+			"big":        json.Number("9007199254740993"),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   bool
+	}{
+		{"empty filter", LogFilter{}, true},
+		{"level match", LogFilter{Level: LevelError}, true},
+		{"level mismatch", LogFilter{Level: LevelInfo}, false},
+		{"type mismatch", LogFilter{Type: TypeAuth}, false},
+		{"level in set", LogFilter{Levels: []string{LevelWarning, LevelError}}, true},
+		{"level not in set", LogFilter{Levels: []string{LevelDebug, LevelInfo}}, false},
+		{"type in set", LogFilter{Types: []string{TypeAuth, TypeAPI}}, true},
+		{"at min level", LogFilter{MinLevel: LevelError}, true},
+		{"above min level", LogFilter{MinLevel: LevelWarning}, true},
+		{"min level and set", LogFilter{MinLevel: LevelWarning, Levels: []string{LevelInfo, LevelError}}, true},
+		{"min level excludes set", LogFilter{MinLevel: LevelError, Levels: []string{LevelDebug}}, false},
+		{"numeric attribute", LogFilter{Attributes: map[string]string{"user_id": "42"}}, true},
+		{"string attribute", LogFilter{Attributes: map[string]string{"request_id": "abc"}}, true},
+		{"bool attribute", LogFilter{Attributes: map[string]string{"retry": "true"}}, true},
+		{"big number attribute", LogFilter{Attributes: map[string]string{"big": "9007199254740993"}}, true},
+		{"inside time range", LogFilter{Since: ts.Add(-time.Minute), Until: ts.Add(time.Minute)}, true},
+		{"since is inclusive", LogFilter{Since: ts}, true},
+		{"until is exclusive", LogFilter{Until: ts}, false},
+		{"before since", LogFilter{Since: ts.Add(time.Second)}, false},
+		{"attribute mismatch", LogFilter{Attributes: map[string]string{"user_id": "7"}}, false},
+		{"missing attribute", LogFilter{Attributes: map[string]string{"tenant": "x"}}, false},
+		{"same tenant", LogFilter{Tenant: "billing"}, true},
+		{"other tenant", LogFilter{Tenant: "search"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Matches(entry); got != tc.want {
+				t.Errorf("Matches() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLogFilterLevelSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   []string
+	}{
+		{"unfiltered", LogFilter{}, nil},
+		{"single and multi", LogFilter{Level: LevelError, Levels: []string{LevelInfo, LevelError}}, []string{LevelError, LevelInfo}},
+		{"min level", LogFilter{MinLevel: LevelWarning}, []string{LevelWarning, LevelError}},
+		{"min level intersects", LogFilter{MinLevel: LevelInfo, Levels: []string{LevelDebug, LevelError}}, []string{LevelError}},
+		{"empty intersection", LogFilter{MinLevel: LevelError, Level: LevelDebug}, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.filter.LevelSet()
+			if (got == nil) != (tc.want == nil) || strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("LevelSet() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-01-15T09:00:00Z", want: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{value: "2024-01-15T11:00:00+02:00", want: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{value: "-15m", want: now.Add(-15 * time.Minute)},
+		{value: "-2h30m", want: now.Add(-150 * time.Minute)},
+		{value: "-7d", want: now.AddDate(0, 0, -7)},
+		{value: "now", want: now},
+		{value: "yesterday", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseTime(tc.value, now)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTime(%q) expected error", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q) error: %v", tc.value, err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// MaxNameLength is the longest level or type name; it fits within the
+// level and type columns.
+const MaxNameLength = 32
+
+// LevelDef is a log level with a numeric severity used for ordering; higher
+// is more severe.
+type LevelDef struct {
+	Name     string `json:"name"`
+	Severity int    `json:"severity"`
+}
+
+// Registry holds the accepted levels and types.
+type Registry struct {
+	levels   []LevelDef
+	severity map[string]int
+	types    []string
+	typeSet  map[string]bool
+}
+
+// DefaultLevels and DefaultTypes are used when no registry is configured.
+var (
+	DefaultLevels = []LevelDef{
+		{LevelDebug, 10}, {LevelInfo, 20}, {LevelWarning, 30}, {LevelError, 40},
+	}
+	DefaultTypes = []string{TypeSystem, TypeAuth, TypeDatabase, TypeUser, TypeAPI}
+)
+
+var registry atomic.Pointer[Registry]
+
+func init() {
+	r, err := NewRegistry(DefaultLevels, DefaultTypes)
+	if err != nil {
+		panic(err)
+	}
+	registry.Store(r)
+}
+
+// NewRegistry validates the definitions and builds a Registry. Levels are
+// kept in order of increasing severity.
+func NewRegistry(levels []LevelDef, types []string) (*Registry, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("at least one level is required")
+	}
+	if len(types) == 0 {
+		return nil, errors.New("at least one type is required")
+	}
+
+	r := &Registry{
+		levels:   slices.Clone(levels),
+		severity: make(map[string]int, len(levels)),
+		typeSet:  make(map[string]bool, len(types)),
+	}
+	for _, l := range levels {
+		if err := validName(l.Name); err != nil {
+			return nil, fmt.Errorf("level %w", err)
+		}
+		if _, dup := r.severity[l.Name]; dup {
+			return nil, fmt.Errorf("duplicate level %q", l.Name)
+		}
+		r.severity[l.Name] = l.Severity
+	}
+	slices.SortStableFunc(r.levels, func(a, b LevelDef) int { return a.Severity - b.Severity })
+
+	for _, t := range types {
+		if err := validName(t); err != nil {
+			return nil, fmt.Errorf("type %w", err)
+		}
+		if r.typeSet[t] {
+			return nil, fmt.Errorf("duplicate type %q", t)
+		}
+		r.typeSet[t] = true
+		r.types = append(r.types, t)
+	}
+	return r, nil
+}
+
+func validName(name string) error {
+	if name == "" || len(name) > MaxNameLength {
+		return fmt.Errorf("name %q must be 1-%d characters", name, MaxNameLength)
+	}
+	for _, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' && c != '-' {
+			return fmt.Errorf("name %q may only contain A-Z, 0-9, '_' and '-'", name)
+		}
+	}
+	return nil
+}
+
+// ParseLevels parses a comma-separated list of NAME:SEVERITY pairs, e.g.
+// "TRACE:0,DEBUG:10,INFO:20". Names are upper-cased. A name without a
+// severity is ranked after the previous one.
+func ParseLevels(spec string) ([]LevelDef, error) {
+	var levels []LevelDef
+	next := 0
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, sev, hasSeverity := strings.Cut(part, ":")
+		l := LevelDef{Name: strings.ToUpper(strings.TrimSpace(name)), Severity: next}
+		if hasSeverity {
+			n, err := strconv.Atoi(strings.TrimSpace(sev))
+			if err != nil {
+				return nil, fmt.Errorf("invalid severity for level %q: %w", l.Name, err)
+			}
+			l.Severity = n
+		}
+		next = l.Severity + 10
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
+
+// ParseTypes parses a comma-separated list of type names.
+func ParseTypes(spec string) []string {
+	var types []string
+	for _, t := range strings.Split(spec, ",") {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// CurrentRegistry returns the registry used by Log.Validate and the HTTP
+// handlers.
+func CurrentRegistry() *Registry {
+	return registry.Load()
+}
+
+// SetRegistry replaces the registry returned by CurrentRegistry.
+func SetRegistry(r *Registry) {
+	registry.Store(r)
+}
+
+// Levels returns the levels ordered by increasing severity.
+func (r *Registry) Levels() []LevelDef {
+	return slices.Clone(r.levels)
+}
+
+// LevelNames returns the level names ordered by increasing severity.
+func (r *Registry) LevelNames() []string {
+	names := make([]string, len(r.levels))
+	for i, l := range r.levels {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Types returns the type names in configuration order.
+func (r *Registry) Types() []string {
+	return slices.Clone(r.types)
+}
+
+// DefaultType is the type for entries whose source does not name one:
+// SYSTEM when registered, otherwise the first configured type.
+func (r *Registry) DefaultType() string {
+	if r.typeSet[TypeSystem] {
+		return TypeSystem
+	}
+	return r.types[0]
+}
+
+// ValidLevel reports whether name is a registered level.
+func (r *Registry) ValidLevel(name string) bool {
+	_, ok := r.severity[name]
+	return ok
+}
+
+// ValidType reports whether name is a registered type.
+func (r *Registry) ValidType(name string) bool {
+	return r.typeSet[name]
+}
+
+// Severity returns the severity of a registered level.
+func (r *Registry) Severity(name string) (int, bool) {
+	s, ok := r.severity[name]
+	return s, ok
+}
+
+// ValidLevel reports whether name is a level in the current registry.
+func ValidLevel(name string) bool {
+	return CurrentRegistry().ValidLevel(name)
+}
+
+// ValidType reports whether name is a type in the current registry.
+func ValidType(name string) bool {
+	return CurrentRegistry().ValidType(name)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("trace:0, DEBUG:10,INFO,WARNING:30,,FATAL:50")
+	if err != nil {
+		t.Fatalf("ParseLevels() error: %v", err)
+	}
+	want := []LevelDef{{"TRACE", 0}, {"DEBUG", 10}, {"INFO", 20}, {"WARNING", 30}, {"FATAL", 50}}
+	if len(levels) != len(want) {
+		t.Fatalf("ParseLevels() = %v, want %v", levels, want)
+	}
+	for i := range want {
+		if levels[i] != want[i] {
+			t.Errorf("levels[%d] = %v, want %v", i, levels[i], want[i])
+		}
+	}
+
+	if _, err := ParseLevels("INFO:high"); err == nil {
+		t.Error("ParseLevels() with a non-numeric severity should fail")
+	}
+}
+
+func TestParseTypes(t *testing.T) {
+	got := ParseTypes(" system, payments ,,QUEUE")
+	if strings.Join(got, ",") != "SYSTEM,PAYMENTS,QUEUE" {
+		t.Errorf("ParseTypes() = %v", got)
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	reg, err := NewRegistry([]LevelDef{{"FATAL", 50}, {"TRACE", 0}, {"INFO", 20}}, []string{"PAYMENTS", "QUEUE"})
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	if got := strings.Join(reg.LevelNames(), ","); got != "TRACE,INFO,FATAL" {
+		t.Errorf("LevelNames() = %s, want ordered by severity", got)
+	}
+	if sev, ok := reg.Severity("FATAL"); !ok || sev != 50 {
+		t.Errorf("Severity(FATAL) = %d, %v", sev, ok)
+	}
+	if !reg.ValidType("QUEUE") || reg.ValidType("API") || reg.ValidLevel("ERROR") {
+		t.Error("ValidType/ValidLevel should only accept registered names")
+	}
+	if got := reg.DefaultType(); got != "PAYMENTS" {
+		t.Errorf("DefaultType() = %s, want the first type when SYSTEM is not registered", got)
+	}
+
+	invalid := []struct {
+		name   string
+		levels []LevelDef
+		types  []string
+	}{
+		{"no levels", nil, []string{"SYSTEM"}},
+		{"no types", DefaultLevels, nil},
+		{"duplicate level", []LevelDef{{"INFO", 1}, {"INFO", 2}}, []string{"SYSTEM"}},
+		{"duplicate type", DefaultLevels, []string{"SYSTEM", "SYSTEM"}},
+		{"lowercase", []LevelDef{{"info", 1}}, []string{"SYSTEM"}},
+		{"too long", DefaultLevels, []string{strings.Repeat("X", MaxNameLength+1)}},
+	}
+	for _, tc := range invalid {
+		if _, err := NewRegistry(tc.levels, tc.types); err == nil {
+			t.Errorf("%s: NewRegistry() should fail", tc.name)
+		}
+	}
+}
+
+func TestValidateUsesRegistry(t *testing.T) {
+	defer SetRegistry(CurrentRegistry())
+
+	reg, err := NewRegistry([]LevelDef{{"TRACE", 0}, {"INFO", 20}}, []string{"PAYMENTS"})
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	SetRegistry(reg)
+
+	ok := Log{Level: "TRACE", Type: "PAYMENTS", Message: "charge created"}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate() error for registered level and type: %v", err)
+	}
+	bad := Log{Level: LevelError, Type: "PAYMENTS", Message: "x"}
+	err = bad.Validate()
+	if err == nil || !strings.Contains(err.Error(), "TRACE, INFO") {
+		t.Errorf("Validate() error = %v, want it to list the registered levels", err)
+	}
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	SearchFullText  = "fts"
+	SearchSubstring = "substring"
+	SearchRegex     = "regex"
+
+	// snippetRadius is the number of bytes kept around the first match when a
+	// snippet is cut out of a long message.
+	snippetRadius = 80
+
+	// maxCachedPatterns bounds the compiled search patterns kept in
+	// patternCache; the cache is cleared when it is full.
+	maxCachedPatterns = 256
+)
+
+// patternCache memoizes compiled search patterns by source, since a filter
+// is matched against every entry of the memory store, every streamed entry
+// and every alert evaluation.
+var (
+	patternMu    sync.RWMutex
+	patternCache = make(map[string]*regexp.Regexp)
+)
+
+var ValidSearchModes = map[string]bool{
+	SearchFullText: true, SearchSubstring: true, SearchRegex: true,
+}
+
+// EffectiveSearchMode returns the search mode, defaulting to full-text.
+func (f LogFilter) EffectiveSearchMode() string {
+	if f.SearchMode == "" {
+		return SearchFullText
+	}
+	return f.SearchMode
+}
+
+// ValidateSearch checks the search mode and, for regex searches, that the
+// pattern compiles.
+func (f LogFilter) ValidateSearch() error {
+	if f.SearchMode != "" && !ValidSearchModes[f.SearchMode] {
+		return errors.New("invalid search mode: must be one of fts, substring, regex")
+	}
+	if f.Query != "" && f.EffectiveSearchMode() == SearchRegex {
+		if _, err := CompilePattern("(?i)" + f.Query); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// matchRanges returns the byte ranges of message that match the search, or
+// nil when it does not match. Full-text searches require every query term to
+// appear as a whole word, mirroring plainto_tsquery with the simple config.
+func (f LogFilter) matchRanges(message string) [][2]int {
+	switch f.EffectiveSearchMode() {
+	case SearchSubstring:
+		return regexpRanges("(?i)"+regexp.QuoteMeta(f.Query), message)
+	case SearchRegex:
+		return regexpRanges("(?i)"+f.Query, message)
+	default:
+		terms := SearchTerms(f.Query)
+		if len(terms) == 0 {
+			return nil
+		}
+		found := make(map[string]bool, len(terms))
+		var ranges [][2]int
+		for _, w := range wordRanges(message) {
+			word := strings.ToLower(message[w[0]:w[1]])
+			for _, term := range terms {
+				if word == term {
+					found[term] = true
+					ranges = append(ranges, w)
+					break
+				}
+			}
+		}
+		if len(found) != len(terms) {
+			return nil
+		}
+		return ranges
+	}
+}
+
+// Highlight wraps every match in message with start and stop. Long messages
+// are trimmed to a window around the first match. It returns "" when the
+// filter has no query or the message does not match.
+func (f LogFilter) Highlight(message, start, stop string) string {
+	if f.Query == "" {
+		return ""
+	}
+	ranges := f.matchRanges(message)
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	lo, hi := 0, len(message)
+	if hi > 2*snippetRadius+ranges[0][1]-ranges[0][0] {
+		lo = max(0, ranges[0][0]-snippetRadius)
+		hi = min(len(message), ranges[0][1]+snippetRadius)
+		for lo > 0 && !utf8Start(message[lo]) {
+			lo--
+		}
+		for hi < len(message) && !utf8Start(message[hi]) {
+			hi++
+		}
+	}
+
+	var b strings.Builder
+	if lo > 0 {
+		b.WriteString("…")
+	}
+	pos := lo
+	for _, r := range ranges {
+		if r[0] < pos || r[1] > hi {
+			continue
+		}
+		b.WriteString(message[pos:r[0]])
+		b.WriteString(start)
+		b.WriteString(message[r[0]:r[1]])
+		b.WriteString(stop)
+		pos = r[1]
+	}
+	b.WriteString(message[pos:hi])
+	if hi < len(message) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// CompilePattern returns the compiled pattern, compiling it only once while
+// it stays in the bounded cache. Stores use it for regex filters evaluated
+// per row.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	patternMu.RLock()
+	re, ok := patternCache[pattern]
+	patternMu.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternMu.Lock()
+	defer patternMu.Unlock()
+	if len(patternCache) >= maxCachedPatterns {
+		clear(patternCache)
+	}
+	patternCache[pattern] = re
+	return re, nil
+}
+
+func regexpRanges(pattern, s string) [][2]int {
+	re, err := CompilePattern(pattern)
+	if err != nil {
+		return nil
+	}
+	var ranges [][2]int
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		if m[0] < m[1] {
+			ranges = append(ranges, [2]int{m[0], m[1]})
+		}
+	}
+	return ranges
+}
+
+// SearchTerms splits a full-text query into distinct lower-cased words.
+func SearchTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, w := range wordRanges(query) {
+		term := strings.ToLower(query[w[0]:w[1]])
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func wordRanges(s string) [][2]int {
+	var ranges [][2]int
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			ranges = append(ranges, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		ranges = append(ranges, [2]int{start, len(s)})
+	}
+	return ranges
+}
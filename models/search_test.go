@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  LogFilter
+		wantErr bool
+	}{
+		{"no query", LogFilter{}, false},
+		{"default mode", LogFilter{Query: "timeout"}, false},
+		{"substring", LogFilter{Query: "50%", SearchMode: SearchSubstring}, false},
+		{"valid regex", LogFilter{Query: `conn(ection)? (lost|reset)`, SearchMode: SearchRegex}, false},
+		{"invalid regex", LogFilter{Query: `(unclosed`, SearchMode: SearchRegex}, true},
+		{"unknown mode", LogFilter{Query: "x", SearchMode: "fuzzy"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.filter.ValidateSearch()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateSearch() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchMatches(t *testing.T) {
+	entry := Log{Message: "Database connection timeout after 30s (pool_size=10)"}
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   bool
+	}{
+		{"all words present", LogFilter{Query: "connection TIMEOUT"}, true},
+		{"one word missing", LogFilter{Query: "connection refused"}, false},
+		{"partial word is not a full-text match", LogFilter{Query: "connect"}, false},
+		{"substring", LogFilter{Query: "connect", SearchMode: SearchSubstring}, true},
+		{"substring is literal", LogFilter{Query: "pool_size=10)", SearchMode: SearchSubstring}, true},
+		{"regex", LogFilter{Query: `timeout after \d+s`, SearchMode: SearchRegex}, true},
+		{"regex mismatch", LogFilter{Query: `^timeout`, SearchMode: SearchRegex}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Matches(entry); got != tc.want {
+				t.Errorf("Matches() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Run("marks every match", func(t *testing.T) {
+		f := LogFilter{Query: "timeout"}
+		got := f.Highlight("Timeout: retry after timeout", "<mark>", "</mark>")
+		want := "<mark>Timeout</mark>: retry after <mark>timeout</mark>"
+		if got != want {
+			t.Errorf("Highlight() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		f := LogFilter{Query: "panic"}
+		if got := f.Highlight("all good", "[", "]"); got != "" {
+			t.Errorf("Highlight() = %q, want empty", got)
+		}
+	})
+
+	t.Run("long message is trimmed around the match", func(t *testing.T) {
+		f := LogFilter{Query: "needle", SearchMode: SearchSubstring}
+		msg := strings.Repeat("a", 500) + "needle" + strings.Repeat("b", 500)
+		got := f.Highlight(msg, "[", "]")
+		if !strings.Contains(got, "[needle]") {
+			t.Errorf("Highlight() = %q, want marked match", got)
+		}
+		if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+			t.Errorf("Highlight() should be trimmed on both sides, got %q", got)
+		}
+		if len(got) >= len(msg) {
+			t.Errorf("Highlight() length = %d, want shorter than message", len(got))
+		}
+	})
+}
+
+func TestCompilePatternCache(t *testing.T) {
+	first, err := CompilePattern("(?i)time(out)?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := CompilePattern("(?i)time(out)?"); again != first {
+		t.Error("pattern was compiled again")
+	}
+	if _, err := CompilePattern("("); err == nil {
+		t.Error("invalid pattern compiled")
+	}
+
+	for i := 0; i < maxCachedPatterns+1; i++ {
+		CompilePattern(fmt.Sprintf("p%d", i))
+	}
+	patternMu.RLock()
+	n := len(patternCache)
+	patternMu.RUnlock()
+	if n > maxCachedPatterns {
+		t.Errorf("cache holds %d patterns, want at most %d", n, maxCachedPatterns)
+	}
+}
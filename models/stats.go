@@ -0,0 +1,90 @@
+package models
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Dimensions that stats can be grouped by.
+const (
+	GroupByLevel = "level"
+	GroupByType  = "type"
+)
+
+// MaxStatsBuckets bounds the number of intervals one stats query may span.
+const MaxStatsBuckets = 1440
+
+// StatsQuery counts the entries matching Filter per Interval, optionally
+// grouped by level and/or type. Since and Until must both be set.
+type StatsQuery struct {
+	Filter   LogFilter
+	Interval time.Duration
+	GroupBy  []string
+}
+
+// StatsBucket is the number of entries in the interval starting at Start.
+// Level and Type are set when the query is grouped by them.
+type StatsBucket struct {
+	Start time.Time `json:"start"`
+	Level string    `json:"level,omitempty"`
+	Type  string    `json:"type,omitempty"`
+	Count int64     `json:"count"`
+}
+
+// Validate checks the interval, the grouping and the number of buckets.
+func (q StatsQuery) Validate() error {
+	if q.Interval < time.Second || q.Interval%time.Second != 0 {
+		return errors.New("interval must be a whole number of seconds")
+	}
+	for i, g := range q.GroupBy {
+		if g != GroupByLevel && g != GroupByType {
+			return fmt.Errorf("invalid group_by %q: must be %s or %s", g, GroupByLevel, GroupByType)
+		}
+		if slices.Contains(q.GroupBy[:i], g) {
+			return fmt.Errorf("group_by %q given twice", g)
+		}
+	}
+	if q.Filter.Since.IsZero() || q.Filter.Until.IsZero() {
+		return errors.New("stats need both since and until")
+	}
+	if n := q.Filter.Until.Sub(q.BucketStart(q.Filter.Since)) / q.Interval; n > MaxStatsBuckets {
+		return fmt.Errorf("%d intervals requested; at most %d are allowed", n, MaxStatsBuckets)
+	}
+	return nil
+}
+
+// Grouped reports whether the query groups by dim.
+func (q StatsQuery) Grouped(dim string) bool {
+	return slices.Contains(q.GroupBy, dim)
+}
+
+// BucketStart returns the start of the interval containing t. Intervals are
+// aligned to the Unix epoch, so hourly buckets start on the hour in UTC.
+func (q StatsQuery) BucketStart(t time.Time) time.Time {
+	secs := int64(q.Interval / time.Second)
+	unix := t.Unix()
+	return time.Unix(unix-((unix%secs)+secs)%secs, 0).UTC()
+}
+
+// Key returns the bucket of l under this query, without a count.
+func (q StatsQuery) Key(l Log) StatsBucket {
+	b := StatsBucket{Start: q.BucketStart(q.Filter.TimeOf(l))}
+	if q.Grouped(GroupByLevel) {
+		b.Level = l.Level
+	}
+	if q.Grouped(GroupByType) {
+		b.Type = l.Type
+	}
+	return b
+}
+
+// SortStats orders buckets by start, then level and type, the order the
+// stores return them in.
+func SortStats(buckets []StatsBucket) {
+	slices.SortFunc(buckets, func(a, b StatsBucket) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.Level, b.Level), cmp.Compare(a.Type, b.Type))
+	})
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsQueryValidate(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	window := LogFilter{Since: since, Until: since.Add(6 * time.Hour)}
+
+	tests := []struct {
+		name  string
+		query StatsQuery
+		want  string // substring of the error, or "" for valid
+	}{
+		{"valid", StatsQuery{Filter: window, Interval: time.Minute, GroupBy: []string{"level", "type"}}, ""},
+		{"sub-second interval", StatsQuery{Filter: window, Interval: 1500 * time.Millisecond}, "whole number"},
+		{"zero interval", StatsQuery{Filter: window}, "whole number"},
+		{"unknown group", StatsQuery{Filter: window, Interval: time.Minute, GroupBy: []string{"tenant"}}, "invalid group_by"},
+		{"duplicate group", StatsQuery{Filter: window, Interval: time.Minute, GroupBy: []string{"level", "level"}}, "twice"},
+		{"open range", StatsQuery{Filter: LogFilter{Since: since}, Interval: time.Minute}, "since and until"},
+		{"too many buckets", StatsQuery{Filter: window, Interval: time.Second}, "at most"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.query.Validate()
+			if (tc.want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tc.want)) {
+				t.Errorf("Validate() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestStatsQueryKey(t *testing.T) {
+	q := StatsQuery{Interval: 5 * time.Minute, GroupBy: []string{GroupByLevel}}
+	l := Log{Timestamp: time.Date(2024, 3, 1, 10, 7, 30, 0, time.FixedZone("UTC+3", 3*3600)), Level: "ERROR", Type: "API"}
+
+	got := q.Key(l)
+	want := StatsBucket{Start: time.Date(2024, 3, 1, 7, 5, 0, 0, time.UTC), Level: "ERROR"}
+	if got != want {
+		t.Errorf("Key() = %+v, want %+v", got, want)
+	}
+	if day := (StatsQuery{Interval: 24 * time.Hour}).BucketStart(l.Timestamp); !day.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily BucketStart() = %v, want UTC midnight", day)
+	}
+}
+
+func TestSortStats(t *testing.T) {
+	t0 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	buckets := []StatsBucket{
+		{Start: t0.Add(time.Minute), Level: "ERROR"},
+		{Start: t0, Level: "INFO"},
+		{Start: t0, Level: "ERROR", Type: "DATABASE"},
+		{Start: t0, Level: "ERROR", Type: "API"},
+	}
+	SortStats(buckets)
+	if buckets[0].Type != "API" || buckets[1].Type != "DATABASE" || buckets[2].Level != "INFO" || !buckets[3].Start.After(t0) {
+		t.Errorf("SortStats() = %+v", buckets)
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Time fields a LogFilter can filter and sort by.
+const (
+	// TimeFieldTimestamp is when the event happened, as sent by the client.
+	TimeFieldTimestamp = "timestamp"
+	// TimeFieldReceivedAt is when golog received the entry.
+	TimeFieldReceivedAt = "received_at"
+)
+
+// Timestamp policy modes for client-supplied times outside the allowed
+// range.
+const (
+	TimestampClamp  = "clamp"
+	TimestampReject = "reject"
+)
+
+// ValidTimeField reports whether name is a time field; empty selects
+// TimeFieldTimestamp.
+func ValidTimeField(name string) bool {
+	return name == "" || name == TimeFieldTimestamp || name == TimeFieldReceivedAt
+}
+
+// TimeColumn returns the column the filter's time range and ordering apply
+// to. It only returns the two known column names, so it is safe to use in
+// SQL.
+func (f LogFilter) TimeColumn() string {
+	if f.TimeField == TimeFieldReceivedAt {
+		return TimeFieldReceivedAt
+	}
+	return TimeFieldTimestamp
+}
+
+// TimeOf returns the time of l the filter's time range and ordering apply to.
+func (f LogFilter) TimeOf(l Log) time.Time {
+	if f.TimeField == TimeFieldReceivedAt {
+		return l.ReceivedAt
+	}
+	return l.Timestamp
+}
+
+// CursorAfter returns a cursor that continues after l in the given direction
+// of the filter's time ordering.
+func (f LogFilter) CursorAfter(l Log, backward bool) Cursor {
+	return Cursor{Timestamp: f.TimeOf(l), ID: l.ID, Backward: backward}
+}
+
+// TimestampPolicy bounds the timestamps clients send with their entries.
+// MaxFuture and MaxPast limit how far ahead of or behind the receive time a
+// timestamp may be; zero leaves that side unbounded. Entries outside the
+// range are rejected or, with TimestampClamp, moved to the nearest allowed
+// time, keeping the original in the client_timestamp attribute.
+type TimestampPolicy struct {
+	Mode      string
+	MaxFuture time.Duration
+	MaxPast   time.Duration
+}
+
+// Validate checks the mode and limits.
+func (p TimestampPolicy) Validate() error {
+	if p.Mode != TimestampClamp && p.Mode != TimestampReject {
+		return fmt.Errorf("mode %q must be %s or %s", p.Mode, TimestampClamp, TimestampReject)
+	}
+	if p.MaxFuture < 0 || p.MaxPast < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// Apply enforces the policy on an entry received at now and records now as
+// its receive time. Entries without a timestamp get now.
+func (p TimestampPolicy) Apply(l *Log, now time.Time) error {
+	l.ReceivedAt = now
+	if l.Timestamp.IsZero() {
+		l.Timestamp = now
+		return nil
+	}
+
+	bound := l.Timestamp
+	switch {
+	case p.MaxFuture > 0 && l.Timestamp.After(now.Add(p.MaxFuture)):
+		bound = now.Add(p.MaxFuture)
+		if p.Mode == TimestampReject {
+			return fmt.Errorf("timestamp %s is more than %s in the future", l.Timestamp.UTC().Format(time.RFC3339), p.MaxFuture)
+		}
+	case p.MaxPast > 0 && l.Timestamp.Before(now.Add(-p.MaxPast)):
+		bound = now.Add(-p.MaxPast)
+		if p.Mode == TimestampReject {
+			return fmt.Errorf("timestamp %s is more than %s in the past", l.Timestamp.UTC().Format(time.RFC3339), p.MaxPast)
+		}
+	default:
+		return nil
+	}
+
+	if len(l.Attributes) < MaxAttributes {
+		if l.Attributes == nil {
+			l.Attributes = make(map[string]any)
+		}
+		l.Attributes["client_timestamp"] = l.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	l.Timestamp = bound
+	return nil
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampPolicy(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clamp := TimestampPolicy{Mode: TimestampClamp, MaxFuture: 5 * time.Minute, MaxPast: 24 * time.Hour}
+	reject := TimestampPolicy{Mode: TimestampReject, MaxFuture: 5 * time.Minute}
+
+	tests := []struct {
+		name    string
+		policy  TimestampPolicy
+		ts      time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{"missing", clamp, time.Time{}, now, false},
+		{"within range", clamp, now.Add(-time.Hour), now.Add(-time.Hour), false},
+		{"clamped future", clamp, now.Add(time.Hour), now.Add(5 * time.Minute), false},
+		{"clamped past", clamp, now.Add(-48 * time.Hour), now.Add(-24 * time.Hour), false},
+		{"rejected future", reject, now.Add(time.Hour), time.Time{}, true},
+		{"unbounded past", reject, now.AddDate(-5, 0, 0), now.AddDate(-5, 0, 0), false},
+		{"no policy", TimestampPolicy{}, now.Add(time.Hour), now.Add(time.Hour), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l := Log{Timestamp: tc.ts}
+			err := tc.policy.Apply(&l, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && !l.Timestamp.Equal(tc.want) {
+				t.Errorf("timestamp = %v, want %v", l.Timestamp, tc.want)
+			}
+			if !l.ReceivedAt.Equal(now) {
+				t.Errorf("received_at = %v, want %v", l.ReceivedAt, now)
+			}
+			clamped := err == nil && !tc.ts.IsZero() && !tc.ts.Equal(tc.want)
+			if got := l.Attributes["client_timestamp"]; clamped != (got != nil) {
+				t.Errorf("client_timestamp = %v, want it only when clamped", got)
+			}
+		})
+	}
+}
+
+func TestTimestampPolicyValidate(t *testing.T) {
+	if err := (TimestampPolicy{Mode: "drop"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown mode")
+	}
+	if err := (TimestampPolicy{Mode: TimestampClamp, MaxPast: -time.Hour}).Validate(); err == nil {
+		t.Error("Validate() accepted a negative limit")
+	}
+}
+
+func TestFilterTimeField(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := Log{ID: 3, Timestamp: ts, ReceivedAt: ts.Add(time.Hour)}
+	f := LogFilter{TimeField: TimeFieldReceivedAt, Since: ts.Add(time.Minute)}
+	if !f.Matches(l) || f.TimeColumn() != "received_at" {
+		t.Errorf("received_at filter should match %+v", l)
+	}
+	if c := f.CursorAfter(l, false); !c.Timestamp.Equal(l.ReceivedAt) || c.ID != 3 {
+		t.Errorf("CursorAfter() = %+v", c)
+	}
+	f.TimeField = ""
+	if f.Matches(l) || f.TimeColumn() != "timestamp" {
+		t.Errorf("timestamp filter should not match %+v", l)
+	}
+}
@@ -0,0 +1,231 @@
+// Package otlp converts OpenTelemetry (OTLP) log records into log entries.
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Optional levels used when they are registered.
+const (
+	levelTrace = "TRACE"
+	levelFatal = "FATAL"
+)
+
+// Level maps an OTLP severity number onto a golog level. TRACE and FATAL
+// records keep their level when it is registered and otherwise become DEBUG
+// and ERROR. Records without a severity number fall back to their severity
+// text, then to INFO.
+func Level(number logspb.SeverityNumber, text string) string {
+	switch {
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL && models.ValidLevel(levelFatal):
+		return levelFatal
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return models.LevelError
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return models.LevelWarning
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return models.LevelInfo
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG:
+		return models.LevelDebug
+	case number > logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED:
+		if models.ValidLevel(levelTrace) {
+			return levelTrace
+		}
+		return models.LevelDebug
+	}
+
+	if t := strings.ToUpper(text); models.ValidLevel(t) {
+		return t
+	}
+	switch strings.ToUpper(text) {
+	case "TRACE", "DEBUG":
+		return models.LevelDebug
+	case "WARN", "WARNING":
+		return models.LevelWarning
+	case "ERROR", "FATAL", "CRITICAL":
+		return models.LevelError
+	default:
+		return models.LevelInfo
+	}
+}
+
+// Type maps the resource's service.name onto a golog type, defaulting to
+// the registry's default type when the service is not named after a
+// registered type.
+func Type(serviceName string) string {
+	reg := models.CurrentRegistry()
+	if t := strings.ToUpper(serviceName); reg.ValidType(t) {
+		return t
+	}
+	return reg.DefaultType()
+}
+
+// Result is the outcome of converting an export request.
+type Result struct {
+	Entries []models.Log
+
+	// Rejected counts records that could not be converted; Error describes
+	// the first of them.
+	Rejected int
+	Error    string
+}
+
+// Convert flattens every log record in req into a log entry. Resource
+// attributes are merged into each record's attributes, with the record's own
+// attributes taking precedence, and trace context is kept as trace_id and
+// span_id. The record's time, or its observed time, becomes the entry's
+// timestamp.
+func Convert(req *collogspb.ExportLogsServiceRequest) Result {
+	var res Result
+	for _, rl := range req.GetResourceLogs() {
+		resourceAttrs := rl.GetResource().GetAttributes()
+		var service string
+		for _, kv := range resourceAttrs {
+			if kv.GetKey() == "service.name" {
+				service = kv.GetValue().GetStringValue()
+			}
+		}
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, rec := range sl.GetLogRecords() {
+				entry := models.Log{
+					Level:      Level(rec.GetSeverityNumber(), rec.GetSeverityText()),
+					Type:       Type(service),
+					Message:    truncate(bodyString(rec.GetBody()), models.MaxMessageLength),
+					Attributes: make(map[string]any),
+				}
+
+				if len(rec.GetTraceId()) > 0 {
+					entry.Attributes["trace_id"] = hex.EncodeToString(rec.GetTraceId())
+				}
+				if len(rec.GetSpanId()) > 0 {
+					entry.Attributes["span_id"] = hex.EncodeToString(rec.GetSpanId())
+				}
+				if ts := recordTime(rec); !ts.IsZero() {
+					entry.Timestamp = ts
+					entry.Attributes["otel_timestamp"] = ts.UTC().Format(time.RFC3339Nano)
+				}
+				if rec.GetSeverityText() != "" {
+					entry.Attributes["severity_text"] = rec.GetSeverityText()
+				}
+				if name := sl.GetScope().GetName(); name != "" {
+					entry.Attributes["otel.scope.name"] = name
+				}
+				addAttributes(entry.Attributes, rec.GetAttributes())
+				addAttributes(entry.Attributes, resourceAttrs)
+
+				if err := entry.Validate(); err != nil {
+					if res.Rejected == 0 {
+						res.Error = fmt.Sprintf("log record %d: %v", len(res.Entries)+res.Rejected+1, err)
+					}
+					res.Rejected++
+					continue
+				}
+				res.Entries = append(res.Entries, entry)
+			}
+		}
+	}
+	return res
+}
+
+func recordTime(rec *logspb.LogRecord) time.Time {
+	ns := rec.GetTimeUnixNano()
+	if ns == 0 {
+		ns = rec.GetObservedTimeUnixNano()
+	}
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+// addAttributes copies kvs into attrs without overwriting existing keys and
+// drops whatever would make the entry fail validation.
+func addAttributes(attrs map[string]any, kvs []*commonpb.KeyValue) {
+	for _, kv := range kvs {
+		key := kv.GetKey()
+		if _, ok := attrs[key]; ok || key == "" || len(key) > models.MaxAttributeKey {
+			continue
+		}
+		if len(attrs) >= models.MaxAttributes {
+			return
+		}
+		attrs[key] = anyValue(kv.GetValue())
+	}
+}
+
+// anyValue converts an OTLP AnyValue into the JSON-compatible value stored in
+// attributes.
+func anyValue(v *commonpb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		if math.IsNaN(v.DoubleValue) || math.IsInf(v.DoubleValue, 0) {
+			// Not representable in JSON.
+			return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		}
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		out := make([]any, len(v.ArrayValue.GetValues()))
+		for i, e := range v.ArrayValue.GetValues() {
+			out[i] = anyValue(e)
+		}
+		return out
+	case *commonpb.AnyValue_KvlistValue:
+		out := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			out[kv.GetKey()] = anyValue(kv.GetValue())
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// bodyString renders the record body as the message: strings as-is and
+// structured bodies as JSON.
+func bodyString(v *commonpb.AnyValue) string {
+	if s, ok := v.GetValue().(*commonpb.AnyValue_StringValue); ok {
+		return s.StringValue
+	}
+	value := anyValue(v)
+	if value == nil {
+		return ""
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
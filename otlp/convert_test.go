@@ -0,0 +1,147 @@
+package otlp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func str(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func kv(key string, v *commonpb.AnyValue) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: v}
+}
+
+func request(service string, records ...*logspb.LogRecord) *collogspb.ExportLogsServiceRequest {
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				kv("service.name", str(service)),
+				kv("host.name", str("web-1")),
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: "checkout"},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		number logspb.SeverityNumber
+		text   string
+		want   string
+	}{
+		{logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, "", models.LevelDebug},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG4, "", models.LevelDebug},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "", models.LevelInfo},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO4, "", models.LevelInfo},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_WARN2, "", models.LevelWarning},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "info", models.LevelError},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, "", models.LevelError},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "warn", models.LevelWarning},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "", models.LevelInfo},
+	}
+	for _, tc := range tests {
+		if got := Level(tc.number, tc.text); got != tc.want {
+			t.Errorf("Level(%v, %q) = %s, want %s", tc.number, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestLevelConfiguredLevels(t *testing.T) {
+	defer models.SetRegistry(models.CurrentRegistry())
+
+	levels := append([]models.LevelDef{{Name: "TRACE", Severity: 0}}, models.DefaultLevels...)
+	levels = append(levels, models.LevelDef{Name: "FATAL", Severity: 50})
+	reg, err := models.NewRegistry(levels, []string{"PAYMENTS"})
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	models.SetRegistry(reg)
+
+	if got := Level(logspb.SeverityNumber_SEVERITY_NUMBER_TRACE2, ""); got != "TRACE" {
+		t.Errorf("TRACE2 maps to %s, want TRACE", got)
+	}
+	if got := Level(logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, ""); got != "FATAL" {
+		t.Errorf("FATAL maps to %s, want FATAL", got)
+	}
+	if got := Type("payments"); got != "PAYMENTS" {
+		t.Errorf("Type(payments) = %s, want PAYMENTS", got)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	req := request("api",
+		&logspb.LogRecord{
+			TimeUnixNano:   1705314600000000000,
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+			SeverityText:   "ERROR",
+			Body:           str("payment failed"),
+			Attributes: []*commonpb.KeyValue{
+				kv("http.status_code", &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 502}}),
+				kv("host.name", str("overridden")),
+			},
+			TraceId: []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+			SpanId:  []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+		},
+		&logspb.LogRecord{
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+			Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+				Values: []*commonpb.KeyValue{kv("event", str("login"))},
+			}}},
+		},
+		&logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO},
+	)
+
+	res := Convert(req)
+	if len(res.Entries) != 2 || res.Rejected != 1 {
+		t.Fatalf("Convert() = %d entries, %d rejected; want 2, 1", len(res.Entries), res.Rejected)
+	}
+	if !strings.Contains(res.Error, "log record 3") {
+		t.Errorf("Error = %q, want it to name record 3", res.Error)
+	}
+
+	first := res.Entries[0]
+	if first.Level != models.LevelError || first.Type != models.TypeAPI || first.Message != "payment failed" {
+		t.Errorf("first entry = %+v", first)
+	}
+	if !first.Timestamp.Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v, want the record time", first.Timestamp)
+	}
+	want := map[string]any{
+		"trace_id":         "5b8efff798038103d269b633813fc60c",
+		"span_id":          "eee19b7ec3c1b174",
+		"otel_timestamp":   "2024-01-15T10:30:00Z",
+		"http.status_code": int64(502),
+		"host.name":        "overridden",
+		"service.name":     "api",
+		"otel.scope.name":  "checkout",
+	}
+	for k, v := range want {
+		if first.Attributes[k] != v {
+			t.Errorf("attribute %q = %#v, want %#v", k, first.Attributes[k], v)
+		}
+	}
+
+	if got := res.Entries[1].Message; got != `{"event":"login"}` {
+		t.Errorf("structured body rendered as %q", got)
+	}
+}
+
+func TestConvertUnknownService(t *testing.T) {
+	res := Convert(request("checkout-service", &logspb.LogRecord{Body: str("hi")}))
+	if len(res.Entries) != 1 || res.Entries[0].Type != models.TypeSystem {
+		t.Fatalf("Convert() = %+v, want one SYSTEM entry", res.Entries)
+	}
+}
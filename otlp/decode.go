@@ -0,0 +1,179 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// DecodeProtobuf decodes a binary ExportLogsServiceRequest.
+func DecodeProtobuf(data []byte) (*collogspb.ExportLogsServiceRequest, error) {
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP protobuf: %w", err)
+	}
+	return &req, nil
+}
+
+// DecodeJSON decodes an ExportLogsServiceRequest in the OTLP/JSON encoding.
+// It differs from the canonical protobuf JSON mapping in that trace and span
+// IDs are hex strings rather than base64, so protojson cannot be used.
+func DecodeJSON(data []byte) (*collogspb.ExportLogsServiceRequest, error) {
+	var in jsonRequest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&in); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	for _, rl := range in.ResourceLogs {
+		out := &logspb.ResourceLogs{
+			Resource: &resourcepb.Resource{Attributes: rl.Resource.Attributes.proto()},
+		}
+		for _, sl := range rl.ScopeLogs {
+			scope := &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{Name: sl.Scope.Name, Version: sl.Scope.Version},
+			}
+			for _, rec := range sl.LogRecords {
+				r, err := rec.proto()
+				if err != nil {
+					return nil, err
+				}
+				scope.LogRecords = append(scope.LogRecords, r)
+			}
+			out.ScopeLogs = append(out.ScopeLogs, scope)
+		}
+		req.ResourceLogs = append(req.ResourceLogs, out)
+	}
+	return req, nil
+}
+
+type jsonRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes jsonKeyValues `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"scope"`
+			LogRecords []jsonLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         jsonInt       `json:"timeUnixNano"`
+	ObservedTimeUnixNano jsonInt       `json:"observedTimeUnixNano"`
+	SeverityNumber       int32         `json:"severityNumber"`
+	SeverityText         string        `json:"severityText"`
+	Body                 *jsonValue    `json:"body"`
+	Attributes           jsonKeyValues `json:"attributes"`
+	TraceID              string        `json:"traceId"`
+	SpanID               string        `json:"spanId"`
+}
+
+func (r jsonLogRecord) proto() (*logspb.LogRecord, error) {
+	traceID, err := hex.DecodeString(r.TraceID)
+	if err != nil || (len(traceID) != 0 && len(traceID) != 16) {
+		return nil, fmt.Errorf("invalid traceId %q", r.TraceID)
+	}
+	spanID, err := hex.DecodeString(r.SpanID)
+	if err != nil || (len(spanID) != 0 && len(spanID) != 8) {
+		return nil, fmt.Errorf("invalid spanId %q", r.SpanID)
+	}
+	return &logspb.LogRecord{
+		TimeUnixNano:         uint64(r.TimeUnixNano),
+		ObservedTimeUnixNano: uint64(r.ObservedTimeUnixNano),
+		SeverityNumber:       logspb.SeverityNumber(r.SeverityNumber),
+		SeverityText:         r.SeverityText,
+		Body:                 r.Body.proto(),
+		Attributes:           r.Attributes.proto(),
+		TraceId:              traceID,
+		SpanId:               spanID,
+	}, nil
+}
+
+type jsonKeyValues []struct {
+	Key   string     `json:"key"`
+	Value *jsonValue `json:"value"`
+}
+
+func (kvs jsonKeyValues) proto() []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, len(kvs))
+	for i, kv := range kvs {
+		out[i] = &commonpb.KeyValue{Key: kv.Key, Value: kv.Value.proto()}
+	}
+	return out
+}
+
+type jsonValue struct {
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	IntValue    *jsonInt `json:"intValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+	BytesValue  []byte   `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []*jsonValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values jsonKeyValues `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+func (v *jsonValue) proto() *commonpb.AnyValue {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: *v.StringValue}}
+	case v.BoolValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: *v.BoolValue}}
+	case v.IntValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(*v.IntValue)}}
+	case v.DoubleValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: *v.DoubleValue}}
+	case v.BytesValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.BytesValue}}
+	case v.ArrayValue != nil:
+		values := make([]*commonpb.AnyValue, len(v.ArrayValue.Values))
+		for i, e := range v.ArrayValue.Values {
+			values[i] = e.proto()
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case v.KvlistValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: v.KvlistValue.Values.proto()}}}
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
+
+// jsonInt accepts 64-bit integers encoded either as JSON numbers or, as
+// OTLP/JSON encoders usually do, as decimal strings.
+type jsonInt int64
+
+func (n *jsonInt) UnmarshalJSON(b []byte) error {
+	s := string(bytes.Trim(b, `"`))
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		u, uerr := strconv.ParseUint(s, 10, 64)
+		if uerr != nil {
+			return fmt.Errorf("invalid integer %s", b)
+		}
+		v = int64(u)
+	}
+	*n = jsonInt(v)
+	return nil
+}
@@ -0,0 +1,98 @@
+package otlp
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const exampleJSON = `{
+  "resourceLogs": [{
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "database"}}]},
+    "scopeLogs": [{
+      "scope": {"name": "pgx"},
+      "logRecords": [{
+        "timeUnixNano": "1705314600000000000",
+        "severityNumber": 13,
+        "severityText": "WARN",
+        "traceId": "5b8efff798038103d269b633813fc60c",
+        "spanId": "eee19b7ec3c1b174",
+        "body": {"stringValue": "slow query"},
+        "attributes": [
+          {"key": "db.rows", "value": {"intValue": "42"}},
+          {"key": "db.cached", "value": {"boolValue": false}},
+          {"key": "db.ratio", "value": {"doubleValue": 0.5}},
+          {"key": "db.tags", "value": {"arrayValue": {"values": [{"stringValue": "a"}, {"intValue": 1}]}}}
+        ]
+      }]
+    }]
+  }]
+}`
+
+func TestDecodeJSON(t *testing.T) {
+	req, err := DecodeJSON([]byte(exampleJSON))
+	if err != nil {
+		t.Fatalf("DecodeJSON() error: %v", err)
+	}
+
+	res := Convert(req)
+	if len(res.Entries) != 1 {
+		t.Fatalf("Convert() = %+v, want one entry", res)
+	}
+	e := res.Entries[0]
+	if e.Level != "WARNING" || e.Type != "DATABASE" || e.Message != "slow query" {
+		t.Errorf("entry = %+v", e)
+	}
+	want := map[string]any{
+		"trace_id":       "5b8efff798038103d269b633813fc60c",
+		"span_id":        "eee19b7ec3c1b174",
+		"otel_timestamp": "2024-01-15T10:30:00Z",
+		"db.rows":        int64(42),
+		"db.cached":      false,
+		"db.ratio":       0.5,
+	}
+	for k, v := range want {
+		if e.Attributes[k] != v {
+			t.Errorf("attribute %q = %#v, want %#v", k, e.Attributes[k], v)
+		}
+	}
+	if tags, ok := e.Attributes["db.tags"].([]any); !ok || len(tags) != 2 || tags[1] != int64(1) {
+		t.Errorf("db.tags = %#v", e.Attributes["db.tags"])
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"spanId":"abcd"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"soon"}]}]}]}`,
+	} {
+		if _, err := DecodeJSON([]byte(body)); err == nil {
+			t.Errorf("DecodeJSON(%s) should fail", body)
+		}
+	}
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	want, err := DecodeJSON([]byte(exampleJSON))
+	if err != nil {
+		t.Fatalf("DecodeJSON() error: %v", err)
+	}
+	data, err := proto.Marshal(want)
+	if err != nil {
+		t.Fatalf("proto.Marshal() error: %v", err)
+	}
+
+	got, err := DecodeProtobuf(data)
+	if err != nil {
+		t.Fatalf("DecodeProtobuf() error: %v", err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("DecodeProtobuf() = %v, want %v", got, want)
+	}
+
+	if _, err := DecodeProtobuf([]byte{0xff, 0xff}); err == nil {
+		t.Error("DecodeProtobuf() should fail on malformed input")
+	}
+}
@@ -0,0 +1,207 @@
+// Package retention purges old log entries according to a policy of
+// maximum ages and a maximum row count.
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Defaults for unset Policy fields.
+const (
+	DefaultInterval   = time.Hour
+	DefaultBatchSize  = 5000
+	DefaultBatchPause = 100 * time.Millisecond
+
+	// MinInterval keeps purges from running back to back.
+	MinInterval = time.Minute
+	// MaxBatchSize bounds how many rows a single DELETE may remove.
+	MaxBatchSize = 100000
+)
+
+// Policy decides which entries are purged. An entry's maximum age is the TTL
+// of its level if one is set, otherwise the TTL of its type, otherwise
+// MaxAge. A zero MaxAge keeps entries without a level or type TTL forever.
+// MaxRows, when set, then deletes the oldest entries beyond that count. It
+// limits the number of rows, not the size of the table on disk.
+type Policy struct {
+	MaxAge  models.Duration            `json:"max_age,omitempty"`
+	Levels  map[string]models.Duration `json:"levels,omitempty"`
+	Types   map[string]models.Duration `json:"types,omitempty"`
+	MaxRows int64                      `json:"max_rows,omitempty"`
+
+	// Interval is the time between purges.
+	Interval models.Duration `json:"interval,omitempty"`
+	// BatchSize is the number of rows deleted per statement, and BatchPause
+	// the delay between statements, so that no purge holds locks for long.
+	BatchSize  int             `json:"batch_size,omitempty"`
+	BatchPause models.Duration `json:"batch_pause,omitempty"`
+	// DryRun only counts what would be deleted.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// withDefaults fills in unset fields.
+func (p Policy) withDefaults() Policy {
+	if p.Interval <= 0 {
+		p.Interval = models.Duration(DefaultInterval)
+	}
+	if p.BatchSize <= 0 {
+		p.BatchSize = DefaultBatchSize
+	}
+	if p.BatchPause <= 0 {
+		p.BatchPause = models.Duration(DefaultBatchPause)
+	}
+	return p
+}
+
+// Validate checks the TTLs, limits and schedule.
+func (p Policy) Validate() error {
+	if p.MaxAge < 0 {
+		return errors.New("max_age must not be negative")
+	}
+	if p.MaxRows < 0 {
+		return errors.New("max_rows must not be negative")
+	}
+	if p.MaxAge == 0 && p.MaxRows == 0 && len(p.Levels) == 0 && len(p.Types) == 0 {
+		return errors.New("set max_age, max_rows or a level or type TTL")
+	}
+	for l, ttl := range p.Levels {
+		if !models.ValidLevel(l) {
+			return fmt.Errorf("invalid level %q", l)
+		}
+		if ttl <= 0 {
+			return fmt.Errorf("TTL of level %s must be positive", l)
+		}
+	}
+	for t, ttl := range p.Types {
+		if !models.ValidType(t) {
+			return fmt.Errorf("invalid type %q", t)
+		}
+		if ttl <= 0 {
+			return fmt.Errorf("TTL of type %s must be positive", t)
+		}
+	}
+	if p.Interval != 0 && time.Duration(p.Interval) < MinInterval {
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+	if p.BatchSize < 0 || p.BatchSize > MaxBatchSize {
+		return fmt.Errorf("batch_size must be between 1 and %d", MaxBatchSize)
+	}
+	return nil
+}
+
+// Horizon returns the age after which every entry has expired, which allows
+// whole partitions to be dropped. It is false when some entries are kept
+// forever.
+func (p Policy) Horizon() (time.Duration, bool) {
+	if p.MaxAge == 0 {
+		// Only level TTLs apply to every entry, and only if each level
+		// has one.
+		for _, l := range models.CurrentRegistry().LevelNames() {
+			if _, ok := p.Levels[l]; !ok {
+				return 0, false
+			}
+		}
+	}
+	horizon := time.Duration(p.MaxAge)
+	for _, ttl := range p.Levels {
+		horizon = max(horizon, time.Duration(ttl))
+	}
+	if p.MaxAge > 0 {
+		for _, ttl := range p.Types {
+			horizon = max(horizon, time.Duration(ttl))
+		}
+	}
+	return horizon, horizon > 0
+}
+
+// Condition selects entries to purge. Nil Levels or Types match any value;
+// entries with a level in ExceptLevels or a type in ExceptTypes never match.
+type Condition struct {
+	Before       time.Time
+	Levels       []string
+	Types        []string
+	ExceptLevels []string
+	ExceptTypes  []string
+}
+
+// Matches reports whether l satisfies the condition, for stores that keep
+// entries in memory.
+func (c Condition) Matches(l models.Log) bool {
+	if !c.Before.IsZero() && !l.Timestamp.Before(c.Before) {
+		return false
+	}
+	if c.Levels != nil && !slices.Contains(c.Levels, l.Level) {
+		return false
+	}
+	if c.Types != nil && !slices.Contains(c.Types, l.Type) {
+		return false
+	}
+	return !slices.Contains(c.ExceptLevels, l.Level) && !slices.Contains(c.ExceptTypes, l.Type)
+}
+
+// rule is one age-based part of a policy.
+type rule struct {
+	name string
+	cond Condition
+}
+
+// rules turns the TTLs into disjoint conditions relative to now, most
+// specific first.
+func (p Policy) rules(now time.Time) []rule {
+	levels := sortedKeys(p.Levels)
+	types := sortedKeys(p.Types)
+
+	var rules []rule
+	for _, l := range levels {
+		rules = append(rules, rule{
+			name: "level " + l,
+			cond: Condition{Before: now.Add(-time.Duration(p.Levels[l])), Levels: []string{l}},
+		})
+	}
+	for _, t := range types {
+		rules = append(rules, rule{
+			name: "type " + t,
+			cond: Condition{Before: now.Add(-time.Duration(p.Types[t])), Types: []string{t}, ExceptLevels: levels},
+		})
+	}
+	if p.MaxAge > 0 {
+		rules = append(rules, rule{
+			name: "max_age",
+			cond: Condition{Before: now.Add(-time.Duration(p.MaxAge)), ExceptLevels: levels, ExceptTypes: types},
+		})
+	}
+	return rules
+}
+
+func sortedKeys(m map[string]models.Duration) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoadPolicy reads a JSON policy from path.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policy{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return Policy{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,136 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func days(n int) models.Duration {
+	return models.Duration(time.Duration(n) * 24 * time.Hour)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"empty", Policy{}, "set max_age"},
+		{"negative age", Policy{MaxAge: -1}, "max_age"},
+		{"negative rows", Policy{MaxRows: -1}, "max_rows"},
+		{"unknown level", Policy{Levels: map[string]models.Duration{"TRACE": days(1)}}, "invalid level"},
+		{"zero level TTL", Policy{Levels: map[string]models.Duration{"DEBUG": 0}}, "must be positive"},
+		{"unknown type", Policy{Types: map[string]models.Duration{"NETWORK": days(1)}}, "invalid type"},
+		{"short interval", Policy{MaxAge: days(1), Interval: models.Duration(time.Second)}, "interval"},
+		{"huge batch", Policy{MaxAge: days(1), BatchSize: MaxBatchSize + 1}, "batch_size"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+	if err := (Policy{MaxRows: 1000}).Validate(); err != nil {
+		t.Errorf("Validate() of max_rows only: %v", err)
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	p := Policy{
+		MaxAge: days(30),
+		Levels: map[string]models.Duration{"ERROR": days(90), "DEBUG": days(3)},
+		Types:  map[string]models.Duration{"AUTH": days(180)},
+	}
+	rules := p.rules(now)
+	if len(rules) != 4 {
+		t.Fatalf("rules() = %d rules, want 4", len(rules))
+	}
+
+	entry := func(age int, level, typ string) models.Log {
+		return models.Log{Timestamp: now.AddDate(0, 0, -age), Level: level, Type: typ}
+	}
+	tests := []struct {
+		name string
+		log  models.Log
+		rule string // the rule that purges it, or "" to keep it
+	}{
+		{"old debug", entry(4, "DEBUG", "API"), "level DEBUG"},
+		{"recent debug", entry(2, "DEBUG", "API"), ""},
+		{"error kept past max_age", entry(60, "ERROR", "API"), ""},
+		{"old error", entry(91, "ERROR", "API"), "level ERROR"},
+		{"level TTL wins over type TTL", entry(4, "DEBUG", "AUTH"), "level DEBUG"},
+		{"auth kept past max_age", entry(60, "INFO", "AUTH"), ""},
+		{"old auth", entry(181, "INFO", "AUTH"), "type AUTH"},
+		{"old info", entry(31, "INFO", "API"), "max_age"},
+		{"recent info", entry(29, "INFO", "API"), ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var matched []string
+			for _, r := range rules {
+				if r.cond.Matches(tc.log) {
+					matched = append(matched, r.name)
+				}
+			}
+			if (tc.rule == "" && len(matched) != 0) || (tc.rule != "" && (len(matched) != 1 || matched[0] != tc.rule)) {
+				t.Errorf("matching rules = %v, want %q", matched, tc.rule)
+			}
+		})
+	}
+}
+
+func TestPolicyHorizon(t *testing.T) {
+	allLevels := make(map[string]models.Duration)
+	for _, l := range models.CurrentRegistry().LevelNames() {
+		allLevels[l] = days(7)
+	}
+	allLevels["ERROR"] = days(90)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   models.Duration // 0 when entries may be kept forever
+	}{
+		{"max_age", Policy{MaxAge: days(30)}, days(30)},
+		{"longer level and type TTLs", Policy{MaxAge: days(30), Levels: map[string]models.Duration{"ERROR": days(90)}, Types: map[string]models.Duration{"AUTH": days(180)}}, days(180)},
+		{"some levels without TTL", Policy{Levels: map[string]models.Duration{"DEBUG": days(3)}}, 0},
+		{"every level", Policy{Levels: allLevels, Types: map[string]models.Duration{"AUTH": days(180)}}, days(90)},
+		{"max_rows only", Policy{MaxRows: 1000}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.policy.Horizon()
+			if ok != (tc.want > 0) || models.Duration(got) != tc.want {
+				t.Errorf("Horizon() = %v, %v, want %v", got, ok, time.Duration(tc.want))
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retention.json")
+	os.WriteFile(path, []byte(`{"max_age": "30d", "levels": {"DEBUG": "72h"}, "max_rows": 1000000, "dry_run": true}`), 0o644)
+
+	p, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error: %v", err)
+	}
+	if p.MaxAge != days(30) || p.Levels["DEBUG"] != days(3) || p.MaxRows != 1000000 || !p.DryRun {
+		t.Errorf("LoadPolicy() = %+v", p)
+	}
+	if d := p.withDefaults(); time.Duration(d.Interval) != DefaultInterval || d.BatchSize != DefaultBatchSize {
+		t.Errorf("defaults = %+v", d)
+	}
+
+	os.WriteFile(path, []byte(`{"levels": {"TRACE": "1d"}}`), 0o644)
+	if _, err := LoadPolicy(path); err == nil {
+		t.Error("LoadPolicy() accepted an unknown level")
+	}
+}
@@ -0,0 +1,221 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrRunning is returned by Run while another purge is in progress.
+var ErrRunning = errors.New("a purge is already running")
+
+// Store counts and deletes log entries.
+type Store interface {
+	CountLogs(c Condition) (int64, error)
+	// DeleteLogs deletes up to limit of the oldest entries matching c and
+	// returns how many were deleted.
+	DeleteLogs(c Condition, limit int) (int64, error)
+}
+
+// RuleResult reports what one part of the policy deleted, or would have
+// deleted in a dry run.
+type RuleResult struct {
+	Rule string `json:"rule"`
+	// Before is the cutoff of age-based rules.
+	Before  *time.Time `json:"before,omitempty"`
+	Deleted int64      `json:"deleted"`
+}
+
+// Run is the outcome of one purge.
+type Run struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	DryRun     bool         `json:"dry_run"`
+	Deleted    int64        `json:"deleted"`
+	Rules      []RuleResult `json:"rules"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// Status describes the purger for monitoring.
+type Status struct {
+	Policy  Policy    `json:"policy"`
+	Running bool      `json:"running"`
+	LastRun *Run      `json:"last_run,omitempty"`
+	NextRun time.Time `json:"next_run"`
+}
+
+// Purger applies a Policy periodically.
+type Purger struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+	wg     sync.WaitGroup
+
+	// run serializes purges; mu guards the fields below.
+	run     sync.Mutex
+	mu      sync.Mutex
+	running bool
+	last    *Run
+	next    time.Time
+}
+
+// NewPurger creates a Purger applying policy to store. Call Start to purge
+// on schedule.
+func NewPurger(store Store, policy Policy) *Purger {
+	return &Purger{store: store, policy: policy.withDefaults(), now: time.Now}
+}
+
+// Start purges immediately and then every policy interval until ctx is
+// done.
+func (p *Purger) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		interval := time.Duration(p.policy.Interval)
+		for {
+			next := p.now().Add(interval)
+			p.setNext(next)
+			if _, err := p.Run(ctx, false); err != nil && !errors.Is(err, ErrRunning) && ctx.Err() == nil {
+				log.Printf("Error purging logs: %v", err)
+			}
+			timer := time.NewTimer(next.Sub(p.now()))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the goroutine started by Start has returned.
+func (p *Purger) Wait() {
+	p.wg.Wait()
+}
+
+// Run purges once. With dryRun, or when the policy is a dry run, entries
+// are only counted. It returns ErrRunning without purging while another
+// purge is in progress.
+func (p *Purger) Run(ctx context.Context, dryRun bool) (Run, error) {
+	if !p.run.TryLock() {
+		return Run{}, ErrRunning
+	}
+	defer p.run.Unlock()
+	p.setRunning(true)
+
+	now := p.now()
+	r := Run{StartedAt: now.UTC(), DryRun: dryRun || p.policy.DryRun}
+	err := p.purge(ctx, now, &r)
+	r.FinishedAt = p.now().UTC()
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	switch {
+	case r.DryRun:
+		log.Printf("Retention dry run: %d entries would be deleted", r.Deleted)
+	case r.Deleted > 0:
+		log.Printf("Retention purge deleted %d entries in %s", r.Deleted, r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond))
+	}
+
+	p.mu.Lock()
+	p.running = false
+	p.last = &r
+	p.mu.Unlock()
+	return r, err
+}
+
+func (p *Purger) purge(ctx context.Context, now time.Time, r *Run) error {
+	for _, rule := range p.policy.rules(now) {
+		before := rule.cond.Before.UTC()
+		res := RuleResult{Rule: rule.name, Before: &before}
+		n, err := p.apply(ctx, rule.cond, -1, r.DryRun)
+		res.Deleted = n
+		r.Deleted += n
+		r.Rules = append(r.Rules, res)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.policy.MaxRows > 0 {
+		total, err := p.store.CountLogs(Condition{})
+		if err != nil {
+			return err
+		}
+		if r.DryRun {
+			// The age rules above did not delete anything.
+			total -= r.Deleted
+		}
+		res := RuleResult{Rule: "max_rows"}
+		if excess := total - p.policy.MaxRows; excess > 0 {
+			n, err := p.apply(ctx, Condition{}, excess, r.DryRun)
+			res.Deleted = n
+			r.Deleted += n
+			r.Rules = append(r.Rules, res)
+			return err
+		}
+		r.Rules = append(r.Rules, res)
+	}
+	return nil
+}
+
+// apply deletes entries matching c in batches, at most max of them unless
+// max is negative. A dry run counts them instead.
+func (p *Purger) apply(ctx context.Context, c Condition, max int64, dryRun bool) (int64, error) {
+	if dryRun {
+		if max >= 0 {
+			return max, nil
+		}
+		return p.store.CountLogs(c)
+	}
+
+	var deleted int64
+	for max < 0 || deleted < max {
+		limit := p.policy.BatchSize
+		if max >= 0 {
+			limit = int(min(int64(limit), max-deleted))
+		}
+		n, err := p.store.DeleteLogs(c, limit)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if n < int64(limit) {
+			break
+		}
+		select {
+		case <-time.After(time.Duration(p.policy.BatchPause)):
+		case <-ctx.Done():
+			return deleted, ctx.Err()
+		}
+	}
+	return deleted, nil
+}
+
+// Policy returns the policy with defaults filled in.
+func (p *Purger) Policy() Policy {
+	return p.policy
+}
+
+// Status returns the policy and the outcome of the last purge.
+func (p *Purger) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Status{Policy: p.policy, Running: p.running, LastRun: p.last, NextRun: p.next}
+}
+
+func (p *Purger) setRunning(running bool) {
+	p.mu.Lock()
+	p.running = running
+	p.mu.Unlock()
+}
+
+func (p *Purger) setNext(t time.Time) {
+	p.mu.Lock()
+	p.next = t.UTC()
+	p.mu.Unlock()
+}
@@ -0,0 +1,147 @@
+package retention
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// fakeStore keeps entries oldest first.
+type fakeStore struct {
+	mu      sync.Mutex
+	logs    []models.Log
+	deletes int
+	block   chan struct{}
+}
+
+func (s *fakeStore) CountLogs(c Condition) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, l := range s.logs {
+		if c.Matches(l) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakeStore) DeleteLogs(c Condition, limit int) (int64, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletes++
+	var kept []models.Log
+	var n int64
+	for _, l := range s.logs {
+		if n < int64(limit) && c.Matches(l) {
+			n++
+			continue
+		}
+		kept = append(kept, l)
+	}
+	s.logs = kept
+	return n, nil
+}
+
+func newFakeStore(now time.Time, ages ...int) *fakeStore {
+	s := &fakeStore{}
+	sort.Sort(sort.Reverse(sort.IntSlice(ages)))
+	for i, age := range ages {
+		s.logs = append(s.logs, models.Log{ID: i + 1, Timestamp: now.AddDate(0, 0, -age), Level: "INFO", Type: "API"})
+	}
+	return s
+}
+
+func TestPurgerRun(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := newFakeStore(now, 40, 39, 38, 37, 36, 20, 10, 5, 1)
+	p := NewPurger(store, Policy{MaxAge: days(30), MaxRows: 3, BatchSize: 2, BatchPause: models.Duration(time.Nanosecond)})
+	p.now = func() time.Time { return now }
+
+	dry, err := p.Run(context.Background(), true)
+	if err != nil || !dry.DryRun || dry.Deleted != 6 || len(store.logs) != 9 || store.deletes != 0 {
+		t.Fatalf("dry run = %+v, %v; %d entries left", dry, err, len(store.logs))
+	}
+
+	run, err := p.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if run.Deleted != 6 || len(run.Rules) != 2 || run.Rules[0].Deleted != 5 || run.Rules[1].Rule != "max_rows" || run.Rules[1].Deleted != 1 {
+		t.Errorf("Run() = %+v", run)
+	}
+	if len(store.logs) != 3 || store.logs[0].ID != 7 {
+		t.Errorf("remaining entries = %+v, want the 3 newest", store.logs)
+	}
+	// Five old entries in batches of two take three statements, the one
+	// excess row a fourth.
+	if store.deletes != 4 {
+		t.Errorf("DeleteLogs() called %d times, want 4", store.deletes)
+	}
+	if st := p.Status(); st.LastRun == nil || st.LastRun.Deleted != 6 || st.Running {
+		t.Errorf("Status() = %+v", st)
+	}
+}
+
+func TestPurgerPolicyDryRun(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now, 40, 1)
+	p := NewPurger(store, Policy{MaxAge: days(30), DryRun: true})
+
+	run, err := p.Run(context.Background(), false)
+	if err != nil || !run.DryRun || run.Deleted != 1 || len(store.logs) != 2 {
+		t.Errorf("Run() = %+v, %v; %d entries left", run, err, len(store.logs))
+	}
+}
+
+func TestPurgerRunning(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now, 40)
+	store.block = make(chan struct{})
+	p := NewPurger(store, Policy{MaxAge: days(30)})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(context.Background(), false)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for !p.Status().Running {
+		if time.Now().After(deadline) {
+			t.Fatal("purge did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := p.Run(context.Background(), false); err != ErrRunning {
+		t.Errorf("concurrent Run() error = %v, want ErrRunning", err)
+	}
+	close(store.block)
+	<-done
+}
+
+func TestPurgerStart(t *testing.T) {
+	store := newFakeStore(time.Now(), 40, 1)
+	p := NewPurger(store, Policy{MaxAge: days(30)})
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Status().LastRun == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Start() did not purge")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	p.Wait()
+	if st := p.Status(); len(store.logs) != 1 || st.NextRun.IsZero() {
+		t.Errorf("after Start(): %d entries, status %+v", len(store.logs), st)
+	}
+}
@@ -0,0 +1,256 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	ruleColumns  = "id, name, levels, types, min_level, attributes, pattern, threshold, window_seconds, enabled, created_at, updated_at"
+	alertColumns = "id, rule_id, rule_name, state, count, message, log_id, fired_at, resolved_at"
+)
+
+// AlertStore keeps alert rules and history in the same database file as
+// the logs.
+type AlertStore struct {
+	db *sql.DB
+}
+
+// AlertStore returns an alerting.Store sharing the store's database.
+func (s *Store) AlertStore() *AlertStore {
+	return &AlertStore{db: s.db}
+}
+
+// ListRules returns all rules ordered by id.
+func (s *AlertStore) ListRules() ([]alerting.Rule, error) {
+	rows, err := s.db.Query("SELECT " + ruleColumns + " FROM alert_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []alerting.Rule
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule returns the rule with the given id.
+func (s *AlertStore) GetRule(id int) (alerting.Rule, error) {
+	r, err := scanRule(s.db.QueryRow("SELECT "+ruleColumns+" FROM alert_rules WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, alerting.ErrNotFound
+	}
+	return r, err
+}
+
+// CreateRule inserts r and returns it with its id and timestamps.
+func (s *AlertStore) CreateRule(r alerting.Rule) (alerting.Rule, error) {
+	levels, types, attrs, err := marshalRule(r)
+	if err != nil {
+		return r, err
+	}
+	r.CreatedAt = time.Now().UTC()
+	r.UpdatedAt = r.CreatedAt
+	res, err := s.db.Exec(
+		`INSERT INTO alert_rules (name, levels, types, min_level, attributes, pattern, threshold, window_seconds, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Name, levels, types, r.MinLevel, attrs, r.Pattern, r.Threshold, windowSeconds(r.Window), r.Enabled,
+		formatTime(r.CreatedAt), formatTime(r.UpdatedAt),
+	)
+	if err != nil {
+		return r, ruleError(err)
+	}
+	id, err := res.LastInsertId()
+	r.ID = int(id)
+	return r, err
+}
+
+// UpdateRule replaces the rule with r.ID.
+func (s *AlertStore) UpdateRule(r alerting.Rule) (alerting.Rule, error) {
+	existing, err := s.GetRule(r.ID)
+	if err != nil {
+		return r, err
+	}
+	levels, types, attrs, err := marshalRule(r)
+	if err != nil {
+		return r, err
+	}
+	r.CreatedAt = existing.CreatedAt
+	r.UpdatedAt = time.Now().UTC()
+	_, err = s.db.Exec(
+		`UPDATE alert_rules SET name = ?, levels = ?, types = ?, min_level = ?, attributes = ?, pattern = ?,
+		threshold = ?, window_seconds = ?, enabled = ?, updated_at = ? WHERE id = ?`,
+		r.Name, levels, types, r.MinLevel, attrs, r.Pattern, r.Threshold, windowSeconds(r.Window), r.Enabled,
+		formatTime(r.UpdatedAt), r.ID,
+	)
+	return r, ruleError(err)
+}
+
+// DeleteRule removes the rule with the given id. Its history is kept with
+// rule_id cleared.
+func (s *AlertStore) DeleteRule(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return alerting.ErrNotFound
+	}
+	if _, err := tx.Exec("UPDATE alert_history SET rule_id = NULL WHERE rule_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// InsertAlert records a firing alert and returns its id.
+func (s *AlertStore) InsertAlert(a alerting.Alert) (int, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO alert_history (rule_id, rule_name, state, count, message, log_id, fired_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		nullInt(a.RuleID), a.RuleName, a.State, a.Count, a.Message, nullInt(a.LogID), formatTime(a.FiredAt),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// ResolveAlert marks an alert as resolved with its final count.
+func (s *AlertStore) ResolveAlert(id, count int, at time.Time) error {
+	_, err := s.db.Exec(
+		"UPDATE alert_history SET state = ?, count = ?, resolved_at = ? WHERE id = ?",
+		alerting.StateResolved, count, formatTime(at), id,
+	)
+	return err
+}
+
+// ListAlerts returns matching alerts, newest first.
+func (s *AlertStore) ListAlerts(filter alerting.AlertFilter) ([]alerting.Alert, error) {
+	where := " WHERE 1=1"
+	var args []any
+	if filter.RuleID != 0 {
+		where += " AND rule_id = ?"
+		args = append(args, filter.RuleID)
+	}
+	if filter.State != "" {
+		where += " AND state = ?"
+		args = append(args, filter.State)
+	}
+	args = append(args, filter.PageSize())
+
+	rows, err := s.db.Query("SELECT "+alertColumns+" FROM alert_history"+where+" ORDER BY fired_at DESC, id DESC LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []alerting.Alert
+	for rows.Next() {
+		var a alerting.Alert
+		var ruleID, logID sql.NullInt64
+		var firedAt string
+		var resolvedAt sql.NullString
+		if err := rows.Scan(&a.ID, &ruleID, &a.RuleName, &a.State, &a.Count, &a.Message, &logID, &firedAt, &resolvedAt); err != nil {
+			return nil, err
+		}
+		a.RuleID = int(ruleID.Int64)
+		a.LogID = int(logID.Int64)
+		if a.FiredAt, err = time.Parse(time.RFC3339Nano, firedAt); err != nil {
+			return nil, fmt.Errorf("parsing fired_at of alert %d: %w", a.ID, err)
+		}
+		if resolvedAt.Valid {
+			t, err := time.Parse(time.RFC3339Nano, resolvedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("parsing resolved_at of alert %d: %w", a.ID, err)
+			}
+			a.ResolvedAt = &t
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func scanRule(row rowScanner) (alerting.Rule, error) {
+	var r alerting.Rule
+	var levels, types, attrs, createdAt, updatedAt string
+	var window int64
+	err := row.Scan(&r.ID, &r.Name, &levels, &types, &r.MinLevel, &attrs,
+		&r.Pattern, &r.Threshold, &window, &r.Enabled, &createdAt, &updatedAt)
+	if err != nil {
+		return r, err
+	}
+	r.Window = models.Duration(time.Duration(window) * time.Second)
+	for _, f := range []struct {
+		data string
+		dest any
+	}{{levels, &r.Levels}, {types, &r.Types}, {attrs, &r.Attributes}} {
+		if err := json.Unmarshal([]byte(f.data), f.dest); err != nil {
+			return r, fmt.Errorf("decoding alert rule %d: %w", r.ID, err)
+		}
+	}
+	if len(r.Attributes) == 0 {
+		r.Attributes = nil
+	}
+	if r.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return r, fmt.Errorf("parsing created_at of alert rule %d: %w", r.ID, err)
+	}
+	if r.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return r, fmt.Errorf("parsing updated_at of alert rule %d: %w", r.ID, err)
+	}
+	return r, nil
+}
+
+func marshalRule(r alerting.Rule) (levels, types, attrs string, err error) {
+	encode := func(v any, empty string) string {
+		if err != nil {
+			return ""
+		}
+		var b []byte
+		b, err = json.Marshal(v)
+		if string(b) == "null" {
+			return empty
+		}
+		return string(b)
+	}
+	levels = encode(r.Levels, "[]")
+	types = encode(r.Types, "[]")
+	attrs = encode(r.Attributes, "{}")
+	return levels, types, attrs, err
+}
+
+func windowSeconds(d models.Duration) int64 {
+	return int64(time.Duration(d) / time.Second)
+}
+
+// ruleError maps a unique constraint violation on the rule name to
+// alerting.ErrNameTaken.
+func ruleError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return alerting.ErrNameTaken
+	}
+	return err
+}
+
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
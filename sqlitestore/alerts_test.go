@@ -0,0 +1,81 @@
+package sqlitestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+func TestAlertRules(t *testing.T) {
+	store, _ := openTestStore(t)
+	alerts := store.AlertStore()
+
+	r := alerting.NewRule()
+	r.Name = "database errors"
+	r.Levels = []string{"ERROR"}
+	r.Attributes = map[string]string{"region": "eu"}
+	r.Threshold = 20
+	created, err := alerts.CreateRule(r)
+	if err != nil || created.ID != 1 {
+		t.Fatalf("CreateRule() = %+v, %v", created, err)
+	}
+	if _, err := alerts.CreateRule(r); err != alerting.ErrNameTaken {
+		t.Errorf("duplicate CreateRule() error = %v, want ErrNameTaken", err)
+	}
+
+	created.Enabled = false
+	created.Window = models.Duration(time.Minute)
+	if _, err := alerts.UpdateRule(created); err != nil {
+		t.Fatalf("UpdateRule() error: %v", err)
+	}
+	got, err := alerts.GetRule(created.ID)
+	if err != nil {
+		t.Fatalf("GetRule() error: %v", err)
+	}
+	if got.Enabled || time.Duration(got.Window) != time.Minute || got.Levels[0] != "ERROR" ||
+		got.Attributes["region"] != "eu" || len(got.Types) != 0 || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("GetRule() = %+v", got)
+	}
+
+	if _, err := alerts.UpdateRule(alerting.Rule{ID: 42, Name: "x"}); err != alerting.ErrNotFound {
+		t.Errorf("UpdateRule() of unknown id error = %v", err)
+	}
+	if err := alerts.DeleteRule(42); err != alerting.ErrNotFound {
+		t.Errorf("DeleteRule() of unknown id error = %v", err)
+	}
+}
+
+func TestAlertHistory(t *testing.T) {
+	store, _ := openTestStore(t)
+	alerts := store.AlertStore()
+
+	r := alerting.NewRule()
+	r.Name = "panics"
+	rule, err := alerts.CreateRule(r)
+	if err != nil {
+		t.Fatalf("CreateRule() error: %v", err)
+	}
+
+	fired := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	first, _ := alerts.InsertAlert(alerting.Alert{RuleID: rule.ID, RuleName: rule.Name, State: alerting.StateFiring, Count: 1, Message: "panic: a", LogID: 7, FiredAt: fired})
+	second, _ := alerts.InsertAlert(alerting.Alert{RuleID: rule.ID, RuleName: rule.Name, State: alerting.StateFiring, Count: 1, Message: "panic: b", FiredAt: fired.Add(time.Hour)})
+	if err := alerts.ResolveAlert(first, 3, fired.Add(time.Minute)); err != nil {
+		t.Fatalf("ResolveAlert() error: %v", err)
+	}
+
+	firing, err := alerts.ListAlerts(alerting.AlertFilter{State: alerting.StateFiring})
+	if err != nil || len(firing) != 1 || firing[0].ID != second {
+		t.Fatalf("firing alerts = %+v, %v", firing, err)
+	}
+
+	if err := alerts.DeleteRule(rule.ID); err != nil {
+		t.Fatalf("DeleteRule() error: %v", err)
+	}
+	all, _ := alerts.ListAlerts(alerting.AlertFilter{})
+	if len(all) != 2 || all[1].ID != first || all[1].RuleID != 0 || all[1].Count != 3 || all[1].LogID != 7 ||
+		all[1].ResolvedAt == nil || !all[1].ResolvedAt.Equal(fired.Add(time.Minute)) {
+		t.Errorf("history after deleting the rule = %+v", all)
+	}
+}
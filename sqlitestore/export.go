@@ -0,0 +1,43 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// ExportLogs calls fn with every entry matching the filter, oldest first by
+// the filter's time field, ignoring its pagination fields. Rows are read one at a time within a
+// read-only transaction, which sees a consistent snapshot of the file. An
+// error from fn stops the export and is returned.
+func (s *Store) ExportLogs(ctx context.Context, filter models.LogFilter, fn func(models.Log) error) error {
+	where, args := whereClause(filter)
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+logColumns+" FROM logs"+where+" ORDER BY "+filter.TimeColumn()+", id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	return tx.Commit()
+}
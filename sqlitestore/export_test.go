@@ -0,0 +1,40 @@
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestExportLogs(t *testing.T) {
+	s, _ := openTestStore(t)
+	insert(t, s,
+		models.Log{Level: "ERROR", Type: "API", Message: "a"},
+		models.Log{Level: "INFO", Type: "API", Message: "b"},
+		models.Log{Level: "ERROR", Type: "DATABASE", Message: "c", Attributes: map[string]any{"table": "users"}},
+	)
+
+	var got []models.Log
+	err := s.ExportLogs(context.Background(), models.LogFilter{Levels: []string{"ERROR"}, Limit: 1}, func(l models.Log) error {
+		got = append(got, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportLogs() error: %v", err)
+	}
+	if len(got) != 2 || got[0].Message != "a" || got[1].Attributes["table"] != "users" {
+		t.Errorf("ExportLogs() = %+v, want both errors oldest first", got)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = s.ExportLogs(context.Background(), models.LogFilter{}, func(models.Log) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("ExportLogs() = %v after %d calls, want the callback error after 1", err, calls)
+	}
+}
@@ -0,0 +1,48 @@
+package sqlitestore
+
+import (
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// ImportLogs inserts archived entries in a single transaction, keeping their
+// timestamps; entries without one are stamped with the current time. Unless
+// notify is set, the rows are marked quiet and listeners skip them. It
+// returns the number of rows inserted.
+func (s *Store) ImportLogs(entries []models.Log, notify bool) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO logs (timestamp, received_at, level, type, message, attributes, tenant, quiet) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, e := range entries {
+		attrs, err := marshalAttributes(e.Attributes)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := stmt.Exec(formatTime(eventTime(e, now)), formatTime(now), e.Level, e.Type, e.Message, attrs, e.Tenant, !notify); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if notify {
+		s.wakeListeners()
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,42 @@
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestImportLogs(t *testing.T) {
+	store, _ := openTestStore(t)
+	store.SetPollInterval(20 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan models.Log)
+	if err := store.ListenForLogs(ctx, ch); err != nil {
+		t.Fatalf("ListenForLogs() error: %v", err)
+	}
+
+	ts := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	n, err := store.ImportLogs([]models.Log{
+		{Timestamp: ts, Level: models.LevelError, Type: models.TypeAPI, Message: "archived", Attributes: map[string]any{"code": "500"}},
+		{Level: models.LevelInfo, Type: models.TypeAPI, Message: "undated"},
+	}, false)
+	if err != nil || n != 2 {
+		t.Fatalf("ImportLogs() = %d, %v", n, err)
+	}
+	if n, err := store.ImportLogs([]models.Log{{Timestamp: ts.Add(time.Second), Level: models.LevelInfo, Type: models.TypeAPI, Message: "announced"}}, true); err != nil || n != 1 {
+		t.Fatalf("ImportLogs(notify) = %d, %v", n, err)
+	}
+
+	// Only the entry imported with notify reaches listeners.
+	if l := receive(t, ch); l.Message != "announced" {
+		t.Errorf("received %q, want announced", l.Message)
+	}
+
+	logs, err := store.GetLogs(models.LogFilter{Until: ts.Add(time.Hour)})
+	if err != nil || len(logs) != 2 || !logs[1].Timestamp.Equal(ts) || logs[1].Attributes["code"] != "500" {
+		t.Errorf("GetLogs() = %+v, %v, want the archived timestamps kept", logs, err)
+	}
+}
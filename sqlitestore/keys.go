@@ -0,0 +1,144 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mstgnz/golog/auth"
+	"github.com/mstgnz/golog/models"
+)
+
+const keyColumns = "id, name, prefix, key_hash, scopes, created_at, revoked_at, tenant"
+
+// KeyStore keeps API keys and failed authentication attempts in the same
+// database file as the logs.
+type KeyStore struct {
+	db *sql.DB
+}
+
+// KeyStore returns an auth.Store sharing the store's database.
+func (s *Store) KeyStore() *KeyStore {
+	return &KeyStore{db: s.db}
+}
+
+// CreateKey inserts k and returns it with its id and creation time.
+func (s *KeyStore) CreateKey(k auth.Key) (auth.Key, error) {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return k, err
+	}
+	k.CreatedAt = time.Now().UTC()
+	res, err := s.db.Exec(
+		"INSERT INTO api_keys (name, prefix, key_hash, scopes, created_at, tenant) VALUES (?, ?, ?, ?, ?, ?)",
+		k.Name, k.Prefix, k.Hash, string(scopes), formatTime(k.CreatedAt), k.Tenant,
+	)
+	if err != nil {
+		return k, err
+	}
+	id, err := res.LastInsertId()
+	k.ID = int(id)
+	return k, err
+}
+
+// KeyByHash returns the key with the given token hash.
+func (s *KeyStore) KeyByHash(hash string) (auth.Key, error) {
+	k, err := scanKey(s.db.QueryRow("SELECT "+keyColumns+" FROM api_keys WHERE key_hash = ?", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, auth.ErrNotFound
+	}
+	return k, err
+}
+
+// ListKeys returns all keys ordered by id.
+func (s *KeyStore) ListKeys() ([]auth.Key, error) {
+	rows, err := s.db.Query("SELECT " + keyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []auth.Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeKey marks the key with the given id revoked.
+func (s *KeyStore) RevokeKey(id int) error {
+	res, err := s.db.Exec("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", formatTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return auth.ErrNotFound
+	}
+	return nil
+}
+
+// RecordFailure stores a rejected request.
+func (s *KeyStore) RecordFailure(f auth.Failure) error {
+	_, err := s.db.Exec(
+		`INSERT INTO auth_failures (key_prefix, remote_addr, method, path, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		f.KeyPrefix, f.RemoteAddr, f.Method, f.Path, f.Reason, formatTime(f.CreatedAt),
+	)
+	return err
+}
+
+// ListFailures returns up to limit rejected requests, newest first.
+func (s *KeyStore) ListFailures(limit int) ([]auth.Failure, error) {
+	rows, err := s.db.Query(
+		"SELECT id, key_prefix, remote_addr, method, path, reason, created_at FROM auth_failures ORDER BY id DESC LIMIT ?",
+		models.LogFilter{Limit: limit}.PageSize(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []auth.Failure
+	for rows.Next() {
+		var f auth.Failure
+		var createdAt string
+		if err := rows.Scan(&f.ID, &f.KeyPrefix, &f.RemoteAddr, &f.Method, &f.Path, &f.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		if f.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+			return nil, fmt.Errorf("parsing created_at of auth failure %d: %w", f.ID, err)
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+func scanKey(row rowScanner) (auth.Key, error) {
+	var k auth.Key
+	var scopes, createdAt string
+	var revokedAt sql.NullString
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &scopes, &createdAt, &revokedAt, &k.Tenant); err != nil {
+		return k, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+		return k, fmt.Errorf("decoding scopes of api key %d: %w", k.ID, err)
+	}
+	var err error
+	if k.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return k, fmt.Errorf("parsing created_at of api key %d: %w", k.ID, err)
+	}
+	if revokedAt.Valid {
+		t, err := time.Parse(time.RFC3339Nano, revokedAt.String)
+		if err != nil {
+			return k, fmt.Errorf("parsing revoked_at of api key %d: %w", k.ID, err)
+		}
+		k.RevokedAt = &t
+	}
+	return k, nil
+}
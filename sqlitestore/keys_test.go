@@ -0,0 +1,56 @@
+package sqlitestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/auth"
+)
+
+func TestKeyStore(t *testing.T) {
+	store, _ := openTestStore(t)
+	keys := store.KeyStore()
+
+	k, token, _ := auth.NewKey("ci", "billing", []string{auth.ScopeIngest, auth.ScopeRead})
+	created, err := keys.CreateKey(k)
+	if err != nil || created.ID != 1 {
+		t.Fatalf("CreateKey() = %+v, %v", created, err)
+	}
+	got, err := keys.KeyByHash(auth.HashToken(token))
+	if err != nil || got.Name != "ci" || got.Tenant != "billing" || len(got.Scopes) != 2 || got.Revoked() || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("KeyByHash() = %+v, %v", got, err)
+	}
+	if _, err := keys.KeyByHash("missing"); err != auth.ErrNotFound {
+		t.Errorf("KeyByHash() of unknown hash error = %v", err)
+	}
+
+	if err := keys.RevokeKey(1); err != nil {
+		t.Fatalf("RevokeKey() error: %v", err)
+	}
+	first, _ := keys.KeyByHash(k.Hash)
+	time.Sleep(time.Millisecond)
+	keys.RevokeKey(1)
+	list, _ := keys.ListKeys()
+	if len(list) != 1 || !first.Revoked() || !list[0].RevokedAt.Equal(*first.RevokedAt) {
+		t.Errorf("ListKeys() = %+v, want one key revoked once", list)
+	}
+	if err := keys.RevokeKey(9); err != auth.ErrNotFound {
+		t.Errorf("RevokeKey() of unknown id error = %v", err)
+	}
+}
+
+func TestKeyStoreFailures(t *testing.T) {
+	store, _ := openTestStore(t)
+	keys := store.KeyStore()
+
+	at := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for _, reason := range []string{"missing API key", "invalid API key"} {
+		if err := keys.RecordFailure(auth.Failure{RemoteAddr: "10.0.0.1:5123", Method: "GET", Path: "/api/logs", Reason: reason, CreatedAt: at}); err != nil {
+			t.Fatalf("RecordFailure() error: %v", err)
+		}
+	}
+	failures, err := keys.ListFailures(0)
+	if err != nil || len(failures) != 2 || failures[0].Reason != "invalid API key" || !failures[0].CreatedAt.Equal(at) {
+		t.Errorf("ListFailures() = %+v, %v", failures, err)
+	}
+}
@@ -0,0 +1,55 @@
+package sqlitestore
+
+import (
+	"strings"
+
+	"github.com/mstgnz/golog/retention"
+)
+
+// CountLogs counts the entries matching c.
+func (s *Store) CountLogs(c retention.Condition) (int64, error) {
+	where, args := retentionWhere(c)
+	var n int64
+	err := s.db.QueryRow("SELECT COUNT(*) FROM logs"+where, args...).Scan(&n)
+	return n, err
+}
+
+// DeleteLogs deletes up to limit of the oldest entries matching c.
+func (s *Store) DeleteLogs(c retention.Condition, limit int) (int64, error) {
+	where, args := retentionWhere(c)
+	args = append(args, limit)
+	res, err := s.db.Exec("DELETE FROM logs WHERE id IN (SELECT id FROM logs"+where+" ORDER BY timestamp, id LIMIT ?)", args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func retentionWhere(c retention.Condition) (string, []any) {
+	where := " WHERE 1=1"
+	var args []any
+	if !c.Before.IsZero() {
+		where += " AND timestamp < ?"
+		args = append(args, formatTime(c.Before))
+	}
+	if c.Levels != nil {
+		where += " AND " + anyOf("level", c.Levels, &args)
+	}
+	if c.Types != nil {
+		where += " AND " + anyOf("type", c.Types, &args)
+	}
+	where += noneOf("level", c.ExceptLevels, &args)
+	where += noneOf("type", c.ExceptTypes, &args)
+	return where, args
+}
+
+// noneOf excludes a set of values; an empty set excludes nothing.
+func noneOf(column string, values []string, args *[]any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	for _, v := range values {
+		*args = append(*args, v)
+	}
+	return " AND " + column + " NOT IN (?" + strings.Repeat(", ?", len(values)-1) + ")"
+}
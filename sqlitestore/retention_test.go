@@ -0,0 +1,52 @@
+package sqlitestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/retention"
+)
+
+func TestRetention(t *testing.T) {
+	s, _ := openTestStore(t)
+	insert(t, s,
+		models.Log{Level: "DEBUG", Type: "API", Message: "old debug"},
+		models.Log{Level: "ERROR", Type: "API", Message: "old error"},
+		models.Log{Level: "INFO", Type: "AUTH", Message: "old auth"},
+		models.Log{Level: "INFO", Type: "API", Message: "old info"},
+		models.Log{Level: "INFO", Type: "API", Message: "new info"},
+	)
+	old := time.Now().AddDate(0, 0, -10)
+	if _, err := s.db.Exec("UPDATE logs SET timestamp = ? WHERE message LIKE 'old%'", formatTime(old)); err != nil {
+		t.Fatalf("backdating entries: %v", err)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -1)
+	c := retention.Condition{Before: cutoff, ExceptLevels: []string{"ERROR"}, ExceptTypes: []string{"AUTH"}}
+	if n, err := s.CountLogs(c); err != nil || n != 2 {
+		t.Errorf("CountLogs() = %d, %v, want 2", n, err)
+	}
+	if n, err := s.CountLogs(retention.Condition{Before: cutoff, Levels: []string{"DEBUG"}}); err != nil || n != 1 {
+		t.Errorf("CountLogs(DEBUG) = %d, %v, want 1", n, err)
+	}
+
+	if n, err := s.DeleteLogs(c, 1); err != nil || n != 1 {
+		t.Fatalf("DeleteLogs() = %d, %v, want 1", n, err)
+	}
+	if n, err := s.DeleteLogs(c, 10); err != nil || n != 1 {
+		t.Fatalf("DeleteLogs() = %d, %v, want 1", n, err)
+	}
+
+	logs, err := s.GetLogs(models.LogFilter{})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	var kept []string
+	for _, l := range logs {
+		kept = append(kept, l.Message)
+	}
+	if len(kept) != 3 {
+		t.Errorf("remaining entries = %v, want error, auth and new info", kept)
+	}
+}
@@ -0,0 +1,97 @@
+-- SQLite equivalent of the PostgreSQL migrations. Timestamps are stored as
+-- fixed-width UTC RFC3339 strings so that text ordering matches time ordering.
+CREATE TABLE IF NOT EXISTS logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp TEXT NOT NULL,
+    level TEXT NOT NULL,
+    type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    attributes TEXT NOT NULL DEFAULT '{}',
+    tenant TEXT NOT NULL DEFAULT '',
+    -- Set for imported rows that listeners should not be told about
+    quiet INTEGER NOT NULL DEFAULT 0,
+    -- When the entry was received; timestamp is the client's event time
+    received_at TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_logs_timestamp_id ON logs (timestamp DESC, id DESC);
+
+-- Full-text index over message, kept in sync by triggers
+CREATE VIRTUAL TABLE IF NOT EXISTS logs_fts USING fts5(
+    message,
+    content='logs',
+    content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS logs_fts_insert AFTER INSERT ON logs BEGIN
+    INSERT INTO logs_fts (rowid, message) VALUES (NEW.id, NEW.message);
+END;
+
+CREATE TRIGGER IF NOT EXISTS logs_fts_delete AFTER DELETE ON logs BEGIN
+    INSERT INTO logs_fts (logs_fts, rowid, message) VALUES ('delete', OLD.id, OLD.message);
+END;
+
+-- Alert rules and the history of alerts they raised. levels, types and
+-- attributes hold JSON.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    levels TEXT NOT NULL DEFAULT '[]',
+    types TEXT NOT NULL DEFAULT '[]',
+    min_level TEXT NOT NULL DEFAULT '',
+    attributes TEXT NOT NULL DEFAULT '{}',
+    pattern TEXT NOT NULL DEFAULT '',
+    threshold INTEGER NOT NULL DEFAULT 0,
+    window_seconds INTEGER NOT NULL,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS alert_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER,
+    rule_name TEXT NOT NULL,
+    state TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    message TEXT NOT NULL,
+    log_id INTEGER,
+    fired_at TEXT NOT NULL,
+    resolved_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_history_fired_at ON alert_history (fired_at DESC, id DESC);
+
+-- Webhook deliveries that failed after all retries
+CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    destination TEXT NOT NULL,
+    url TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+-- API keys, stored as SHA-256 hashes of the token, and rejected requests
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    revoked_at TEXT,
+    tenant TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS auth_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    key_prefix TEXT NOT NULL,
+    remote_addr TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
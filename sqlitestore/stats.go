@@ -0,0 +1,56 @@
+package sqlitestore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Stats counts the entries matching the query's filter per interval. Empty
+// intervals are omitted.
+func (s *Store) Stats(q models.StatsQuery) ([]models.StatsBucket, error) {
+	where, args := whereClause(q.Filter)
+	secs := int64(q.Interval / time.Second)
+
+	// Only level and type pass StatsQuery.Validate, so they are safe to use
+	// as column names. Rows are ordered like models.SortStats.
+	columns, order := "", "bucket"
+	for _, g := range q.GroupBy {
+		columns += ", " + g
+	}
+	for _, g := range []string{models.GroupByLevel, models.GroupByType} {
+		if q.Grouped(g) {
+			order += ", " + g
+		}
+	}
+	query := fmt.Sprintf("SELECT CAST(strftime('%%s', %s) AS INTEGER) / ? * ? AS bucket%s, COUNT(*) FROM logs%s GROUP BY bucket%s ORDER BY %s",
+		q.Filter.TimeColumn(), columns, where, columns, order)
+	args = append([]any{secs, secs}, args...)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []models.StatsBucket
+	for rows.Next() {
+		var b models.StatsBucket
+		var start int64
+		dest := []any{&start}
+		for _, g := range q.GroupBy {
+			if g == models.GroupByLevel {
+				dest = append(dest, &b.Level)
+			} else {
+				dest = append(dest, &b.Type)
+			}
+		}
+		if err := rows.Scan(append(dest, &b.Count)...); err != nil {
+			return nil, err
+		}
+		b.Start = time.Unix(start, 0).UTC()
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
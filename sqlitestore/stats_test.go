@@ -0,0 +1,44 @@
+package sqlitestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestStats(t *testing.T) {
+	s, _ := openTestStore(t)
+	insert(t, s,
+		models.Log{Level: "ERROR", Type: "API", Message: "a"},
+		models.Log{Level: "ERROR", Type: "DATABASE", Message: "b"},
+		models.Log{Level: "INFO", Type: "API", Message: "c"},
+		models.Log{Level: "ERROR", Type: "API", Message: "d"},
+	)
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for id, ts := range map[int]time.Time{1: base.Add(10 * time.Second), 2: base.Add(50 * time.Second), 3: base.Add(70 * time.Second), 4: base.Add(80 * time.Second)} {
+		if _, err := s.db.Exec("UPDATE logs SET timestamp = ? WHERE id = ?", formatTime(ts), id); err != nil {
+			t.Fatalf("setting timestamp: %v", err)
+		}
+	}
+	window := models.LogFilter{Since: base, Until: base.Add(time.Hour)}
+
+	buckets, err := s.Stats(models.StatsQuery{Filter: window, Interval: time.Minute})
+	if err != nil {
+		t.Fatalf("Stats() error: %v", err)
+	}
+	want := []models.StatsBucket{{Start: base, Count: 2}, {Start: base.Add(time.Minute), Count: 2}}
+	if len(buckets) != 2 || buckets[0] != want[0] || buckets[1] != want[1] {
+		t.Errorf("Stats() = %+v, want %+v", buckets, want)
+	}
+
+	window.Levels = []string{"ERROR"}
+	buckets, err = s.Stats(models.StatsQuery{Filter: window, Interval: time.Hour, GroupBy: []string{models.GroupByType}})
+	if err != nil {
+		t.Fatalf("Stats() error: %v", err)
+	}
+	want = []models.StatsBucket{{Start: base, Type: "API", Count: 2}, {Start: base, Type: "DATABASE", Count: 1}}
+	if len(buckets) != 2 || buckets[0] != want[0] || buckets[1] != want[1] {
+		t.Errorf("grouped Stats() = %+v, want %+v", buckets, want)
+	}
+}
@@ -0,0 +1,528 @@
+// Package sqlitestore implements the log store on SQLite for deployments
+// that cannot run PostgreSQL.
+package sqlitestore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed schema.sql
+var schema string
+
+const (
+	logColumns = "id, timestamp, level, type, message, attributes, tenant, received_at"
+
+	// timeFormat is fixed-width so that stored timestamps sort as text.
+	timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+	// DefaultPollInterval is how often listeners look for rows inserted by
+	// other processes sharing the database file.
+	DefaultPollInterval = time.Second
+
+	feedBatchSize = 500
+)
+
+func init() {
+	// SQLite rewrites "X REGEXP Y" to regexp(Y, X) but ships no implementation.
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, _ := args[0].(string)
+		value, _ := args[1].(string)
+		re, err := models.CompilePattern("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(value), nil
+	})
+}
+
+// Store implements the log store on a SQLite database file.
+type Store struct {
+	db           *sql.DB
+	pollInterval time.Duration
+
+	// inserted wakes listeners immediately after a local insert instead of
+	// waiting for the next poll.
+	mu       sync.Mutex
+	inserted map[chan struct{}]struct{}
+}
+
+// Open opens (creating if needed) the SQLite database at path and applies
+// the schema.
+func Open(path string) (*Store, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// Every connection to :memory: is a separate database.
+		db.SetMaxOpenConns(1)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying sqlite schema: %w", err)
+	}
+	if err := upgrade(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("upgrading sqlite schema: %w", err)
+	}
+	log.Printf("Opened SQLite database %s", path)
+	return newStore(db), nil
+}
+
+// addedColumns lists columns introduced after the first release. SQLite has
+// no ADD COLUMN IF NOT EXISTS, so upgrade checks for each one.
+var addedColumns = []struct{ table, column, definition string }{
+	{"logs", "tenant", "TEXT NOT NULL DEFAULT ''"},
+	{"api_keys", "tenant", "TEXT NOT NULL DEFAULT ''"},
+	{"logs", "quiet", "INTEGER NOT NULL DEFAULT 0"},
+	{"logs", "received_at", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// upgrade brings a database file created by an older version up to date.
+func upgrade(db *sql.DB) error {
+	for _, c := range addedColumns {
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", c.table, c.column).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			if _, err := db.Exec("ALTER TABLE " + c.table + " ADD COLUMN " + c.column + " " + c.definition); err != nil {
+				return err
+			}
+		}
+	}
+	// Rows from before received_at was added were stamped on receipt.
+	if _, err := db.Exec("UPDATE logs SET received_at = timestamp WHERE received_at = ''"); err != nil {
+		return err
+	}
+	// Created here rather than in schema.sql because older files only have
+	// these columns once the loop above has run.
+	for _, index := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_logs_tenant_timestamp_id ON logs (tenant, timestamp DESC, id DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_logs_received_at_id ON logs (received_at DESC, id DESC)",
+	} {
+		if _, err := db.Exec(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newStore(db *sql.DB) *Store {
+	return &Store{
+		db:           db,
+		pollInterval: DefaultPollInterval,
+		inserted:     make(map[chan struct{}]struct{}),
+	}
+}
+
+// SetPollInterval changes how often listeners started afterwards poll for
+// new rows.
+func (s *Store) SetPollInterval(d time.Duration) {
+	s.pollInterval = d
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// GetLogs retrieves log entries with optional filtering and pagination.
+func (s *Store) GetLogs(filter models.LogFilter) ([]models.Log, error) {
+	where, args := whereClause(filter)
+
+	timeColumn, order := filter.TimeColumn(), "DESC"
+	if filter.Cursor != nil {
+		op := "<"
+		if filter.Cursor.Backward {
+			op, order = ">", "ASC"
+		}
+		where += fmt.Sprintf(" AND (%s, id) %s (?, ?)", timeColumn, op)
+		args = append(args, formatTime(filter.Cursor.Timestamp), filter.Cursor.ID)
+	}
+
+	query := "SELECT " + logColumns + " FROM logs" + where +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ? OFFSET ?", timeColumn, order, order)
+	args = append(args, filter.PageSize(), filter.Offset)
+
+	logs, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logs {
+		logs[i].Snippet = filter.Highlight(logs[i].Message, "<mark>", "</mark>")
+	}
+	if filter.Cursor != nil && filter.Cursor.Backward {
+		slices.Reverse(logs)
+	}
+	return logs, nil
+}
+
+// whereClause renders the filter conditions with the same semantics as the
+// PostgreSQL store.
+func whereClause(filter models.LogFilter) (string, []any) {
+	where := " WHERE 1=1"
+	var args []any
+
+	if filter.Tenant != "" {
+		where += " AND tenant = ?"
+		args = append(args, filter.Tenant)
+	}
+
+	if levels := filter.LevelSet(); levels != nil {
+		where += " AND " + anyOf("level", levels, &args)
+	}
+	if types := filter.TypeSet(); types != nil {
+		where += " AND " + anyOf("type", types, &args)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND " + filter.TimeColumn() + " >= ?"
+		args = append(args, formatTime(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		where += " AND " + filter.TimeColumn() + " < ?"
+		args = append(args, formatTime(filter.Until))
+	}
+
+	keys := make([]string, 0, len(filter.Attributes))
+	for k := range filter.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// Render values like PostgreSQL's ->> so that booleans compare as
+		// "true"/"false" rather than 1/0.
+		where += " AND EXISTS (SELECT 1 FROM json_each(logs.attributes) a WHERE a.key = ?" +
+			" AND CASE a.type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(a.value AS TEXT) END = ?)"
+		args = append(args, k, filter.Attributes[k])
+	}
+
+	if filter.Query != "" {
+		switch filter.EffectiveSearchMode() {
+		case models.SearchSubstring:
+			where += ` AND message LIKE ? ESCAPE '\'`
+			args = append(args, "%"+likeEscaper.Replace(filter.Query)+"%")
+		case models.SearchRegex:
+			where += " AND message REGEXP ?"
+			args = append(args, filter.Query)
+		default:
+			q := ftsQuery(filter.Query)
+			if q == "" {
+				// Like an empty tsquery in PostgreSQL, a query without words
+				// matches nothing.
+				where += " AND 0"
+				break
+			}
+			where += " AND id IN (SELECT rowid FROM logs_fts WHERE logs_fts MATCH ?)"
+			args = append(args, q)
+		}
+	}
+
+	return where, args
+}
+
+// anyOf matches column against a set of values; an empty set matches
+// nothing.
+func anyOf(column string, values []string, args *[]any) string {
+	if len(values) == 0 {
+		return "0"
+	}
+	for _, v := range values {
+		*args = append(*args, v)
+	}
+	return column + " IN (?" + strings.Repeat(", ?", len(values)-1) + ")"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ftsQuery quotes every search term so FTS5 treats them as plain words that
+// must all be present.
+func ftsQuery(query string) string {
+	terms := models.SearchTerms(query)
+	for i, t := range terms {
+		terms[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// InsertLog inserts a new log entry and returns its ID. The entry keeps its
+// timestamp and receive time; either defaults to the current time.
+func (s *Store) InsertLog(logEntry models.Log) (int, error) {
+	attrs, err := marshalAttributes(logEntry.Attributes)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	res, err := s.db.Exec(
+		"INSERT INTO logs (timestamp, received_at, level, type, message, attributes, tenant) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		formatTime(eventTime(logEntry, now)), formatTime(receiptTime(logEntry, now)), logEntry.Level, logEntry.Type, logEntry.Message, attrs, logEntry.Tenant,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	s.wakeListeners()
+	return int(id), nil
+}
+
+// InsertLogs inserts entries in a single transaction and returns their IDs
+// in input order.
+func (s *Store) InsertLogs(entries []models.Log) ([]int, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO logs (timestamp, received_at, level, type, message, attributes, tenant) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		attrs, err := marshalAttributes(e.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		res, err := stmt.Exec(formatTime(eventTime(e, now)), formatTime(receiptTime(e, now)), e.Level, e.Type, e.Message, attrs, e.Tenant)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = int(id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.wakeListeners()
+	return ids, nil
+}
+
+// Permanent reports whether an insert failed because of the entries rather
+// than the database, so that retrying it cannot succeed.
+func (s *Store) Permanent(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_TOOBIG, sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_MISMATCH:
+		return true
+	}
+	return false
+}
+
+func (s *Store) wakeListeners() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.inserted {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ListenForLogs delivers rows inserted after the call to logChan. SQLite has
+// no LISTEN/NOTIFY, so the feed polls for rows with a higher id, waking early
+// when this process inserts. The goroutine stops and closes logChan when ctx
+// is cancelled.
+func (s *Store) ListenForLogs(ctx context.Context, logChan chan<- models.Log) error {
+	var lastID int
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM logs").Scan(&lastID); err != nil {
+		return err
+	}
+
+	wake := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.inserted[wake] = struct{}{}
+	s.mu.Unlock()
+
+	log.Println("Listening for new SQLite rows")
+
+	interval := s.pollInterval
+	go func() {
+		defer close(logChan)
+		defer func() {
+			s.mu.Lock()
+			delete(s.inserted, wake)
+			s.mu.Unlock()
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-ticker.C:
+			}
+
+			for {
+				logs, last, n, err := s.feed(lastID)
+				if err != nil {
+					log.Printf("Error polling for new logs: %v\n", err)
+					break
+				}
+				for _, l := range logs {
+					select {
+					case logChan <- l:
+					case <-ctx.Done():
+						return
+					}
+				}
+				lastID = last
+				if n < feedBatchSize {
+					break
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// feed reads the next batch of rows after lastID for ListenForLogs. Rows
+// imported quietly are passed over but still advance the returned id. n is
+// the number of rows read, including quiet ones.
+func (s *Store) feed(lastID int) (logs []models.Log, last, n int, err error) {
+	rows, err := s.db.Query("SELECT "+logColumns+", quiet FROM logs WHERE id > ? ORDER BY id LIMIT ?", lastID, feedBatchSize)
+	if err != nil {
+		return nil, lastID, 0, err
+	}
+	defer rows.Close()
+
+	last = lastID
+	for rows.Next() {
+		var quiet bool
+		l, err := scanLog(rows, &quiet)
+		if err != nil {
+			return nil, lastID, 0, err
+		}
+		if !quiet {
+			logs = append(logs, l)
+		}
+		last = l.ID
+		n++
+	}
+	return logs, last, n, rows.Err()
+}
+
+func (s *Store) query(query string, args ...any) ([]models.Log, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.Log
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLog reads a row selected with logColumns followed by any extra columns.
+func scanLog(row rowScanner, extra ...any) (models.Log, error) {
+	var l models.Log
+	var ts, receivedAt, attrs string
+	dest := append([]any{&l.ID, &ts, &l.Level, &l.Type, &l.Message, &attrs, &l.Tenant, &receivedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return l, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return l, fmt.Errorf("parsing timestamp of log %d: %w", l.ID, err)
+	}
+	l.Timestamp = t
+	if l.ReceivedAt, err = time.Parse(time.RFC3339Nano, receivedAt); err != nil {
+		return l, fmt.Errorf("parsing received_at of log %d: %w", l.ID, err)
+	}
+
+	if attrs != "" && attrs != "{}" {
+		dec := json.NewDecoder(bytes.NewReader([]byte(attrs)))
+		dec.UseNumber()
+		if err := dec.Decode(&l.Attributes); err != nil {
+			return l, fmt.Errorf("decoding attributes of log %d: %w", l.ID, err)
+		}
+	}
+	return l, nil
+}
+
+func marshalAttributes(attrs map[string]any) (string, error) {
+	if len(attrs) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return "", fmt.Errorf("encoding attributes: %w", err)
+	}
+	return string(b), nil
+}
+
+// eventTime returns the timestamp of l, or now when it has none.
+func eventTime(l models.Log, now time.Time) time.Time {
+	if l.Timestamp.IsZero() {
+		return now
+	}
+	return l.Timestamp
+}
+
+// receiptTime returns when l was received, or now when that is not known
+// yet, e.g. for entries that did not pass through an ingestion buffer.
+func receiptTime(l models.Log, now time.Time) time.Time {
+	if l.ReceivedAt.IsZero() {
+		return now
+	}
+	return l.ReceivedAt
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
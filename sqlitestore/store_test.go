@@ -0,0 +1,309 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "golog.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func insert(t *testing.T, s *Store, entries ...models.Log) {
+	t.Helper()
+	for _, e := range entries {
+		if _, err := s.InsertLog(e); err != nil {
+			t.Fatalf("InsertLog() error: %v", err)
+		}
+	}
+}
+
+func ids(logs []models.Log) []int {
+	out := make([]int, len(logs))
+	for i, l := range logs {
+		out[i] = l.ID
+	}
+	return out
+}
+
+func TestGetLogs(t *testing.T) {
+	store, _ := openTestStore(t)
+	insert(t, store,
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "System started"},
+		models.Log{Level: models.LevelError, Type: models.TypeDatabase, Message: "Connection timeout after 30s", Attributes: map[string]any{"user_id": 42, "retry": true}},
+		models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "Request failed: 100%_done", Attributes: map[string]any{"user_id": "7"}, Tenant: "billing"},
+		models.Log{Level: models.LevelWarning, Type: models.TypeAuth, Message: "panic: nil pointer"},
+	)
+
+	tests := []struct {
+		name   string
+		filter models.LogFilter
+		want   []int
+	}{
+		{"all newest first", models.LogFilter{}, []int{4, 3, 2, 1}},
+		{"level", models.LogFilter{Level: models.LevelError}, []int{3, 2}},
+		{"level and type", models.LogFilter{Level: models.LevelError, Type: models.TypeAPI}, []int{3}},
+		{"level set", models.LogFilter{Levels: []string{models.LevelInfo, models.LevelWarning}}, []int{4, 1}},
+		{"type set", models.LogFilter{Types: []string{models.TypeAuth, models.TypeAPI}}, []int{4, 3}},
+		{"min level", models.LogFilter{MinLevel: models.LevelWarning}, []int{4, 3, 2}},
+		{"min level excludes level", models.LogFilter{MinLevel: models.LevelError, Level: models.LevelInfo}, []int{}},
+		{"numeric attribute", models.LogFilter{Attributes: map[string]string{"user_id": "42"}}, []int{2}},
+		{"string attribute", models.LogFilter{Attributes: map[string]string{"user_id": "7"}}, []int{3}},
+		{"bool attribute", models.LogFilter{Attributes: map[string]string{"retry": "true"}}, []int{2}},
+		{"tenant", models.LogFilter{Tenant: "billing"}, []int{3}},
+		{"unknown tenant", models.LogFilter{Tenant: "search"}, []int{}},
+		{"full-text", models.LogFilter{Query: "TIMEOUT connection"}, []int{2}},
+		{"full-text needs whole words", models.LogFilter{Query: "connect"}, []int{}},
+		{"full-text without words", models.LogFilter{Query: "!!!"}, []int{}},
+		{"substring", models.LogFilter{Query: "connect", SearchMode: models.SearchSubstring}, []int{2}},
+		{"substring is literal", models.LogFilter{Query: "100%_done", SearchMode: models.SearchSubstring}, []int{3}},
+		{"regex", models.LogFilter{Query: `^panic: \w+`, SearchMode: models.SearchRegex}, []int{4}},
+		{"limit and offset", models.LogFilter{Limit: 2, Offset: 1}, []int{3, 2}},
+		{"since in the future", models.LogFilter{Since: time.Now().Add(time.Hour)}, []int{}},
+		{"until in the future", models.LogFilter{Until: time.Now().Add(time.Hour)}, []int{4, 3, 2, 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs, err := store.GetLogs(tc.filter)
+			if err != nil {
+				t.Fatalf("GetLogs() error: %v", err)
+			}
+			if got := ids(logs); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("GetLogs() ids = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetLogsRoundTrip(t *testing.T) {
+	store, _ := openTestStore(t)
+	before := time.Now().Add(-time.Second)
+	insert(t, store, models.Log{
+		Level:      models.LevelInfo,
+		Type:       models.TypeAPI,
+		Message:    "Request handled",
+		Attributes: map[string]any{"big": 9007199254740993, "path": "/api/users"},
+	})
+
+	logs, err := store.GetLogs(models.LogFilter{Query: "request"})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("GetLogs() = %d logs, want 1", len(logs))
+	}
+	l := logs[0]
+	if l.Timestamp.Before(before) || l.Timestamp.After(time.Now()) {
+		t.Errorf("Timestamp = %v, want close to now", l.Timestamp)
+	}
+	if got := models.AttributeString(l.Attributes["big"]); got != "9007199254740993" {
+		t.Errorf("big attribute = %s, want 9007199254740993", got)
+	}
+	if l.Snippet != "<mark>Request</mark> handled" {
+		t.Errorf("Snippet = %q, want highlighted match", l.Snippet)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp TEXT NOT NULL, level TEXT NOT NULL,
+		type TEXT NOT NULL, message TEXT NOT NULL, attributes TEXT NOT NULL DEFAULT '{}');
+		INSERT INTO logs (timestamp, level, type, message) VALUES ('2024-01-15T10:30:00.000000000Z', 'INFO', 'SYSTEM', 'old entry')`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("creating old schema: %v", err)
+	}
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer store.Close()
+	insert(t, store, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "new entry", Tenant: "billing"})
+
+	logs, err := store.GetLogs(models.LogFilter{})
+	if err != nil || len(logs) != 2 || logs[0].Tenant != "billing" || logs[1].Tenant != "" {
+		t.Fatalf("GetLogs() after upgrade = %+v, %v", logs, err)
+	}
+	if !logs[1].ReceivedAt.Equal(logs[1].Timestamp) {
+		t.Errorf("old entry received_at = %v, want its timestamp %v", logs[1].ReceivedAt, logs[1].Timestamp)
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	store, _ := openTestStore(t)
+	for i := 0; i < 5; i++ {
+		insert(t, store, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "m"})
+	}
+
+	first, _ := store.GetLogs(models.LogFilter{Limit: 2})
+	next := models.LogFilter{}.CursorAfter(first[len(first)-1], false)
+	second, err := store.GetLogs(models.LogFilter{Limit: 2, Cursor: &next})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if got := ids(second); fmt.Sprint(got) != "[3 2]" {
+		t.Fatalf("second page = %v, want [3 2]", got)
+	}
+
+	prev := models.LogFilter{}.CursorAfter(second[0], true)
+	back, err := store.GetLogs(models.LogFilter{Limit: 2, Cursor: &prev})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if got := ids(back); fmt.Sprint(got) != "[5 4]" {
+		t.Errorf("prev page = %v, want [5 4]", got)
+	}
+}
+
+func TestClientTimestamps(t *testing.T) {
+	store, _ := openTestStore(t)
+	before := time.Now().Add(-time.Second)
+	old := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	insert(t, store,
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "late", Timestamp: old},
+		models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "now"},
+	)
+	if _, err := store.InsertLogs([]models.Log{{Level: models.LevelInfo, Type: models.TypeSystem, Message: "older", Timestamp: old.Add(-time.Hour)}}); err != nil {
+		t.Fatalf("InsertLogs() error: %v", err)
+	}
+
+	byEvent, err := store.GetLogs(models.LogFilter{})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if got := ids(byEvent); fmt.Sprint(got) != "[2 1 3]" {
+		t.Fatalf("by timestamp = %v, want [2 1 3]", got)
+	}
+	if l := byEvent[1]; !l.Timestamp.Equal(old) || l.ReceivedAt.Before(before) {
+		t.Errorf("late entry = %+v, want client timestamp and receive time", l)
+	}
+
+	byReceipt, err := store.GetLogs(models.LogFilter{TimeField: models.TimeFieldReceivedAt, Since: before, Limit: 2})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if got := ids(byReceipt); fmt.Sprint(got) != "[3 2]" {
+		t.Fatalf("by received_at = %v, want [3 2]", got)
+	}
+	filter := models.LogFilter{TimeField: models.TimeFieldReceivedAt, Limit: 2}
+	next := filter.CursorAfter(byReceipt[1], false)
+	filter.Cursor = &next
+	rest, err := store.GetLogs(filter)
+	if err != nil || fmt.Sprint(ids(rest)) != "[1]" {
+		t.Errorf("next page by received_at = %v, %v, want [1]", ids(rest), err)
+	}
+}
+
+func TestInsertLogs(t *testing.T) {
+	store, _ := openTestStore(t)
+	insert(t, store, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "first"})
+
+	got, err := store.InsertLogs([]models.Log{
+		{Level: models.LevelInfo, Type: models.TypeSystem, Message: "second batch entry"},
+		{Level: models.LevelError, Type: models.TypeAPI, Message: "third batch entry", Attributes: map[string]any{"status": 500}},
+	})
+	if err != nil {
+		t.Fatalf("InsertLogs() error: %v", err)
+	}
+	if fmt.Sprint(got) != "[2 3]" {
+		t.Errorf("InsertLogs() ids = %v, want [2 3]", got)
+	}
+
+	// Rows inserted in bulk must be indexed for full-text search too.
+	logs, err := store.GetLogs(models.LogFilter{Query: "batch"})
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if fmt.Sprint(ids(logs)) != "[3 2]" {
+		t.Errorf("GetLogs() ids = %v, want [3 2]", ids(logs))
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	store, _ := openTestStore(t)
+	insert(t, store, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "first"})
+
+	_, err := store.db.Exec("INSERT INTO logs (id, timestamp, received_at, level, type, message, attributes, tenant) SELECT id, timestamp, received_at, level, type, message, attributes, tenant FROM logs")
+	if err == nil {
+		t.Fatal("inserting a duplicate id succeeded")
+	}
+	if !store.Permanent(err) {
+		t.Errorf("Permanent(%v) = false, want true", err)
+	}
+	if store.Permanent(sql.ErrConnDone) {
+		t.Error("Permanent(sql.ErrConnDone) = true, want false")
+	}
+}
+
+func receive(t *testing.T, ch <-chan models.Log) models.Log {
+	t.Helper()
+	select {
+	case l, ok := <-ch:
+		if !ok {
+			t.Fatal("log channel closed unexpectedly")
+		}
+		return l
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for log")
+	}
+	return models.Log{}
+}
+
+func TestListenForLogs(t *testing.T) {
+	store, path := openTestStore(t)
+	insert(t, store, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "before listen"})
+
+	store.SetPollInterval(20 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan models.Log)
+	if err := store.ListenForLogs(ctx, ch); err != nil {
+		t.Fatalf("ListenForLogs() error: %v", err)
+	}
+
+	insert(t, store, models.Log{Level: models.LevelInfo, Type: models.TypeSystem, Message: "local insert"})
+	if l := receive(t, ch); l.Message != "local insert" {
+		t.Errorf("received %q, want local insert", l.Message)
+	}
+
+	// Rows written by another process are picked up by polling.
+	other, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() second handle: %v", err)
+	}
+	defer other.Close()
+	insert(t, other, models.Log{Level: models.LevelError, Type: models.TypeAPI, Message: "other process"})
+	if l := receive(t, ch); l.Message != "other process" {
+		t.Errorf("received %q, want other process", l.Message)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
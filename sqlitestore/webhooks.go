@@ -0,0 +1,58 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+	"github.com/mstgnz/golog/webhook"
+)
+
+// DeadLetterStore keeps failed webhook deliveries in the same database file
+// as the logs.
+type DeadLetterStore struct {
+	db *sql.DB
+}
+
+// DeadLetterStore returns a webhook.DeadLetterStore sharing the store's
+// database.
+func (s *Store) DeadLetterStore() *DeadLetterStore {
+	return &DeadLetterStore{db: s.db}
+}
+
+// InsertDeadLetter records a failed delivery.
+func (s *DeadLetterStore) InsertDeadLetter(d webhook.DeadLetter) error {
+	_, err := s.db.Exec(
+		`INSERT INTO webhook_dead_letters (destination, url, event, payload, error, attempts, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		d.Destination, d.URL, d.Event, d.Payload, d.Error, d.Attempts, formatTime(d.CreatedAt),
+	)
+	return err
+}
+
+// ListDeadLetters returns up to limit failed deliveries, newest first.
+func (s *DeadLetterStore) ListDeadLetters(limit int) ([]webhook.DeadLetter, error) {
+	rows, err := s.db.Query(
+		"SELECT id, destination, url, event, payload, error, attempts, created_at FROM webhook_dead_letters ORDER BY id DESC LIMIT ?",
+		models.LogFilter{Limit: limit}.PageSize(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []webhook.DeadLetter
+	for rows.Next() {
+		var d webhook.DeadLetter
+		var createdAt string
+		if err := rows.Scan(&d.ID, &d.Destination, &d.URL, &d.Event, &d.Payload, &d.Error, &d.Attempts, &createdAt); err != nil {
+			return nil, err
+		}
+		if d.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+			return nil, fmt.Errorf("parsing created_at of dead letter %d: %w", d.ID, err)
+		}
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
@@ -0,0 +1,32 @@
+package sqlitestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/webhook"
+)
+
+func TestDeadLetterStore(t *testing.T) {
+	store, _ := openTestStore(t)
+	letters := store.DeadLetterStore()
+
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for _, dest := range []string{"oncall", "chat"} {
+		err := letters.InsertDeadLetter(webhook.DeadLetter{
+			Destination: dest, URL: "https://hooks.example.com", Event: webhook.EventLog,
+			Payload: "{}", Error: "queue full", CreatedAt: created,
+		})
+		if err != nil {
+			t.Fatalf("InsertDeadLetter() error: %v", err)
+		}
+	}
+
+	got, err := letters.ListDeadLetters(1)
+	if err != nil {
+		t.Fatalf("ListDeadLetters() error: %v", err)
+	}
+	if len(got) != 1 || got[0].Destination != "chat" || got[0].ID != 2 || !got[0].CreatedAt.Equal(created) {
+		t.Errorf("ListDeadLetters() = %+v", got)
+	}
+}
@@ -0,0 +1,302 @@
+// Package syslog receives RFC 5424 and RFC 3164 syslog messages over UDP and
+// TCP and stores them as log entries.
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// defaultPriority is user.notice, which RFC 3164 assumes for frames without
+// a PRI part.
+const defaultPriority = 13
+
+// levelFatal is not one of the built-in levels but is used when configured.
+const levelFatal = "FATAL"
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// Level maps a syslog severity (0-7) onto a golog level. emerg, alert and
+// crit map to FATAL when that level is registered.
+func Level(severity int) string {
+	switch {
+	case severity <= 2 && models.ValidLevel(levelFatal):
+		return levelFatal
+	case severity <= 3:
+		return models.LevelError
+	case severity == 4:
+		return models.LevelWarning
+	case severity == 7:
+		return models.LevelDebug
+	default:
+		return models.LevelInfo
+	}
+}
+
+// Type maps a syslog facility and app-name onto a golog type. An app-name
+// that names a registered type (e.g. "database") wins; otherwise the auth
+// facilities map to AUTH and everything else to the registry's default type.
+func Type(facility int, appName string) string {
+	reg := models.CurrentRegistry()
+	if t := strings.ToUpper(appName); reg.ValidType(t) {
+		return t
+	}
+	if (facility == 4 || facility == 10) && reg.ValidType(models.TypeAuth) {
+		return models.TypeAuth
+	}
+	return reg.DefaultType()
+}
+
+// Parse converts a single syslog frame into a log entry. RFC 5424 frames are
+// recognised by their version field; anything else is parsed leniently as
+// RFC 3164. The header timestamp becomes the entry's timestamp and the other
+// header fields are kept as attributes.
+func Parse(frame []byte) (models.Log, error) {
+	return parse(frame, time.Now())
+}
+
+// parse is Parse for a frame received at now, which completes RFC 3164
+// timestamps.
+func parse(frame []byte, now time.Time) (models.Log, error) {
+	s := strings.TrimRight(string(frame), "\r\n\x00")
+	if strings.TrimSpace(s) == "" {
+		return models.Log{}, errors.New("empty syslog message")
+	}
+
+	pri, rest, err := parsePriority(s)
+	if err != nil {
+		return models.Log{}, err
+	}
+	facility, severity := pri/8, pri%8
+
+	attrs := map[string]any{
+		"facility": facilityNames[facility],
+		"severity": severityNames[severity],
+	}
+	var appName, msg string
+	var ts time.Time
+	if after, ok := strings.CutPrefix(rest, "1 "); ok {
+		appName, msg, ts, err = parse5424(after, attrs)
+		if err != nil {
+			return models.Log{}, err
+		}
+	} else {
+		appName, msg, ts = parse3164(rest, attrs, now)
+	}
+
+	msg = truncate(strings.TrimSpace(msg), models.MaxMessageLength)
+	if msg == "" {
+		msg = "-"
+	}
+	return models.Log{
+		Timestamp:  ts,
+		Level:      Level(severity),
+		Type:       Type(facility, appName),
+		Message:    msg,
+		Attributes: attrs,
+	}, nil
+}
+
+func parsePriority(s string) (int, string, error) {
+	if !strings.HasPrefix(s, "<") {
+		return defaultPriority, s, nil
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return 0, "", errors.New("invalid syslog priority")
+	}
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", fmt.Errorf("invalid syslog priority %q", s[1:end])
+	}
+	return pri, s[end+1:], nil
+}
+
+// parse5424 parses what follows "<PRI>1 ":
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parse5424(s string, attrs map[string]any) (appName, msg string, ts time.Time, err error) {
+	fields := make([]string, 5)
+	for i := range fields {
+		var ok bool
+		fields[i], s, ok = strings.Cut(s, " ")
+		if !ok && i < len(fields)-1 {
+			return "", "", ts, errors.New("truncated RFC 5424 header")
+		}
+	}
+
+	if fields[0] != "-" {
+		if ts, err = time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+			return "", "", ts, fmt.Errorf("invalid RFC 5424 timestamp %q", fields[0])
+		}
+		attrs["syslog_timestamp"] = fields[0]
+	}
+	setNonNil(attrs, "hostname", fields[1])
+	setNonNil(attrs, "app_name", fields[2])
+	setNonNil(attrs, "proc_id", fields[3])
+	setNonNil(attrs, "msg_id", fields[4])
+
+	msg, err = parseStructuredData(s, attrs)
+	if err != nil {
+		return "", "", ts, err
+	}
+	msg = strings.TrimPrefix(msg, "\ufeff")
+	if fields[2] == "-" {
+		return "", msg, ts, nil
+	}
+	return fields[2], msg, ts, nil
+}
+
+// parseStructuredData consumes the STRUCTURED-DATA part, storing each
+// parameter as "sd.<id>.<name>", and returns the remaining message.
+func parseStructuredData(s string, attrs map[string]any) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if s[0] == '-' {
+		return strings.TrimPrefix(s[1:], " "), nil
+	}
+	if s[0] != '[' {
+		return "", errors.New("invalid RFC 5424 structured data")
+	}
+
+	for len(s) > 0 && s[0] == '[' {
+		end := strings.IndexAny(s, " ]")
+		if end < 0 {
+			return "", errors.New("unterminated structured data element")
+		}
+		id := s[1:end]
+		s = s[end:]
+
+		for len(s) > 0 && s[0] == ' ' {
+			s = s[1:]
+			eq := strings.Index(s, `="`)
+			if eq <= 0 {
+				return "", fmt.Errorf("invalid parameter in structured data element %q", id)
+			}
+			name := s[:eq]
+			s = s[eq+2:]
+
+			var value strings.Builder
+			i := 0
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return "", fmt.Errorf("unterminated parameter %q in structured data", name)
+			}
+			s = s[i+1:]
+			addAttribute(attrs, "sd."+id+"."+name, value.String())
+		}
+
+		if len(s) == 0 || s[0] != ']' {
+			return "", fmt.Errorf("unterminated structured data element %q", id)
+		}
+		s = s[1:]
+	}
+	return strings.TrimPrefix(s, " "), nil
+}
+
+// parse3164 parses what follows the PRI of a BSD syslog frame:
+// Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+// Real-world senders often omit the timestamp or hostname, so every part is
+// optional. The timestamp has no year or zone; it is read in the local time
+// zone and in the year that puts it closest to now.
+func parse3164(s string, attrs map[string]any, now time.Time) (appName, msg string, ts time.Time) {
+	if len(s) >= 16 && s[15] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, s[:15], time.Local); err == nil {
+			ts = stampYear(t, now)
+			attrs["syslog_timestamp"] = s[:15]
+			s = s[16:]
+			if host, rest, ok := strings.Cut(s, " "); ok && !isTag(host) {
+				attrs["hostname"] = host
+				s = rest
+			}
+		}
+	}
+
+	tag, rest, ok := strings.Cut(s, ":")
+	if !ok || !isTag(tag+":") {
+		return "", s, ts
+	}
+	if name, pid, ok := strings.Cut(tag, "["); ok {
+		tag = name
+		attrs["proc_id"] = strings.TrimSuffix(pid, "]")
+	}
+	attrs["app_name"] = tag
+	return tag, rest, ts
+}
+
+// stampYear places a year-less RFC 3164 time in the year of now, or in the
+// previous or next year when that is closer, e.g. for a December message
+// received on January 1st.
+func stampYear(t, now time.Time) time.Time {
+	now = now.In(t.Location())
+	best := time.Time{}
+	for _, year := range []int{now.Year() - 1, now.Year(), now.Year() + 1} {
+		c := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+		if best.IsZero() || c.Sub(now).Abs() < best.Sub(now).Abs() {
+			best = c
+		}
+	}
+	return best
+}
+
+// isTag reports whether token looks like "name:" or "name[pid]:".
+func isTag(token string) bool {
+	if !strings.HasSuffix(token, ":") {
+		return false
+	}
+	name := strings.TrimSuffix(token, ":")
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		if !strings.HasSuffix(name, "]") {
+			return false
+		}
+		name = name[:i]
+	}
+	if name == "" || len(name) > 48 {
+		return false
+	}
+	return !strings.ContainsAny(name, " \t[]")
+}
+
+func setNonNil(attrs map[string]any, key, value string) {
+	if value != "-" && value != "" {
+		attrs[key] = value
+	}
+}
+
+// addAttribute drops parameters that would make the entry fail validation
+// rather than rejecting the whole message.
+func addAttribute(attrs map[string]any, key, value string) {
+	if len(attrs) >= models.MaxAttributes || len(key) > models.MaxAttributeKey {
+		return
+	}
+	attrs[key] = value
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
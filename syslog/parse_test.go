@@ -0,0 +1,197 @@
+package syslog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		frame     string
+		wantLevel string
+		wantType  string
+		wantMsg   string
+		wantAttrs map[string]any
+	}{
+		{
+			name:      "rfc5424",
+			frame:     `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`,
+			wantLevel: models.LevelInfo,
+			wantType:  models.TypeSystem,
+			wantMsg:   "An application event",
+			wantAttrs: map[string]any{
+				"facility":                         "local4",
+				"severity":                         "notice",
+				"syslog_timestamp":                 "2003-10-11T22:14:15.003Z",
+				"hostname":                         "mymachine.example.com",
+				"app_name":                         "evntslog",
+				"msg_id":                           "ID47",
+				"sd.exampleSDID@32473.iut":         "3",
+				"sd.exampleSDID@32473.eventSource": "Application",
+			},
+		},
+		{
+			name:      "rfc5424 auth facility without structured data",
+			frame:     "<34>1 2003-10-11T22:14:15.003Z host su 123 - - 'su root' failed for lonvick on /dev/pts/8",
+			wantLevel: models.LevelError,
+			wantType:  models.TypeAuth,
+			wantMsg:   "'su root' failed for lonvick on /dev/pts/8",
+			wantAttrs: map[string]any{"facility": "auth", "severity": "crit", "app_name": "su", "proc_id": "123"},
+		},
+		{
+			name:      "rfc5424 escaped structured data and bom",
+			frame:     "<15>1 - - database - - [meta q=\"a \\\"b\\\" \\] c\"][other x=\"1\"] \ufeffslow query",
+			wantLevel: models.LevelDebug,
+			wantType:  models.TypeDatabase,
+			wantMsg:   "slow query",
+			wantAttrs: map[string]any{"app_name": "database", "sd.meta.q": `a "b" ] c`, "sd.other.x": "1"},
+		},
+		{
+			name:      "rfc5424 without message",
+			frame:     "<12>1 - host app - - -",
+			wantLevel: models.LevelWarning,
+			wantType:  models.TypeSystem,
+			wantMsg:   "-",
+		},
+		{
+			name:      "rfc3164",
+			frame:     "<38>Oct 11 22:14:15 mymachine sshd[4123]: Accepted publickey for root\n",
+			wantLevel: models.LevelInfo,
+			wantType:  models.TypeAuth,
+			wantMsg:   "Accepted publickey for root",
+			wantAttrs: map[string]any{
+				"facility":         "auth",
+				"syslog_timestamp": "Oct 11 22:14:15",
+				"hostname":         "mymachine",
+				"app_name":         "sshd",
+				"proc_id":          "4123",
+			},
+		},
+		{
+			name:      "rfc3164 without hostname",
+			frame:     "<11>Oct  1 02:03:04 api: upstream timed out",
+			wantLevel: models.LevelError,
+			wantType:  models.TypeAPI,
+			wantMsg:   "upstream timed out",
+			wantAttrs: map[string]any{"app_name": "api"},
+		},
+		{
+			name:      "no priority or header",
+			frame:     "plain text from a printer",
+			wantLevel: models.LevelInfo,
+			wantType:  models.TypeSystem,
+			wantMsg:   "plain text from a printer",
+			wantAttrs: map[string]any{"facility": "user", "severity": "notice"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse([]byte(tc.frame))
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if got.Level != tc.wantLevel || got.Type != tc.wantType || got.Message != tc.wantMsg {
+				t.Errorf("Parse() = %s/%s %q, want %s/%s %q", got.Level, got.Type, got.Message, tc.wantLevel, tc.wantType, tc.wantMsg)
+			}
+			for k, v := range tc.wantAttrs {
+				if got.Attributes[k] != v {
+					t.Errorf("attribute %q = %v, want %v", k, got.Attributes[k], v)
+				}
+			}
+			if err := got.Validate(); err != nil {
+				t.Errorf("parsed entry does not validate: %v", err)
+			}
+			if ts, ok := tc.wantAttrs["syslog_timestamp"].(string); ok && got.Timestamp.IsZero() {
+				t.Errorf("timestamp not set from %q", ts)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	got, err := Parse([]byte("<165>1 2003-10-11T22:14:15.003-07:00 host app - - - m"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if want := time.Date(2003, 10, 12, 5, 14, 15, 3e6, time.UTC); !got.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", got.Timestamp, want)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 5, 0, 0, time.Local)
+	got, err = parse([]byte("<38>Dec 31 23:59:00 host sshd: late"), now)
+	if err != nil {
+		t.Fatalf("parse() error: %v", err)
+	}
+	if want := time.Date(2023, 12, 31, 23, 59, 0, 0, time.Local); !got.Timestamp.Equal(want) {
+		t.Errorf("rfc3164 timestamp = %v, want %v", got.Timestamp, want)
+	}
+	got, _ = parse([]byte("<38>Jan  1 00:04:00 host sshd: on time"), now)
+	if want := time.Date(2024, 1, 1, 0, 4, 0, 0, time.Local); !got.Timestamp.Equal(want) {
+		t.Errorf("rfc3164 timestamp = %v, want %v", got.Timestamp, want)
+	}
+
+	got, _ = Parse([]byte("<13>1 - host app - - - m"))
+	if !got.Timestamp.IsZero() {
+		t.Errorf("timestamp = %v, want none for a nil TIMESTAMP", got.Timestamp)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, frame := range []string{
+		"",
+		"<999>1 - - - - - - too high",
+		"<abc>oops",
+		"<13>1 not-a-time host app - - - msg",
+		"<13>1 - host app - - [unterminated x=\"1\" msg",
+		"<13>1 - host",
+	} {
+		if _, err := Parse([]byte(frame)); err == nil {
+			t.Errorf("Parse(%q) should fail", frame)
+		}
+	}
+}
+
+func TestParseTruncatesLongMessages(t *testing.T) {
+	got, err := Parse([]byte("<14>" + strings.Repeat("é", models.MaxMessageLength)))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("truncated entry does not validate: %v", err)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	want := []string{
+		models.LevelError, models.LevelError, models.LevelError, models.LevelError,
+		models.LevelWarning, models.LevelInfo, models.LevelInfo, models.LevelDebug,
+	}
+	for severity, level := range want {
+		if got := Level(severity); got != level {
+			t.Errorf("Level(%d) = %s, want %s", severity, got, level)
+		}
+	}
+}
+
+func TestLevelFatalWhenRegistered(t *testing.T) {
+	defer models.SetRegistry(models.CurrentRegistry())
+
+	levels := append(models.DefaultLevels, models.LevelDef{Name: "FATAL", Severity: 50})
+	reg, err := models.NewRegistry(levels, models.DefaultTypes)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	models.SetRegistry(reg)
+
+	if got := Level(0); got != "FATAL" {
+		t.Errorf("Level(0) = %s, want FATAL", got)
+	}
+	if got := Level(3); got != models.LevelError {
+		t.Errorf("Level(3) = %s, want ERROR", got)
+	}
+}
@@ -0,0 +1,214 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+const (
+	// maxFrameSize bounds a single message. RFC 5425 requires receivers to
+	// handle at least 2048 octets and recommends 8192.
+	maxFrameSize = 64 * 1024
+
+	// idleTimeout closes TCP connections that stop sending.
+	idleTimeout = 5 * time.Minute
+)
+
+// Sink stores parsed entries; handlers.Server satisfies it, applying the
+// tenant quotas and the ingestion buffer.
+type Sink interface {
+	Ingest(entry models.Log) error
+}
+
+// Server accepts syslog messages and passes them to a sink.
+type Server struct {
+	sink       Sink
+	timestamps models.TimestampPolicy
+
+	wg sync.WaitGroup
+}
+
+// NewServer creates a Server that passes entries to sink. Message timestamps
+// are bounded by policy like those sent over HTTP.
+func NewServer(sink Sink, policy models.TimestampPolicy) *Server {
+	return &Server{sink: sink, timestamps: policy}
+}
+
+// ListenAndServe listens on the given UDP and TCP addresses (either may be
+// empty to disable it) and serves until ctx is cancelled. Errors binding the
+// sockets are returned immediately.
+func (s *Server) ListenAndServe(ctx context.Context, udpAddr, tcpAddr string) error {
+	if udpAddr != "" {
+		conn, err := net.ListenPacket("udp", udpAddr)
+		if err != nil {
+			return fmt.Errorf("syslog udp: %w", err)
+		}
+		log.Printf("Syslog listening on udp %s", conn.LocalAddr())
+		s.ServeUDP(ctx, conn)
+	}
+	if tcpAddr != "" {
+		ln, err := net.Listen("tcp", tcpAddr)
+		if err != nil {
+			return fmt.Errorf("syslog tcp: %w", err)
+		}
+		log.Printf("Syslog listening on tcp %s", ln.Addr())
+		s.ServeTCP(ctx, ln)
+	}
+	return nil
+}
+
+// Wait blocks until all listeners and connections have stopped.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// ServeUDP reads one message per datagram from conn until ctx is cancelled,
+// then closes conn.
+func (s *Server) ServeUDP(ctx context.Context, conn net.PacketConn) {
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer s.wg.Done()
+		buf := make([]byte, maxFrameSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					log.Printf("Syslog udp read error: %v", err)
+					continue
+				}
+				return
+			}
+			s.handle(buf[:n], addr)
+		}
+	}()
+}
+
+// ServeTCP accepts connections on ln until ctx is cancelled, then closes ln
+// and all open connections.
+func (s *Server) ServeTCP(ctx context.Context, ln net.Listener) {
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		<-ctx.Done()
+		ln.Close()
+	}()
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					log.Printf("Syslog tcp accept error: %v", err)
+					continue
+				}
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serveConn(ctx, conn)
+			}()
+		}
+	}()
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReaderSize(conn, 16*1024)
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		frame, err := readFrame(r)
+		if len(frame) > 0 {
+			s.handle(frame, conn.RemoteAddr())
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Printf("Syslog tcp %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readFrame reads one message using RFC 6587 framing: octet counting
+// ("LEN SP MSG") when the frame starts with a digit, otherwise a line
+// terminated by LF or NUL.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		digits, err := r.ReadSlice(' ')
+		if err != nil {
+			return nil, fmt.Errorf("invalid octet count: %w", err)
+		}
+		n, err := strconv.Atoi(string(digits[:len(digits)-1]))
+		if err != nil || n > maxFrameSize {
+			return nil, fmt.Errorf("invalid octet count %q", digits[:len(digits)-1])
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	var frame []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			// A final unterminated line is still a message.
+			return frame, err
+		}
+		if b == '\n' || b == 0 {
+			return frame, nil
+		}
+		if len(frame) == maxFrameSize {
+			return nil, errors.New("message exceeds maximum frame size")
+		}
+		frame = append(frame, b)
+	}
+}
+
+func (s *Server) handle(frame []byte, from net.Addr) {
+	now := time.Now()
+	entry, err := parse(frame, now)
+	if err != nil {
+		log.Printf("Syslog: dropping message from %s: %v", from, err)
+		return
+	}
+	if from != nil && len(entry.Attributes) < models.MaxAttributes {
+		entry.Attributes["remote_addr"] = from.String()
+	}
+	if err := entry.Validate(); err != nil {
+		log.Printf("Syslog: dropping message from %s: %v", from, err)
+		return
+	}
+	if err := s.timestamps.Apply(&entry, now); err != nil {
+		log.Printf("Syslog: dropping message from %s: %v", from, err)
+		return
+	}
+	if err := s.sink.Ingest(entry); err != nil {
+		log.Printf("Syslog: error storing message from %s: %v", from, err)
+	}
+}
@@ -0,0 +1,144 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	entries []models.Log
+}
+
+func (f *fakeStore) Ingest(l models.Log) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, l)
+	return nil
+}
+
+func (f *fakeStore) waitFor(t *testing.T, n int) []models.Log {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		if len(f.entries) >= n {
+			out := append([]models.Log(nil), f.entries...)
+			f.mu.Unlock()
+			return out
+		}
+		f.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d entries", n)
+	return nil
+}
+
+func TestReadFrame(t *testing.T) {
+	input := "<13>1 - h a - - - first\n" +
+		"27 <13>1 - h a - - - two\nlines" +
+		"<13>nul terminated\x00" +
+		"\n" +
+		"<13>last without newline"
+	r := bufio.NewReader(strings.NewReader(input))
+
+	want := []string{
+		"<13>1 - h a - - - first",
+		"<13>1 - h a - - - two\nlines",
+		"<13>nul terminated",
+		"",
+		"<13>last without newline",
+	}
+	for i, w := range want {
+		frame, err := readFrame(r)
+		if string(frame) != w {
+			t.Fatalf("frame %d = %q, want %q (err %v)", i, frame, w, err)
+		}
+	}
+	if _, err := readFrame(r); err == nil {
+		t.Error("readFrame() at end of input should return an error")
+	}
+}
+
+func TestServeUDP(t *testing.T) {
+	store := &fakeStore{}
+	srv := NewServer(store, models.TimestampPolicy{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error: %v", err)
+	}
+	srv.ServeUDP(ctx, conn)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	defer client.Close()
+	client.Write([]byte("<11>1 2024-01-15T10:30:00Z web api - - - upstream timeout"))
+
+	got := store.waitFor(t, 1)
+	if got[0].Level != models.LevelError || got[0].Type != models.TypeAPI || got[0].Message != "upstream timeout" {
+		t.Errorf("stored %+v", got[0])
+	}
+	if got[0].Attributes["remote_addr"] == nil {
+		t.Error("remote_addr attribute should be set")
+	}
+	if want := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC); !got[0].Timestamp.Equal(want) || got[0].ReceivedAt.IsZero() {
+		t.Errorf("timestamp = %v, received_at = %v; want the header time and a receive time", got[0].Timestamp, got[0].ReceivedAt)
+	}
+
+	cancel()
+	srv.Wait()
+}
+
+func TestServeTCP(t *testing.T) {
+	store := &fakeStore{}
+	srv := NewServer(store, models.TimestampPolicy{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	srv.ServeTCP(ctx, ln)
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	defer client.Close()
+	client.Write([]byte("<38>Oct 11 22:14:15 host sshd[1]: login ok\n38 <12>1 - host db - - - disk almost full"))
+
+	got := store.waitFor(t, 2)
+	if got[0].Type != models.TypeAuth || got[0].Message != "login ok" {
+		t.Errorf("first entry = %+v", got[0])
+	}
+	if got[1].Level != models.LevelWarning || got[1].Message != "disk almost full" {
+		t.Errorf("second entry = %+v", got[1])
+	}
+
+	// Cancelling must also close connections that are still open.
+	cancel()
+	srv.Wait()
+}
+
+func TestTimestampPolicy(t *testing.T) {
+	store := &fakeStore{}
+	srv := NewServer(store, models.TimestampPolicy{Mode: models.TimestampReject, MaxPast: time.Hour})
+
+	srv.handle([]byte("<11>1 2024-01-15T10:30:00Z web api - - - too old"), nil)
+	srv.handle([]byte("<11>1 - web api - - - no timestamp"), nil)
+	got := store.waitFor(t, 1)
+	if len(got) != 1 || got[0].Message != "no timestamp" {
+		t.Errorf("stored %+v, want only the entry within the policy", got)
+	}
+}
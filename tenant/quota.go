@@ -0,0 +1,131 @@
+// Package tenant enforces per-tenant ingestion quotas.
+package tenant
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Window is the period quotas are counted over.
+const Window = time.Minute
+
+// Quotas limits how many entries each tenant may ingest per Window. Counts
+// are kept in memory per process and reset at the start of every window.
+type Quotas struct {
+	defaultLimit int
+	limits       map[string]int
+	now          func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+	used  map[string]int
+}
+
+// Usage is a tenant's consumption in the current window.
+type Usage struct {
+	Tenant string `json:"tenant"`
+	Used   int    `json:"used"`
+	// Limit is the tenant's quota; 0 means unlimited.
+	Limit int `json:"limit"`
+}
+
+// NewQuotas creates Quotas allowing defaultLimit entries per Window to every
+// tenant without an entry in limits. A limit of 0 means unlimited.
+func NewQuotas(defaultLimit int, limits map[string]int) *Quotas {
+	return &Quotas{defaultLimit: defaultLimit, limits: limits, now: time.Now, used: make(map[string]int)}
+}
+
+// Limit returns the quota of tenant; 0 means unlimited.
+func (q *Quotas) Limit(tenant string) int {
+	if l, ok := q.limits[tenant]; ok {
+		return l
+	}
+	return q.defaultLimit
+}
+
+// Allow charges counts, entries per tenant, if every tenant's count fits in
+// its remaining quota. Otherwise nothing is charged and retryAfter is the
+// time until the window resets, or 0 when a count exceeds a whole quota and
+// can never be accepted.
+func (q *Quotas) Allow(counts map[string]int) (retryAfter time.Duration, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.roll(now)
+	for t, n := range counts {
+		limit := q.Limit(t)
+		switch {
+		case limit == 0:
+		case n > limit:
+			return 0, false
+		case q.used[t]+n > limit:
+			retryAfter = q.start.Add(Window).Sub(now)
+		}
+	}
+	if retryAfter > 0 {
+		return retryAfter, false
+	}
+	for t, n := range counts {
+		if q.Limit(t) > 0 {
+			q.used[t] += n
+		}
+	}
+	return 0, true
+}
+
+// Usage returns the consumption of every tenant that ingested in the
+// current window.
+func (q *Quotas) Usage() []Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.roll(q.now())
+	usage := make([]Usage, 0, len(q.used))
+	for t, n := range q.used {
+		usage = append(usage, Usage{Tenant: t, Used: n, Limit: q.Limit(t)})
+	}
+	return usage
+}
+
+// roll starts a new window once the current one has passed.
+func (q *Quotas) roll(now time.Time) {
+	if start := now.Truncate(Window); !start.Equal(q.start) {
+		q.start = start
+		clear(q.used)
+	}
+}
+
+// ParseLimits parses per-tenant quotas in the form "name:limit,...".
+func ParseLimits(v string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid quota %q: want name:limit", item)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid quota %q: tenant name is required", item)
+		}
+		if err := models.ValidateTenant(name); err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid quota %q: limit must be a non-negative integer", item)
+		}
+		if _, dup := limits[name]; dup {
+			return nil, fmt.Errorf("duplicate quota for tenant %q", name)
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
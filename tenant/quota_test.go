@@ -0,0 +1,58 @@
+package tenant
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotasAllow(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 15, 0, time.UTC)
+	q := NewQuotas(10, map[string]int{"billing": 3, "internal": 0})
+	q.now = func() time.Time { return now }
+
+	if _, ok := q.Allow(map[string]int{"billing": 2}); !ok {
+		t.Fatal("Allow() rejected entries within the quota")
+	}
+	retry, ok := q.Allow(map[string]int{"billing": 2})
+	if ok || retry != 45*time.Second {
+		t.Errorf("Allow() over quota = %v, %v, want rejected until the window resets", retry, ok)
+	}
+	if _, ok := q.Allow(map[string]int{"billing": 1}); !ok {
+		t.Error("a rejected request should not consume quota")
+	}
+	if retry, ok := q.Allow(map[string]int{"billing": 4}); ok || retry != 0 {
+		t.Errorf("Allow() larger than the quota = %v, %v, want rejected for good", retry, ok)
+	}
+	if _, ok := q.Allow(map[string]int{"search": 10}); !ok {
+		t.Error("other tenants should use the default quota")
+	}
+	if _, ok := q.Allow(map[string]int{"internal": 1000}); !ok {
+		t.Error("a limit of 0 should be unlimited")
+	}
+	if _, ok := q.Allow(map[string]int{"search": 1, "other": 1}); ok {
+		t.Error("Allow() should reject the batch when one tenant is over quota")
+	}
+	if _, ok := q.Allow(map[string]int{"other": 10}); !ok {
+		t.Error("a rejected batch should not consume any tenant's quota")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := q.Allow(map[string]int{"billing": 3}); !ok {
+		t.Error("quota should reset in the next window")
+	}
+	if u := q.Usage(); len(u) != 1 || u[0] != (Usage{Tenant: "billing", Used: 3, Limit: 3}) {
+		t.Errorf("Usage() = %+v", u)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" billing:500, search:0,")
+	if err != nil || len(limits) != 2 || limits["billing"] != 500 || limits["search"] != 0 {
+		t.Errorf("ParseLimits() = %v, %v", limits, err)
+	}
+	for _, v := range []string{"billing", ":5", "billing:-1", "billing:x", "Billing:5", "a:1,a:2"} {
+		if _, err := ParseLimits(v); err == nil {
+			t.Errorf("ParseLimits(%q) expected error", v)
+		}
+	}
+}
@@ -0,0 +1,590 @@
+// Package wal buffers ingested log entries in a write-ahead log on disk and
+// writes them to the store in the background, so that entries survive store
+// outages and restarts.
+//
+// Entries are written to the store at least once: after a crash between a
+// write and the following checkpoint, the last batch is written again.
+package wal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+var (
+	// ErrFull is returned by Append when the entries do not fit in the
+	// buffer until more of it has been written to the store.
+	ErrFull = errors.New("ingestion buffer is full")
+	// ErrClosed is returned by Append after Close.
+	ErrClosed = errors.New("ingestion buffer is closed")
+)
+
+const (
+	segmentExt     = ".wal"
+	checkpointFile = "checkpoint"
+	deadLetterFile = "dead-letters.ndjson"
+
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Store writes buffered entries.
+type Store interface {
+	InsertLogs(entries []models.Log) ([]int, error)
+}
+
+// Options configures a Buffer. Zero values select the defaults.
+type Options struct {
+	// MaxBytes bounds the size of the entries not yet written to the store.
+	// Defaults to 256 MiB.
+	MaxBytes int64
+	// SegmentBytes is the size at which a new segment file is started.
+	// Defaults to 16 MiB.
+	SegmentBytes int64
+	// BatchSize is the most entries written to the store at once. Defaults
+	// to 1000.
+	BatchSize int
+	// Permanent reports whether a failed write would fail again however
+	// often it is retried, e.g. because an entry violates a constraint.
+	// Such batches are split to set the rejected entries aside in the
+	// dead-letter file. When nil, every error is retried.
+	Permanent func(error) bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = 256 << 20
+	}
+	if o.SegmentBytes <= 0 {
+		o.SegmentBytes = 16 << 20
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 1000
+	}
+	return o
+}
+
+// Stats describes the buffer for monitoring.
+type Stats struct {
+	// Entries and Bytes are the buffer depth: what has been accepted but
+	// not yet written to the store.
+	Entries  int64 `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+	Segments int   `json:"segments"`
+	Written  int64 `json:"written"`
+	// DeadLettered counts the entries the store rejected permanently,
+	// which were appended to the dead-letter file instead.
+	DeadLettered int64      `json:"dead_lettered"`
+	LastWrite    *time.Time `json:"last_write,omitempty"`
+	// LastError is the error of the last failed write, cleared by the next
+	// successful one.
+	LastError string `json:"last_error,omitempty"`
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// deadLetter is a line of the dead-letter file.
+type deadLetter struct {
+	RejectedAt time.Time  `json:"rejected_at"`
+	Error      string     `json:"error"`
+	Entry      models.Log `json:"entry"`
+}
+
+// position is the next record to write to the store, and the contents of
+// the checkpoint file.
+type position struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Buffer is a write-ahead log of entries waiting to be written to a Store.
+type Buffer struct {
+	dir   string
+	store Store
+	opts  Options
+	wake  chan struct{}
+	wg    sync.WaitGroup
+
+	// mu guards the fields below. segments are oldest first; the last one
+	// is appended to through active.
+	mu           sync.Mutex
+	active       *os.File
+	segments     []segment
+	read         position
+	entries      int64
+	bytes        int64
+	written      int64
+	deadLettered int64
+	lastWrite    time.Time
+	lastError    string
+}
+
+// Open opens the buffer in dir, creating it if needed. Entries left from a
+// previous run are written to store once Start is called.
+func Open(dir string, store Store, opts Options) (*Buffer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	b := &Buffer{dir: dir, store: store, opts: opts.withDefaults(), wake: make(chan struct{}, 1)}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	if b.entries > 0 {
+		log.Printf("Replaying %d buffered log entries from %s", b.entries, dir)
+	}
+	return b, nil
+}
+
+// load restores the pending segments after the checkpoint, truncating torn
+// records, and starts a new segment to append to.
+func (b *Buffer) load() error {
+	data, err := os.ReadFile(filepath.Join(b.dir, checkpointFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &b.read); err != nil {
+			return fmt.Errorf("invalid checkpoint: %w", err)
+		}
+	}
+
+	seqs, err := b.list()
+	if err != nil {
+		return err
+	}
+	last := b.read.Segment
+	for _, seq := range seqs {
+		last = max(last, seq)
+		start := int64(0)
+		if seq == b.read.Segment {
+			start = b.read.Offset
+		}
+		var size, entries int64
+		if seq >= b.read.Segment {
+			if size, entries, err = b.scan(seq, start); err != nil {
+				return err
+			}
+		}
+		if size <= start {
+			if err := os.Remove(b.path(seq)); err != nil {
+				return err
+			}
+			continue
+		}
+		b.segments = append(b.segments, segment{seq: seq, size: size})
+		b.entries += entries
+		b.bytes += size - start
+	}
+	if len(b.segments) == 0 || b.segments[0].seq != b.read.Segment {
+		b.read = position{Segment: last + 1}
+		if len(b.segments) > 0 {
+			b.read.Segment = b.segments[0].seq
+		}
+	}
+	f, err := b.create(last + 1)
+	if err != nil {
+		return err
+	}
+	b.active = f
+	b.segments = append(b.segments, segment{seq: last + 1})
+	return nil
+}
+
+// list returns the sequence numbers of the segment files in order.
+func (b *Buffer) list() ([]uint64, error) {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), segmentExt)
+		if !ok || f.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+// scan counts the records of a segment from start and returns the size of
+// its valid part, truncating any torn or damaged records after it.
+func (b *Buffer) scan(seq uint64, start int64) (int64, int64, error) {
+	f, err := os.OpenFile(b.path(seq), os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	if start > info.Size() {
+		return 0, 0, fmt.Errorf("checkpoint is past the end of %s", b.path(seq))
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	r := bufio.NewReader(f)
+	size, entries := start, int64(0)
+	for {
+		payload, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return size, entries, nil
+		}
+		if errors.Is(err, errCorrupt) {
+			log.Printf("Truncating %s at offset %d: %v", b.path(seq), size, err)
+			return size, entries, f.Truncate(size)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		size += headerSize + int64(len(payload))
+		entries++
+	}
+}
+
+// create creates a new segment file to append to.
+func (b *Buffer) create(seq uint64) (*os.File, error) {
+	return os.OpenFile(b.path(seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+}
+
+func (b *Buffer) path(seq uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// Append durably buffers entries. It returns ErrFull, buffering none of
+// them, when they do not fit.
+func (b *Buffer) Append(entries []models.Log) error {
+	var buf []byte
+	for _, e := range entries {
+		var err error
+		if buf, err = appendRecord(buf, e); err != nil {
+			return err
+		}
+	}
+	n := int64(len(buf))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.active == nil {
+		return ErrClosed
+	}
+	if b.bytes+n > b.opts.MaxBytes {
+		return ErrFull
+	}
+	if last := b.segments[len(b.segments)-1]; last.size > 0 && last.size+n > b.opts.SegmentBytes {
+		// The new segment is opened before the old one is closed so that a
+		// failure leaves the buffer appending to the old one.
+		f, err := b.create(last.seq + 1)
+		if err != nil {
+			return err
+		}
+		if err := b.active.Close(); err != nil {
+			log.Printf("Error closing %s: %v", b.path(last.seq), err)
+		}
+		b.active = f
+		b.segments = append(b.segments, segment{seq: last.seq + 1})
+	}
+
+	last := &b.segments[len(b.segments)-1]
+	if _, err := b.active.Write(buf); err != nil {
+		b.active.Truncate(last.size)
+		return err
+	}
+	if err := b.active.Sync(); err != nil {
+		b.active.Truncate(last.size)
+		return err
+	}
+	last.size += n
+	b.bytes += n
+	b.entries += int64(len(entries))
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start writes buffered entries to the store in batches until ctx is done.
+// Failed writes are retried with backoff.
+func (b *Buffer) Start(ctx context.Context) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		backoff := minBackoff
+		for {
+			n, err := b.flush()
+			if err != nil {
+				b.mu.Lock()
+				b.lastError = err.Error()
+				b.mu.Unlock()
+				log.Printf("Error writing buffered logs: %v", err)
+				timer := time.NewTimer(backoff)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				backoff = min(backoff*2, maxBackoff)
+				continue
+			}
+			backoff = minBackoff
+			if n == 0 {
+				select {
+				case <-b.wake:
+				case <-ctx.Done():
+					return
+				}
+			} else if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the goroutine started by Start has returned.
+func (b *Buffer) Wait() {
+	b.wg.Wait()
+}
+
+// Close closes the segment being appended to. Entries not yet written to
+// the store stay on disk for the next Open.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.active == nil {
+		return nil
+	}
+	err := b.active.Close()
+	b.active = nil
+	return err
+}
+
+// Stats returns the buffer depth and the outcome of recent writes.
+func (b *Buffer) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := Stats{
+		Entries:      b.entries,
+		Bytes:        b.bytes,
+		MaxBytes:     b.opts.MaxBytes,
+		Segments:     len(b.segments),
+		Written:      b.written,
+		DeadLettered: b.deadLettered,
+		LastError:    b.lastError,
+	}
+	if !b.lastWrite.IsZero() {
+		t := b.lastWrite.UTC()
+		s.LastWrite = &t
+	}
+	return s
+}
+
+// flush writes the next batch to the store and returns how many records it
+// consumed.
+func (b *Buffer) flush() (int, error) {
+	entries, records, end, err := b.next()
+	if err != nil {
+		return 0, err
+	}
+	rejected := 0
+	if len(entries) > 0 {
+		if rejected, err = b.write(entries); err != nil {
+			return 0, err
+		}
+	}
+	return records, b.advance(end, records, rejected)
+}
+
+// write inserts entries into the store and returns how many of them it
+// rejected permanently. A batch failing that way is split in halves until
+// the rejected entries are found, which are set aside in the dead-letter
+// file. When a later half fails for another reason, the whole batch is
+// retried, so the halves already inserted are inserted again.
+func (b *Buffer) write(entries []models.Log) (int, error) {
+	_, err := b.store.InsertLogs(entries)
+	if err == nil || b.opts.Permanent == nil || !b.opts.Permanent(err) {
+		return 0, err
+	}
+	if len(entries) == 1 {
+		return 1, b.reject(entries[0], err)
+	}
+	half := len(entries) / 2
+	first, err := b.write(entries[:half])
+	if err != nil {
+		return 0, err
+	}
+	second, err := b.write(entries[half:])
+	return first + second, err
+}
+
+// reject appends an entry the store rejected to the dead-letter file.
+func (b *Buffer) reject(e models.Log, cause error) error {
+	data, err := json.Marshal(deadLetter{RejectedAt: time.Now().UTC(), Error: cause.Error(), Entry: e})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(b.dir, deadLetterFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("Store rejected buffered log entry, moved to %s: %v", path, cause)
+	return nil
+}
+
+// next reads up to a batch of entries from the read position and returns
+// them with the number of records read and the position after them.
+// Records that cannot be decoded are logged and skipped.
+func (b *Buffer) next() ([]models.Log, int, position, error) {
+	b.mu.Lock()
+	pos := b.read
+	segments := slices.Clone(b.segments)
+	b.mu.Unlock()
+
+	var entries []models.Log
+	records := 0
+	for _, seg := range segments {
+		if records >= b.opts.BatchSize {
+			break
+		}
+		if seg.seq < pos.Segment {
+			continue
+		}
+		if seg.seq > pos.Segment {
+			pos = position{Segment: seg.seq}
+		}
+		if pos.Offset >= seg.size {
+			continue
+		}
+
+		f, err := os.Open(b.path(seg.seq))
+		if err != nil {
+			return nil, 0, pos, err
+		}
+		if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, 0, pos, err
+		}
+		r := bufio.NewReader(f)
+		for pos.Offset < seg.size && records < b.opts.BatchSize {
+			payload, err := readRecord(r)
+			if err != nil {
+				f.Close()
+				return nil, 0, pos, fmt.Errorf("reading %s at offset %d: %w", b.path(seg.seq), pos.Offset, err)
+			}
+			pos.Offset += headerSize + int64(len(payload))
+			records++
+			var e models.Log
+			if err := json.Unmarshal(payload, &e); err != nil {
+				log.Printf("Skipping undecodable buffered log entry: %v", err)
+				continue
+			}
+			entries = append(entries, e)
+		}
+		f.Close()
+	}
+	return entries, records, pos, nil
+}
+
+// advance moves the read position to end after records were written, of
+// which rejected went to the dead-letter file, removing fully written
+// segments and saving the checkpoint.
+func (b *Buffer) advance(end position, records, rejected int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if end == b.read {
+		return nil
+	}
+	b.read = end
+	for len(b.segments) > 1 {
+		first := b.segments[0]
+		if first.seq > b.read.Segment || first.seq == b.read.Segment && b.read.Offset < first.size {
+			break
+		}
+		if err := os.Remove(b.path(first.seq)); err != nil {
+			log.Printf("Error removing %s: %v", b.path(first.seq), err)
+		}
+		b.segments = b.segments[1:]
+		if b.read.Segment < b.segments[0].seq {
+			b.read = position{Segment: b.segments[0].seq}
+		}
+	}
+
+	b.bytes = -b.read.Offset
+	for _, seg := range b.segments {
+		b.bytes += seg.size
+	}
+	b.entries -= int64(records)
+	b.deadLettered += int64(rejected)
+	if records > rejected {
+		b.written += int64(records - rejected)
+		b.lastWrite = time.Now()
+		b.lastError = ""
+	}
+	return b.checkpoint()
+}
+
+// checkpoint atomically saves the read position.
+func (b *Buffer) checkpoint() error {
+	data, err := json.Marshal(b.read)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(b.dir, checkpointFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(b.dir, checkpointFile))
+}
@@ -0,0 +1,395 @@
+package wal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+var errRejected = errors.New("entry rejected")
+
+func permanent(err error) bool {
+	return errors.Is(err, errRejected)
+}
+
+type fakeStore struct {
+	mu    sync.Mutex
+	logs  []models.Log
+	fails int
+	// reject fails every batch containing one of these messages.
+	reject map[string]bool
+	calls  int
+}
+
+func (s *fakeStore) InsertLogs(entries []models.Log) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.fails > 0 {
+		s.fails--
+		return nil, errors.New("database is down")
+	}
+	for _, e := range entries {
+		if s.reject[e.Message] {
+			return nil, errRejected
+		}
+	}
+	ids := make([]int, len(entries))
+	for i := range entries {
+		s.logs = append(s.logs, entries[i])
+		ids[i] = len(s.logs)
+	}
+	return ids, nil
+}
+
+func (s *fakeStore) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []string
+	for _, l := range s.logs {
+		msgs = append(msgs, l.Message)
+	}
+	return msgs
+}
+
+func entries(from, to int) []models.Log {
+	var logs []models.Log
+	for i := from; i < to; i++ {
+		logs = append(logs, models.Log{Level: "INFO", Type: "API", Message: fmt.Sprintf("m%d", i)})
+	}
+	return logs
+}
+
+// waitWritten waits until the store holds n entries.
+func waitWritten(t *testing.T, store *fakeStore, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.messages()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("store has %d entries, want %d", len(store.messages()), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func equal(got []string, from, to int) bool {
+	if len(got) != to-from {
+		return false
+	}
+	for i, msg := range got {
+		if msg != fmt.Sprintf("m%d", from+i) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBuffer(t *testing.T) {
+	store := &fakeStore{}
+	b, err := Open(t.TempDir(), store, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Start(ctx)
+
+	if err := b.Append(entries(0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	waitWritten(t, store, 3)
+	if err := b.Append(entries(3, 5)); err != nil {
+		t.Fatal(err)
+	}
+	waitWritten(t, store, 5)
+	cancel()
+	b.Wait()
+
+	if got := store.messages(); !equal(got, 0, 5) {
+		t.Errorf("store = %v, want m0..m4", got)
+	}
+	st := b.Stats()
+	if st.Entries != 0 || st.Bytes != 0 || st.Written != 5 || st.LastWrite == nil {
+		t.Errorf("stats = %+v, want 5 written and nothing pending", st)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	b, err := Open(dir, &fakeStore{}, Options{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Append(entries(0, 5)); err != nil {
+		t.Fatal(err)
+	}
+	// Write one batch, then stop as if the process exited.
+	if n, err := b.flush(); err != nil || n != 2 {
+		t.Fatalf("flush = %d, %v, want 2", n, err)
+	}
+	b.Close()
+
+	store := &fakeStore{}
+	b, err = Open(dir, store, Options{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := b.Stats(); st.Entries != 3 {
+		t.Errorf("entries after reopen = %d, want 3", st.Entries)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Start(ctx)
+	waitWritten(t, store, 3)
+	cancel()
+	b.Wait()
+	b.Close()
+	if got := store.messages(); !equal(got, 2, 5) {
+		t.Errorf("replayed = %v, want m2..m4", got)
+	}
+
+	b, err = Open(dir, store, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if st := b.Stats(); st.Entries != 0 || st.Segments != 1 {
+		t.Errorf("stats after replay = %+v, want nothing pending in one segment", st)
+	}
+}
+
+func TestFull(t *testing.T) {
+	b, err := Open(t.TempDir(), &fakeStore{}, Options{MaxBytes: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Append(entries(0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	before := b.Stats()
+	if err := b.Append(entries(1, 4)); !errors.Is(err, ErrFull) {
+		t.Fatalf("err = %v, want ErrFull", err)
+	}
+	if st := b.Stats(); st != before {
+		t.Errorf("stats = %+v after a rejected append, want %+v", st, before)
+	}
+
+	if n, err := b.flush(); err != nil || n != 1 {
+		t.Fatalf("flush = %d, %v, want 1", n, err)
+	}
+	if err := b.Append(entries(1, 2)); err != nil {
+		t.Errorf("append after flush: %v", err)
+	}
+
+	b.Close()
+	if err := b.Append(entries(2, 3)); !errors.Is(err, ErrClosed) {
+		t.Errorf("err = %v, want ErrClosed", err)
+	}
+}
+
+func TestStoreOutage(t *testing.T) {
+	store := &fakeStore{fails: 1}
+	b, err := Open(t.TempDir(), store, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Append(entries(0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.flush(); err == nil {
+		t.Fatal("flush succeeded while the store is down")
+	}
+	if st := b.Stats(); st.Entries != 2 {
+		t.Errorf("entries = %d after a failed write, want 2", st.Entries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.Start(ctx)
+	waitWritten(t, store, 2)
+	if got := store.messages(); !equal(got, 0, 2) {
+		t.Errorf("store = %v, want m0 m1", got)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	dir := t.TempDir()
+	b, err := Open(dir, &fakeStore{}, Options{SegmentBytes: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := b.Append(entries(i, i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := b.Stats(); st.Segments < 2 {
+		t.Fatalf("segments = %d, want a rotation", st.Segments)
+	}
+
+	// A record torn by a crash is dropped on the next open.
+	last := b.path(b.segments[len(b.segments)-1].seq)
+	b.Close()
+	f, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1})
+	f.Close()
+
+	store := &fakeStore{}
+	b, err = Open(dir, store, Options{SegmentBytes: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if st := b.Stats(); st.Entries != 4 {
+		t.Errorf("entries = %d, want 4", st.Entries)
+	}
+	for {
+		n, err := b.flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if got := store.messages(); !equal(got, 0, 4) {
+		t.Errorf("store = %v, want m0..m3", got)
+	}
+	if st := b.Stats(); st.Segments != 1 || st.Bytes != 0 {
+		t.Errorf("stats = %+v, want only the active segment left", st)
+	}
+}
+
+func TestRotateFailure(t *testing.T) {
+	dir := t.TempDir()
+	store := &fakeStore{}
+	b, err := Open(dir, store, Options{SegmentBytes: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if err := b.Append(entries(0, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file in the way of the next segment makes the rotation fail.
+	next := b.path(b.segments[0].seq + 1)
+	if err := os.WriteFile(next, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Append(entries(1, 3)); err == nil {
+		t.Fatal("append succeeded although the next segment could not be created")
+	}
+	if st := b.Stats(); st.Entries != 1 || st.Segments != 1 {
+		t.Errorf("stats = %+v after a failed rotation, want 1 entry in 1 segment", st)
+	}
+
+	// The buffer is still open and rotates once the file is out of the way.
+	if err := os.Remove(next); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Append(entries(1, 3)); err != nil {
+		t.Fatalf("append after a failed rotation: %v", err)
+	}
+	for {
+		n, err := b.flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if got := store.messages(); !equal(got, 0, 3) {
+		t.Errorf("store = %v, want m0..m2", got)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	store := &fakeStore{reject: map[string]bool{"m2": true, "m5": true}}
+	b, err := Open(dir, store, Options{Permanent: permanent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Append(entries(0, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := b.flush(); err != nil || n != 8 {
+		t.Fatalf("flush = %d, %v, want 8", n, err)
+	}
+	if got, want := store.messages(), []string{"m0", "m1", "m3", "m4", "m6", "m7"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("store = %v, want %v", got, want)
+	}
+	st := b.Stats()
+	if st.Entries != 0 || st.Written != 6 || st.DeadLettered != 2 {
+		t.Errorf("stats = %+v, want 6 written and 2 dead-lettered", st)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, deadLetterFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rejected []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var dl deadLetter
+		if err := json.Unmarshal([]byte(line), &dl); err != nil {
+			t.Fatalf("invalid dead letter %q: %v", line, err)
+		}
+		if dl.Error != errRejected.Error() || dl.RejectedAt.IsZero() {
+			t.Errorf("dead letter = %+v", dl)
+		}
+		rejected = append(rejected, dl.Entry.Message)
+	}
+	if fmt.Sprint(rejected) != "[m2 m5]" {
+		t.Errorf("dead letters = %v, want [m2 m5]", rejected)
+	}
+
+	// The rejected entries are not written again after a restart.
+	b.Close()
+	b, err = Open(dir, store, Options{Permanent: permanent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if st := b.Stats(); st.Entries != 0 {
+		t.Errorf("entries = %d after reopening, want 0", st.Entries)
+	}
+}
+
+func TestRejectedWithoutClassifier(t *testing.T) {
+	// Without Permanent every error is retried and nothing is set aside.
+	store := &fakeStore{reject: map[string]bool{"m1": true}}
+	b, err := Open(t.TempDir(), store, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Append(entries(0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.flush(); !errors.Is(err, errRejected) {
+		t.Fatalf("flush error = %v, want errRejected", err)
+	}
+	if st := b.Stats(); st.Entries != 2 || st.DeadLettered != 0 || store.calls != 1 {
+		t.Errorf("stats = %+v after %d calls, want 2 entries pending after 1 call", st, store.calls)
+	}
+}
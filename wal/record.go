@@ -0,0 +1,65 @@
+package wal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// A record is a big-endian payload length and CRC-32C checksum followed by
+// the JSON-encoded entry.
+const headerSize = 8
+
+// maxRecordSize bounds a payload so that a damaged length cannot cause a
+// huge allocation.
+const maxRecordSize = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorrupt is returned for a torn or damaged record, typically the tail
+// of a segment that was being written during a crash.
+var errCorrupt = errors.New("corrupt record")
+
+// appendRecord appends the record for l to buf.
+func appendRecord(buf []byte, l models.Log) ([]byte, error) {
+	payload, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	var hdr [headerSize]byte
+	binary.BigEndian.PutUint32(hdr[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(hdr[4:], crc32.Checksum(payload, crcTable))
+	buf = append(buf, hdr[:]...)
+	return append(buf, payload...), nil
+}
+
+// readRecord reads the payload of the next record from r. It returns io.EOF
+// at a clean end and errCorrupt for a torn or damaged record.
+func readRecord(r io.Reader) ([]byte, error) {
+	var hdr [headerSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errCorrupt
+		}
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:4])
+	if n > maxRecordSize {
+		return nil, errCorrupt
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errCorrupt
+		}
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(hdr[4:]) {
+		return nil, errCorrupt
+	}
+	return payload, nil
+}
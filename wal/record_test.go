@@ -0,0 +1,50 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestRecord(t *testing.T) {
+	var buf []byte
+	var err error
+	for _, msg := range []string{"one", "two"} {
+		if buf, err = appendRecord(buf, models.Log{Level: "INFO", Type: "API", Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := bytes.NewReader(buf)
+	for _, want := range []string{`"message":"one"`, `"message":"two"`} {
+		payload, err := readRecord(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(payload, []byte(want)) {
+			t.Errorf("payload = %s, want it to contain %s", payload, want)
+		}
+	}
+	if _, err := readRecord(r); !errors.Is(err, io.EOF) {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+
+	t.Run("torn", func(t *testing.T) {
+		for _, n := range []int{3, headerSize + 2} {
+			if _, err := readRecord(bytes.NewReader(buf[:n])); !errors.Is(err, errCorrupt) {
+				t.Errorf("%d bytes: err = %v, want errCorrupt", n, err)
+			}
+		}
+	})
+
+	t.Run("checksum", func(t *testing.T) {
+		damaged := bytes.Clone(buf)
+		damaged[headerSize+1] ^= 0xff
+		if _, err := readRecord(bytes.NewReader(damaged)); !errors.Is(err, errCorrupt) {
+			t.Errorf("err = %v, want errCorrupt", err)
+		}
+	})
+}
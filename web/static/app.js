@@ -3,10 +3,19 @@ document.addEventListener('DOMContentLoaded', function() {
     const logTable = document.getElementById('log-body');
     const levelFilter = document.getElementById('level-filter');
     const typeFilter = document.getElementById('type-filter');
+    const searchInput = document.getElementById('search-input');
+    const searchMode = document.getElementById('search-mode');
     const addLogBtn = document.getElementById('add-log-btn');
     const modal = document.getElementById('add-log-modal');
     const closeBtn = document.querySelector('.close');
     const addLogForm = document.getElementById('add-log-form');
+    const apiKeyInput = document.getElementById('api-key');
+
+    // The key is kept in this browser only and sent with every API request
+    apiKeyInput.value = localStorage.getItem('golog-api-key') || '';
+
+    // Levels and types are configurable, so the dropdowns come from the server
+    loadRegistry();
 
     // Initial load of logs
     fetchLogs();
@@ -14,9 +23,15 @@ document.addEventListener('DOMContentLoaded', function() {
     // Set up event listeners
     levelFilter.addEventListener('change', fetchLogs);
     typeFilter.addEventListener('change', fetchLogs);
+    searchInput.addEventListener('change', fetchLogs);
+    searchMode.addEventListener('change', fetchLogs);
     addLogBtn.addEventListener('click', openModal);
     closeBtn.addEventListener('click', closeModal);
     addLogForm.addEventListener('submit', submitLog);
+    apiKeyInput.addEventListener('change', function() {
+        localStorage.setItem('golog-api-key', apiKeyInput.value);
+        fetchLogs();
+    });
 
     // Close modal when clicking outside
     window.addEventListener('click', function(event) {
@@ -29,22 +44,75 @@ document.addEventListener('DOMContentLoaded', function() {
     startEventSource();
 
     // Functions
+    function loadRegistry() {
+        fetch('/api/registry')
+            .then(response => response.json())
+            .then(registry => {
+                const levels = registry.levels.map(level => level.name);
+                fillOptions(levelFilter, levels);
+                fillOptions(document.getElementById('log-level'), levels);
+                fillOptions(typeFilter, registry.types);
+                fillOptions(document.getElementById('log-type'), registry.types);
+            })
+            .catch(error => {
+                console.error('Error loading levels and types:', error);
+            });
+    }
+
+    function fillOptions(select, values) {
+        values.forEach(value => {
+            const option = document.createElement('option');
+            option.value = value;
+            option.textContent = value;
+            select.appendChild(option);
+        });
+    }
+
+    function authHeaders() {
+        return apiKeyInput.value ? { 'X-API-Key': apiKeyInput.value } : {};
+    }
+
+    function filterParams() {
+        const params = [];
+
+        if (levelFilter.value) params.push(`level=${encodeURIComponent(levelFilter.value)}`);
+        if (typeFilter.value) params.push(`type=${encodeURIComponent(typeFilter.value)}`);
+        if (searchInput.value) {
+            params.push(`q=${encodeURIComponent(searchInput.value)}`);
+            params.push(`mode=${searchMode.value}`);
+        }
+
+        return params;
+    }
+
+    function escapeHTML(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML;
+    }
+
+    // Snippets mark matches with <mark>; everything else is escaped.
+    function renderSnippet(snippet) {
+        return escapeHTML(snippet)
+            .replace(/&lt;mark&gt;/g, '<mark>')
+            .replace(/&lt;\/mark&gt;/g, '</mark>');
+    }
+
     function fetchLogs() {
-        const level = levelFilter.value;
-        const type = typeFilter.value;
-        
         let url = '/api/logs';
-        const params = [];
-        
-        if (level) params.push(`level=${level}`);
-        if (type) params.push(`type=${type}`);
-        
+        const params = filterParams();
+
         if (params.length > 0) {
             url += '?' + params.join('&');
         }
 
-        fetch(url)
-            .then(response => response.json())
+        fetch(url, { headers: authHeaders() })
+            .then(response => {
+                if (!response.ok) {
+                    throw new Error(`HTTP ${response.status}`);
+                }
+                return response.json();
+            })
             .then(logs => {
                 renderLogs(logs);
             })
@@ -72,12 +140,13 @@ document.addEventListener('DOMContentLoaded', function() {
         const row = document.createElement('tr');
         
         const timestamp = new Date(log.timestamp).toLocaleString();
+        const received = log.received_at ? `Received ${new Date(log.received_at).toLocaleString()}` : '';
         
         row.innerHTML = `
-            <td>${timestamp}</td>
-            <td class="level-${log.level}">${log.level}</td>
-            <td>${log.type}</td>
-            <td>${log.message}</td>
+            <td title="${escapeHTML(received)}">${timestamp}</td>
+            <td class="level-${escapeHTML(log.level)}">${escapeHTML(log.level)}</td>
+            <td>${escapeHTML(log.type)}</td>
+            <td>${log.snippet ? renderSnippet(log.snippet) : escapeHTML(log.message)}</td>
         `;
         
         // Add new logs at the top
@@ -85,15 +154,11 @@ document.addEventListener('DOMContentLoaded', function() {
     }
 
     function startEventSource() {
-        const level = levelFilter.value;
-        const type = typeFilter.value;
-        
         let url = '/api/logs/stream';
-        const params = [];
-        
-        if (level) params.push(`level=${level}`);
-        if (type) params.push(`type=${type}`);
-        
+        const params = filterParams();
+        // EventSource cannot send headers, so the key goes in the query
+        if (apiKeyInput.value) params.push(`api_key=${encodeURIComponent(apiKeyInput.value)}`);
+
         if (params.length > 0) {
             url += '?' + params.join('&');
         }
@@ -121,6 +186,16 @@ document.addEventListener('DOMContentLoaded', function() {
             eventSource.close();
             startEventSource();
         });
+
+        searchInput.addEventListener('change', function() {
+            eventSource.close();
+            startEventSource();
+        });
+
+        apiKeyInput.addEventListener('change', function() {
+            eventSource.close();
+            startEventSource();
+        });
     }
 
     function openModal() {
@@ -143,9 +218,7 @@ document.addEventListener('DOMContentLoaded', function() {
 
         fetch('/api/logs', {
             method: 'POST',
-            headers: {
-                'Content-Type': 'application/json'
-            },
+            headers: Object.assign({ 'Content-Type': 'application/json' }, authHeaders()),
             body: JSON.stringify(logData)
         })
         .then(response => {
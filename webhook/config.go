@@ -0,0 +1,162 @@
+// Package webhook delivers alerts and matching log entries to HTTP
+// endpoints as signed JSON payloads.
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// Defaults for unset Destination fields.
+const (
+	DefaultRateLimit   = 5.0
+	DefaultBurst       = 10
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultQueueSize   = 1000
+
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff = time.Minute
+)
+
+// Destination is an endpoint that receives webhooks.
+type Destination struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Secret signs each request with HMAC-SHA256 when set.
+	Secret string `json:"secret,omitempty"`
+
+	// Alerts subscribes to alert firing and resolution events. Filter
+	// subscribes to log entries matching it.
+	Alerts bool              `json:"alerts"`
+	Filter *models.LogFilter `json:"filter,omitempty"`
+
+	// Template renders the request body from a Payload; the result must be
+	// JSON. The Payload itself is sent when empty.
+	Template string            `json:"template,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+
+	// RateLimit is the sustained number of requests per second, with bursts
+	// of up to Burst requests.
+	RateLimit   float64         `json:"rate_limit,omitempty"`
+	Burst       int             `json:"burst,omitempty"`
+	MaxAttempts int             `json:"max_attempts,omitempty"`
+	Backoff     models.Duration `json:"backoff,omitempty"`
+	Timeout     models.Duration `json:"timeout,omitempty"`
+	QueueSize   int             `json:"queue_size,omitempty"`
+}
+
+// withDefaults fills in unset fields.
+func (d Destination) withDefaults() Destination {
+	if d.RateLimit <= 0 {
+		d.RateLimit = DefaultRateLimit
+	}
+	if d.Burst <= 0 {
+		d.Burst = DefaultBurst
+	}
+	if d.MaxAttempts <= 0 {
+		d.MaxAttempts = DefaultMaxAttempts
+	}
+	if d.Backoff <= 0 {
+		d.Backoff = models.Duration(DefaultBackoff)
+	}
+	if d.Timeout <= 0 {
+		d.Timeout = models.Duration(DefaultTimeout)
+	}
+	if d.QueueSize <= 0 {
+		d.QueueSize = DefaultQueueSize
+	}
+	return d
+}
+
+// Validate checks the destination, its filter and its template.
+func (d Destination) Validate() error {
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q", d.URL)
+	}
+	if !d.Alerts && d.Filter == nil {
+		return errors.New("subscribe to alerts, a log filter or both")
+	}
+	if f := d.Filter; f != nil {
+		for _, l := range f.LevelSet() {
+			if !models.ValidLevel(l) {
+				return fmt.Errorf("invalid level %q", l)
+			}
+		}
+		for _, t := range f.TypeSet() {
+			if !models.ValidType(t) {
+				return fmt.Errorf("invalid type %q", t)
+			}
+		}
+		if f.MinLevel != "" && !models.ValidLevel(f.MinLevel) {
+			return fmt.Errorf("invalid min_level %q", f.MinLevel)
+		}
+		if err := f.ValidateSearch(); err != nil {
+			return err
+		}
+	}
+	if _, err := parseTemplate(d.Template); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("payload").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// toJSON lets templates embed values safely, e.g. {"text": {{json .Log.Message}}}.
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// LoadDestinations reads a JSON array of destinations from path.
+// Environment variables in url, secret and header values are expanded, so
+// secrets need not be stored in the file.
+func LoadDestinations(path string) ([]Destination, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dests []Destination
+	if err := json.Unmarshal(data, &dests); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names := make(map[string]bool, len(dests))
+	for i := range dests {
+		d := &dests[i]
+		d.URL = os.ExpandEnv(d.URL)
+		d.Secret = os.ExpandEnv(d.Secret)
+		for k, v := range d.Headers {
+			d.Headers[k] = os.ExpandEnv(v)
+		}
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: destination %q: %w", path, d.Name, err)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("%s: duplicate destination %q", path, d.Name)
+		}
+		names[d.Name] = true
+	}
+	return dests, nil
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mstgnz/golog/models"
+)
+
+func TestDestinationValidate(t *testing.T) {
+	valid := Destination{Name: "oncall", URL: "https://hooks.example.com/golog", Alerts: true}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(d *Destination)
+		want   string
+	}{
+		{"missing name", func(d *Destination) { d.Name = "" }, "name"},
+		{"bad scheme", func(d *Destination) { d.URL = "ftp://example.com" }, "invalid url"},
+		{"no subscription", func(d *Destination) { d.Alerts = false }, "subscribe"},
+		{"bad level", func(d *Destination) { d.Filter = &models.LogFilter{Levels: []string{"SEVERE"}} }, "invalid level"},
+		{"bad type", func(d *Destination) { d.Filter = &models.LogFilter{Type: "QUEUE"} }, "invalid type"},
+		{"bad regex", func(d *Destination) {
+			d.Filter = &models.LogFilter{Query: "(", SearchMode: models.SearchRegex}
+		}, "regex"},
+		{"bad template", func(d *Destination) { d.Template = "{{.Nope" }, "invalid template"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := valid
+			tc.modify(&d)
+			if err := d.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadDestinations(t *testing.T) {
+	t.Setenv("GOLOG_TEST_SECRET", "s3cret")
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	os.WriteFile(path, []byte(`[
+		{"name": "oncall", "url": "https://hooks.example.com/golog", "secret": "${GOLOG_TEST_SECRET}", "alerts": true},
+		{"name": "db", "url": "http://localhost:9000/hook", "filter": {"types": ["DATABASE"], "min_level": "WARNING"}, "rate_limit": 1, "backoff": "2s"}
+	]`), 0o644)
+
+	dests, err := LoadDestinations(path)
+	if err != nil {
+		t.Fatalf("LoadDestinations() error: %v", err)
+	}
+	if len(dests) != 2 || dests[0].Secret != "s3cret" {
+		t.Fatalf("LoadDestinations() = %+v", dests)
+	}
+	if f := dests[1].Filter; f == nil || f.MinLevel != "WARNING" || f.Types[0] != "DATABASE" || dests[1].RateLimit != 1 {
+		t.Errorf("second destination = %+v", dests[1])
+	}
+
+	os.WriteFile(path, []byte(`[
+		{"name": "a", "url": "http://localhost/a", "alerts": true},
+		{"name": "a", "url": "http://localhost/b", "alerts": true}
+	]`), 0o644)
+	if _, err := LoadDestinations(path); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("LoadDestinations() error = %v, want duplicate name", err)
+	}
+}
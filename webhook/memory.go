@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"sync"
+
+	"github.com/mstgnz/golog/models"
+)
+
+// maxMemoryDeadLetters bounds MemoryDeadLetters; the oldest entries are
+// discarded first.
+const maxMemoryDeadLetters = 1000
+
+// MemoryDeadLetters keeps failed deliveries in memory for the memory
+// driver.
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+	lastID  int
+}
+
+// NewMemoryDeadLetters creates an empty MemoryDeadLetters.
+func NewMemoryDeadLetters() *MemoryDeadLetters {
+	return &MemoryDeadLetters{}
+}
+
+// InsertDeadLetter records d.
+func (s *MemoryDeadLetters) InsertDeadLetter(d DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	d.ID = s.lastID
+	s.letters = append(s.letters, d)
+	if len(s.letters) > maxMemoryDeadLetters {
+		s.letters = s.letters[1:]
+	}
+	return nil
+}
+
+// ListDeadLetters returns up to limit dead letters, newest first.
+func (s *MemoryDeadLetters) ListDeadLetters(limit int) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limit = models.LogFilter{Limit: limit}.PageSize()
+	var out []DeadLetter
+	for i := len(s.letters) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, s.letters[i])
+	}
+	return out, nil
+}
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+// Event names sent in the X-Golog-Event header and Payload.Event.
+const (
+	EventLog   = "log"
+	EventAlert = "alert"
+)
+
+// Request headers.
+const (
+	HeaderEvent     = "X-Golog-Event"
+	HeaderDelivery  = "X-Golog-Delivery"
+	HeaderTimestamp = "X-Golog-Timestamp"
+	HeaderSignature = "X-Golog-Signature"
+)
+
+// Payload is the data a destination's template is rendered from, and the
+// request body when it has no template.
+type Payload struct {
+	Event       string          `json:"event"`
+	Destination string          `json:"destination"`
+	Timestamp   time.Time       `json:"timestamp"`
+	Log         *models.Log     `json:"log,omitempty"`
+	Rule        *alerting.Rule  `json:"rule,omitempty"`
+	Alert       *alerting.Alert `json:"alert,omitempty"`
+}
+
+// DeadLetter is a delivery that failed after all attempts.
+type DeadLetter struct {
+	ID          int       `json:"id"`
+	Destination string    `json:"destination"`
+	URL         string    `json:"url"`
+	Event       string    `json:"event"`
+	Payload     string    `json:"payload"`
+	Error       string    `json:"error"`
+	Attempts    int       `json:"attempts"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// deadLetterQueueSize bounds the failed deliveries waiting to be recorded.
+// Further failures are dropped and counted rather than blocking the caller.
+const deadLetterQueueSize = 1000
+
+// DeadLetterStore records failed deliveries.
+type DeadLetterStore interface {
+	InsertDeadLetter(d DeadLetter) error
+	ListDeadLetters(limit int) ([]DeadLetter, error)
+}
+
+// Notifier queues events per destination and delivers them in the
+// background. It implements alerting.Notifier, and Observe can be
+// registered as a log observer.
+type Notifier struct {
+	deadLetters DeadLetterStore
+	client      *http.Client
+	dests       []*destination
+	wg          sync.WaitGroup
+
+	// dead queues failed deliveries for the dead-letter writer, so that
+	// Observe and Notify never wait for the store.
+	dead    chan DeadLetter
+	dropped atomic.Int64
+}
+
+type destination struct {
+	Destination
+	tmpl    *template.Template
+	limiter *limiter
+	queue   chan Payload
+}
+
+// NewNotifier validates the destinations and creates a Notifier. Call
+// Start to begin delivering.
+func NewNotifier(dests []Destination, deadLetters DeadLetterStore) (*Notifier, error) {
+	n := &Notifier{deadLetters: deadLetters, client: &http.Client{}, dead: make(chan DeadLetter, deadLetterQueueSize)}
+	for _, d := range dests {
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("destination %q: %w", d.Name, err)
+		}
+		d = d.withDefaults()
+		tmpl, _ := parseTemplate(d.Template)
+		n.dests = append(n.dests, &destination{
+			Destination: d,
+			tmpl:        tmpl,
+			limiter:     newLimiter(d.RateLimit, d.Burst),
+			queue:       make(chan Payload, d.QueueSize),
+		})
+	}
+	return n, nil
+}
+
+// Start runs one delivery worker per destination and the dead-letter
+// writer until ctx is done. Queued events that have not been sent by then
+// are dropped; queued dead letters are still recorded.
+func (n *Notifier) Start(ctx context.Context) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		for {
+			select {
+			case d := <-n.dead:
+				n.record(d)
+			case <-ctx.Done():
+				for len(n.dead) > 0 {
+					n.record(<-n.dead)
+				}
+				if dropped := n.Dropped(); dropped > 0 {
+					log.Printf("Webhooks dropped %d dead letters because the queue was full", dropped)
+				}
+				return
+			}
+		}
+	}()
+	for _, d := range n.dests {
+		n.wg.Add(1)
+		go func(d *destination) {
+			defer n.wg.Done()
+			for {
+				select {
+				case p := <-d.queue:
+					n.deliver(ctx, d, p)
+				case <-ctx.Done():
+					if dropped := len(d.queue); dropped > 0 {
+						log.Printf("Webhook %q stopped with %d undelivered events", d.Name, dropped)
+					}
+					return
+				}
+			}
+		}(d)
+	}
+}
+
+// Wait blocks until the workers started by Start have returned.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// Observe queues a log entry for every destination whose filter matches it.
+func (n *Notifier) Observe(l models.Log) {
+	for _, d := range n.dests {
+		if d.Filter != nil && d.Filter.Matches(l) {
+			n.enqueue(d, Payload{Event: EventLog, Log: &l})
+		}
+	}
+}
+
+// Notify queues an alert event for every destination subscribed to alerts.
+func (n *Notifier) Notify(ev alerting.Event) {
+	for _, d := range n.dests {
+		if d.Alerts {
+			n.enqueue(d, Payload{Event: EventAlert, Log: ev.Log, Rule: &ev.Rule, Alert: &ev.Alert})
+		}
+	}
+}
+
+// Dropped returns the number of failed deliveries that were not recorded
+// because the dead-letter queue was full.
+func (n *Notifier) Dropped() int64 {
+	return n.dropped.Load()
+}
+
+// DeadLetters returns the most recent failed deliveries.
+func (n *Notifier) DeadLetters(limit int) ([]DeadLetter, error) {
+	return n.deadLetters.ListDeadLetters(limit)
+}
+
+func (n *Notifier) enqueue(d *destination, p Payload) {
+	p.Destination = d.Name
+	p.Timestamp = time.Now().UTC()
+	select {
+	case d.queue <- p:
+	default:
+		n.deadLetter(d, p, nil, 0, errors.New("queue full"))
+	}
+}
+
+// deliver sends p, retrying with exponential backoff on network errors,
+// 429 and 5xx responses.
+func (n *Notifier) deliver(ctx context.Context, d *destination, p Payload) {
+	body, err := d.render(p)
+	if err != nil {
+		n.deadLetter(d, p, body, 0, err)
+		return
+	}
+
+	id := deliveryID()
+	backoff := time.Duration(d.Backoff)
+	for attempt := 1; ; attempt++ {
+		if err := d.limiter.wait(ctx); err != nil {
+			return
+		}
+		retry, wait, err := n.send(ctx, d, p.Event, id, body)
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !retry || attempt == d.MaxAttempts {
+			n.deadLetter(d, p, body, attempt, err)
+			return
+		}
+		if sleep(ctx, max(backoff, wait)) != nil {
+			return
+		}
+		backoff = min(backoff*2, MaxBackoff)
+	}
+}
+
+// send makes one delivery attempt. It reports whether a failure is worth
+// retrying and how long the receiver asked us to wait.
+func (n *Notifier) send(ctx context.Context, d *destination, event, id string, body []byte) (retry bool, wait time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(d.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golog-webhook")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderTimestamp, ts)
+	if d.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.Secret, ts, body))
+	}
+	for k, v := range d.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+	err = fmt.Errorf("unexpected status %s", resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		secs, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return true, time.Duration(secs) * time.Second, err
+	}
+	return resp.StatusCode >= 500, 0, err
+}
+
+func (d *destination) render(p Payload) ([]byte, error) {
+	if d.tmpl == nil {
+		return json.Marshal(p)
+	}
+	var buf bytes.Buffer
+	if err := d.tmpl.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return buf.Bytes(), errors.New("template did not produce valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// deadLetter queues a failed delivery for the dead-letter writer, or drops
+// and counts it when the queue is full. body is the rendered request body,
+// or nil to store the payload instead.
+func (n *Notifier) deadLetter(d *destination, p Payload, body []byte, attempts int, cause error) {
+	if body == nil {
+		body, _ = json.Marshal(p)
+	}
+	select {
+	case n.dead <- DeadLetter{
+		Destination: d.Name,
+		URL:         d.URL,
+		Event:       p.Event,
+		Payload:     string(body),
+		Error:       cause.Error(),
+		Attempts:    attempts,
+		CreatedAt:   time.Now().UTC(),
+	}:
+	default:
+		n.dropped.Add(1)
+	}
+}
+
+// record logs a failed delivery and stores it.
+func (n *Notifier) record(d DeadLetter) {
+	log.Printf("Webhook %q failed after %d attempts: %s", d.Destination, d.Attempts, d.Error)
+	if err := n.deadLetters.InsertDeadLetter(d); err != nil {
+		log.Printf("Error recording webhook dead letter: %v", err)
+	}
+}
+
+// Sign returns the X-Golog-Signature value for a request body: the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with secret. Receivers should
+// recompute it and reject stale timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
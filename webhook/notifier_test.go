@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstgnz/golog/alerting"
+	"github.com/mstgnz/golog/models"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a local webhook endpoint answering with the given statuses in
+// turn, repeating the last one.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, received{header: req.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status = r.statuses[0]
+			if len(r.statuses) > 1 {
+				r.statuses = r.statuses[1:]
+			}
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func startNotifier(t *testing.T, dests ...Destination) (*Notifier, *MemoryDeadLetters) {
+	t.Helper()
+	dl := NewMemoryDeadLetters()
+	n, err := NewNotifier(dests, dl)
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		n.Wait()
+	})
+	return n, dl
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAlertDeliveryIsSigned(t *testing.T) {
+	recv := newReceiver(t)
+	n, _ := startNotifier(t, Destination{Name: "oncall", URL: recv.URL, Secret: "s3cret", Alerts: true})
+
+	logEntry := models.Log{ID: 9, Level: "ERROR", Type: "DATABASE", Message: "connection refused"}
+	n.Notify(alerting.Event{
+		Rule:  alerting.Rule{ID: 1, Name: "database errors"},
+		Alert: alerting.Alert{ID: 3, RuleName: "database errors", State: alerting.StateFiring, Count: 21},
+		Log:   &logEntry,
+	})
+	eventually(t, "delivery", func() bool { return recv.count() == 1 })
+
+	req := recv.requests[0]
+	if got, want := req.header.Get(HeaderSignature), Sign("s3cret", req.header.Get(HeaderTimestamp), req.body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if req.header.Get(HeaderEvent) != EventAlert || req.header.Get(HeaderDelivery) == "" {
+		t.Errorf("headers = %v", req.header)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(req.body, &p); err != nil {
+		t.Fatalf("body is not a payload: %v", err)
+	}
+	if p.Event != EventAlert || p.Destination != "oncall" || p.Alert.Count != 21 || p.Rule.Name != "database errors" || p.Log.ID != 9 {
+		t.Errorf("payload = %s", req.body)
+	}
+}
+
+func TestLogFilterAndTemplate(t *testing.T) {
+	recv := newReceiver(t)
+	n, _ := startNotifier(t, Destination{
+		Name:     "chat",
+		URL:      recv.URL,
+		Filter:   &models.LogFilter{Levels: []string{"ERROR"}},
+		Template: `{"text": {{json .Log.Message}}, "source": "{{.Destination}}"}`,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+
+	n.Observe(models.Log{Level: "INFO", Message: "ignored"})
+	n.Observe(models.Log{Level: "ERROR", Message: `disk "data" full`})
+	n.Notify(alerting.Event{Rule: alerting.Rule{Name: "not subscribed"}})
+	eventually(t, "delivery", func() bool { return recv.count() == 1 })
+	time.Sleep(20 * time.Millisecond)
+
+	if recv.count() != 1 {
+		t.Fatalf("got %d requests, want only the matching log", recv.count())
+	}
+	req := recv.requests[0]
+	if string(req.body) != `{"text": "disk \"data\" full", "source": "chat"}` {
+		t.Errorf("body = %s", req.body)
+	}
+	if req.header.Get("Authorization") != "Bearer token" || req.header.Get(HeaderSignature) != "" {
+		t.Errorf("headers = %v", req.header)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int
+		wantAttempts int // 0 when the delivery succeeds
+	}{
+		{"recovers after server errors", []int{503, 500, 200}, 3, 0},
+		{"gives up after max attempts", []int{500}, 3, 3},
+		{"rate limited", []int{429, 204}, 2, 0},
+		{"client error is not retried", []int{400}, 1, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recv := newReceiver(t, tc.statuses...)
+			n, dl := startNotifier(t, Destination{
+				Name: "flaky", URL: recv.URL, Alerts: true,
+				MaxAttempts: 3, Backoff: models.Duration(time.Millisecond),
+			})
+			n.Notify(alerting.Event{Rule: alerting.Rule{Name: "r"}})
+
+			eventually(t, "requests", func() bool { return recv.count() == tc.wantRequests })
+			time.Sleep(20 * time.Millisecond)
+			if recv.count() != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", recv.count(), tc.wantRequests)
+			}
+
+			letters, _ := dl.ListDeadLetters(0)
+			if tc.wantAttempts == 0 {
+				if len(letters) != 0 {
+					t.Errorf("dead letters = %+v, want none", letters)
+				}
+				return
+			}
+			if len(letters) != 1 || letters[0].Attempts != tc.wantAttempts || letters[0].Destination != "flaky" || letters[0].Event != EventAlert {
+				t.Errorf("dead letters = %+v", letters)
+			}
+		})
+	}
+}
+
+func TestInvalidTemplateOutputIsDeadLettered(t *testing.T) {
+	recv := newReceiver(t)
+	n, dl := startNotifier(t, Destination{Name: "bad", URL: recv.URL, Alerts: true, Template: `{"text": {{.Rule.Name}}}`})
+	n.Notify(alerting.Event{Rule: alerting.Rule{Name: "unquoted"}})
+
+	eventually(t, "dead letter", func() bool {
+		letters, _ := dl.ListDeadLetters(0)
+		return len(letters) == 1
+	})
+	letters, _ := dl.ListDeadLetters(0)
+	if letters[0].Attempts != 0 || letters[0].Payload != `{"text": unquoted}` || recv.count() != 0 {
+		t.Errorf("dead letter = %+v, requests = %d", letters[0], recv.count())
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	dl := NewMemoryDeadLetters()
+	// Not started, so nothing drains the queue.
+	n, err := NewNotifier([]Destination{{Name: "slow", URL: "http://127.0.0.1:1", Alerts: true, QueueSize: 1}}, dl)
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+	n.Notify(alerting.Event{})
+	n.Notify(alerting.Event{})
+	if d := <-n.dead; d.Error != "queue full" || d.Destination != "slow" {
+		t.Errorf("dead letter = %+v", d)
+	}
+
+	// Nothing records dead letters either, so once their queue is full too
+	// further events are dropped without blocking.
+	for i := 0; i < deadLetterQueueSize+3; i++ {
+		n.Notify(alerting.Event{})
+	}
+	if got := n.Dropped(); got != 3 {
+		t.Errorf("Dropped() = %d, want 3", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n.Start(ctx)
+	n.Wait()
+	if letters, _ := dl.ListDeadLetters(0); len(letters) == 0 {
+		t.Error("queued dead letters were not recorded on shutdown")
+	}
+}
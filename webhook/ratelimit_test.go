@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	l := newLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("reserve() %d within burst = %v, want 0", i, d)
+		}
+	}
+	if d := l.reserve(); d != 500*time.Millisecond {
+		t.Errorf("reserve() after burst = %v, want 500ms at 2/s", d)
+	}
+	if d := l.reserve(); d != time.Second {
+		t.Errorf("second reserve() after burst = %v, want 1s", d)
+	}
+
+	// Tokens refill over time but never above the burst size.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("reserve() %d after refill = %v, want 0", i, d)
+		}
+	}
+	if d := l.reserve(); d == 0 {
+		t.Error("refill exceeded the burst size")
+	}
+}